@@ -0,0 +1,67 @@
+package admin
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"code.cloudfoundry.org/lager"
+)
+
+type reloader interface {
+	Reload(pathToServicesConfig string) error
+}
+
+// ReloadHandler re-reads the services config so that new plans show up in
+// the catalog without restarting the broker.
+type ReloadHandler struct {
+	logger     lager.Logger
+	services   reloader
+	configPath string
+	username   string
+	password   string
+}
+
+func NewReloadHandler(logger lager.Logger, services reloader, configPath, username, password string) *ReloadHandler {
+	return &ReloadHandler{
+		logger:     logger.Session("admin-reload"),
+		services:   services,
+		configPath: configPath,
+		username:   username,
+		password:   password,
+	}
+}
+
+func (h *ReloadHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !authorized(r, h.username, h.password) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="k8sbroker admin"`)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	err := h.services.Reload(h.configPath)
+	if err != nil {
+		h.logger.Error("failed-to-reload-services", err, lager.Data{"path": h.configPath})
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.logger.Info("reloaded-services", lager.Data{"path": h.configPath})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func authorized(r *http.Request, expectedUsername, expectedPassword string) bool {
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		return false
+	}
+
+	usernameMatches := subtle.ConstantTimeCompare([]byte(username), []byte(expectedUsername)) == 1
+	passwordMatches := subtle.ConstantTimeCompare([]byte(password), []byte(expectedPassword)) == 1
+	return usernameMatches && passwordMatches
+}
@@ -0,0 +1,77 @@
+package admin_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	"code.cloudfoundry.org/k8sbroker/admin"
+	"code.cloudfoundry.org/k8sbroker/k8sbroker/k8sbroker_fake"
+	"code.cloudfoundry.org/lager/lagertest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ReloadHandler", func() {
+	var (
+		fakeServices       *k8sbroker_fake.FakeServices
+		handler            http.Handler
+		method             string
+		username, password string
+		recorder           *httptest.ResponseRecorder
+	)
+
+	BeforeEach(func() {
+		fakeServices = &k8sbroker_fake.FakeServices{}
+		handler = admin.NewReloadHandler(lagertest.NewTestLogger("test-admin"), fakeServices, "/path/to/services.json", "admin", "secret")
+		method = "POST"
+		username, password = "admin", "secret"
+	})
+
+	JustBeforeEach(func() {
+		recorder = httptest.NewRecorder()
+		request := httptest.NewRequest(method, "/admin/reload", nil)
+		request.SetBasicAuth(username, password)
+		handler.ServeHTTP(recorder, request)
+	})
+
+	It("reloads the services config", func() {
+		Expect(recorder.Code).To(Equal(http.StatusNoContent))
+		Expect(fakeServices.ReloadCallCount()).To(Equal(1))
+		Expect(fakeServices.ReloadArgsForCall(0)).To(Equal("/path/to/services.json"))
+	})
+
+	Context("when the reload fails", func() {
+		BeforeEach(func() {
+			fakeServices.ReloadReturns(errors.New("bad-config"))
+		})
+
+		It("reports the error", func() {
+			Expect(recorder.Code).To(Equal(http.StatusInternalServerError))
+			Expect(recorder.Body.String()).To(ContainSubstring("bad-config"))
+		})
+	})
+
+	Context("when the credentials are wrong", func() {
+		BeforeEach(func() {
+			password = "broker-password"
+		})
+
+		It("is unauthorized", func() {
+			Expect(recorder.Code).To(Equal(http.StatusUnauthorized))
+			Expect(fakeServices.ReloadCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("when the method is not POST", func() {
+		BeforeEach(func() {
+			method = "GET"
+		})
+
+		It("is not allowed", func() {
+			Expect(recorder.Code).To(Equal(http.StatusMethodNotAllowed))
+			Expect(recorder.Header().Get("Allow")).To(Equal("POST"))
+			Expect(fakeServices.ReloadCallCount()).To(Equal(0))
+		})
+	})
+})
@@ -0,0 +1,89 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"code.cloudfoundry.org/k8sbroker/k8sbroker"
+	"code.cloudfoundry.org/lager"
+	"github.com/pivotal-cf/brokerapi"
+)
+
+const bindingsPathPrefix = "/admin/service_instances/"
+
+type bindingLister interface {
+	ListBindings(ctx context.Context, instanceID string) ([]k8sbroker.BindingSummary, error)
+}
+
+// BindingsResponse is the body of a successful GET.
+type BindingsResponse struct {
+	Bindings []k8sbroker.BindingSummary `json:"bindings"`
+}
+
+// BindingsHandler serves GET /admin/service_instances/:id/bindings, listing
+// an instance's bindings along with the phase of each binding's claim.
+type BindingsHandler struct {
+	logger   lager.Logger
+	bindings bindingLister
+	username string
+	password string
+}
+
+func NewBindingsHandler(logger lager.Logger, bindings bindingLister, username, password string) *BindingsHandler {
+	return &BindingsHandler{
+		logger:   logger.Session("admin-bindings"),
+		bindings: bindings,
+		username: username,
+		password: password,
+	}
+}
+
+func (h *BindingsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	instanceID, ok := bindingsInstanceID(r.URL.Path)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !authorized(r, h.username, h.password) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="k8sbroker admin"`)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	bindings, err := h.bindings.ListBindings(r.Context(), instanceID)
+	if err == brokerapi.ErrInstanceDoesNotExist {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed-to-list-bindings", err, lager.Data{"instance_id": instanceID})
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(BindingsResponse{Bindings: bindings})
+	if err != nil {
+		h.logger.Error("failed-to-encode-bindings", err)
+	}
+}
+
+func bindingsInstanceID(path string) (string, bool) {
+	if !strings.HasPrefix(path, bindingsPathPrefix) || !strings.HasSuffix(path, "/bindings") {
+		return "", false
+	}
+	instanceID := strings.TrimSuffix(strings.TrimPrefix(path, bindingsPathPrefix), "/bindings")
+	if instanceID == "" || strings.Contains(instanceID, "/") {
+		return "", false
+	}
+	return instanceID, true
+}
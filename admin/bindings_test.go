@@ -0,0 +1,129 @@
+package admin_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	"code.cloudfoundry.org/k8sbroker/admin"
+	"code.cloudfoundry.org/k8sbroker/k8sbroker"
+	"code.cloudfoundry.org/lager/lagertest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/brokerapi"
+)
+
+type fakeBindingLister struct {
+	bindings   []k8sbroker.BindingSummary
+	err        error
+	instanceID string
+}
+
+func (f *fakeBindingLister) ListBindings(ctx context.Context, instanceID string) ([]k8sbroker.BindingSummary, error) {
+	f.instanceID = instanceID
+	return f.bindings, f.err
+}
+
+var _ = Describe("BindingsHandler", func() {
+	var (
+		lister             *fakeBindingLister
+		handler            http.Handler
+		method, path       string
+		username, password string
+		recorder           *httptest.ResponseRecorder
+	)
+
+	BeforeEach(func() {
+		lister = &fakeBindingLister{
+			bindings: []k8sbroker.BindingSummary{{
+				BindingID:  "some-binding-id",
+				AppGUID:    "some-app-guid",
+				ClaimName:  "some-claim",
+				Namespace:  "some-namespace",
+				AccessMode: "ReadWriteMany",
+				Phase:      "Bound",
+			}},
+		}
+		handler = admin.NewBindingsHandler(lagertest.NewTestLogger("test-admin"), lister, "admin", "secret")
+		method = "GET"
+		path = "/admin/service_instances/some-instance-id/bindings"
+		username, password = "admin", "secret"
+	})
+
+	JustBeforeEach(func() {
+		recorder = httptest.NewRecorder()
+		request := httptest.NewRequest(method, path, nil)
+		request.SetBasicAuth(username, password)
+		handler.ServeHTTP(recorder, request)
+	})
+
+	It("lists the bindings of the instance", func() {
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		Expect(recorder.Header().Get("Content-Type")).To(Equal("application/json"))
+		Expect(lister.instanceID).To(Equal("some-instance-id"))
+		Expect(recorder.Body.String()).To(MatchJSON(`{
+			"bindings": [{
+				"binding_id": "some-binding-id",
+				"app_guid": "some-app-guid",
+				"claim_name": "some-claim",
+				"namespace": "some-namespace",
+				"access_mode": "ReadWriteMany",
+				"phase": "Bound"
+			}]
+		}`))
+	})
+
+	Context("when the instance does not exist", func() {
+		BeforeEach(func() {
+			lister.err = brokerapi.ErrInstanceDoesNotExist
+		})
+
+		It("is not found", func() {
+			Expect(recorder.Code).To(Equal(http.StatusNotFound))
+		})
+	})
+
+	Context("when listing fails", func() {
+		BeforeEach(func() {
+			lister.err = errors.New("list-failed")
+		})
+
+		It("reports the error", func() {
+			Expect(recorder.Code).To(Equal(http.StatusInternalServerError))
+			Expect(recorder.Body.String()).To(ContainSubstring("list-failed"))
+		})
+	})
+
+	Context("when the path is not an instance's bindings", func() {
+		BeforeEach(func() {
+			path = "/admin/service_instances/some-instance-id"
+		})
+
+		It("is not found", func() {
+			Expect(recorder.Code).To(Equal(http.StatusNotFound))
+			Expect(lister.instanceID).To(BeEmpty())
+		})
+	})
+
+	Context("when the credentials are wrong", func() {
+		BeforeEach(func() {
+			password = "broker-password"
+		})
+
+		It("is unauthorized", func() {
+			Expect(recorder.Code).To(Equal(http.StatusUnauthorized))
+		})
+	})
+
+	Context("when the method is not GET", func() {
+		BeforeEach(func() {
+			method = "DELETE"
+		})
+
+		It("is not allowed", func() {
+			Expect(recorder.Code).To(Equal(http.StatusMethodNotAllowed))
+			Expect(recorder.Header().Get("Allow")).To(Equal("GET"))
+		})
+	})
+})
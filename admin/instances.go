@@ -0,0 +1,69 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/service-broker-store/brokerstore"
+)
+
+type instanceLister interface {
+	ListInstances() (map[string]brokerstore.ServiceInstance, error)
+}
+
+// InstancesDescription is returned with every listing, as the broker's store
+// cannot enumerate its instances and the list may be incomplete.
+const InstancesDescription = "Instances are found through the persistent volumes and claims labelled as managed by the broker. " +
+	"Dynamically provisioned instances that were never bound and instances still provisioning asynchronously are not listed."
+
+// InstancesResponse is the body of a successful GET, keyed by instance ID.
+type InstancesResponse struct {
+	ServiceInstances map[string]brokerstore.ServiceInstance `json:"service_instances"`
+	Description      string                                 `json:"description"`
+}
+
+// InstancesHandler lists the provisioned service instances so that operators
+// do not have to query the broker's store directly.
+type InstancesHandler struct {
+	logger    lager.Logger
+	instances instanceLister
+	username  string
+	password  string
+}
+
+func NewInstancesHandler(logger lager.Logger, instances instanceLister, username, password string) *InstancesHandler {
+	return &InstancesHandler{
+		logger:    logger.Session("admin-instances"),
+		instances: instances,
+		username:  username,
+		password:  password,
+	}
+}
+
+func (h *InstancesHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !authorized(r, h.username, h.password) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="k8sbroker admin"`)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	instances, err := h.instances.ListInstances()
+	if err != nil {
+		h.logger.Error("failed-to-list-instances", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(InstancesResponse{ServiceInstances: instances, Description: InstancesDescription})
+	if err != nil {
+		h.logger.Error("failed-to-encode-instances", err)
+	}
+}
@@ -0,0 +1,123 @@
+package admin_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	"code.cloudfoundry.org/k8sbroker/admin"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/service-broker-store/brokerstore"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type fakeInstanceLister struct {
+	instances map[string]brokerstore.ServiceInstance
+	err       error
+}
+
+func (f *fakeInstanceLister) ListInstances() (map[string]brokerstore.ServiceInstance, error) {
+	return f.instances, f.err
+}
+
+var _ = Describe("InstancesHandler", func() {
+	var (
+		lister             *fakeInstanceLister
+		handler            http.Handler
+		method             string
+		username, password string
+		recorder           *httptest.ResponseRecorder
+	)
+
+	BeforeEach(func() {
+		lister = &fakeInstanceLister{
+			instances: map[string]brokerstore.ServiceInstance{
+				"some-instance-id": {
+					ServiceID:          "some-service-id",
+					PlanID:             "some-plan-id",
+					OrganizationGUID:   "some-org-guid",
+					SpaceGUID:          "some-space-guid",
+					ServiceFingerPrint: map[string]interface{}{"Name": "some-instance-id"},
+				},
+			},
+		}
+		handler = admin.NewInstancesHandler(lagertest.NewTestLogger("test-admin"), lister, "admin", "secret")
+		method = "GET"
+		username, password = "admin", "secret"
+	})
+
+	JustBeforeEach(func() {
+		recorder = httptest.NewRecorder()
+		request := httptest.NewRequest(method, "/v2/service_instances", nil)
+		request.SetBasicAuth(username, password)
+		handler.ServeHTTP(recorder, request)
+	})
+
+	It("lists the instances keyed by ID", func() {
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		Expect(recorder.Header().Get("Content-Type")).To(Equal("application/json"))
+		Expect(recorder.Body.String()).To(MatchJSON(`{
+			"service_instances": {
+				"some-instance-id": {
+					"service_id": "some-service-id",
+					"plan_id": "some-plan-id",
+					"organization_guid": "some-org-guid",
+					"space_guid": "some-space-guid",
+					"fingerprint": {"Name": "some-instance-id"}
+				}
+			},
+			"description": "Instances are found through the persistent volumes and claims labelled as managed by the broker. Dynamically provisioned instances that were never bound and instances still provisioning asynchronously are not listed."
+		}`))
+
+		var response admin.InstancesResponse
+		Expect(json.Unmarshal(recorder.Body.Bytes(), &response)).To(Succeed())
+		Expect(response.ServiceInstances).To(HaveKey("some-instance-id"))
+	})
+
+	Context("when there are no instances", func() {
+		BeforeEach(func() {
+			lister.instances = map[string]brokerstore.ServiceInstance{}
+		})
+
+		It("returns an empty object", func() {
+			var response admin.InstancesResponse
+			Expect(json.Unmarshal(recorder.Body.Bytes(), &response)).To(Succeed())
+			Expect(response.ServiceInstances).To(BeEmpty())
+			Expect(response.Description).To(Equal(admin.InstancesDescription))
+		})
+	})
+
+	Context("when listing fails", func() {
+		BeforeEach(func() {
+			lister.err = errors.New("list-failed")
+		})
+
+		It("reports the error", func() {
+			Expect(recorder.Code).To(Equal(http.StatusInternalServerError))
+			Expect(recorder.Body.String()).To(ContainSubstring("list-failed"))
+		})
+	})
+
+	Context("when the credentials are wrong", func() {
+		BeforeEach(func() {
+			password = "broker-password"
+		})
+
+		It("is unauthorized", func() {
+			Expect(recorder.Code).To(Equal(http.StatusUnauthorized))
+		})
+	})
+
+	Context("when the method is not GET", func() {
+		BeforeEach(func() {
+			method = "DELETE"
+		})
+
+		It("is not allowed", func() {
+			Expect(recorder.Code).To(Equal(http.StatusMethodNotAllowed))
+			Expect(recorder.Header().Get("Allow")).To(Equal("GET"))
+		})
+	})
+})
@@ -0,0 +1,96 @@
+package audit
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	"code.cloudfoundry.org/k8sbroker/requestid"
+	"code.cloudfoundry.org/lager"
+)
+
+// AuditLogger records every request to the wrapped handler, including the
+// basic-auth user and a hash of the body, so that provisions and
+// deprovisions can be traced to the caller.
+type AuditLogger struct {
+	logger lager.Logger
+	clock  clock.Clock
+	next   http.Handler
+}
+
+func New(logger lager.Logger, clock clock.Clock, next http.Handler) *AuditLogger {
+	return &AuditLogger{
+		logger: logger.Session("audit"),
+		clock:  clock,
+		next:   next,
+	}
+}
+
+func (a *AuditLogger) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var body []byte
+	if r.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(r.Body)
+		if err != nil {
+			a.logger.Error("failed-to-read-body", err)
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	user, _, _ := r.BasicAuth()
+	data := lager.Data{
+		"method":      r.Method,
+		"path":        r.URL.Path,
+		"user":        user,
+		"body_sha256": fmt.Sprintf("%x", sha256.Sum256(body)),
+		"request_id":  requestid.FromContext(r.Context()),
+		"timestamp":   a.clock.Now().UTC().Format(time.RFC3339Nano),
+	}
+
+	recorder := &statusRecorder{ResponseWriter: w}
+	defer func() {
+		if p := recover(); p != nil {
+			data["status"] = http.StatusInternalServerError
+			data["panic"] = fmt.Sprint(p)
+			a.logger.Info("request", data)
+			panic(p)
+		}
+
+		data["status"] = recorder.Status()
+		a.logger.Info("request", data)
+	}()
+
+	a.next.ServeHTTP(recorder, r)
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	if s.status == 0 {
+		s.status = status
+	}
+	s.ResponseWriter.WriteHeader(status)
+}
+
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	return s.ResponseWriter.Write(b)
+}
+
+// Status is the status code written, which is 200 when the handler wrote
+// nothing.
+func (s *statusRecorder) Status() int {
+	if s.status == 0 {
+		return http.StatusOK
+	}
+	return s.status
+}
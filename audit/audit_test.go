@@ -0,0 +1,103 @@
+package audit_test
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/k8sbroker/audit"
+	"code.cloudfoundry.org/k8sbroker/requestid"
+	"code.cloudfoundry.org/lager/lagertest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("AuditLogger", func() {
+	var (
+		logger      *lagertest.TestLogger
+		fakeClock   *fakeclock.FakeClock
+		next        http.HandlerFunc
+		request     *http.Request
+		recorder    *httptest.ResponseRecorder
+		body        string
+		handledBody string
+		panicked    interface{}
+	)
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("test-broker")
+		fakeClock = fakeclock.NewFakeClock(time.Date(2019, time.January, 2, 3, 4, 5, 0, time.UTC))
+		body = `{"service_id": "some-service-id", "plan_id": "some-plan-id"}`
+		request = httptest.NewRequest("PUT", "/v2/service_instances/some-instance-id", strings.NewReader(body))
+		request.SetBasicAuth("some-user", "some-password")
+		request = request.WithContext(requestid.NewContext(request.Context(), "some-request-id"))
+		next = func(w http.ResponseWriter, r *http.Request) {
+			b, _ := ioutil.ReadAll(r.Body)
+			handledBody = string(b)
+			w.WriteHeader(http.StatusCreated)
+		}
+		panicked = nil
+	})
+
+	JustBeforeEach(func() {
+		recorder = httptest.NewRecorder()
+		func() {
+			defer func() { panicked = recover() }()
+			audit.New(logger, fakeClock, next).ServeHTTP(recorder, request)
+		}()
+	})
+
+	It("logs the request to the audit session", func() {
+		Expect(logger.LogMessages()).To(Equal([]string{"test-broker.audit.request"}))
+		data := logger.Logs()[0].Data
+		Expect(data).To(HaveKeyWithValue("method", "PUT"))
+		Expect(data).To(HaveKeyWithValue("path", "/v2/service_instances/some-instance-id"))
+		Expect(data).To(HaveKeyWithValue("user", "some-user"))
+		Expect(data).To(HaveKeyWithValue("body_sha256", fmt.Sprintf("%x", sha256.Sum256([]byte(body)))))
+		Expect(data).To(HaveKeyWithValue("request_id", "some-request-id"))
+		Expect(data).To(HaveKeyWithValue("timestamp", "2019-01-02T03:04:05Z"))
+		Expect(data).To(HaveKeyWithValue("status", http.StatusCreated))
+	})
+
+	It("passes the body on to the handler", func() {
+		Expect(handledBody).To(Equal(body))
+		Expect(recorder.Code).To(Equal(http.StatusCreated))
+	})
+
+	It("does not log the password", func() {
+		Expect(logger.Buffer()).NotTo(gbytes.Say("some-password"))
+	})
+
+	Context("when the handler writes a body without a status", func() {
+		BeforeEach(func() {
+			next = func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("{}"))
+			}
+		})
+
+		It("logs a 200", func() {
+			Expect(logger.Logs()[0].Data).To(HaveKeyWithValue("status", http.StatusOK))
+		})
+	})
+
+	Context("when the handler panics", func() {
+		BeforeEach(func() {
+			next = func(w http.ResponseWriter, r *http.Request) {
+				panic("boom")
+			}
+		})
+
+		It("logs the request as a 500 and panics again", func() {
+			Expect(panicked).To(Equal("boom"))
+			Expect(logger.LogMessages()).To(Equal([]string{"test-broker.audit.request"}))
+			Expect(logger.Logs()[0].Data).To(HaveKeyWithValue("status", http.StatusInternalServerError))
+			Expect(logger.Logs()[0].Data).To(HaveKeyWithValue("panic", "boom"))
+		})
+	})
+})
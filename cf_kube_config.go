@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"k8s.io/client-go/rest"
+)
+
+// cfKubeCredentials are the credentials of the service bound to the broker
+// app that give it access to the Kubernetes API.
+type cfKubeCredentials struct {
+	Host   string `json:"host"`
+	Token  string `json:"token"`
+	CACert string `json:"ca_cert"`
+}
+
+type vcapService struct {
+	Name        string            `json:"name"`
+	Credentials cfKubeCredentials `json:"credentials"`
+}
+
+// kubeConfigFromVCAPServices builds the kube config from the credentials of
+// the named service in VCAP_SERVICES, for a broker pushed as a CF app.
+func kubeConfigFromVCAPServices(vcapServices string, serviceName string) (*rest.Config, error) {
+	if vcapServices == "" {
+		return nil, errors.New("VCAP_SERVICES is not set")
+	}
+
+	var servicesByLabel map[string][]vcapService
+	err := json.Unmarshal([]byte(vcapServices), &servicesByLabel)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse VCAP_SERVICES: %s", err.Error())
+	}
+
+	for _, services := range servicesByLabel {
+		for _, service := range services {
+			if service.Name != serviceName {
+				continue
+			}
+
+			credentials := service.Credentials
+			if credentials.Host == "" || credentials.Token == "" {
+				return nil, fmt.Errorf("service %s in VCAP_SERVICES needs host and token credentials", serviceName)
+			}
+			return &rest.Config{
+				Host:        credentials.Host,
+				BearerToken: credentials.Token,
+				TLSClientConfig: rest.TLSClientConfig{
+					CAData: []byte(credentials.CACert),
+				},
+			}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("service %s not found in VCAP_SERVICES", serviceName)
+}
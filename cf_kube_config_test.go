@@ -0,0 +1,52 @@
+package main
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("kubeConfigFromVCAPServices", func() {
+	var vcapServices string
+
+	BeforeEach(func() {
+		vcapServices = `{
+			"p.mysql": [{"name": "broker-db", "credentials": {"username": "some-user"}}],
+			"user-provided": [{
+				"name": "k8s",
+				"credentials": {
+					"host": "https://10.0.0.1:6443",
+					"token": "some-token",
+					"ca_cert": "-----BEGIN CERTIFICATE-----\nsome-ca\n-----END CERTIFICATE-----"
+				}
+			}]
+		}`
+	})
+
+	It("builds the kube config from the named service's credentials", func() {
+		config, err := kubeConfigFromVCAPServices(vcapServices, "k8s")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(config.Host).To(Equal("https://10.0.0.1:6443"))
+		Expect(config.BearerToken).To(Equal("some-token"))
+		Expect(string(config.TLSClientConfig.CAData)).To(Equal("-----BEGIN CERTIFICATE-----\nsome-ca\n-----END CERTIFICATE-----"))
+	})
+
+	It("errors when the service is not bound", func() {
+		_, err := kubeConfigFromVCAPServices(vcapServices, "other-k8s")
+		Expect(err).To(MatchError("service other-k8s not found in VCAP_SERVICES"))
+	})
+
+	It("errors when the service has no token", func() {
+		_, err := kubeConfigFromVCAPServices(vcapServices, "broker-db")
+		Expect(err).To(MatchError("service broker-db in VCAP_SERVICES needs host and token credentials"))
+	})
+
+	It("errors when VCAP_SERVICES is not set", func() {
+		_, err := kubeConfigFromVCAPServices("", "k8s")
+		Expect(err).To(MatchError("VCAP_SERVICES is not set"))
+	})
+
+	It("errors when VCAP_SERVICES is not JSON", func() {
+		_, err := kubeConfigFromVCAPServices("{", "k8s")
+		Expect(err).To(MatchError(ContainSubstring("cannot parse VCAP_SERVICES")))
+	})
+})
@@ -0,0 +1,91 @@
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/service-broker-store/brokerstore"
+)
+
+const (
+	StatusOK       = "ok"
+	StatusDegraded = "degraded"
+)
+
+// pinger is implemented by stores that can be checked without reloading
+// their state.
+type pinger interface {
+	Ping() error
+}
+
+// CSIBackends checks the CSI endpoints of the services, keyed by service ID.
+type CSIBackends interface {
+	ConnectionHealthCheck() map[string]error
+}
+
+type Response struct {
+	Status      string            `json:"status"`
+	Store       string            `json:"store"`
+	CSIBackends map[string]string `json:"csi_backends,omitempty"`
+}
+
+type HealthHandler struct {
+	logger   lager.Logger
+	store    brokerstore.Store
+	backends CSIBackends
+}
+
+// NewHealthHandler checks the store and, unless backends is nil, the CSI
+// endpoints of the services on every request.
+func NewHealthHandler(logger lager.Logger, store brokerstore.Store, backends CSIBackends) *HealthHandler {
+	return &HealthHandler{
+		logger:   logger.Session("health"),
+		store:    store,
+		backends: backends,
+	}
+}
+
+func (h *HealthHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	response := Response{Status: StatusOK, Store: StatusOK}
+	status := http.StatusOK
+
+	err := h.checkStore()
+	if err != nil {
+		h.logger.Error("store-unhealthy", err)
+		response = Response{Status: StatusDegraded, Store: err.Error()}
+		status = http.StatusServiceUnavailable
+	}
+
+	if h.backends != nil {
+		for serviceID, err := range h.backends.ConnectionHealthCheck() {
+			if response.CSIBackends == nil {
+				response.CSIBackends = map[string]string{}
+			}
+			response.CSIBackends[serviceID] = StatusOK
+			if err != nil {
+				h.logger.Error("csi-backend-unhealthy", err, lager.Data{"service_id": serviceID})
+				response.CSIBackends[serviceID] = err.Error()
+				response.Status = StatusDegraded
+				status = http.StatusServiceUnavailable
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *HealthHandler) checkStore() error {
+	if p, ok := h.store.(pinger); ok {
+		return p.Ping()
+	}
+	return h.store.Restore(h.logger)
+}
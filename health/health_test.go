@@ -0,0 +1,139 @@
+package health_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	"code.cloudfoundry.org/k8sbroker/health"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/service-broker-store/brokerstore/brokerstorefakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type fakePingStore struct {
+	*brokerstorefakes.FakeStore
+	pingErr error
+}
+
+func (s *fakePingStore) Ping() error {
+	return s.pingErr
+}
+
+type fakeCSIBackends map[string]error
+
+func (b fakeCSIBackends) ConnectionHealthCheck() map[string]error {
+	return b
+}
+
+var _ = Describe("HealthHandler", func() {
+	var (
+		fakeStore *brokerstorefakes.FakeStore
+		handler   http.Handler
+		method    string
+		recorder  *httptest.ResponseRecorder
+		response  health.Response
+	)
+
+	BeforeEach(func() {
+		fakeStore = &brokerstorefakes.FakeStore{}
+		handler = health.NewHealthHandler(lagertest.NewTestLogger("test-health"), fakeStore, nil)
+		method = "GET"
+		response = health.Response{}
+	})
+
+	JustBeforeEach(func() {
+		recorder = httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(method, "/healthz", nil))
+		if recorder.Code != http.StatusMethodNotAllowed {
+			Expect(json.Unmarshal(recorder.Body.Bytes(), &response)).To(Succeed())
+		}
+	})
+
+	It("reports ok", func() {
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		Expect(recorder.Header().Get("Content-Type")).To(Equal("application/json"))
+		Expect(response).To(Equal(health.Response{Status: "ok", Store: "ok"}))
+	})
+
+	It("checks the store", func() {
+		Expect(fakeStore.RestoreCallCount()).To(Equal(1))
+	})
+
+	Context("when the store fails", func() {
+		BeforeEach(func() {
+			fakeStore.RestoreReturns(errors.New("connection refused"))
+		})
+
+		It("reports degraded", func() {
+			Expect(recorder.Code).To(Equal(http.StatusServiceUnavailable))
+			Expect(response).To(Equal(health.Response{Status: "degraded", Store: "connection refused"}))
+		})
+	})
+
+	Context("when the store can be pinged", func() {
+		var pingStore *fakePingStore
+
+		BeforeEach(func() {
+			pingStore = &fakePingStore{FakeStore: fakeStore}
+			handler = health.NewHealthHandler(lagertest.NewTestLogger("test-health"), pingStore, nil)
+		})
+
+		It("pings rather than restoring", func() {
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(fakeStore.RestoreCallCount()).To(Equal(0))
+		})
+
+		Context("when the ping fails", func() {
+			BeforeEach(func() {
+				pingStore.pingErr = errors.New("timeout")
+			})
+
+			It("reports degraded", func() {
+				Expect(recorder.Code).To(Equal(http.StatusServiceUnavailable))
+				Expect(response).To(Equal(health.Response{Status: "degraded", Store: "timeout"}))
+			})
+		})
+	})
+
+	Context("when the services have CSI backends", func() {
+		var backends fakeCSIBackends
+
+		BeforeEach(func() {
+			backends = fakeCSIBackends{"csi-service-id": nil}
+			handler = health.NewHealthHandler(lagertest.NewTestLogger("test-health"), fakeStore, backends)
+		})
+
+		It("reports each backend", func() {
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(response).To(Equal(health.Response{Status: "ok", Store: "ok", CSIBackends: map[string]string{"csi-service-id": "ok"}}))
+		})
+
+		Context("when a backend cannot be reached", func() {
+			BeforeEach(func() {
+				backends["gone-service-id"] = errors.New("connection refused")
+			})
+
+			It("reports degraded", func() {
+				Expect(recorder.Code).To(Equal(http.StatusServiceUnavailable))
+				Expect(response).To(Equal(health.Response{
+					Status:      "degraded",
+					Store:       "ok",
+					CSIBackends: map[string]string{"csi-service-id": "ok", "gone-service-id": "connection refused"},
+				}))
+			})
+		})
+	})
+
+	Context("when the method is not GET", func() {
+		BeforeEach(func() {
+			method = "POST"
+		})
+
+		It("is not allowed", func() {
+			Expect(recorder.Code).To(Equal(http.StatusMethodNotAllowed))
+		})
+	})
+})
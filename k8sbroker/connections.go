@@ -0,0 +1,227 @@
+package k8sbroker
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// ErrConnection is a service whose CSI identity endpoint did not answer.
+type ErrConnection struct {
+	ServiceID string
+	ConnAddr  string
+	Err       error
+}
+
+func (e ErrConnection) Error() string {
+	return fmt.Sprintf("service %s: cannot reach CSI endpoint %s: %s", e.ServiceID, e.ConnAddr, e.Err.Error())
+}
+
+// ConnectionErrors lists every service TestAllConnections could not reach, in
+// catalog order.
+type ConnectionErrors []ErrConnection
+
+func (e ConnectionErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// TestAllConnections calls GetPluginInfo on the CSI identity endpoint of
+// every service with a connection_address, all at once, giving each timeout
+// to answer. Services without a connection_address are skipped.
+func (s *services) TestAllConnections(ctx context.Context, timeout time.Duration) error {
+	var errs ConnectionErrors
+	for _, target := range s.checkConnections(ctx, timeout) {
+		if target.Err != nil {
+			errs = append(errs, target)
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// connectionHealthCheckTimeout is how long each CSI endpoint has to answer a
+// health check.
+const connectionHealthCheckTimeout = 5 * time.Second
+
+// ConnectionHealthCheck checks the CSI identity endpoint of every service with
+// a connection_address like TestAllConnections, and returns each service's
+// error, nil for those that answered.
+func (s *services) ConnectionHealthCheck() map[string]error {
+	results := map[string]error{}
+	for _, target := range s.checkConnections(context.Background(), connectionHealthCheckTimeout) {
+		results[target.ServiceID] = target.Err
+	}
+	return results
+}
+
+// checkConnections calls GetPluginInfo on every service's CSI endpoint at
+// once and returns the outcome of each in catalog order.
+func (s *services) checkConnections(ctx context.Context, timeout time.Duration) []ErrConnection {
+	s.mutex.RLock()
+	var targets []ErrConnection
+	var creds []credentials.TransportCredentials
+	for _, service := range s.catalog.services {
+		if connAddr := s.catalog.connAddrs[service.ID]; connAddr != "" {
+			targets = append(targets, ErrConnection{ServiceID: service.ID, ConnAddr: connAddr})
+			creds = append(creds, s.catalog.connCredentials[service.ID])
+		}
+	}
+	s.mutex.RUnlock()
+
+	var wg sync.WaitGroup
+	for i := range targets {
+		wg.Add(1)
+		go func(target *ErrConnection, creds credentials.TransportCredentials) {
+			defer wg.Done()
+			_, target.Err = getPluginInfo(ctx, target.ConnAddr, creds, timeout)
+		}(&targets[i], creds[i])
+	}
+	wg.Wait()
+
+	return targets
+}
+
+// ErrDriverNameMismatch is a service whose CSI plugin reports a name other
+// than the service's driver_name.
+type ErrDriverNameMismatch struct {
+	ServiceID  string
+	DriverName string
+	PluginName string
+}
+
+func (e ErrDriverNameMismatch) Error() string {
+	return fmt.Sprintf("service %s: CSI plugin is %s, not driver_name %s", e.ServiceID, e.PluginName, e.DriverName)
+}
+
+// ValidateService checks that the service's CSI identity endpoint answers
+// GetPluginInfo within timeout with the service's driver_name. A service
+// without a connection_address has nothing to check; one without a
+// driver_name only has to answer.
+func (s *services) ValidateService(serviceID string, timeout time.Duration) error {
+	s.mutex.RLock()
+	var found bool
+	for _, service := range s.catalog.services {
+		if service.ID == serviceID {
+			found = true
+			break
+		}
+	}
+	connAddr := s.catalog.connAddrs[serviceID]
+	creds := s.catalog.connCredentials[serviceID]
+	driverName := s.catalog.serviceDriverNames[serviceID]
+	s.mutex.RUnlock()
+
+	if !found {
+		return fmt.Errorf("service %s not found", serviceID)
+	}
+	if connAddr == "" {
+		return nil
+	}
+
+	info, err := getPluginInfo(context.Background(), connAddr, creds, timeout)
+	if err != nil {
+		return ErrConnection{ServiceID: serviceID, ConnAddr: connAddr, Err: err}
+	}
+	if driverName != "" && info.GetName() != driverName {
+		return ErrDriverNameMismatch{ServiceID: serviceID, DriverName: driverName, PluginName: info.GetName()}
+	}
+	return nil
+}
+
+// getPluginInfo does not wait for the connection to be established: the call
+// fails as soon as the endpoint refuses it, and otherwise within timeout. The
+// connection is plaintext when creds is nil.
+func getPluginInfo(ctx context.Context, connAddr string, creds credentials.TransportCredentials, timeout time.Duration) (*csi.GetPluginInfoResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	transport := grpc.WithInsecure()
+	if creds != nil {
+		transport = grpc.WithTransportCredentials(creds)
+	}
+	conn, err := grpc.DialContext(ctx, connAddr, transport)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	return csi.NewIdentityClient(conn).GetPluginInfo(ctx, &csi.GetPluginInfoRequest{})
+}
+
+// ErrInvalidTLSConfig is a service whose TLS settings cannot be used to
+// connect to its CSI endpoint.
+type ErrInvalidTLSConfig struct {
+	ServiceID string
+	Reason    string
+}
+
+func (e ErrInvalidTLSConfig) Error() string {
+	return fmt.Sprintf("service %s: invalid TLS config: %s", e.ServiceID, e.Reason)
+}
+
+// readTransportCredentials returns the TLS credentials for the service's CSI
+// endpoint, or nil when none of its TLS paths are set. tls_cert_path and
+// tls_key_path go together and authenticate the broker; tls_ca_cert_path
+// verifies the endpoint, which is otherwise checked against the system roots.
+func readTransportCredentials(fs http.FileSystem, service Service) (credentials.TransportCredentials, error) {
+	if service.TLSCertPath == "" && service.TLSKeyPath == "" && service.TLSCACertPath == "" {
+		return nil, nil
+	}
+	if (service.TLSCertPath == "") != (service.TLSKeyPath == "") {
+		return nil, ErrInvalidTLSConfig{ServiceID: service.ID, Reason: "tls_cert_path and tls_key_path must be set together"}
+	}
+
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if service.TLSCertPath != "" {
+		certPEM, err := readFile(fs, service.TLSCertPath)
+		if err != nil {
+			return nil, ErrInvalidTLSConfig{ServiceID: service.ID, Reason: err.Error()}
+		}
+		keyPEM, err := readFile(fs, service.TLSKeyPath)
+		if err != nil {
+			return nil, ErrInvalidTLSConfig{ServiceID: service.ID, Reason: err.Error()}
+		}
+		cert, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			return nil, ErrInvalidTLSConfig{ServiceID: service.ID, Reason: err.Error()}
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	if service.TLSCACertPath != "" {
+		caPEM, err := readFile(fs, service.TLSCACertPath)
+		if err != nil {
+			return nil, ErrInvalidTLSConfig{ServiceID: service.ID, Reason: err.Error()}
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(caPEM) {
+			return nil, ErrInvalidTLSConfig{ServiceID: service.ID, Reason: "no certificates in " + service.TLSCACertPath}
+		}
+	}
+	return credentials.NewTLS(config), nil
+}
+
+func readFile(fs http.FileSystem, path string) ([]byte, error) {
+	file, err := fs.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ioutil.ReadAll(file)
+}
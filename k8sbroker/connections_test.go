@@ -0,0 +1,399 @@
+package k8sbroker_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"testing/fstest"
+	"time"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+
+	. "code.cloudfoundry.org/k8sbroker/k8sbroker"
+)
+
+type fakeIdentityServer struct {
+	csi.UnimplementedIdentityServer
+	err error
+}
+
+func (f *fakeIdentityServer) GetPluginInfo(ctx context.Context, req *csi.GetPluginInfoRequest) (*csi.GetPluginInfoResponse, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &csi.GetPluginInfoResponse{Name: "some-csi-plugin", VendorVersion: "1.0.0"}, nil
+}
+
+var _ = Describe("TestAllConnections", func() {
+	var (
+		identity     *fakeIdentityServer
+		server       *grpc.Server
+		listener     net.Listener
+		unreachable  string
+		servicesJSON string
+		timeout      time.Duration
+		elapsed      time.Duration
+		err          error
+	)
+
+	BeforeEach(func() {
+		timeout = 500 * time.Millisecond
+		identity = &fakeIdentityServer{}
+		server = grpc.NewServer()
+		csi.RegisterIdentityServer(server, identity)
+
+		listener, err = net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		go server.Serve(listener)
+
+		closed, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		unreachable = closed.Addr().String()
+		Expect(closed.Close()).To(Succeed())
+
+		servicesJSON = fmt.Sprintf(`[
+			{"id": "csi-service-id", "name": "csi", "connection_address": %q},
+			{"id": "nfs-service-id", "name": "nfs"}
+		]`, listener.Addr().String())
+	})
+
+	AfterEach(func() {
+		server.Stop()
+	})
+
+	JustBeforeEach(func() {
+		services, loadErr := NewServicesFromFS(http.FS(fstest.MapFS{
+			"services.json": &fstest.MapFile{Data: []byte(servicesJSON)},
+		}), "services.json")
+		Expect(loadErr).NotTo(HaveOccurred())
+
+		start := time.Now()
+		err = services.TestAllConnections(context.Background(), timeout)
+		elapsed = time.Since(start)
+	})
+
+	It("succeeds when every CSI endpoint answers", func() {
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	Context("when a CSI endpoint cannot be reached", func() {
+		BeforeEach(func() {
+			servicesJSON = fmt.Sprintf(`[
+				{"id": "csi-service-id", "name": "csi", "connection_address": %q},
+				{"id": "gone-service-id", "name": "gone", "connection_address": %q}
+			]`, listener.Addr().String(), unreachable)
+		})
+
+		It("reports that service only", func() {
+			Expect(err).To(HaveOccurred())
+			connectionErrs, ok := err.(ConnectionErrors)
+			Expect(ok).To(BeTrue())
+			Expect(connectionErrs).To(HaveLen(1))
+			Expect(connectionErrs[0].ServiceID).To(Equal("gone-service-id"))
+			Expect(connectionErrs[0].ConnAddr).To(Equal(unreachable))
+			Expect(err.Error()).To(ContainSubstring("service gone-service-id: cannot reach CSI endpoint " + unreachable))
+		})
+
+		Context("with a long timeout", func() {
+			BeforeEach(func() {
+				timeout = time.Minute
+			})
+
+			It("does not wait it out for a refused connection", func() {
+				Expect(err).To(HaveOccurred())
+				Expect(elapsed).To(BeNumerically("<", 10*time.Second))
+			})
+		})
+	})
+
+	Context("when GetPluginInfo fails", func() {
+		BeforeEach(func() {
+			identity.err = errors.New("plugin-not-ready")
+		})
+
+		It("reports the error", func() {
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("plugin-not-ready"))
+		})
+	})
+})
+
+var _ = Describe("ConnectionHealthCheck", func() {
+	var (
+		server      *grpc.Server
+		listener    net.Listener
+		unreachable string
+		results     map[string]error
+	)
+
+	BeforeEach(func() {
+		server = grpc.NewServer()
+		csi.RegisterIdentityServer(server, &fakeIdentityServer{})
+
+		var err error
+		listener, err = net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		go server.Serve(listener)
+
+		closed, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		unreachable = closed.Addr().String()
+		Expect(closed.Close()).To(Succeed())
+	})
+
+	AfterEach(func() {
+		server.Stop()
+	})
+
+	JustBeforeEach(func() {
+		servicesJSON := fmt.Sprintf(`[
+			{"id": "csi-service-id", "name": "csi", "connection_address": %q},
+			{"id": "gone-service-id", "name": "gone", "connection_address": %q},
+			{"id": "nfs-service-id", "name": "nfs"}
+		]`, listener.Addr().String(), unreachable)
+		services, err := NewServicesFromFS(http.FS(fstest.MapFS{
+			"services.json": &fstest.MapFile{Data: []byte(servicesJSON)},
+		}), "services.json")
+		Expect(err).NotTo(HaveOccurred())
+
+		results = services.ConnectionHealthCheck()
+	})
+
+	It("returns the outcome of every service with a CSI endpoint", func() {
+		Expect(results).To(HaveLen(2))
+		Expect(results).To(HaveKeyWithValue("csi-service-id", BeNil()))
+		Expect(results["gone-service-id"]).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("ValidateService", func() {
+	var (
+		server       *grpc.Server
+		listener     net.Listener
+		services     Services
+		servicesJSON string
+		serviceID    string
+		err          error
+	)
+
+	BeforeEach(func() {
+		server = grpc.NewServer()
+		csi.RegisterIdentityServer(server, &fakeIdentityServer{})
+
+		listener, err = net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		go server.Serve(listener)
+
+		serviceID = "csi-service-id"
+		servicesJSON = fmt.Sprintf(`[
+			{"id": "csi-service-id", "name": "csi", "driver_name": "some-csi-plugin", "connection_address": %q},
+			{"id": "other-csi-service-id", "name": "other-csi", "driver_name": "other-csi-plugin", "connection_address": %q},
+			{"id": "unnamed-csi-service-id", "name": "unnamed-csi", "connection_address": %q},
+			{"id": "nfs-service-id", "name": "nfs", "driver_name": "nfs"}
+		]`, listener.Addr().String(), listener.Addr().String(), listener.Addr().String())
+	})
+
+	AfterEach(func() {
+		server.Stop()
+	})
+
+	JustBeforeEach(func() {
+		var loadErr error
+		services, loadErr = NewServicesFromFS(http.FS(fstest.MapFS{
+			"services.json": &fstest.MapFile{Data: []byte(servicesJSON)},
+		}), "services.json")
+		Expect(loadErr).NotTo(HaveOccurred())
+
+		err = services.ValidateService(serviceID, 500*time.Millisecond)
+	})
+
+	It("succeeds when the plugin has the service's driver name", func() {
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	Context("when the plugin has another name", func() {
+		BeforeEach(func() {
+			serviceID = "other-csi-service-id"
+		})
+
+		It("errors", func() {
+			Expect(err).To(Equal(ErrDriverNameMismatch{ServiceID: "other-csi-service-id", DriverName: "other-csi-plugin", PluginName: "some-csi-plugin"}))
+			Expect(err.Error()).To(Equal("service other-csi-service-id: CSI plugin is some-csi-plugin, not driver_name other-csi-plugin"))
+		})
+	})
+
+	Context("when the service has no driver name", func() {
+		BeforeEach(func() {
+			serviceID = "unnamed-csi-service-id"
+		})
+
+		It("only checks that the plugin answers", func() {
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	Context("when the service has no connection address", func() {
+		BeforeEach(func() {
+			serviceID = "nfs-service-id"
+		})
+
+		It("has nothing to check", func() {
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	Context("when the plugin cannot be reached", func() {
+		BeforeEach(func() {
+			server.Stop()
+		})
+
+		It("errors", func() {
+			connectionErr, ok := err.(ErrConnection)
+			Expect(ok).To(BeTrue())
+			Expect(connectionErr.ServiceID).To(Equal("csi-service-id"))
+		})
+	})
+
+	Context("when the service does not exist", func() {
+		BeforeEach(func() {
+			serviceID = "missing-service-id"
+		})
+
+		It("errors", func() {
+			Expect(err).To(MatchError("service missing-service-id not found"))
+		})
+	})
+})
+
+// selfSignedCert returns a certificate for 127.0.0.1 and its PEM encoded
+// certificate and key.
+func selfSignedCert() (tls.Certificate, []byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).NotTo(HaveOccurred())
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "csi-plugin"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	Expect(err).NotTo(HaveOccurred())
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	Expect(err).NotTo(HaveOccurred())
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	Expect(err).NotTo(HaveOccurred())
+	return cert, certPEM, keyPEM
+}
+
+var _ = Describe("CSI endpoints served over TLS", func() {
+	var (
+		server       *grpc.Server
+		listener     net.Listener
+		files        fstest.MapFS
+		servicesJSON string
+		loadErr      error
+		err          error
+	)
+
+	BeforeEach(func() {
+		cert, certPEM, keyPEM := selfSignedCert()
+		server = grpc.NewServer(grpc.Creds(credentials.NewServerTLSFromCert(&cert)))
+		csi.RegisterIdentityServer(server, &fakeIdentityServer{})
+
+		listener, err = net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		go server.Serve(listener)
+
+		files = fstest.MapFS{
+			"cert.pem": &fstest.MapFile{Data: certPEM},
+			"key.pem":  &fstest.MapFile{Data: keyPEM},
+			"ca.pem":   &fstest.MapFile{Data: certPEM},
+		}
+		servicesJSON = fmt.Sprintf(`[
+			{"id": "csi-service-id", "name": "csi", "connection_address": %q,
+			 "tls_cert_path": "cert.pem", "tls_key_path": "key.pem", "tls_ca_cert_path": "ca.pem"}
+		]`, listener.Addr().String())
+	})
+
+	AfterEach(func() {
+		server.Stop()
+	})
+
+	JustBeforeEach(func() {
+		files["services.json"] = &fstest.MapFile{Data: []byte(servicesJSON)}
+		var services Services
+		services, loadErr = NewServicesFromFS(http.FS(files), "services.json")
+		if loadErr == nil {
+			err = services.TestAllConnections(context.Background(), 500*time.Millisecond)
+		}
+	})
+
+	It("connects with the service's certificates", func() {
+		Expect(loadErr).NotTo(HaveOccurred())
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	Context("when the service has no TLS config", func() {
+		BeforeEach(func() {
+			servicesJSON = fmt.Sprintf(`[{"id": "csi-service-id", "name": "csi", "connection_address": %q}]`, listener.Addr().String())
+		})
+
+		It("cannot reach the endpoint in plaintext", func() {
+			Expect(loadErr).NotTo(HaveOccurred())
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("when only the CA is set", func() {
+		BeforeEach(func() {
+			servicesJSON = fmt.Sprintf(`[{"id": "csi-service-id", "name": "csi", "connection_address": %q, "tls_ca_cert_path": "ca.pem"}]`, listener.Addr().String())
+		})
+
+		It("verifies the endpoint without a client certificate", func() {
+			Expect(loadErr).NotTo(HaveOccurred())
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	Context("when the certificate is set without its key", func() {
+		BeforeEach(func() {
+			servicesJSON = fmt.Sprintf(`[{"id": "csi-service-id", "name": "csi", "connection_address": %q, "tls_cert_path": "cert.pem"}]`, listener.Addr().String())
+		})
+
+		It("refuses the services config", func() {
+			Expect(loadErr).To(Equal(ErrInvalidTLSConfig{ServiceID: "csi-service-id", Reason: "tls_cert_path and tls_key_path must be set together"}))
+		})
+	})
+
+	Context("when the CA file holds no certificate", func() {
+		BeforeEach(func() {
+			files["ca.pem"] = &fstest.MapFile{Data: []byte("not a certificate")}
+		})
+
+		It("refuses the services config", func() {
+			Expect(loadErr).To(MatchError("service csi-service-id: invalid TLS config: no certificates in ca.pem"))
+		})
+	})
+})
@@ -0,0 +1,61 @@
+package k8sbroker
+
+import (
+	"bytes"
+	"text/template"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type DashboardURLTemplate struct {
+	template *template.Template
+}
+
+// NewDashboardURLTemplate parses a Go template, e.g.
+// "https://dashboard.example.com/#/persistentvolumes/{{.Name}}", that is
+// rendered with the ServiceFingerPrint of each provisioned instance. The
+// template is rendered once with a placeholder fingerprint so that mistakes
+// are reported at startup rather than on the first provision. An empty text
+// gives a template that renders no URL.
+func NewDashboardURLTemplate(text string) (*DashboardURLTemplate, error) {
+	if text == "" {
+		return &DashboardURLTemplate{}, nil
+	}
+
+	tmpl, err := template.New("dashboard-url").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &DashboardURLTemplate{template: tmpl}
+	_, err = t.Render(ServiceFingerPrint{
+		Name: "instance-id",
+		Volume: &v1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: "instance-id"},
+			Spec: v1.PersistentVolumeSpec{
+				PersistentVolumeSource: v1.PersistentVolumeSource{
+					NFS: &v1.NFSVolumeSource{Server: "server", Path: "/share"},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func (t *DashboardURLTemplate) Render(fingerprint ServiceFingerPrint) (string, error) {
+	if t == nil || t.template == nil {
+		return "", nil
+	}
+
+	var url bytes.Buffer
+	err := t.template.Execute(&url, fingerprint)
+	if err != nil {
+		return "", err
+	}
+	return url.String(), nil
+}
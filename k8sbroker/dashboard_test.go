@@ -0,0 +1,64 @@
+package k8sbroker_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	. "code.cloudfoundry.org/k8sbroker/k8sbroker"
+)
+
+var _ = Describe("DashboardURLTemplate", func() {
+	var fingerprint ServiceFingerPrint
+
+	BeforeEach(func() {
+		fingerprint = ServiceFingerPrint{
+			Name: "some-instance-id",
+			Volume: &v1.PersistentVolume{
+				ObjectMeta: metav1.ObjectMeta{Name: "some-volume"},
+			},
+		}
+	})
+
+	It("renders the fingerprint", func() {
+		tmpl, err := NewDashboardURLTemplate("https://dashboard.example.com/#/persistentvolumes/{{.Volume.Name}}?instance={{.Name}}")
+		Expect(err).NotTo(HaveOccurred())
+
+		url, err := tmpl.Render(fingerprint)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(url).To(Equal("https://dashboard.example.com/#/persistentvolumes/some-volume?instance=some-instance-id"))
+	})
+
+	It("renders no URL for an empty template", func() {
+		tmpl, err := NewDashboardURLTemplate("")
+		Expect(err).NotTo(HaveOccurred())
+
+		url, err := tmpl.Render(fingerprint)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(url).To(BeEmpty())
+	})
+
+	It("renders no URL for a nil template", func() {
+		var tmpl *DashboardURLTemplate
+		Expect(tmpl.Render(fingerprint)).To(BeEmpty())
+	})
+
+	It("errors on invalid syntax", func() {
+		_, err := NewDashboardURLTemplate("https://dashboard.example.com/{{.Name")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("errors when the template refers to an unknown field", func() {
+		_, err := NewDashboardURLTemplate("https://dashboard.example.com/{{.Namespace}}")
+		Expect(err).To(MatchError(ContainSubstring("Namespace")))
+	})
+
+	It("errors when the fingerprint lacks a field the template uses", func() {
+		tmpl, err := NewDashboardURLTemplate("https://dashboard.example.com/{{.Volume.Spec.NFS.Server}}")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = tmpl.Render(fingerprint)
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -5,30 +5,146 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"path"
+	"reflect"
 
 	"code.cloudfoundry.org/clock"
 	"code.cloudfoundry.org/goshims/osshim"
+	"code.cloudfoundry.org/k8sbroker/metrics"
+	"code.cloudfoundry.org/k8sbroker/requestid"
 	"code.cloudfoundry.org/lager"
 	"code.cloudfoundry.org/service-broker-store/brokerstore"
 
 	"github.com/pivotal-cf/brokerapi"
 	v1 "k8s.io/api/core/v1"
+	rbacapiv1 "k8s.io/api/rbac/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/kubernetes"
 	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	rbacv1 "k8s.io/client-go/kubernetes/typed/rbac/v1"
+	storagev1 "k8s.io/client-go/kubernetes/typed/storage/v1"
 )
 
 const (
 	PermissionVolumeMount = brokerapi.RequiredPermission("volume_mount")
 	DefaultContainerPath  = "/var/vcap/data"
+	DefaultDriverName     = "nfs"
 )
 
+const (
+	ProvisionInProgress = "in_progress"
+	ProvisionSucceeded  = "succeeded"
+	ProvisionFailed     = "failed"
+)
+
+// CapacityLimitAnnotation records the upper bound of a requested capacity
+// range on the persistent volume.
+const CapacityLimitAnnotation = "k8sbroker.cloudfoundry.org/storage-limit"
+
+// ServiceAccountAnnotation names the service account that was granted access
+// to a persistent volume claim.
+const ServiceAccountAnnotation = "kubernetes.io/service-account.name"
+
+// DeleteAfterAnnotation records on a deprovisioned instance's persistent
+// volume when its grace period ends and the volume will be deleted.
+const DeleteAfterAnnotation = "k8sbroker.cloudfoundry.org/delete-after"
+
+// eventsTimeout caps the lookup of the events that explain an error.
+const eventsTimeout = 3 * time.Second
+
+const availablePollInterval = time.Second
+
+// currentFingerprintVersion is written on every new ServiceFingerPrint. Bump
+// it, and teach migrateFingerprint to upgrade the previous version, when a
+// change to the fingerprint needs existing entries rewritten.
+const currentFingerprintVersion = 1
+
+// ManagedByLabel marks the kubernetes objects created by the broker.
+const ManagedByLabel = "managed-by"
+
+// NameLabel holds the instance name on the volume provisioned for it, and is
+// what claims select the volume by.
+const NameLabel = "name"
+
+// BindingIDLabel holds the binding a claim was created for, so that
+// PurgeStalePVCs can find claims whose binding never reached the store.
+const BindingIDLabel = "binding-id"
+
+// InstanceIDLabel holds the instance a claim was created for, so that Bind
+// does not take over a claim of the same name made for another instance.
+const InstanceIDLabel = "instance-id"
+
+// PVCNamingStrategy decides the name of the claim created by Bind.
+type PVCNamingStrategy string
+
+const (
+	// PVCNamingVolumeName names the claim after the instance's volume, so an
+	// instance has at most one claim in each namespace.
+	PVCNamingVolumeName PVCNamingStrategy = "volume-name"
+	// PVCNamingBindingID names the claim after the binding.
+	PVCNamingBindingID PVCNamingStrategy = "binding-id"
+	// PVCNamingVolumeBindingID names the claim after the instance's volume and
+	// the first eight characters of the binding ID.
+	PVCNamingVolumeBindingID PVCNamingStrategy = "volume-binding-id"
+)
+
+// Quotas limit the number of persistent volumes the broker provisions and the
+// number of claims bound to each of them. Zero means unlimited.
+type Quotas struct {
+	MaxPVCount        int
+	MaxPVCPerInstance int
+}
+
+const (
+	OperationProvision   = "provision"
+	OperationDeprovision = "deprovision"
+)
+
+// ReadWriteOncePod is not yet defined by the k8s.io/api version in use.
+const ReadWriteOncePod = v1.PersistentVolumeAccessMode("ReadWriteOncePod")
+
+var accessModes = map[string]v1.PersistentVolumeAccessMode{
+	"RWX":  v1.ReadWriteMany,
+	"ROX":  v1.ReadOnlyMany,
+	"RWO":  v1.ReadWriteOnce,
+	"RWOP": ReadWriteOncePod,
+}
+
 var ErrEmptySpecFile = errors.New("At least one service must be provided in specfile")
 
+// ErrDynamicInstanceUpdate is returned by Update when it is given parameters
+// for a dynamically provisioned instance, whose volume the broker does not own.
+var ErrDynamicInstanceUpdate = brokerapi.NewFailureResponse(errors.New("the parameters of a dynamically provisioned instance cannot be updated"), http.StatusUnprocessableEntity, "dynamic-instance-update")
+
+// ErrNfsSourceChange is returned by Update when it is given a new server or
+// share. The NFS source of a persistent volume cannot be changed once the
+// volume exists.
+var ErrNfsSourceChange = brokerapi.NewFailureResponse(errors.New("the server and share of an instance cannot be changed, create a new instance instead"), http.StatusUnprocessableEntity, "nfs-source-change")
+
+// ErrVolumeExpansionUnsupported is returned by Update when it is asked to grow
+// an NFS volume. Kubernetes cannot expand NFS persistent volumes.
+var ErrVolumeExpansionUnsupported = brokerapi.NewFailureResponse(errors.New("the capacity of an NFS volume cannot be increased, create a new instance instead"), http.StatusUnprocessableEntity, "volume-expansion-unsupported")
+
+// ErrReadOnlyUnsupported is returned by Bind when it is asked for a read-only
+// binding of a service whose access_modes has no read_only mode.
+var ErrReadOnlyUnsupported = brokerapi.NewFailureResponse(errors.New("the service does not support read-only bindings"), http.StatusUnprocessableEntity, "read-only-unsupported")
+
+// ErrProvisionWorkersBusy is returned by an asynchronous Provision while every
+// provision worker is creating a volume.
+var ErrProvisionWorkersBusy = brokerapi.NewFailureResponse(errors.New("too many instances are being provisioned, try again later"), http.StatusTooManyRequests, "provision-workers-busy")
+
 type ErrInvalidService struct {
 	Index int
 }
@@ -37,6 +153,92 @@ func (e ErrInvalidService) Error() string {
 	return fmt.Sprintf("Invalid service in specfile at index %d", e.Index)
 }
 
+type ErrInvalidPlan struct {
+	PlanID string
+}
+
+func (e ErrInvalidPlan) Error() string {
+	return fmt.Sprintf("Invalid plan %s", e.PlanID)
+}
+
+type ErrInvalidReclaimPolicy struct {
+	Policy string
+}
+
+func (e ErrInvalidReclaimPolicy) Error() string {
+	return fmt.Sprintf("%s: invalid reclaim_policy %q, must be one of Retain or Delete", brokerapi.ErrRawParamsInvalid.Error(), e.Policy)
+}
+
+func (e ErrInvalidReclaimPolicy) Unwrap() error {
+	return brokerapi.ErrRawParamsInvalid
+}
+
+// ValidationError describes a provision parameter that is missing or invalid.
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e ValidationError) Error() string {
+	return e.Message
+}
+
+// ValidationErrors collects every ValidationError found in a configuration
+// so that all of them can be reported at once.
+type ValidationErrors []ValidationError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// ErrVolumeEvent adds the message of the latest kubernetes event about a
+// volume to the error returned when creating it.
+type ErrVolumeEvent struct {
+	Err   error
+	Event string
+}
+
+func (e ErrVolumeEvent) Error() string {
+	return fmt.Sprintf("%s: %s", e.Err.Error(), e.Event)
+}
+
+func (e ErrVolumeEvent) Unwrap() error {
+	return e.Err
+}
+
+type ErrClaimConflict struct {
+	Namespace string
+	Name      string
+}
+
+func (e ErrClaimConflict) Error() string {
+	return fmt.Sprintf("%s: persistent volume claim %s/%s exists with a different spec", brokerapi.ErrBindingAlreadyExists.Error(), e.Namespace, e.Name)
+}
+
+func (e ErrClaimConflict) Unwrap() error {
+	return brokerapi.ErrBindingAlreadyExists
+}
+
+// ErrClaimOwnedByOtherInstance is a claim that Bind would create under a name
+// another instance's binding already uses in the namespace.
+type ErrClaimOwnedByOtherInstance struct {
+	Namespace  string
+	Name       string
+	InstanceID string
+}
+
+func (e ErrClaimOwnedByOtherInstance) Error() string {
+	return fmt.Sprintf("%s: persistent volume claim %s/%s belongs to instance %s, give the volumes unique names with --resourceNamePrefix or name claims with --pvcNamingStrategy=volume-binding-id", brokerapi.ErrBindingAlreadyExists.Error(), e.Namespace, e.Name, e.InstanceID)
+}
+
+func (e ErrClaimOwnedByOtherInstance) Unwrap() error {
+	return brokerapi.ErrBindingAlreadyExists
+}
+
 type ErrInvalidSpecFile struct {
 	err error
 }
@@ -46,36 +248,197 @@ func (e ErrInvalidSpecFile) Error() string {
 }
 
 type ServiceFingerPrint struct {
-	Name   string
-	Volume *v1.PersistentVolume
+	Version        int                  `json:",omitempty"`
+	Name           string               `json:"Name"`
+	NamePrefix     string               `json:",omitempty"`
+	Volume         *v1.PersistentVolume `json:"Volume"`
+	ReclaimPolicy  v1.PersistentVolumeReclaimPolicy
+	NodeAffinity   *v1.VolumeNodeAffinity
+	Labels         map[string]string `json:",omitempty"`
+	Annotations    map[string]string `json:",omitempty"`
+	CapacityRange  *CapacityRange    `json:",omitempty"`
+	ProvisionState *ProvisionState
+	Bindings       map[string]BindingFingerPrint `json:",omitempty"`
+	DeleteAfter    *time.Time                    `json:",omitempty"`
+	VolumeContext  map[string]string             `json:",omitempty"`
+
+	// DynamicProvisioning instances have no Volume. Bind claims a volume
+	// from StorageClassName, with a claim named after the instance in each
+	// namespace listed in ClaimNamespaces.
+	DynamicProvisioning bool                    `json:",omitempty"`
+	StorageClassName    string                  `json:",omitempty"`
+	ClaimNamespaces     []string                `json:",omitempty"`
+	VolumeMode          v1.PersistentVolumeMode `json:",omitempty"`
+}
+
+// volumeMode is the mode the instance's volume was provisioned with, or ""
+// when none was given and Kubernetes defaults it to Filesystem.
+func (f *ServiceFingerPrint) volumeMode() v1.PersistentVolumeMode {
+	if f.Volume != nil && f.Volume.Spec.VolumeMode != nil {
+		return *f.Volume.Spec.VolumeMode
+	}
+	return f.VolumeMode
+}
+
+// ResourceName is the instance name with the --resourceNamePrefix it was
+// provisioned under, which names its volume or, when dynamically
+// provisioned, its claims.
+func (f *ServiceFingerPrint) ResourceName() string {
+	return f.NamePrefix + f.Name
+}
+
+// claimInUse reports whether a binding other than bindingID uses the claim.
+// Claims named after the volume are shared by the instance's bindings in a
+// namespace, and must stay until the last of them is unbound.
+func (f *ServiceFingerPrint) claimInUse(namespace, claimName, bindingID string) bool {
+	for id, binding := range f.Bindings {
+		if id != bindingID && binding.Namespace == namespace && binding.ClaimName == claimName {
+			return true
+		}
+	}
+	return false
+}
+
+// BindingFingerPrint records the claim that was created for a binding. The
+// store only keeps brokerapi.BindDetails for a binding, so these are kept on
+// the instance fingerprint keyed by binding ID.
+type BindingFingerPrint struct {
+	ClaimName      string
+	Namespace      string
+	AccessMode     string
+	ServiceAccount string `json:",omitempty"`
+}
+
+// InstanceDetailsSpec is what GetInstance returns. It has the fields of the
+// OSB fetch instance response.
+type InstanceDetailsSpec struct {
+	ServiceID    string
+	PlanID       string
+	DashboardURL string
+	Parameters   map[string]interface{}
+}
+
+// ProvisionState tracks the progress of an asynchronous provision. It is nil
+// for instances that were provisioned synchronously.
+type ProvisionState struct {
+	Status      string
+	Description string
+}
+
+// provisionInProgress reports whether the instance's asynchronous provision
+// has yet to finish, during which it cannot be bound, updated or deprovisioned.
+func (f *ServiceFingerPrint) provisionInProgress() bool {
+	return f.ProvisionState != nil && f.ProvisionState.Status == ProvisionInProgress
 }
 
 type Service struct {
-	DriverName string `json:"driver_name"`
-	ConnAddr   string `json:"connection_address"`
+	DriverName           string       `json:"driver_name"`
+	ConnAddr             string       `json:"connection_address"`
+	ParametersSchemaPath string       `json:"parameters_schema_path"`
+	AccessModes          *AccessModes `json:"access_modes"`
+	// TLSCertPath, TLSKeyPath and TLSCACertPath secure the connection to
+	// ConnAddr. It is plaintext only when all three are empty.
+	TLSCertPath   string `json:"tls_cert_path"`
+	TLSKeyPath    string `json:"tls_key_path"`
+	TLSCACertPath string `json:"tls_ca_cert_path"`
 
 	brokerapi.Service
 }
 
+// ModeMapper gives the mount mode and the claim access mode of a read-write
+// or read-only binding, as supported by a service's driver.
+type ModeMapper interface {
+	MapMode(readonly bool) (string, v1.PersistentVolumeAccessMode, error)
+}
+
+// AccessModes is the access_modes of a service in the services config. It
+// names the access mode of read-write and read-only claims as RWX, ROX, RWO
+// or RWOP. A service without read_only cannot be bound read-only.
+type AccessModes struct {
+	ReadWrite string `json:"read_write"`
+	ReadOnly  string `json:"read_only"`
+}
+
+// DefaultAccessModes maps the bindings of services without access_modes.
+var DefaultAccessModes = AccessModes{ReadWrite: "RWX", ReadOnly: "ROX"}
+
+func (m AccessModes) MapMode(readonly bool) (string, v1.PersistentVolumeAccessMode, error) {
+	if readonly {
+		mode, ok := accessModes[m.ReadOnly]
+		if !ok {
+			return "", "", ErrReadOnlyUnsupported
+		}
+		return "r", mode, nil
+	}
+
+	mode, ok := accessModes[m.ReadWrite]
+	if !ok {
+		return "", "", fmt.Errorf("invalid read_write access mode %q", m.ReadWrite)
+	}
+	return "rw", mode, nil
+}
+
 type lock interface {
 	Lock()
 	Unlock()
 }
 
 type Broker struct {
-	logger           lager.Logger
-	os               osshim.Os
-	clock            clock.Clock
-	servicesRegistry Services
-	store            brokerstore.Store
-	client           kubernetes.Interface
-	namespace        string
-	mutex            *sync.Mutex
+	logger            lager.Logger
+	os                osshim.Os
+	clock             clock.Clock
+	servicesRegistry  Services
+	store             brokerstore.Store
+	client            kubernetes.Interface
+	watchClient       kubernetes.Interface
+	namespace         string
+	allowedNamespaces map[string]bool
+	validator         *ParameterValidator
+	metrics           Metrics
+	mutex             *sync.Mutex
+	quotas            Quotas
+	dashboardURL      *DashboardURLTemplate
+	gracePeriod       time.Duration
+	pvcNaming         PVCNamingStrategy
+	annotationDelay   time.Duration
+	provisionWorkers  chan struct{}
+	labelPrefix       string
+	namePrefix        string
+	extraAttributes   map[string]string
+	watchTimeout      time.Duration
+	provisionTimeout  time.Duration
+	extraPVLabels     map[string]string
+	extraPVCLabels    map[string]string
+
+	// provisionResults holds the brokerapi.LastOperation of each asynchronous
+	// provision whose volume watch has seen it finish, keyed by instance ID.
+	provisionResults sync.Map
 }
 
 type NfsConfig struct {
-	Server string `json:"server"`
-	Share  string `json:"share"`
+	Server              string                 `json:"server"`
+	Share               string                 `json:"share"`
+	PathTemplate        string                 `json:"path_template"`
+	ReclaimPolicy       string                 `json:"reclaim_policy"`
+	MountOptions        []string               `json:"mount_options"`
+	StorageClassName    string                 `json:"storage_class_name"`
+	VolumeMode          string                 `json:"volume_mode"`
+	NodeAffinity        *v1.VolumeNodeAffinity `json:"node_affinity"`
+	Labels              map[string]string      `json:"labels"`
+	Annotations         map[string]string      `json:"annotations"`
+	CapacityRange       *CapacityRange         `json:"capacity_range"`
+	DynamicProvisioning bool                   `json:"dynamic_provisioning"`
+}
+
+type CapacityRange struct {
+	RequiredBytes int64 `json:"requiredBytes"`
+	LimitBytes    int64 `json:"limitBytes"`
+}
+
+//go:generate counterfeiter -o k8sbroker_fake/fake_metrics.go . Metrics
+type Metrics interface {
+	Observe(operation string, duration time.Duration, err error)
+	VolumeHealthCheckFailed()
 }
 
 //go:generate counterfeiter -o k8sbroker_fake/fake_k8s_client.go . K8sClient
@@ -98,14 +461,79 @@ type K8sPersistentVolumeClaims interface {
 	corev1.PersistentVolumeClaimInterface
 }
 
+//go:generate counterfeiter -o k8sbroker_fake/fake_k8s_namespaces.go . K8sNamespaces
+type K8sNamespaces interface {
+	corev1.NamespaceInterface
+}
+
+//go:generate counterfeiter -o k8sbroker_fake/fake_k8s_events.go . K8sEvents
+type K8sEvents interface {
+	corev1.EventInterface
+}
+
+//go:generate counterfeiter -o k8sbroker_fake/fake_k8s_rbac_v1.go . K8sRbacV1
+type K8sRbacV1 interface {
+	rbacv1.RbacV1Interface
+}
+
+//go:generate counterfeiter -o k8sbroker_fake/fake_k8s_roles.go . K8sRoles
+type K8sRoles interface {
+	rbacv1.RoleInterface
+}
+
+//go:generate counterfeiter -o k8sbroker_fake/fake_k8s_role_bindings.go . K8sRoleBindings
+type K8sRoleBindings interface {
+	rbacv1.RoleBindingInterface
+}
+
+//go:generate counterfeiter -o k8sbroker_fake/fake_k8s_storage_v1.go . K8sStorageV1
+type K8sStorageV1 interface {
+	storagev1.StorageV1Interface
+}
+
+//go:generate counterfeiter -o k8sbroker_fake/fake_k8s_storage_classes.go . K8sStorageClasses
+type K8sStorageClasses interface {
+	storagev1.StorageClassInterface
+}
+
+// Config holds the broker-wide settings of New. Zero values leave a setting
+// off, except ParallelProvisionWorkers, which is unlimited when zero.
+type Config struct {
+	// Namespace is where claims are created when a bind names none.
+	Namespace string
+	// AllowedNamespaces are the namespaces a bind may target, any when empty.
+	AllowedNamespaces          []string
+	Quotas                     Quotas
+	DashboardURL               *DashboardURLTemplate
+	DeprovisionGracePeriod     time.Duration
+	PVCNaming                  PVCNamingStrategy
+	AnnotationPropagationDelay time.Duration
+	ParallelProvisionWorkers   int
+	// LabelPrefix starts the keys of the labels the broker manages.
+	LabelPrefix           string
+	ResourceNamePrefix    string
+	ExtraVolumeAttributes map[string]string
+	ProvisionWatchTimeout time.Duration
+	ProvisionTimeout      time.Duration
+	// ExtraPVLabels and ExtraPVCLabels are added to every persistent volume
+	// and claim the broker creates.
+	ExtraPVLabels  map[string]string
+	ExtraPVCLabels map[string]string
+	// WatchClient makes the broker's watches, which must not be cut off by a
+	// request timeout. The broker watches through its client when it is nil.
+	WatchClient kubernetes.Interface
+}
+
 func New(
 	logger lager.Logger,
 	os osshim.Os,
 	clock clock.Clock,
 	store brokerstore.Store,
 	client kubernetes.Interface,
-	namespace string,
+	validator *ParameterValidator,
 	servicesRegistry Services,
+	metrics Metrics,
+	config Config,
 ) (*Broker, error) {
 
 	logger = logger.Session("new-k8s-broker")
@@ -113,15 +541,45 @@ func New(
 	defer logger.Info("end")
 
 	theBroker := Broker{
-		logger:           logger,
-		os:               os,
-		mutex:            &sync.Mutex{},
-		clock:            clock,
-		store:            store,
-		client:           client,
-		namespace:        namespace,
-		servicesRegistry: servicesRegistry,
+		logger:            logger,
+		os:                os,
+		mutex:             &sync.Mutex{},
+		clock:             clock,
+		store:             store,
+		client:            client,
+		watchClient:       config.WatchClient,
+		namespace:         config.Namespace,
+		allowedNamespaces: map[string]bool{},
+		validator:         validator,
+		servicesRegistry:  servicesRegistry,
+		metrics:           metrics,
+		quotas:            config.Quotas,
+		dashboardURL:      config.DashboardURL,
+		gracePeriod:       config.DeprovisionGracePeriod,
+		pvcNaming:         config.PVCNaming,
+		annotationDelay:   config.AnnotationPropagationDelay,
+		labelPrefix:       config.LabelPrefix,
+		namePrefix:        config.ResourceNamePrefix,
+		extraAttributes:   config.ExtraVolumeAttributes,
+		watchTimeout:      config.ProvisionWatchTimeout,
+		provisionTimeout:  config.ProvisionTimeout,
+		extraPVLabels:     config.ExtraPVLabels,
+		extraPVCLabels:    config.ExtraPVCLabels,
+	}
+	if theBroker.watchClient == nil {
+		theBroker.watchClient = client
+	}
+	if config.ParallelProvisionWorkers > 0 {
+		theBroker.provisionWorkers = make(chan struct{}, config.ParallelProvisionWorkers)
 	}
+	for _, allowed := range config.AllowedNamespaces {
+		theBroker.allowedNamespaces[allowed] = true
+	}
+
+	if errs := validation.IsQualifiedName(config.LabelPrefix + NameLabel); len(errs) > 0 {
+		return nil, fmt.Errorf("invalid label prefix %q: %s", config.LabelPrefix, strings.Join(errs, "; "))
+	}
+
 	err := store.Restore(logger)
 	if err != nil {
 		return nil, err
@@ -139,71 +597,127 @@ func (b *Broker) Services(_ context.Context) ([]brokerapi.Service, error) {
 }
 
 func (b *Broker) Provision(context context.Context, instanceID string, details brokerapi.ProvisionDetails, asyncAllowed bool) (_ brokerapi.ProvisionedServiceSpec, e error) {
-	logger := b.logger.Session("provision").WithData(lager.Data{"instanceID": instanceID, "details": details})
+	logger := b.logger.Session("provision").WithData(lager.Data{"instanceID": instanceID, "details": details, "request_id": requestid.FromContext(context)})
 	logger.Info("start")
 	defer logger.Info("end")
+	defer func(start time.Time) { b.observe(metrics.Provision, start, e) }(b.clock.Now())
+
+	err := b.servicesRegistry.ValidateParameters(details.ServiceID, details.RawParameters)
+	if err != nil {
+		logger.Error("provision-parameters-schema-error", err)
+		return brokerapi.ProvisionedServiceSpec{}, err
+	}
 
 	var configuration NfsConfig
 	logger.Debug("provision-raw-parameters", lager.Data{"RawParameters": details.RawParameters})
-	err := json.Unmarshal(details.RawParameters, &configuration)
+	err = json.Unmarshal(details.RawParameters, &configuration)
 	if err != nil {
 		logger.Error("provision-raw-parameters-decode-error", err)
 		return brokerapi.ProvisionedServiceSpec{}, brokerapi.ErrRawParamsInvalid
 	}
 
-	if configuration.Server == "" {
-		return brokerapi.ProvisionedServiceSpec{}, errors.New("config requires a \"server\"")
+	if configuration.PathTemplate != "" {
+		configuration.Share, err = renderShare(configuration, PathTemplateData{
+			InstanceID:       instanceID,
+			PlanID:           details.PlanID,
+			OrganizationGUID: details.OrganizationGUID,
+			SpaceGUID:        details.SpaceGUID,
+		})
+		if err != nil {
+			return brokerapi.ProvisionedServiceSpec{}, err
+		}
+	}
+
+	err = validateNfsConfig(configuration)
+	if err != nil {
+		return brokerapi.ProvisionedServiceSpec{}, err
 	}
 
-	if configuration.Share == "" {
-		return brokerapi.ProvisionedServiceSpec{}, errors.New("config requires a \"share\"")
+	err = b.validateMountOptions(configuration.MountOptions)
+	if err != nil {
+		return brokerapi.ProvisionedServiceSpec{}, err
 	}
 
-	quantity, err := resource.ParseQuantity("5G")
+	err = b.validateStorageClass(logger, configuration.StorageClassName)
 	if err != nil {
 		return brokerapi.ProvisionedServiceSpec{}, err
 	}
 
-	volumeRequest := &v1.PersistentVolume{
-		TypeMeta: metav1.TypeMeta{
-			Kind:       "PersistentVolume",
-			APIVersion: "v1",
-		},
-		ObjectMeta: metav1.ObjectMeta{
-			Name:   instanceID,
-			Labels: map[string]string{"name": instanceID},
-		},
+	if configuration.DynamicProvisioning {
+		return b.provisionDynamic(logger, instanceID, details, configuration)
+	}
 
-		Spec: v1.PersistentVolumeSpec{
-			AccessModes: []v1.PersistentVolumeAccessMode{v1.ReadWriteMany},
-			Capacity:    v1.ResourceList{v1.ResourceName(v1.ResourceStorage): quantity},
-			PersistentVolumeSource: v1.PersistentVolumeSource{
-				NFS: &v1.NFSVolumeSource{
-					Server: configuration.Server,
-					Path:   configuration.Share,
-				},
-			},
-		},
+	if configuration.ReclaimPolicy == "" {
+		configuration.ReclaimPolicy = b.servicesRegistry.ReclaimPolicy(details.PlanID)
+	}
+
+	quantity := requestedCapacity(configuration.CapacityRange)
+
+	annotations := configuration.Annotations
+	if capacityRange := configuration.CapacityRange; capacityRange != nil {
+		if capacityRange.LimitBytes > 0 {
+			limit := resource.NewQuantity(capacityRange.LimitBytes, resource.BinarySI)
+			annotations = mergeMetadata(annotations, map[string]string{CapacityLimitAnnotation: limit.String()})
+		}
+	}
+
+	volumeRequest := BuildPersistentVolume(b.namePrefix+instanceID, b.labelPrefix, configuration.Server, configuration.Share, quantity,
+		WithLabels(mergeMetadata(b.extraPVLabels, configuration.Labels)),
+		WithAnnotations(annotations),
+		WithReclaimPolicy(v1.PersistentVolumeReclaimPolicy(configuration.ReclaimPolicy)),
+		WithMountOptions(configuration.MountOptions),
+		WithStorageClassName(configuration.StorageClassName),
+		WithNodeAffinity(configuration.NodeAffinity),
+		WithVolumeMode(v1.PersistentVolumeMode(configuration.VolumeMode)),
+	)
+
+	fingerprint := ServiceFingerPrint{
+		Version:       currentFingerprintVersion,
+		Name:          instanceID,
+		NamePrefix:    b.namePrefix,
+		Volume:        volumeRequest,
+		ReclaimPolicy: volumeRequest.Spec.PersistentVolumeReclaimPolicy,
+		NodeAffinity:  volumeRequest.Spec.NodeAffinity,
+		Labels:        configuration.Labels,
+		Annotations:   configuration.Annotations,
+		CapacityRange: configuration.CapacityRange,
+	}
+
+	dashboardURL, err := b.dashboardURL.Render(fingerprint)
+	if err != nil {
+		logger.Error("error-rendering-dashboard-url", err)
+		return brokerapi.ProvisionedServiceSpec{}, err
+	}
+
+	if asyncAllowed {
+		return b.provisionAsync(logger, instanceID, details, fingerprint, dashboardURL)
 	}
 
-	volume, err := b.client.CoreV1().PersistentVolumes().Create(volumeRequest)
+	volume, created, err := b.createVolumeWithinQuota(logger, instanceID, volumeRequest)
 	if err != nil {
-		logger.Error("error-creating-persistent-volume", err)
 		return brokerapi.ProvisionedServiceSpec{}, err
 	}
 
 	defer func() {
-		if e != nil {
-			err := b.deletePersistentVolume(instanceID)
+		if e != nil && created {
+			err := b.deletePersistentVolume(rollbackContext(), volumeRequest.Name)
 			if err != nil {
-				logger.Error("failed-to-cleanup-persistent-volume", err, lager.Data{"volume": volume})
+				logger.Error("failed-to-cleanup-persistent-volume", err, k8sErrorData(err, lager.Data{"pv_name": volumeRequest.Name, "volume": volume}))
 			}
 		}
 	}()
 	logger.Debug("created-volume", lager.Data{"volume": volume})
 
+	if created && b.provisionTimeout > 0 {
+		volume, err = b.waitForAvailableVolume(logger, volumeRequest.Name)
+		if err != nil {
+			return brokerapi.ProvisionedServiceSpec{}, err
+		}
+	}
+
 	b.mutex.Lock()
 	defer b.mutex.Unlock()
+
 	defer func() {
 		out := b.store.Save(logger)
 		if e == nil {
@@ -211,10 +725,8 @@ func (b *Broker) Provision(context context.Context, instanceID string, details b
 		}
 	}()
 
-	fingerprint := ServiceFingerPrint{
-		instanceID,
-		volume,
-	}
+	fingerprint.Volume = volume
+	fingerprint.VolumeContext = b.volumeContext(volume)
 	instanceDetails := brokerstore.ServiceInstance{
 		details.ServiceID,
 		details.PlanID,
@@ -228,35 +740,34 @@ func (b *Broker) Provision(context context.Context, instanceID string, details b
 	}
 	err = b.store.CreateInstanceDetails(instanceID, instanceDetails)
 	if err != nil {
-		return brokerapi.ProvisionedServiceSpec{}, fmt.Errorf("failed to store instance details %s", instanceID)
+		return brokerapi.ProvisionedServiceSpec{}, fmt.Errorf("failed to store instance details %s: %w", instanceID, err)
 	}
 	logger.Info("service-instance-created", lager.Data{"instanceDetails": instanceDetails})
 
-	return brokerapi.ProvisionedServiceSpec{IsAsync: false}, nil
+	return brokerapi.ProvisionedServiceSpec{IsAsync: false, DashboardURL: dashboardURL}, nil
 }
 
-func (b *Broker) Deprovision(context context.Context, instanceID string, details brokerapi.DeprovisionDetails, asyncAllowed bool) (_ brokerapi.DeprovisionServiceSpec, e error) {
-	logger := b.logger.Session("deprovision")
-	logger.Info("start")
-	defer logger.Info("end")
-
-	if instanceID == "" {
-		return brokerapi.DeprovisionServiceSpec{}, errors.New("volume deletion requires instance ID")
-	}
-	logger.Debug("instance-id", lager.Data{"id": instanceID})
-	instanceDetails, err := b.store.RetrieveInstanceDetails(instanceID)
-	if err != nil {
-		return brokerapi.DeprovisionServiceSpec{}, brokerapi.ErrInstanceDoesNotExist
-	}
-
-	fingerprint, err := getFingerprint(instanceDetails.ServiceFingerPrint)
-	if err != nil {
-		return brokerapi.DeprovisionServiceSpec{}, err
+// provisionDynamic records an instance whose volume a storage class
+// provisioner creates once Bind claims it, so there is no volume to create
+// and nothing to wait for.
+func (b *Broker) provisionDynamic(logger lager.Logger, instanceID string, details brokerapi.ProvisionDetails, configuration NfsConfig) (_ brokerapi.ProvisionedServiceSpec, e error) {
+	fingerprint := ServiceFingerPrint{
+		Version:             currentFingerprintVersion,
+		Name:                instanceID,
+		NamePrefix:          b.namePrefix,
+		Labels:              configuration.Labels,
+		Annotations:         configuration.Annotations,
+		CapacityRange:       configuration.CapacityRange,
+		DynamicProvisioning: true,
+		StorageClassName:    configuration.StorageClassName,
+		VolumeMode:          v1.PersistentVolumeMode(configuration.VolumeMode),
+		VolumeContext:       b.volumeContext(nil),
 	}
 
-	err = b.deletePersistentVolume(fingerprint.Volume.Name)
+	dashboardURL, err := b.dashboardURL.Render(fingerprint)
 	if err != nil {
-		return brokerapi.DeprovisionServiceSpec{}, err
+		logger.Error("error-rendering-dashboard-url", err)
+		return brokerapi.ProvisionedServiceSpec{}, err
 	}
 
 	b.mutex.Lock()
@@ -268,190 +779,2159 @@ func (b *Broker) Deprovision(context context.Context, instanceID string, details
 		}
 	}()
 
-	err = b.store.DeleteInstanceDetails(instanceID)
+	instanceDetails := brokerstore.ServiceInstance{
+		details.ServiceID,
+		details.PlanID,
+		details.OrganizationGUID,
+		details.SpaceGUID,
+		fingerprint,
+	}
+
+	if b.instanceConflicts(instanceDetails, instanceID) {
+		return brokerapi.ProvisionedServiceSpec{}, brokerapi.ErrInstanceAlreadyExists
+	}
+	err = b.store.CreateInstanceDetails(instanceID, instanceDetails)
 	if err != nil {
-		return brokerapi.DeprovisionServiceSpec{}, err
+		return brokerapi.ProvisionedServiceSpec{}, fmt.Errorf("failed to store instance details %s: %w", instanceID, err)
 	}
+	logger.Info("dynamic-service-instance-created", lager.Data{"instanceDetails": instanceDetails})
 
-	return brokerapi.DeprovisionServiceSpec{IsAsync: false, OperationData: "deprovision"}, nil
+	return brokerapi.ProvisionedServiceSpec{IsAsync: false, DashboardURL: dashboardURL}, nil
 }
 
-func (b *Broker) Bind(context context.Context, instanceID string, bindingID string, bindDetails brokerapi.BindDetails) (_ brokerapi.Binding, e error) {
-	logger := b.logger.Session("bind")
-	logger.Info("start", lager.Data{"bindingID": bindingID, "details": bindDetails})
-	defer logger.Info("end")
-
-	b.mutex.Lock()
-	defer b.mutex.Unlock()
+func (b *Broker) provisionAsync(logger lager.Logger, instanceID string, details brokerapi.ProvisionDetails, fingerprint ServiceFingerPrint, dashboardURL string) (_ brokerapi.ProvisionedServiceSpec, e error) {
+	if !b.acquireProvisionWorker() {
+		logger.Info("provision-workers-busy", lager.Data{"workers": cap(b.provisionWorkers)})
+		return brokerapi.ProvisionedServiceSpec{}, ErrProvisionWorkersBusy
+	}
 	defer func() {
-		out := b.store.Save(logger)
-		if e == nil {
-			e = out
+		if e != nil {
+			b.releaseProvisionWorker()
 		}
 	}()
 
-	logger.Info("starting-k8sbroker-bind")
-	instanceDetails, err := b.store.RetrieveInstanceDetails(instanceID)
-	if err != nil {
-		return brokerapi.Binding{}, brokerapi.ErrInstanceDoesNotExist
-	}
-	logger.Info("retrieved-instance-details", lager.Data{"instanceDetails": instanceDetails})
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
 
-	fingerprint, err := getFingerprint(instanceDetails.ServiceFingerPrint)
+	err := b.checkVolumeQuota(logger, instanceID)
 	if err != nil {
-		return brokerapi.Binding{}, err
+		return brokerapi.ProvisionedServiceSpec{}, err
 	}
 
-	params := make(map[string]interface{})
-	logger.Debug(fmt.Sprintf("bindDetails: %#v", bindDetails.RawParameters))
-
-	if bindDetails.RawParameters != nil {
-		err = json.Unmarshal(bindDetails.RawParameters, &params)
-		if err != nil {
-			return brokerapi.Binding{}, err
-		}
+	fingerprint.ProvisionState = &ProvisionState{Status: ProvisionInProgress}
+	instanceDetails := brokerstore.ServiceInstance{
+		details.ServiceID,
+		details.PlanID,
+		details.OrganizationGUID,
+		details.SpaceGUID,
+		fingerprint,
 	}
 
-	if b.bindingConflicts(bindingID, bindDetails) {
-		return brokerapi.Binding{}, brokerapi.ErrBindingAlreadyExists
+	if b.instanceConflicts(instanceDetails, instanceID) {
+		return brokerapi.ProvisionedServiceSpec{}, brokerapi.ErrInstanceAlreadyExists
 	}
-
-	cfMode, k8sMode, err := evaluateMode(params)
+	err = b.store.CreateInstanceDetails(instanceID, instanceDetails)
 	if err != nil {
-		logger.Error("failed-to-parse-quantity", err)
-		return brokerapi.Binding{}, brokerapi.ErrRawParamsInvalid
+		return brokerapi.ProvisionedServiceSpec{}, fmt.Errorf("failed to store instance details %s: %w", instanceID, err)
 	}
 
-	volumeClaim, err := b.client.CoreV1().PersistentVolumeClaims(b.namespace).Create(&v1.PersistentVolumeClaim{
-		TypeMeta: metav1.TypeMeta{
-			Kind:       "PersistentVolumeClaim",
-			APIVersion: "v1",
-		},
-		ObjectMeta: metav1.ObjectMeta{
-			Name: fingerprint.Volume.Name,
-		},
-
-		Spec: v1.PersistentVolumeClaimSpec{
-			AccessModes:      []v1.PersistentVolumeAccessMode{k8sMode},
-			Resources:        v1.ResourceRequirements{Requests: fingerprint.Volume.Spec.Capacity},
-			StorageClassName: &fingerprint.Volume.Spec.StorageClassName,
-			Selector: &metav1.LabelSelector{
-				MatchExpressions: []metav1.LabelSelectorRequirement{
-					{
-						Key:      "name",
-						Operator: metav1.LabelSelectorOpIn,
-						Values:   []string{fingerprint.Volume.Name},
-					},
-				},
-			},
-		},
-	})
+	err = b.store.Save(logger)
 	if err != nil {
-		logger.Error("error-creating-claim", err)
-		return brokerapi.Binding{}, err
+		return brokerapi.ProvisionedServiceSpec{}, err
 	}
+	logger.Info("service-instance-provisioning", lager.Data{"instanceDetails": instanceDetails})
 
-	defer func() {
-		if e != nil {
-			err := b.deletePersistentVolumeClaim(fingerprint.Volume.Name)
-			if err != nil {
-				logger.Error("failed-to-cleanup-persistent-volume-claim", err, lager.Data{"volume-claim": volumeClaim})
-			}
-		}
-	}()
-	logger.Debug("created-volume-claim", lager.Data{"volume-claim": volumeClaim})
+	go b.completeAsyncProvision(logger, instanceID, fingerprint.Volume)
+
+	return brokerapi.ProvisionedServiceSpec{IsAsync: true, DashboardURL: dashboardURL, OperationData: OperationProvision}, nil
+}
+
+// completeAsyncProvision creates the volume of an asynchronous provision and
+// records the outcome on the instance details as they are stored then, rather
+// than on those the provision started with.
+func (b *Broker) completeAsyncProvision(logger lager.Logger, instanceID string, volumeRequest *v1.PersistentVolume) {
+	logger = logger.Session("complete-async-provision")
+	logger.Info("start")
+	defer logger.Info("end")
+	defer b.releaseProvisionWorker()
+
+	volume, _, createErr := b.getOrCreatePersistentVolume(logger, volumeRequest)
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	instanceDetails, err := b.store.RetrieveInstanceDetails(instanceID)
+	if err != nil {
+		logger.Error("failed-to-retrieve-instance-details", err)
+		return
+	}
+	fingerprint, err := getFingerprint(instanceDetails.ServiceFingerPrint)
+	if err != nil {
+		logger.Error("failed-to-read-instance-details", err)
+		return
+	}
+
+	if createErr != nil {
+		fingerprint.ProvisionState = &ProvisionState{Status: ProvisionFailed, Description: createErr.Error()}
+	} else {
+		logger.Debug("created-volume", lager.Data{"volume": volume})
+		fingerprint.Volume = volume
+		fingerprint.VolumeContext = b.volumeContext(volume)
+		fingerprint.ProvisionState = &ProvisionState{Status: ProvisionSucceeded}
+		go b.watchVolume(logger, instanceID, volumeRequest.Name)
+	}
+
+	instanceDetails.ServiceFingerPrint = *fingerprint
+	err = b.updateInstanceDetails(instanceID, instanceDetails)
+	if err != nil {
+		logger.Error("failed-to-store-instance-details", err)
+		return
+	}
+
+	err = b.store.Save(logger)
+	if err != nil {
+		logger.Error("failed-to-save-state", err)
+	}
+}
+
+// watchVolume waits for the volume of an asynchronous provision to leave the
+// pending phase and records the outcome for LastOperation, so that polling
+// Cloud Controllers are answered without a request to the API server. The
+// watch gives up after the provision watch timeout; LastOperation then gets
+// the volume itself.
+func (b *Broker) watchVolume(logger lager.Logger, instanceID string, volumeName string) {
+	logger = logger.Session("watch-volume", lager.Data{"volume": volumeName})
+
+	timeoutSeconds := int64(b.watchTimeout / time.Second)
+	watcher, err := b.watchClient.CoreV1().PersistentVolumes().Watch(metav1.ListOptions{
+		FieldSelector:  fields.OneTermEqualSelector("metadata.name", volumeName).String(),
+		TimeoutSeconds: &timeoutSeconds,
+	})
+	if err != nil {
+		logger.Error("error-watching-persistent-volume", err, k8sErrorData(err, lager.Data{"pv_name": volumeName}))
+		return
+	}
+	defer watcher.Stop()
+
+	timeout := b.clock.NewTimer(b.watchTimeout)
+	defer timeout.Stop()
+
+	for {
+		select {
+		case event, ok := <-watcher.ResultChan():
+			if !ok {
+				return
+			}
+			volume, isVolume := event.Object.(*v1.PersistentVolume)
+			if !isVolume || (event.Type != watch.Added && event.Type != watch.Modified) {
+				continue
+			}
+			result := lastOperationForVolume(volume)
+			if result.State != brokerapi.InProgress {
+				logger.Info("persistent-volume-provisioned", lager.Data{"phase": volume.Status.Phase})
+				b.provisionResults.Store(instanceID, result)
+				return
+			}
+		case <-timeout.C():
+			logger.Info("persistent-volume-watch-timed-out")
+			return
+		}
+	}
+}
+
+// acquireProvisionWorker takes a slot for an asynchronous provision without
+// waiting for one, so that a busy broker turns requests away instead of
+// piling up goroutines. Without a worker limit it always succeeds.
+func (b *Broker) acquireProvisionWorker() bool {
+	if b.provisionWorkers == nil {
+		return true
+	}
+	select {
+	case b.provisionWorkers <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+func (b *Broker) releaseProvisionWorker() {
+	if b.provisionWorkers != nil {
+		<-b.provisionWorkers
+	}
+}
+
+func (b *Broker) Deprovision(context context.Context, instanceID string, details brokerapi.DeprovisionDetails, asyncAllowed bool) (_ brokerapi.DeprovisionServiceSpec, e error) {
+	logger := b.logger.Session("deprovision", lager.Data{"request_id": requestid.FromContext(context)})
+	logger.Info("start")
+	defer logger.Info("end")
+	defer func(start time.Time) { b.observe(metrics.Deprovision, start, e) }(b.clock.Now())
+
+	if instanceID == "" {
+		return brokerapi.DeprovisionServiceSpec{}, errors.New("volume deletion requires instance ID")
+	}
+	logger.Debug("instance-id", lager.Data{"id": instanceID})
+	instanceDetails, err := b.store.RetrieveInstanceDetails(instanceID)
+	if err != nil {
+		logger.Info("instance-does-not-exist", lager.Data{"error": err.Error()})
+		return brokerapi.DeprovisionServiceSpec{}, brokerapi.ErrInstanceDoesNotExist
+	}
+
+	fingerprint, err := getFingerprint(instanceDetails.ServiceFingerPrint)
+	if err != nil {
+		return brokerapi.DeprovisionServiceSpec{}, err
+	}
+	if fingerprint.DeleteAfter != nil {
+		return brokerapi.DeprovisionServiceSpec{}, brokerapi.ErrInstanceDoesNotExist
+	}
+	if fingerprint.provisionInProgress() {
+		logger.Info("provision-in-progress")
+		return brokerapi.DeprovisionServiceSpec{}, brokerapi.ErrConcurrentInstanceAccess
+	}
+	b.provisionResults.Delete(instanceID)
+
+	if fingerprint.DynamicProvisioning {
+		return b.deprovisionDynamic(context, logger, instanceID, fingerprint)
+	}
+
+	// kubernetes only reclaims a volume with a Delete reclaim policy once a
+	// claim bound to it is deleted, so a volume without claims is always
+	// deleted by the broker
+	deleteVolume := len(fingerprint.Bindings) == 0 || fingerprint.ReclaimPolicy != v1.PersistentVolumeReclaimDelete
+	deferDelete := deleteVolume && b.gracePeriod > 0
+
+	if deleteVolume && !deferDelete {
+		err = b.reapplyAnnotations(logger, fingerprint)
+		if err != nil {
+			return brokerapi.DeprovisionServiceSpec{}, err
+		}
+
+		// the volume may already be gone, e.g. deleted by a cluster admin or by
+		// an earlier deprovision that failed later on
+		err = b.deletePersistentVolume(context, fingerprint.Volume.Name)
+		if k8serrors.IsNotFound(err) {
+			logger.Info("persistent-volume-already-deleted", lager.Data{"volume": fingerprint.Volume.Name})
+		} else if err != nil {
+			return brokerapi.DeprovisionServiceSpec{}, err
+		}
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	defer func() {
+		out := b.store.Save(logger)
+		if e == nil {
+			e = out
+		}
+	}()
+
+	if deferDelete {
+		err = b.scheduleDeletion(logger, instanceID, instanceDetails, fingerprint)
+		if err != nil {
+			return brokerapi.DeprovisionServiceSpec{}, err
+		}
+		return brokerapi.DeprovisionServiceSpec{IsAsync: false, OperationData: OperationDeprovision}, nil
+	}
+
+	err = b.store.DeleteInstanceDetails(instanceID)
+	if err != nil {
+		return brokerapi.DeprovisionServiceSpec{}, err
+	}
+
+	return brokerapi.DeprovisionServiceSpec{IsAsync: false, OperationData: OperationDeprovision}, nil
+}
+
+// deprovisionDynamic deletes the claims of a dynamically provisioned instance;
+// the storage class's reclaim policy decides what happens to their volumes.
+func (b *Broker) deprovisionDynamic(ctx context.Context, logger lager.Logger, instanceID string, fingerprint *ServiceFingerPrint) (_ brokerapi.DeprovisionServiceSpec, e error) {
+	for _, namespace := range fingerprint.ClaimNamespaces {
+		err := b.deletePersistentVolumeClaim(ctx, namespace, fingerprint.ResourceName())
+		if k8serrors.IsNotFound(err) {
+			logger.Info("persistent-volume-claim-already-deleted", lager.Data{"namespace": namespace, "claim": fingerprint.ResourceName()})
+		} else if err != nil {
+			logger.Error("failed-to-delete-persistent-volume-claim", err, k8sErrorData(err, lager.Data{"namespace": namespace, "pvc_name": fingerprint.ResourceName()}))
+			return brokerapi.DeprovisionServiceSpec{}, err
+		}
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	defer func() {
+		out := b.store.Save(logger)
+		if e == nil {
+			e = out
+		}
+	}()
+
+	err := b.store.DeleteInstanceDetails(instanceID)
+	if err != nil {
+		return brokerapi.DeprovisionServiceSpec{}, err
+	}
+
+	return brokerapi.DeprovisionServiceSpec{IsAsync: false, OperationData: OperationDeprovision}, nil
+}
+
+func (b *Broker) Bind(context context.Context, instanceID string, bindingID string, bindDetails brokerapi.BindDetails) (_ brokerapi.Binding, e error) {
+	logger := b.logger.Session("bind", lager.Data{"request_id": requestid.FromContext(context)})
+	logger.Info("start", lager.Data{"bindingID": bindingID, "details": bindDetails})
+	defer logger.Info("end")
+	defer func(start time.Time) { b.observe(metrics.Bind, start, e) }(b.clock.Now())
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	defer func() {
+		out := b.store.Save(logger)
+		if e == nil {
+			e = out
+		}
+	}()
+
+	logger.Info("starting-k8sbroker-bind")
+	instanceDetails, err := b.store.RetrieveInstanceDetails(instanceID)
+	if err != nil {
+		return brokerapi.Binding{}, brokerapi.ErrInstanceDoesNotExist
+	}
+	logger.Info("retrieved-instance-details", lager.Data{"instanceDetails": instanceDetails})
+
+	fingerprint, err := getFingerprint(instanceDetails.ServiceFingerPrint)
+	if err != nil {
+		return brokerapi.Binding{}, err
+	}
+	if fingerprint.DeleteAfter != nil {
+		return brokerapi.Binding{}, brokerapi.ErrInstanceDoesNotExist
+	}
+	if fingerprint.provisionInProgress() {
+		logger.Info("provision-in-progress")
+		return brokerapi.Binding{}, brokerapi.ErrConcurrentInstanceAccess
+	}
+
+	params := make(map[string]interface{})
+	logger.Debug(fmt.Sprintf("bindDetails: %#v", bindDetails.RawParameters))
+
+	if bindDetails.RawParameters != nil {
+		err = json.Unmarshal(bindDetails.RawParameters, &params)
+		if err != nil {
+			return brokerapi.Binding{}, err
+		}
+	}
+
+	params, err = b.validator.Validate(params)
+	if err != nil {
+		logger.Error("invalid-bind-parameters", err)
+		return brokerapi.Binding{}, err
+	}
+
+	if b.bindingConflicts(bindingID, bindDetails) {
+		return brokerapi.Binding{}, brokerapi.ErrBindingAlreadyExists
+	}
+
+	if _, ok := fingerprint.Bindings[bindingID]; !ok && b.quotas.MaxPVCPerInstance > 0 && len(fingerprint.Bindings) >= b.quotas.MaxPVCPerInstance {
+		logger.Info("claim-quota-exceeded", lager.Data{"bindings": len(fingerprint.Bindings), "max": b.quotas.MaxPVCPerInstance})
+		return brokerapi.Binding{}, brokerapi.ErrPlanQuotaExceeded
+	}
+
+	cfMode, k8sMode, err := evaluateMode(params, b.servicesRegistry.ModeMapper(instanceDetails.ServiceID))
+	if err != nil {
+		logger.Error("failed-to-evaluate-mode", err)
+		return brokerapi.Binding{}, err
+	}
+
+	namespace, err := b.evaluateNamespace(params)
+	if err != nil {
+		return brokerapi.Binding{}, err
+	}
+
+	labels, annotations, err := evaluateMetadata(params)
+	if err != nil {
+		logger.Error("invalid-metadata", err)
+		return brokerapi.Binding{}, err
+	}
+
+	serviceAccount, err := evaluateServiceAccount(params)
+	if err != nil {
+		return brokerapi.Binding{}, err
+	}
+	if serviceAccount != "" {
+		annotations = mergeMetadata(annotations, map[string]string{ServiceAccountAnnotation: serviceAccount})
+	}
+
+	err = b.ensureNamespace(logger, namespace, instanceDetails)
+	if err != nil {
+		return brokerapi.Binding{}, err
+	}
+
+	var claimName string
+	var claimRequest *v1.PersistentVolumeClaim
+	if fingerprint.DynamicProvisioning {
+		// every binding in a namespace shares the instance's claim, as the
+		// claim is what holds the instance's data
+		claimName = fingerprint.ResourceName()
+		claimRequest = BuildDynamicPersistentVolumeClaim(claimName, namespace, fingerprint, k8sMode,
+			WithClaimLabels(mergeMetadata(mergeMetadata(b.extraPVCLabels, mergeMetadata(fingerprint.Labels, labels)), b.instanceLabels(instanceID))),
+			WithClaimAnnotations(mergeMetadata(fingerprint.Annotations, annotations)),
+			WithClaimVolumeMode(fingerprint.volumeMode()),
+		)
+	} else {
+		switch b.pvcNaming {
+		case PVCNamingBindingID:
+			claimName = fingerprint.NamePrefix + bindingID
+		case PVCNamingVolumeBindingID:
+			claimName = fmt.Sprintf("%s-%s", fingerprint.Volume.Name, shortID(bindingID))
+		default:
+			claimName = fingerprint.Volume.Name
+		}
+		claimRequest = BuildPersistentVolumeClaim(claimName, namespace, b.labelPrefix, fingerprint, k8sMode,
+			WithClaimStorageClass(fingerprint.Volume.Spec.StorageClassName),
+			WithClaimLabels(mergeMetadata(mergeMetadata(b.extraPVCLabels, labels), b.bindingLabels(instanceID, bindingID))),
+			WithClaimAnnotations(annotations),
+			WithClaimVolumeMode(fingerprint.volumeMode()),
+		)
+	}
+
+	// a claim that earlier bindings use must outlive a failed bind
+	sharedClaim := fingerprint.claimInUse(namespace, claimName, bindingID) ||
+		fingerprint.DynamicProvisioning && contains(fingerprint.ClaimNamespaces, namespace)
+
+	volumeClaim, err := b.getOrCreatePVC(logger, namespace, claimRequest, sharedClaim)
+	if err != nil {
+		return brokerapi.Binding{}, err
+	}
+
+	defer func() {
+		if e != nil && !sharedClaim {
+			err := b.deletePersistentVolumeClaim(rollbackContext(), namespace, claimName)
+			if err != nil {
+				logger.Error("failed-to-cleanup-persistent-volume-claim", err, k8sErrorData(err, lager.Data{"namespace": namespace, "pvc_name": claimName, "volume-claim": volumeClaim}))
+			}
+		}
+	}()
+	logger.Debug("created-volume-claim", lager.Data{"volume-claim": volumeClaim})
+
+	if serviceAccount != "" {
+		err = b.grantClaimAccess(logger, namespace, bindingID, volumeClaim.Name, serviceAccount)
+		if err != nil {
+			return brokerapi.Binding{}, err
+		}
+
+		defer func() {
+			if e != nil {
+				err := b.revokeClaimAccess(namespace, bindingID)
+				if err != nil {
+					logger.Error("failed-to-cleanup-claim-access", err, k8sErrorData(err, lager.Data{"namespace": namespace}))
+				}
+			}
+		}()
+	}
 
 	err = b.store.CreateBindingDetails(bindingID, bindDetails)
 	if err != nil {
-		return brokerapi.Binding{}, err
+		return brokerapi.Binding{}, err
+	}
+
+	if fingerprint.Bindings == nil {
+		fingerprint.Bindings = map[string]BindingFingerPrint{}
+	}
+	if fingerprint.DynamicProvisioning && !contains(fingerprint.ClaimNamespaces, namespace) {
+		fingerprint.ClaimNamespaces = append(fingerprint.ClaimNamespaces, namespace)
+	}
+	fingerprint.Bindings[bindingID] = BindingFingerPrint{
+		ClaimName:      volumeClaim.Name,
+		Namespace:      namespace,
+		AccessMode:     string(k8sMode),
+		ServiceAccount: serviceAccount,
+	}
+	instanceDetails.ServiceFingerPrint = *fingerprint
+	err = b.updateInstanceDetails(instanceID, instanceDetails)
+	if err != nil {
+		return brokerapi.Binding{}, err
+	}
+
+	return b.binding(instanceID, instanceDetails, fingerprint, volumeClaim.Name, params, cfMode), nil
+}
+
+// GetInstance returns the instance with the provision parameters that
+// describe its persistent volume as it currently is in kubernetes. An instance
+// that is still provisioning does not exist yet.
+func (b *Broker) GetInstance(context context.Context, instanceID string) (InstanceDetailsSpec, error) {
+	logger := b.logger.Session("get-instance", lager.Data{"request_id": requestid.FromContext(context)})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	instanceDetails, err := b.store.RetrieveInstanceDetails(instanceID)
+	if err != nil {
+		return InstanceDetailsSpec{}, brokerapi.ErrInstanceDoesNotExist
+	}
+
+	fingerprint, err := getFingerprint(instanceDetails.ServiceFingerPrint)
+	if err != nil {
+		return InstanceDetailsSpec{}, err
+	}
+	if fingerprint.DeleteAfter != nil {
+		return InstanceDetailsSpec{}, brokerapi.ErrInstanceDoesNotExist
+	}
+
+	if fingerprint.ProvisionState != nil && fingerprint.ProvisionState.Status != ProvisionSucceeded {
+		return InstanceDetailsSpec{}, brokerapi.ErrInstanceDoesNotExist
+	}
+
+	dashboardURL, err := b.dashboardURL.Render(*fingerprint)
+	if err != nil {
+		logger.Error("error-rendering-dashboard-url", err)
+		return InstanceDetailsSpec{}, err
+	}
+
+	if fingerprint.DynamicProvisioning {
+		return InstanceDetailsSpec{
+			ServiceID:    instanceDetails.ServiceID,
+			PlanID:       instanceDetails.PlanID,
+			DashboardURL: dashboardURL,
+			Parameters:   dynamicProvisionParameters(fingerprint),
+		}, nil
+	}
+
+	volume, err := b.client.CoreV1().PersistentVolumes().Get(fingerprint.Volume.Name, metav1.GetOptions{})
+	if err != nil {
+		logger.Error("error-getting-persistent-volume", err, k8sErrorData(err, lager.Data{"pv_name": fingerprint.Volume.Name}))
+		return InstanceDetailsSpec{}, err
+	}
+
+	return InstanceDetailsSpec{
+		ServiceID:    instanceDetails.ServiceID,
+		PlanID:       instanceDetails.PlanID,
+		DashboardURL: dashboardURL,
+		Parameters:   provisionParameters(volume, fingerprint),
+	}, nil
+}
+
+// provisionParameters restores the parameters given to create-service from
+// the persistent volume and the fingerprint. Parameters that were not given
+// are left out.
+func provisionParameters(volume *v1.PersistentVolume, fingerprint *ServiceFingerPrint) map[string]interface{} {
+	params := map[string]interface{}{}
+
+	if nfs := volume.Spec.NFS; nfs != nil {
+		params["server"] = nfs.Server
+		params["share"] = nfs.Path
+	}
+	if volume.Spec.PersistentVolumeReclaimPolicy != "" {
+		params["reclaim_policy"] = string(volume.Spec.PersistentVolumeReclaimPolicy)
+	}
+	if len(volume.Spec.MountOptions) > 0 {
+		params["mount_options"] = volume.Spec.MountOptions
+	}
+	if volume.Spec.StorageClassName != "" {
+		params["storage_class_name"] = volume.Spec.StorageClassName
+	}
+	if volume.Spec.NodeAffinity != nil {
+		params["node_affinity"] = volume.Spec.NodeAffinity
+	}
+	if len(fingerprint.Labels) > 0 {
+		params["labels"] = fingerprint.Labels
+	}
+	if len(fingerprint.Annotations) > 0 {
+		params["annotations"] = fingerprint.Annotations
+	}
+	if fingerprint.CapacityRange != nil {
+		params["capacity_range"] = fingerprint.CapacityRange
+	}
+
+	return params
+}
+
+// dynamicProvisionParameters restores the parameters given to create-service
+// for a dynamically provisioned instance, which are all in the fingerprint.
+func dynamicProvisionParameters(fingerprint *ServiceFingerPrint) map[string]interface{} {
+	params := map[string]interface{}{
+		"dynamic_provisioning": true,
+		"storage_class_name":   fingerprint.StorageClassName,
+	}
+	if len(fingerprint.Labels) > 0 {
+		params["labels"] = fingerprint.Labels
+	}
+	if len(fingerprint.Annotations) > 0 {
+		params["annotations"] = fingerprint.Annotations
+	}
+	if fingerprint.CapacityRange != nil {
+		params["capacity_range"] = fingerprint.CapacityRange
+	}
+
+	return params
+}
+
+// GetBinding returns the binding as Bind returned it, rebuilt from the stored
+// bind details and binding fingerprint.
+func (b *Broker) GetBinding(context context.Context, instanceID string, bindingID string) (brokerapi.Binding, error) {
+	logger := b.logger.Session("get-binding", lager.Data{"request_id": requestid.FromContext(context)})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	instanceDetails, err := b.store.RetrieveInstanceDetails(instanceID)
+	if err != nil {
+		return brokerapi.Binding{}, brokerapi.ErrInstanceDoesNotExist
+	}
+
+	bindDetails, err := b.store.RetrieveBindingDetails(bindingID)
+	if err != nil {
+		return brokerapi.Binding{}, brokerapi.ErrBindingDoesNotExist
+	}
+
+	fingerprint, err := getFingerprint(instanceDetails.ServiceFingerPrint)
+	if err != nil {
+		return brokerapi.Binding{}, err
+	}
+	if fingerprint.DeleteAfter != nil {
+		return brokerapi.Binding{}, brokerapi.ErrInstanceDoesNotExist
+	}
+
+	params := make(map[string]interface{})
+	if bindDetails.RawParameters != nil {
+		err = json.Unmarshal(bindDetails.RawParameters, &params)
+		if err != nil {
+			return brokerapi.Binding{}, err
+		}
+	}
+
+	params, err = b.validator.Validate(params)
+	if err != nil {
+		return brokerapi.Binding{}, err
+	}
+
+	cfMode, _, err := evaluateMode(params, b.servicesRegistry.ModeMapper(instanceDetails.ServiceID))
+	if err != nil {
+		return brokerapi.Binding{}, err
+	}
+
+	// bindings created before binding fingerprints were recorded use a claim
+	// named after the volume
+	binding, recorded := fingerprint.Bindings[bindingID]
+	claimName := binding.ClaimName
+	if !recorded {
+		claimName = fingerprint.Volume.Name
+	}
+
+	return b.binding(instanceID, instanceDetails, fingerprint, claimName, params, cfMode), nil
+}
+
+// volumeContext is the volume context recorded for an instance: the broker's
+// extra volume attributes overlaid with the volume's own CSI attributes, or
+// nil when there are neither.
+func (b *Broker) volumeContext(volume *v1.PersistentVolume) map[string]string {
+	volumeContext := csiVolumeContext(volume)
+	if len(b.extraAttributes) == 0 {
+		return volumeContext
+	}
+	return mergeMetadata(b.extraAttributes, volumeContext)
+}
+
+func (b *Broker) binding(instanceID string, instanceDetails brokerstore.ServiceInstance, fingerprint *ServiceFingerPrint, claimName string, params map[string]interface{}, cfMode string) brokerapi.Binding {
+	volumeId := fmt.Sprintf("%s-volume", instanceID)
+
+	// instances provisioned before the volume context was recorded still
+	// carry it on the stored volume
+	volumeContext := fingerprint.VolumeContext
+	if volumeContext == nil {
+		volumeContext = csiVolumeContext(fingerprint.Volume)
+	}
+
+	// the CSI node plugin needs the volume context to publish the volume; the
+	// claim name is the broker's own and is not overridden by it
+	mountConfig := map[string]interface{}{}
+	for key, value := range volumeContext {
+		mountConfig[key] = value
+	}
+	mountConfig["name"] = claimName
+
+	driverName := b.servicesRegistry.DriverName(instanceDetails.ServiceID, instanceDetails.PlanID)
+	if driverName == "" {
+		driverName = DefaultDriverName
+	}
+
+	deviceType := "shared"
+	if fingerprint.volumeMode() == v1.PersistentVolumeBlock {
+		deviceType = "block"
+	}
+
+	return brokerapi.Binding{
+		Credentials: struct{}{}, // if nil, cloud controller chokes on response
+		VolumeMounts: []brokerapi.VolumeMount{{
+			ContainerDir: evaluateContainerPath(params, instanceID),
+			Mode:         cfMode,
+			Driver:       driverName,
+			DeviceType:   deviceType,
+			Device: brokerapi.SharedDevice{
+				VolumeId:    volumeId,
+				MountConfig: mountConfig,
+			},
+		}},
+	}
+}
+
+func (b *Broker) Unbind(context context.Context, instanceID string, bindingID string, details brokerapi.UnbindDetails) (e error) {
+	logger := b.logger.Session("unbind", lager.Data{"request_id": requestid.FromContext(context)})
+	logger.Info("start")
+	defer logger.Info("end")
+	defer func(start time.Time) { b.observe(metrics.Unbind, start, e) }(b.clock.Now())
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	defer func() {
+		out := b.store.Save(logger)
+		if e == nil {
+			e = out
+		}
+	}()
+
+	var instanceDetails brokerstore.ServiceInstance
+	var err error
+	if instanceDetails, err = b.store.RetrieveInstanceDetails(instanceID); err != nil {
+		return brokerapi.ErrInstanceDoesNotExist
+	}
+
+	if _, err := b.store.RetrieveBindingDetails(bindingID); err != nil {
+		return brokerapi.ErrBindingDoesNotExist
+	}
+
+	fingerprint, err := getFingerprint(instanceDetails.ServiceFingerPrint)
+	if err != nil {
+		return err
+	}
+
+	// bindings created before binding fingerprints were recorded use the
+	// broker's namespace and a claim named after the volume
+	binding, recorded := fingerprint.Bindings[bindingID]
+	namespace, claimName := binding.Namespace, binding.ClaimName
+	if !recorded {
+		namespace, claimName = b.namespace, fingerprint.Volume.Name
+	}
+
+	if fingerprint.DynamicProvisioning {
+		// the claim holds the instance's data; Deprovision deletes it
+		logger.Info("keeping-dynamically-provisioned-claim", lager.Data{"namespace": namespace, "claim": claimName})
+	} else if fingerprint.claimInUse(namespace, claimName, bindingID) {
+		logger.Info("keeping-shared-claim", lager.Data{"namespace": namespace, "claim": claimName})
+	} else {
+		// the claim may already be gone, e.g. removed by a cluster admin or
+		// by an earlier unbind that failed later on
+		err = b.deletePersistentVolumeClaim(context, namespace, claimName)
+		if k8serrors.IsNotFound(err) {
+			logger.Info("persistent-volume-claim-already-deleted", lager.Data{"namespace": namespace, "claim": claimName})
+		} else if err != nil {
+			logger.Error("failed-to-delete-persistent-volume-claim", err, k8sErrorData(err, lager.Data{"namespace": namespace, "pvc_name": claimName}))
+			return err
+		}
+	}
+
+	if binding.ServiceAccount != "" {
+		err = b.revokeClaimAccess(namespace, bindingID)
+		if err != nil {
+			logger.Error("failed-to-revoke-claim-access", err, k8sErrorData(err, lager.Data{"namespace": namespace}))
+			return err
+		}
+	}
+
+	if err := b.store.DeleteBindingDetails(bindingID); err != nil {
+		return err
+	}
+
+	if recorded {
+		delete(fingerprint.Bindings, bindingID)
+		instanceDetails.ServiceFingerPrint = *fingerprint
+		if err := b.updateInstanceDetails(instanceID, instanceDetails); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *Broker) Update(context context.Context, instanceID string, details brokerapi.UpdateDetails, asyncAllowed bool) (_ brokerapi.UpdateServiceSpec, e error) {
+	logger := b.logger.Session("update").WithData(lager.Data{"instanceID": instanceID, "details": details})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	defer func() {
+		out := b.store.Save(logger)
+		if e == nil {
+			e = out
+		}
+	}()
+
+	instanceDetails, err := b.store.RetrieveInstanceDetails(instanceID)
+	if err != nil {
+		return brokerapi.UpdateServiceSpec{}, brokerapi.ErrInstanceDoesNotExist
+	}
+
+	fingerprint, err := getFingerprint(instanceDetails.ServiceFingerPrint)
+	if err != nil {
+		return brokerapi.UpdateServiceSpec{}, err
+	}
+	if fingerprint.DeleteAfter != nil {
+		return brokerapi.UpdateServiceSpec{}, brokerapi.ErrInstanceDoesNotExist
+	}
+	if fingerprint.provisionInProgress() {
+		logger.Info("provision-in-progress")
+		return brokerapi.UpdateServiceSpec{}, brokerapi.ErrConcurrentInstanceAccess
+	}
+
+	if details.PlanID != "" && details.PlanID != instanceDetails.PlanID {
+		if !b.planExists(instanceDetails.ServiceID, details.PlanID) {
+			return brokerapi.UpdateServiceSpec{}, brokerapi.NewFailureResponse(ErrInvalidPlan{PlanID: details.PlanID}, http.StatusBadRequest, "invalid-plan")
+		}
+		if !b.servicesRegistry.CanUpgrade(instanceDetails.PlanID, details.PlanID) {
+			logger.Info("plan-change-not-supported", lager.Data{"from_plan_id": instanceDetails.PlanID, "to_plan_id": details.PlanID})
+			return brokerapi.UpdateServiceSpec{}, brokerapi.ErrPlanChangeNotSupported
+		}
+		instanceDetails.PlanID = details.PlanID
+	}
+
+	if len(details.RawParameters) > 0 && fingerprint.DynamicProvisioning {
+		return brokerapi.UpdateServiceSpec{}, ErrDynamicInstanceUpdate
+	}
+
+	if len(details.RawParameters) > 0 {
+		var configuration updateConfig
+		logger.Debug("update-raw-parameters", lager.Data{"RawParameters": details.RawParameters})
+		err = json.Unmarshal(details.RawParameters, &configuration)
+		if err != nil {
+			logger.Error("update-raw-parameters-decode-error", err)
+			return brokerapi.UpdateServiceSpec{}, brokerapi.ErrRawParamsInvalid
+		}
+
+		err = validateNfsConfig(configuration.NfsConfig)
+		if err != nil {
+			return brokerapi.UpdateServiceSpec{}, err
+		}
+
+		err = validateUpdateConfig(fingerprint, configuration)
+		if err != nil {
+			return brokerapi.UpdateServiceSpec{}, err
+		}
+
+		if nfsConfigChanged(fingerprint.Volume, configuration.NfsConfig) {
+			logger.Info("nfs-source-change-refused", lager.Data{"server": configuration.Server, "share": configuration.Share})
+			return brokerapi.UpdateServiceSpec{}, ErrNfsSourceChange
+		}
+
+		if configuration.CapacityRange != nil {
+			err = b.expandVolume(logger, fingerprint, *configuration.CapacityRange)
+			if err != nil {
+				return brokerapi.UpdateServiceSpec{}, err
+			}
+		}
+	}
+
+	instanceDetails.ServiceFingerPrint = *fingerprint
+	err = b.updateInstanceDetails(instanceID, instanceDetails)
+	if err != nil {
+		return brokerapi.UpdateServiceSpec{}, fmt.Errorf("failed to store instance details %s: %w", instanceID, err)
+	}
+	logger.Info("service-instance-updated", lager.Data{"instanceDetails": instanceDetails})
+
+	return brokerapi.UpdateServiceSpec{IsAsync: false}, nil
+}
+
+func (b *Broker) LastOperation(_ context.Context, instanceID string, operationData string) (brokerapi.LastOperation, error) {
+	logger := b.logger.Session("last-operation").WithData(lager.Data{"instanceID": instanceID, "operationData": operationData})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	if operationData != OperationProvision && operationData != OperationDeprovision {
+		return brokerapi.LastOperation{}, fmt.Errorf("unrecognized operation %q", operationData)
+	}
+
+	b.mutex.Lock()
+	instanceDetails, err := b.store.RetrieveInstanceDetails(instanceID)
+	b.mutex.Unlock()
+	if err != nil {
+		return brokerapi.LastOperation{}, brokerapi.ErrInstanceDoesNotExist
+	}
+
+	fingerprint, err := getFingerprint(instanceDetails.ServiceFingerPrint)
+	if err != nil {
+		return brokerapi.LastOperation{}, err
+	}
+
+	if operationData == OperationProvision && fingerprint.ProvisionState != nil {
+		switch fingerprint.ProvisionState.Status {
+		case ProvisionInProgress:
+			return brokerapi.LastOperation{State: brokerapi.InProgress}, nil
+		case ProvisionFailed:
+			return brokerapi.LastOperation{State: brokerapi.Failed, Description: fingerprint.ProvisionState.Description}, nil
+		}
+	}
+
+	// a dynamically provisioned instance has no volume of its own to wait for
+	if fingerprint.DynamicProvisioning {
+		return brokerapi.LastOperation{State: brokerapi.Succeeded}, nil
+	}
+
+	if operationData == OperationProvision {
+		if result, ok := b.provisionResults.Load(instanceID); ok {
+			return result.(brokerapi.LastOperation), nil
+		}
+	}
+
+	volume, err := b.client.CoreV1().PersistentVolumes().Get(fingerprint.Volume.Name, metav1.GetOptions{})
+	if err != nil {
+		if operationData == OperationDeprovision && k8serrors.IsNotFound(err) {
+			return brokerapi.LastOperation{State: brokerapi.Succeeded}, nil
+		}
+		logger.Error("error-getting-persistent-volume", err, k8sErrorData(err, lager.Data{"pv_name": fingerprint.Volume.Name}))
+		return brokerapi.LastOperation{}, err
+	}
+
+	if operationData == OperationDeprovision {
+		return brokerapi.LastOperation{State: brokerapi.InProgress, Description: volume.Status.Message}, nil
+	}
+
+	return lastOperationForVolume(volume), nil
+}
+
+func (b *Broker) observe(operation string, start time.Time, err error) {
+	b.metrics.Observe(operation, b.clock.Since(start), err)
+}
+
+func (b *Broker) instanceConflicts(details brokerstore.ServiceInstance, instanceID string) bool {
+	return b.store.IsInstanceConflict(instanceID, brokerstore.ServiceInstance(details))
+}
+
+func (b *Broker) bindingConflicts(bindingID string, details brokerapi.BindDetails) bool {
+	return b.store.IsBindingConflict(bindingID, details)
+}
+
+func (b *Broker) planExists(serviceID string, planID string) bool {
+	for _, service := range b.servicesRegistry.List() {
+		if serviceID != "" && service.ID != serviceID {
+			continue
+		}
+		for _, plan := range service.Plans {
+			if plan.ID == planID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// updateInstanceDetails replaces the stored details for an existing instance.
+// The SQL-backed stores insert rather than upsert, so the old row is removed
+// first, and put back when the new one cannot be stored.
+func (b *Broker) updateInstanceDetails(instanceID string, details brokerstore.ServiceInstance) error {
+	previous, err := b.store.RetrieveInstanceDetails(instanceID)
+	if err != nil {
+		return err
+	}
+
+	err = b.store.DeleteInstanceDetails(instanceID)
+	if err != nil {
+		return err
+	}
+
+	err = b.store.CreateInstanceDetails(instanceID, details)
+	if err != nil {
+		if restoreErr := b.store.CreateInstanceDetails(instanceID, previous); restoreErr != nil {
+			b.logger.Error("restoring-instance-details-error", restoreErr, lager.Data{"instanceID": instanceID})
+		}
+		return err
+	}
+	return nil
+}
+
+// scheduleDeletion keeps a deprovisioned instance in the store until its grace
+// period is over, so that the deletion survives a broker restart. The volume
+// is annotated with the same time for operators.
+func (b *Broker) scheduleDeletion(logger lager.Logger, instanceID string, instanceDetails brokerstore.ServiceInstance, fingerprint *ServiceFingerPrint) error {
+	deleteAfter := b.clock.Now().Add(b.gracePeriod).UTC()
+
+	err := b.patchAnnotations(logger, fingerprint.Volume.Name, mergeMetadata(fingerprint.Annotations, map[string]string{DeleteAfterAnnotation: deleteAfter.Format(time.RFC3339)}))
+	if err != nil {
+		return err
+	}
+
+	fingerprint.DeleteAfter = &deleteAfter
+	instanceDetails.ServiceFingerPrint = *fingerprint
+	err = b.updateInstanceDetails(instanceID, instanceDetails)
+	if err != nil {
+		return err
+	}
+
+	logger.Info("deletion-scheduled", lager.Data{"instance_id": instanceID, "delete_after": deleteAfter})
+	return nil
+}
+
+// reapplyAnnotations puts the instance's user annotations back on its volume
+// before it is deleted, in case they were changed outside the broker, and then
+// gives tooling that reacts to them, such as backups, the annotation
+// propagation delay to do so.
+func (b *Broker) reapplyAnnotations(logger lager.Logger, fingerprint *ServiceFingerPrint) error {
+	if len(fingerprint.Annotations) == 0 {
+		return nil
+	}
+
+	err := b.patchAnnotations(logger, fingerprint.Volume.Name, fingerprint.Annotations)
+	if k8serrors.IsNotFound(err) {
+		// nothing left to annotate; the delete that follows finds it gone too
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if b.annotationDelay > 0 {
+		b.clock.Sleep(b.annotationDelay)
+	}
+	return nil
+}
+
+func (b *Broker) patchAnnotations(logger lager.Logger, volumeName string, annotations map[string]string) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": annotations,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = b.client.CoreV1().PersistentVolumes().Patch(volumeName, types.MergePatchType, patch)
+	if err != nil {
+		logger.Error("error-annotating-persistent-volume", err, k8sErrorData(err, lager.Data{"pv_name": volumeName}))
+		return err
+	}
+	return nil
+}
+
+// rollbackContext is the context of the clean up after a failed request. It
+// is not the request's own, as a cancelled or timed out request is when the
+// resources it created most need deleting.
+var rollbackContext = context.Background
+
+// deletePersistentVolume deletes the named volume unless ctx is already done.
+// The typed client takes no context, so a delete that has been sent is not
+// cancelled.
+func (b *Broker) deletePersistentVolume(ctx context.Context, volumeName string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return b.client.CoreV1().PersistentVolumes().Delete(volumeName, &metav1.DeleteOptions{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "PersistentVolume",
+			APIVersion: "v1",
+		},
+	})
+}
+
+// checkVolumeQuota counts the persistent volumes labelled as managed by the
+// broker. Provisioning an instance whose volume already exists is allowed, as
+// it does not add a volume. Callers must hold b.mutex.
+func (b *Broker) checkVolumeQuota(logger lager.Logger, instanceID string) error {
+	if b.quotas.MaxPVCount <= 0 {
+		return nil
+	}
+
+	volumes, err := b.client.CoreV1().PersistentVolumes().List(metav1.ListOptions{
+		LabelSelector: b.managedBySelector(),
+	})
+	if err != nil {
+		logger.Error("error-listing-persistent-volumes", err, k8sErrorData(err, nil))
+		return err
+	}
+
+	count := 0
+	for _, volume := range volumes.Items {
+		if b.volumeInstanceID(volume) == instanceID {
+			return nil
+		}
+		count++
+	}
+
+	if count >= b.quotas.MaxPVCount {
+		logger.Info("volume-quota-exceeded", lager.Data{"volumes": count, "max": b.quotas.MaxPVCount})
+		return brokerapi.ErrPlanQuotaExceeded
+	}
+	return nil
+}
+
+// createVolumeWithinQuota holds the lock across the quota check and the
+// volume creation so that concurrent provisions cannot both take the last
+// free slot.
+func (b *Broker) createVolumeWithinQuota(logger lager.Logger, instanceID string, volumeRequest *v1.PersistentVolume) (*v1.PersistentVolume, bool, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	err := b.checkVolumeQuota(logger, instanceID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return b.getOrCreatePersistentVolume(logger, volumeRequest)
+}
+
+// waitForAvailableVolume polls a newly created persistent volume until it is
+// Available, giving up after the provision timeout. It does not hold the
+// lock, as a volume can take minutes to become available.
+func (b *Broker) waitForAvailableVolume(logger lager.Logger, volumeName string) (*v1.PersistentVolume, error) {
+	deadline := b.clock.Now().Add(b.provisionTimeout)
+	for {
+		volume, err := b.client.CoreV1().PersistentVolumes().Get(volumeName, metav1.GetOptions{})
+		if err != nil {
+			logger.Error("error-getting-persistent-volume", err, k8sErrorData(err, lager.Data{"pv_name": volumeName}))
+			return nil, err
+		}
+
+		switch volume.Status.Phase {
+		case v1.VolumeAvailable, v1.VolumeBound:
+			return volume, nil
+		case v1.VolumeFailed:
+			return nil, fmt.Errorf("persistent volume %s failed: %s", volumeName, volume.Status.Message)
+		}
+
+		if !b.clock.Now().Before(deadline) {
+			logger.Info("persistent-volume-not-available", lager.Data{"volume": volumeName, "phase": volume.Status.Phase})
+			return nil, fmt.Errorf("persistent volume %s did not become available within %s, last phase %q", volumeName, b.provisionTimeout, volume.Status.Phase)
+		}
+		b.clock.Sleep(availablePollInterval)
+	}
+}
+
+// getOrCreatePersistentVolume reuses an existing volume with the same name and
+// source, e.g. one left behind when the broker's store was lost, so that
+// provisioning the same instance again succeeds.
+func (b *Broker) getOrCreatePersistentVolume(logger lager.Logger, volume *v1.PersistentVolume) (*v1.PersistentVolume, bool, error) {
+	volumes := b.client.CoreV1().PersistentVolumes()
+
+	existing, err := volumes.Get(volume.Name, metav1.GetOptions{})
+	if err == nil {
+		if !reflect.DeepEqual(existing.Spec.PersistentVolumeSource, volume.Spec.PersistentVolumeSource) {
+			logger.Info("conflicting-persistent-volume", lager.Data{"name": volume.Name})
+			return nil, false, brokerapi.ErrInstanceAlreadyExists
+		}
+		logger.Info("reusing-existing-persistent-volume", lager.Data{"name": volume.Name})
+		return existing, false, nil
+	}
+	if !k8serrors.IsNotFound(err) {
+		logger.Error("error-getting-persistent-volume", err, k8sErrorData(err, lager.Data{"pv_name": volume.Name}))
+		return nil, false, err
+	}
+
+	var created *v1.PersistentVolume
+	err = b.withRetry(retryAttempts, retryBackoff, func() error {
+		created, err = volumes.Create(volume)
+		return err
+	})
+	if err != nil {
+		logger.Error("error-creating-persistent-volume", err, k8sErrorData(err, lager.Data{"pv_name": volume.Name}))
+		if event := b.latestEventMessage(logger, "PersistentVolume", metav1.NamespaceDefault, volume.Name); event != "" {
+			return nil, false, ErrVolumeEvent{Err: err, Event: event}
+		}
+		return nil, false, err
+	}
+	return created, true, nil
+}
+
+// latestEventMessage returns the message of the most recent event about the
+// object of the given kind and name, or "" when there is none. Events about
+// cluster scoped objects, such as persistent volumes, are recorded in the
+// default namespace. The typed client takes no context, so the lookup is
+// abandoned after eventsTimeout rather than hold up the error it explains.
+func (b *Broker) latestEventMessage(logger lager.Logger, kind, namespace, name string) string {
+	type listResult struct {
+		events *v1.EventList
+		err    error
+	}
+	results := make(chan listResult, 1)
+	go func() {
+		events, err := b.client.CoreV1().Events(namespace).List(metav1.ListOptions{
+			FieldSelector: fields.AndSelectors(
+				fields.OneTermEqualSelector("involvedObject.kind", kind),
+				fields.OneTermEqualSelector("involvedObject.name", name),
+			).String(),
+		})
+		results <- listResult{events: events, err: err}
+	}()
+
+	timeout := b.clock.NewTimer(eventsTimeout)
+	defer timeout.Stop()
+
+	var events *v1.EventList
+	select {
+	case result := <-results:
+		if result.err != nil {
+			logger.Error("error-listing-events", result.err, k8sErrorData(result.err, lager.Data{"namespace": namespace, "kind": kind, "name": name}))
+			return ""
+		}
+		events = result.events
+	case <-timeout.C():
+		logger.Info("listing-events-timed-out", lager.Data{"namespace": namespace, "kind": kind, "name": name})
+		return ""
+	}
+	if events == nil || len(events.Items) == 0 {
+		return ""
+	}
+
+	latest := events.Items[0]
+	for _, event := range events.Items[1:] {
+		if latest.LastTimestamp.Before(&event.LastTimestamp) {
+			latest = event
+		}
+	}
+	return latest.Message
+}
+
+// getOrCreatePVC creates the claim, or returns the existing claim of that name
+// so that a retried bind does not fail on a claim left behind by an earlier
+// attempt. A claim labelled with another binding is only returned when
+// shared, i.e. when the instance records that binding as using it, so that
+// Unbind keeps it for the binding that remains.
+func (b *Broker) getOrCreatePVC(logger lager.Logger, namespace string, claim *v1.PersistentVolumeClaim, shared bool) (*v1.PersistentVolumeClaim, error) {
+	claims := b.client.CoreV1().PersistentVolumeClaims(namespace)
+
+	existing, err := claims.Get(claim.Name, metav1.GetOptions{})
+	if err == nil {
+		instanceLabel := b.label(InstanceIDLabel)
+		if owner, ok := existing.Labels[instanceLabel]; ok && owner != claim.Labels[instanceLabel] {
+			logger.Info("claim-owned-by-other-instance", lager.Data{"namespace": namespace, "name": claim.Name, "owner": owner})
+			return nil, ErrClaimOwnedByOtherInstance{Namespace: namespace, Name: claim.Name, InstanceID: owner}
+		}
+		bindingLabel := b.label(BindingIDLabel)
+		if owner, ok := existing.Labels[bindingLabel]; ok && owner != claim.Labels[bindingLabel] && !shared {
+			logger.Info("claim-owned-by-unknown-binding", lager.Data{"namespace": namespace, "name": claim.Name, "owner": owner})
+			return nil, ErrClaimConflict{Namespace: namespace, Name: claim.Name}
+		}
+		if !claimSpecMatches(existing.Spec, claim.Spec) {
+			logger.Info("conflicting-claim", lager.Data{"namespace": namespace, "name": claim.Name})
+			return nil, ErrClaimConflict{Namespace: namespace, Name: claim.Name}
+		}
+		logger.Info("reusing-existing-claim", lager.Data{"namespace": namespace, "name": claim.Name})
+		return existing, nil
+	}
+	if !k8serrors.IsNotFound(err) {
+		logger.Error("error-getting-claim", err, k8sErrorData(err, lager.Data{"namespace": namespace, "pvc_name": claim.Name}))
+		return nil, err
+	}
+
+	var created *v1.PersistentVolumeClaim
+	err = b.withRetry(retryAttempts, retryBackoff, func() error {
+		created, err = claims.Create(claim)
+		return err
+	})
+	if err != nil {
+		logger.Error("error-creating-claim", err, k8sErrorData(err, lager.Data{"namespace": namespace, "pvc_name": claim.Name}))
+		return nil, err
+	}
+	return created, nil
+}
+
+func claimSpecMatches(actual, expected v1.PersistentVolumeClaimSpec) bool {
+	if !reflect.DeepEqual(actual.AccessModes, expected.AccessModes) {
+		return false
+	}
+	if !reflect.DeepEqual(actual.Selector, expected.Selector) {
+		return false
+	}
+	actualStorage := actual.Resources.Requests[v1.ResourceStorage]
+	expectedStorage := expected.Resources.Requests[v1.ResourceStorage]
+	return actualStorage.Cmp(expectedStorage) == 0
+}
+
+func claimAccessName(bindingID string) string {
+	return fmt.Sprintf("k8sbroker-%s", bindingID)
+}
+
+// grantClaimAccess lets a service account get the claim created for a
+// binding, through a Role and RoleBinding named after the binding.
+func (b *Broker) grantClaimAccess(logger lager.Logger, namespace, bindingID, claimName, serviceAccount string) error {
+	name := claimAccessName(bindingID)
+	labels := map[string]string{"app.kubernetes.io/managed-by": "k8sbroker"}
+
+	_, err := b.client.RbacV1().Roles(namespace).Create(&rbacapiv1.Role{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels},
+		Rules: []rbacapiv1.PolicyRule{{
+			APIGroups:     []string{""},
+			Resources:     []string{"persistentvolumeclaims"},
+			ResourceNames: []string{claimName},
+			Verbs:         []string{"get", "list"},
+		}},
+	})
+	if err != nil {
+		logger.Error("error-creating-role", err, k8sErrorData(err, lager.Data{"namespace": namespace, "name": name}))
+		return err
+	}
+
+	_, err = b.client.RbacV1().RoleBindings(namespace).Create(&rbacapiv1.RoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels},
+		Subjects: []rbacapiv1.Subject{{
+			Kind:      rbacapiv1.ServiceAccountKind,
+			Name:      serviceAccount,
+			Namespace: namespace,
+		}},
+		RoleRef: rbacapiv1.RoleRef{
+			APIGroup: rbacapiv1.GroupName,
+			Kind:     "Role",
+			Name:     name,
+		},
+	})
+	if err != nil {
+		logger.Error("error-creating-role-binding", err, k8sErrorData(err, lager.Data{"namespace": namespace, "name": name}))
+		if err := b.client.RbacV1().Roles(namespace).Delete(name, &metav1.DeleteOptions{}); err != nil {
+			logger.Error("failed-to-cleanup-role", err, k8sErrorData(err, lager.Data{"namespace": namespace, "name": name}))
+		}
+		return err
+	}
+
+	return nil
+}
+
+// revokeClaimAccess removes the RoleBinding and Role created by
+// grantClaimAccess. Objects that are already gone are ignored.
+func (b *Broker) revokeClaimAccess(namespace, bindingID string) error {
+	name := claimAccessName(bindingID)
+
+	err := b.client.RbacV1().RoleBindings(namespace).Delete(name, &metav1.DeleteOptions{})
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return err
+	}
+
+	err = b.client.RbacV1().Roles(namespace).Delete(name, &metav1.DeleteOptions{})
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return err
+	}
+
+	return nil
+}
+
+// ListInstances returns the stored instances keyed by ID. The store cannot
+// enumerate its instances, so they are found through the persistent volumes
+// and claims labelled as managed by the broker. Dynamically provisioned
+// instances that were never bound, and instances still provisioning
+// asynchronously, have neither and are not listed.
+func (b *Broker) ListInstances() (map[string]brokerstore.ServiceInstance, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	instanceIDs, err := b.managedInstanceIDs()
+	if err != nil {
+		return nil, err
+	}
+
+	instances := map[string]brokerstore.ServiceInstance{}
+	for _, instanceID := range instanceIDs {
+		instance, err := b.store.RetrieveInstanceDetails(instanceID)
+		if err != nil {
+			continue
+		}
+		instances[instanceID] = instance
+	}
+	return instances, nil
+}
+
+// managedInstanceIDs returns the IDs of the instances named by the persistent
+// volumes and claims labelled as managed by the broker, each ID once.
+func (b *Broker) managedInstanceIDs() ([]string, error) {
+	volumes, err := b.client.CoreV1().PersistentVolumes().List(metav1.ListOptions{
+		LabelSelector: b.managedBySelector(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	claims, err := b.client.CoreV1().PersistentVolumeClaims(metav1.NamespaceAll).List(metav1.ListOptions{
+		LabelSelector: b.managedBySelector(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var instanceIDs []string
+	seen := map[string]bool{}
+	add := func(instanceID string) {
+		if instanceID != "" && !seen[instanceID] {
+			seen[instanceID] = true
+			instanceIDs = append(instanceIDs, instanceID)
+		}
+	}
+	for _, volume := range volumes.Items {
+		add(b.volumeInstanceID(volume))
+	}
+	for _, claim := range claims.Items {
+		add(claim.Labels[b.label(InstanceIDLabel)])
+	}
+	return instanceIDs, nil
+}
+
+// BindingSummary describes a binding of an instance together with the current
+// phase of its claim. Phase is empty when the claim no longer exists.
+type BindingSummary struct {
+	BindingID  string `json:"binding_id"`
+	AppGUID    string `json:"app_guid,omitempty"`
+	ClaimName  string `json:"claim_name"`
+	Namespace  string `json:"namespace"`
+	AccessMode string `json:"access_mode"`
+	Phase      string `json:"phase,omitempty"`
+}
+
+// ListBindings returns the bindings of an instance, ordered by binding ID. The
+// store cannot list bindings, so they are read from the instance fingerprint.
+func (b *Broker) ListBindings(context context.Context, instanceID string) ([]BindingSummary, error) {
+	logger := b.logger.Session("list-bindings", lager.Data{"request_id": requestid.FromContext(context), "instance_id": instanceID})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	instanceDetails, err := b.store.RetrieveInstanceDetails(instanceID)
+	if err != nil {
+		return nil, brokerapi.ErrInstanceDoesNotExist
+	}
+
+	fingerprint, err := getFingerprint(instanceDetails.ServiceFingerPrint)
+	if err != nil {
+		return nil, err
+	}
+	if fingerprint.DeleteAfter != nil {
+		return nil, brokerapi.ErrInstanceDoesNotExist
+	}
+
+	bindingIDs := make([]string, 0, len(fingerprint.Bindings))
+	for bindingID := range fingerprint.Bindings {
+		bindingIDs = append(bindingIDs, bindingID)
+	}
+	sort.Strings(bindingIDs)
+
+	summaries := []BindingSummary{}
+	for _, bindingID := range bindingIDs {
+		binding := fingerprint.Bindings[bindingID]
+		summary := BindingSummary{
+			BindingID:  bindingID,
+			ClaimName:  binding.ClaimName,
+			Namespace:  binding.Namespace,
+			AccessMode: binding.AccessMode,
+		}
+
+		bindDetails, err := b.store.RetrieveBindingDetails(bindingID)
+		if err == nil {
+			summary.AppGUID = bindDetails.AppGUID
+		}
+
+		claim, err := b.client.CoreV1().PersistentVolumeClaims(binding.Namespace).Get(binding.ClaimName, metav1.GetOptions{})
+		if err != nil && !k8serrors.IsNotFound(err) {
+			logger.Error("error-getting-persistent-volume-claim", err, k8sErrorData(err, lager.Data{"namespace": binding.Namespace, "pvc_name": binding.ClaimName, "binding_id": bindingID}))
+			return nil, err
+		}
+		if err == nil {
+			summary.Phase = string(claim.Status.Phase)
+		}
+
+		summaries = append(summaries, summary)
+	}
+	return summaries, nil
+}
+
+// StartHealthChecks lists the broker's persistent volumes every interval and
+// reports any in the Failed phase, e.g. because its NFS server went away,
+// through the logs and metrics.
+func (b *Broker) StartHealthChecks(interval time.Duration) {
+	ticker := b.clock.NewTicker(interval)
+	go func() {
+		for range ticker.C() {
+			b.checkVolumeHealth()
+		}
+	}()
+}
+
+// StartDeferredDeletes deletes the volumes of instances deprovisioned with a
+// grace period once it is over, checking every interval.
+func (b *Broker) StartDeferredDeletes(interval time.Duration) {
+	ticker := b.clock.NewTicker(interval)
+	go func() {
+		for range ticker.C() {
+			b.deleteExpiredVolumes()
+		}
+	}()
+}
+
+func (b *Broker) deleteExpiredVolumes() {
+	logger := b.logger.Session("deferred-delete")
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	volumes, err := b.client.CoreV1().PersistentVolumes().List(metav1.ListOptions{
+		LabelSelector: b.managedBySelector(),
+	})
+	if err != nil {
+		logger.Error("error-listing-persistent-volumes", err, k8sErrorData(err, nil))
+		return
+	}
+
+	deleted := false
+	for _, volume := range volumes.Items {
+		instanceID := b.volumeInstanceID(volume)
+		instanceDetails, err := b.store.RetrieveInstanceDetails(instanceID)
+		if err != nil {
+			continue
+		}
+		fingerprint, err := getFingerprint(instanceDetails.ServiceFingerPrint)
+		if err != nil || fingerprint.DeleteAfter == nil || b.clock.Now().Before(*fingerprint.DeleteAfter) {
+			continue
+		}
+
+		logger.Info("deleting-persistent-volume", lager.Data{"instance_id": instanceID})
+		err = b.deletePersistentVolume(context.Background(), volume.Name)
+		if err != nil && !k8serrors.IsNotFound(err) {
+			logger.Error("error-deleting-persistent-volume", err, k8sErrorData(err, lager.Data{"pv_name": volume.Name, "instance_id": instanceID}))
+			continue
+		}
+
+		err = b.store.DeleteInstanceDetails(instanceID)
+		if err != nil {
+			logger.Error("error-deleting-instance-details", err, lager.Data{"instance_id": instanceID})
+			continue
+		}
+		deleted = true
+	}
+
+	if deleted {
+		err = b.store.Save(logger)
+		if err != nil {
+			logger.Error("error-saving-store", err)
+		}
+	}
+}
+
+func (b *Broker) checkVolumeHealth() {
+	logger := b.logger.Session("volume-health-check")
+
+	volumes, err := b.client.CoreV1().PersistentVolumes().List(metav1.ListOptions{
+		LabelSelector: b.managedBySelector(),
+	})
+	if err != nil {
+		logger.Error("error-listing-persistent-volumes", err, k8sErrorData(err, nil))
+		return
+	}
+
+	for _, volume := range volumes.Items {
+		if volume.Status.Phase == v1.VolumeFailed {
+			logger.Error("volume-failed", errors.New(volume.Status.Message), lager.Data{"instance_id": b.volumeInstanceID(volume), "reason": volume.Status.Reason})
+			b.metrics.VolumeHealthCheckFailed()
+		}
+	}
+}
+
+// volumeInstanceID is the ID of the instance a volume was provisioned for.
+// Volumes provisioned before --resourceNamePrefix was set are named after
+// their instance alone.
+func (b *Broker) volumeInstanceID(volume v1.PersistentVolume) string {
+	return strings.TrimPrefix(volume.Name, b.namePrefix)
+}
+
+// DeleteOrphanedVolumes deletes the persistent volumes labelled as managed by
+// the broker that have no instance in the store, e.g. after the store was
+// lost. It is destructive and only run when an operator opts in.
+func (b *Broker) DeleteOrphanedVolumes(logger lager.Logger) error {
+	logger = logger.Session("delete-orphaned-volumes")
+	logger.Info("start")
+	defer logger.Info("end")
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	volumes, err := b.orphanedVolumes(logger)
+	if err != nil {
+		return err
+	}
+
+	for _, volume := range volumes {
+		logger.Info("deleting-orphaned-persistent-volume", lager.Data{"volume": volume.Name})
+		err = b.deletePersistentVolume(context.Background(), volume.Name)
+		if err != nil && !k8serrors.IsNotFound(err) {
+			logger.Error("error-deleting-orphaned-persistent-volume", err, k8sErrorData(err, lager.Data{"pv_name": volume.Name}))
+			return err
+		}
+	}
+
+	return nil
+}
+
+// PurgeStalePVCs deletes the claims that Bind labelled with a binding the
+// store has no entry for, as left behind when the broker stops between
+// creating a claim and saving its binding. Claims are looked for in every
+// namespace, as the namespace bind parameter and the instances' recorded
+// claim namespaces may put them outside the broker's own. It returns the
+// number of claims deleted.
+func (b *Broker) PurgeStalePVCs(ctx context.Context) (int, error) {
+	logger := b.logger.Session("purge-stale-pvcs")
+	logger.Info("start")
+	defer logger.Info("end")
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	claims, err := b.client.CoreV1().PersistentVolumeClaims(metav1.NamespaceAll).List(metav1.ListOptions{
+		LabelSelector: b.managedBySelector(),
+	})
+	if err != nil {
+		logger.Error("error-listing-persistent-volume-claims", err, k8sErrorData(err, nil))
+		return 0, err
 	}
 
-	volumeId := fmt.Sprintf("%s-volume", instanceID)
+	deleted := 0
+	for _, claim := range claims.Items {
+		if err := ctx.Err(); err != nil {
+			return deleted, err
+		}
 
-	return brokerapi.Binding{
-		Credentials: struct{}{}, // if nil, cloud controller chokes on response
-		VolumeMounts: []brokerapi.VolumeMount{{
-			ContainerDir: evaluateContainerPath(params, instanceID),
-			Mode:         cfMode,
-			Driver:       "nfs",
-			DeviceType:   "shared",
-			Device: brokerapi.SharedDevice{
-				VolumeId: volumeId,
-				MountConfig: map[string]interface{}{
-					"name": volumeClaim.Name,
-				},
-			},
-		}},
-	}, nil
+		bindingID := claim.Labels[b.label(BindingIDLabel)]
+		if bindingID == "" {
+			continue
+		}
+		if _, err := b.store.RetrieveBindingDetails(bindingID); err == nil {
+			continue
+		}
+		// the binding the claim was created for is gone, but claims named
+		// after the volume are kept for the instance's other bindings
+		if b.claimInUse(claim.Labels[b.label(InstanceIDLabel)], claim.Namespace, claim.Name) {
+			continue
+		}
+
+		logger.Info("deleting-stale-persistent-volume-claim", lager.Data{"namespace": claim.Namespace, "claim": claim.Name, "binding-id": bindingID})
+		err = b.deletePersistentVolumeClaim(ctx, claim.Namespace, claim.Name)
+		if err != nil && !k8serrors.IsNotFound(err) {
+			logger.Error("error-deleting-stale-persistent-volume-claim", err, k8sErrorData(err, lager.Data{"namespace": claim.Namespace, "pvc_name": claim.Name}))
+			return deleted, err
+		}
+		deleted++
+	}
+
+	return deleted, nil
 }
 
-func (b *Broker) Unbind(context context.Context, instanceID string, bindingID string, details brokerapi.UnbindDetails) (e error) {
-	logger := b.logger.Session("unbind")
+// claimInUse reports whether any binding of the instance uses the claim.
+func (b *Broker) claimInUse(instanceID, namespace, claimName string) bool {
+	if instanceID == "" {
+		return false
+	}
+	instanceDetails, err := b.store.RetrieveInstanceDetails(instanceID)
+	if err != nil {
+		return false
+	}
+	fingerprint, err := getFingerprint(instanceDetails.ServiceFingerPrint)
+	if err != nil {
+		return false
+	}
+	return fingerprint.claimInUse(namespace, claimName, "")
+}
+
+// RecoverPartialProvision deletes the persistent volumes a synchronous
+// Provision created but never stored an instance for, as left behind when the
+// broker stops between the two. Unlike DeleteOrphanedVolumes it leaves alone
+// volumes that are bound to a claim, waiting out a deprovision grace period,
+// not named with the broker's resource name prefix or younger than minAge, so
+// that a provision still running on another broker keeps its volume.
+func (b *Broker) RecoverPartialProvision(logger lager.Logger, minAge time.Duration) error {
+	logger = logger.Session("recover-partial-provision")
 	logger.Info("start")
 	defer logger.Info("end")
 
 	b.mutex.Lock()
 	defer b.mutex.Unlock()
-	defer func() {
-		out := b.store.Save(logger)
-		if e == nil {
-			e = out
+
+	volumes, err := b.orphanedVolumes(logger)
+	if err != nil {
+		return err
+	}
+
+	for _, volume := range volumes {
+		if volume.Status.Phase == v1.VolumeBound {
+			continue
+		}
+		if _, ok := volume.Annotations[DeleteAfterAnnotation]; ok {
+			continue
+		}
+		if !strings.HasPrefix(volume.Name, b.namePrefix) {
+			continue
+		}
+		if b.clock.Since(volume.CreationTimestamp.Time) < minAge {
+			logger.Info("skipping-young-persistent-volume", lager.Data{"volume": volume.Name, "created": volume.CreationTimestamp.Time})
+			continue
 		}
-	}()
 
-	var instanceDetails brokerstore.ServiceInstance
-	var err error
-	if instanceDetails, err = b.store.RetrieveInstanceDetails(instanceID); err != nil {
-		return brokerapi.ErrInstanceDoesNotExist
+		logger.Info("deleting-partially-provisioned-persistent-volume", lager.Data{"volume": volume.Name})
+		err = b.deletePersistentVolume(context.Background(), volume.Name)
+		if err != nil && !k8serrors.IsNotFound(err) {
+			logger.Error("error-deleting-partially-provisioned-persistent-volume", err, k8sErrorData(err, lager.Data{"pv_name": volume.Name}))
+			return err
+		}
 	}
 
-	if _, err := b.store.RetrieveBindingDetails(bindingID); err != nil {
-		return brokerapi.ErrBindingDoesNotExist
+	return nil
+}
+
+// orphanedVolumes lists the persistent volumes managed by the broker that
+// have no instance in the store.
+func (b *Broker) orphanedVolumes(logger lager.Logger) ([]v1.PersistentVolume, error) {
+	volumes, err := b.client.CoreV1().PersistentVolumes().List(metav1.ListOptions{
+		LabelSelector: b.managedBySelector(),
+	})
+	if err != nil {
+		logger.Error("error-listing-persistent-volumes", err, k8sErrorData(err, nil))
+		return nil, err
 	}
 
-	fingerprint, err := getFingerprint(instanceDetails.ServiceFingerPrint)
+	var orphaned []v1.PersistentVolume
+	for _, volume := range volumes.Items {
+		if _, err := b.store.RetrieveInstanceDetails(b.volumeInstanceID(volume)); err != nil {
+			orphaned = append(orphaned, volume)
+		}
+	}
+	return orphaned, nil
+}
+
+// deletePersistentVolumeClaim deletes the named claim unless ctx is already
+// done, as deletePersistentVolume does.
+func (b *Broker) deletePersistentVolumeClaim(ctx context.Context, namespace string, volumeClaimName string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return b.client.CoreV1().PersistentVolumeClaims(namespace).Delete(volumeClaimName, &metav1.DeleteOptions{})
+}
+
+func (b *Broker) evaluateNamespace(parameters map[string]interface{}) (string, error) {
+	if namespace, ok := parameters["namespace"]; ok {
+		namespace, ok := namespace.(string)
+		if !ok || namespace == "" {
+			return "", brokerapi.ErrRawParamsInvalid
+		}
+		if len(b.allowedNamespaces) > 0 && !b.allowedNamespaces[namespace] {
+			return "", brokerapi.NewFailureResponse(fmt.Errorf("namespace %q is not allowed", namespace), http.StatusForbidden, "namespace-not-allowed")
+		}
+		return namespace, nil
+	}
+
+	return b.namespace, nil
+}
+
+// ensureNamespace creates the namespace for a claim if it does not exist yet,
+// labelling it with the org and space of the service instance.
+func (b *Broker) ensureNamespace(logger lager.Logger, namespace string, instanceDetails brokerstore.ServiceInstance) error {
+	_, err := b.client.CoreV1().Namespaces().Get(namespace, metav1.GetOptions{})
+	if err == nil {
+		return nil
+	}
+	if !k8serrors.IsNotFound(err) {
+		logger.Error("error-getting-namespace", err, k8sErrorData(err, lager.Data{"namespace": namespace}))
+		return err
+	}
+
+	_, err = b.client.CoreV1().Namespaces().Create(&v1.Namespace{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Namespace",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: namespace,
+			Labels: map[string]string{
+				b.label(ManagedByLabel):  "k8sbroker",
+				"cloudfoundry.org/org":   instanceDetails.OrganizationGUID,
+				"cloudfoundry.org/space": instanceDetails.SpaceGUID,
+				SpaceLabel:               instanceDetails.SpaceGUID,
+			},
+		},
+	})
+	if err != nil && !k8serrors.IsAlreadyExists(err) {
+		logger.Error("error-creating-namespace", err, k8sErrorData(err, lager.Data{"namespace": namespace}))
+		return err
+	}
+
+	return nil
+}
+
+// renderShare renders the path_template of a provision in place of its
+// share, which must then not be given as well.
+func renderShare(configuration NfsConfig, data PathTemplateData) (string, error) {
+	if configuration.Share != "" {
+		return "", ValidationErrors{{Field: "path_template", Message: "config cannot have both \"share\" and \"path_template\""}}
+	}
+
+	share, err := RenderPathTemplate(configuration.PathTemplate, data)
+	if err != nil {
+		return "", ValidationErrors{{Field: "path_template", Message: err.Error()}}
+	}
+	return share, nil
+}
+
+func validateNfsConfig(configuration NfsConfig) error {
+	var errs ValidationErrors
+
+	if configuration.DynamicProvisioning {
+		// the storage class's provisioner finds the storage, not the broker
+		if configuration.StorageClassName == "" {
+			errs = append(errs, ValidationError{Field: "storage_class_name", Message: "config with \"dynamic_provisioning\" requires a \"storage_class_name\""})
+		}
+	} else {
+		if configuration.Server == "" {
+			errs = append(errs, ValidationError{Field: "server", Message: "config requires a \"server\""})
+		}
+
+		if configuration.Share == "" {
+			errs = append(errs, ValidationError{Field: "share", Message: "config requires a \"share\""})
+		}
+	}
+
+	switch v1.PersistentVolumeMode(configuration.VolumeMode) {
+	case "", v1.PersistentVolumeFilesystem, v1.PersistentVolumeBlock:
+	default:
+		errs = append(errs, ValidationError{Field: "volume_mode", Message: "config \"volume_mode\" must be \"Filesystem\" or \"Block\""})
+	}
+
+	if configuration.NodeAffinity != nil {
+		if configuration.NodeAffinity.Required == nil || len(configuration.NodeAffinity.Required.NodeSelectorTerms) == 0 {
+			errs = append(errs, ValidationError{Field: "node_affinity", Message: "config \"node_affinity\" requires at least one \"nodeSelectorTerms\" entry"})
+		}
+	}
+
+	if capacityRange := configuration.CapacityRange; capacityRange != nil {
+		if capacityRange.RequiredBytes < 0 || capacityRange.LimitBytes < 0 {
+			errs = append(errs, ValidationError{Field: "capacity_range", Message: "config \"capacity_range\" must not be negative"})
+		} else if capacityRange.LimitBytes != 0 && capacityRange.LimitBytes < capacityRange.RequiredBytes {
+			errs = append(errs, ValidationError{Field: "capacity_range", Message: "config \"capacity_range\" limitBytes must not be less than requiredBytes"})
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	err := validateMetadata(configuration.Labels, configuration.Annotations)
+	if err != nil {
+		return err
+	}
+
+	switch v1.PersistentVolumeReclaimPolicy(configuration.ReclaimPolicy) {
+	case "", v1.PersistentVolumeReclaimRetain, v1.PersistentVolumeReclaimDelete:
+	default:
+		return ErrInvalidReclaimPolicy{Policy: configuration.ReclaimPolicy}
+	}
+
+	return nil
+}
+
+// validateMountOptions checks each option, e.g. "timeo=600" or "hard", by
+// name against the options the broker was configured to allow.
+func (b *Broker) validateMountOptions(options []string) error {
+	for _, option := range options {
+		name := strings.SplitN(option, "=", 2)[0]
+		if !b.validator.Allowed(name) {
+			return fmt.Errorf("mount option %q is not allowed", option)
+		}
+	}
+	return nil
+}
+
+// expandVolume grows the volume of an instance and the claims bound to it when
+// the required capacity is larger than the volume's current capacity. NFS
+// volumes cannot be expanded, and the storage class of any other volume must
+// allow volume expansion. The new capacity is recorded in the fingerprint so
+// that later claims request it.
+func (b *Broker) expandVolume(logger lager.Logger, fingerprint *ServiceFingerPrint, capacityRange CapacityRange) error {
+	requested := resource.NewQuantity(capacityRange.RequiredBytes, resource.BinarySI)
+	current := fingerprint.Volume.Spec.Capacity[v1.ResourceStorage]
+	if capacityRange.RequiredBytes == 0 || requested.Cmp(current) <= 0 {
+		fingerprint.CapacityRange = &capacityRange
+		return nil
+	}
+
+	if fingerprint.Volume.Spec.NFS != nil {
+		return ErrVolumeExpansionUnsupported
+	}
+
+	storageClassName := fingerprint.Volume.Spec.StorageClassName
+	if storageClassName == "" {
+		return fmt.Errorf("volume %s has no storage class and cannot be expanded", fingerprint.Volume.Name)
+	}
+
+	storageClass, err := b.client.StorageV1().StorageClasses().Get(storageClassName, metav1.GetOptions{})
 	if err != nil {
+		logger.Error("error-getting-storage-class", err, k8sErrorData(err, lager.Data{"storageClassName": storageClassName}))
 		return err
 	}
+	if storageClass.AllowVolumeExpansion == nil || !*storageClass.AllowVolumeExpansion {
+		return fmt.Errorf("storage class %q does not allow volume expansion", storageClassName)
+	}
 
-	err = b.deletePersistentVolumeClaim(fingerprint.Volume.Name)
+	patch, err := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"resources": map[string]interface{}{
+				"requests": map[string]string{string(v1.ResourceStorage): requested.String()},
+			},
+		},
+	})
 	if err != nil {
 		return err
 	}
 
-	if err := b.store.DeleteBindingDetails(bindingID); err != nil {
+	for bindingID, binding := range fingerprint.Bindings {
+		_, err = b.client.CoreV1().PersistentVolumeClaims(binding.Namespace).Patch(binding.ClaimName, types.StrategicMergePatchType, patch)
+		if err != nil {
+			logger.Error("error-patching-persistent-volume-claim", err, k8sErrorData(err, lager.Data{"namespace": binding.Namespace, "pvc_name": binding.ClaimName, "bindingID": bindingID}))
+			return err
+		}
+	}
+
+	patch, err = json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"capacity": map[string]string{string(v1.ResourceStorage): requested.String()},
+		},
+	})
+	if err != nil {
 		return err
 	}
+
+	_, err = b.client.CoreV1().PersistentVolumes().Patch(fingerprint.Volume.Name, types.StrategicMergePatchType, patch)
+	if err != nil {
+		logger.Error("error-patching-persistent-volume", err, k8sErrorData(err, lager.Data{"pv_name": fingerprint.Volume.Name}))
+		return err
+	}
+
+	volume := fingerprint.Volume.DeepCopy()
+	if volume.Spec.Capacity == nil {
+		volume.Spec.Capacity = v1.ResourceList{}
+	}
+	volume.Spec.Capacity[v1.ResourceStorage] = *requested
+	fingerprint.Volume = volume
+	fingerprint.CapacityRange = &capacityRange
 	return nil
 }
 
-func (b *Broker) Update(context context.Context, instanceID string, details brokerapi.UpdateDetails, asyncAllowed bool) (brokerapi.UpdateServiceSpec, error) {
-	panic("not implemented")
+func (b *Broker) validateStorageClass(logger lager.Logger, name string) error {
+	if name == "" {
+		return nil
+	}
+
+	_, err := b.client.StorageV1().StorageClasses().Get(name, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		return fmt.Errorf("storage class %q does not exist", name)
+	}
+	if err != nil {
+		logger.Error("error-getting-storage-class", err, k8sErrorData(err, lager.Data{"storageClassName": name}))
+		return err
+	}
+
+	return nil
 }
 
-func (b *Broker) LastOperation(_ context.Context, instanceID string, operationData string) (brokerapi.LastOperation, error) {
-	return brokerapi.LastOperation{}, nil
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
 }
 
-func (b *Broker) instanceConflicts(details brokerstore.ServiceInstance, instanceID string) bool {
-	return b.store.IsInstanceConflict(instanceID, brokerstore.ServiceInstance(details))
+// label prefixes a key of a label the broker manages with --labelPrefix.
+func (b *Broker) label(key string) string {
+	return fmt.Sprintf("%s%s", b.labelPrefix, key)
 }
 
-func (b *Broker) bindingConflicts(bindingID string, details brokerapi.BindDetails) bool {
-	return b.store.IsBindingConflict(bindingID, details)
+func (b *Broker) managedBySelector() string {
+	return fmt.Sprintf("%s=k8sbroker", b.label(ManagedByLabel))
 }
 
-func (b *Broker) deletePersistentVolume(volumeName string) error {
-	return b.client.CoreV1().PersistentVolumes().Delete(volumeName, &metav1.DeleteOptions{
-		TypeMeta: metav1.TypeMeta{
-			Kind:       "PersistentVolume",
-			APIVersion: "v1",
-		},
-	})
+// shortID returns the first eight characters of a binding ID, without the
+// trailing hyphens a claim name cannot end with.
+func shortID(id string) string {
+	if len(id) > 8 {
+		id = id[:8]
+	}
+	return strings.TrimRight(id, "-")
+}
+
+// instanceLabels mark a claim created for instanceID. The claim of a
+// dynamically provisioned instance is shared by its bindings and gets only
+// these labels, so that it is never purged as another binding's claim. An ID
+// that is not a valid label value is left off; such claims are not told apart
+// from other instances' claims.
+func (b *Broker) instanceLabels(instanceID string) map[string]string {
+	labels := map[string]string{b.label(ManagedByLabel): "k8sbroker"}
+	if len(validation.IsValidLabelValue(instanceID)) == 0 {
+		labels[b.label(InstanceIDLabel)] = instanceID
+	}
+	return labels
+}
+
+// bindingLabels mark a claim created for bindingID of instanceID. A binding ID
+// that is not a valid label value is left off; such claims are never purged.
+func (b *Broker) bindingLabels(instanceID, bindingID string) map[string]string {
+	labels := b.instanceLabels(instanceID)
+	if len(validation.IsValidLabelValue(bindingID)) == 0 {
+		labels[b.label(BindingIDLabel)] = bindingID
+	}
+	return labels
+}
+
+// k8sErrorData adds the status code and reason of a Kubernetes API error to
+// the data logged with it, so that errors can be counted by kind. Errors that
+// did not come from the API server are logged with code 0 and no reason.
+func k8sErrorData(err error, data lager.Data) lager.Data {
+	withReason := lager.Data{
+		"k8s_error_code": int32(0),
+		"k8s_reason":     string(k8serrors.ReasonForError(err)),
+	}
+	if status, ok := err.(k8serrors.APIStatus); ok {
+		withReason["k8s_error_code"] = status.Status().Code
+	}
+	for key, value := range data {
+		withReason[key] = value
+	}
+	return withReason
+}
+
+// mergeMetadata adds the broker's own labels or annotations to those given by
+// the user. The broker's values always win.
+func mergeMetadata(userValues map[string]string, brokerValues map[string]string) map[string]string {
+	merged := make(map[string]string, len(userValues)+len(brokerValues))
+	for key, value := range userValues {
+		merged[key] = value
+	}
+	for key, value := range brokerValues {
+		merged[key] = value
+	}
+	return merged
+}
+
+func validateMetadata(labels map[string]string, annotations map[string]string) error {
+	for key, value := range labels {
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return fmt.Errorf("invalid label key %q: %s", key, strings.Join(errs, "; "))
+		}
+		if errs := validation.IsValidLabelValue(value); len(errs) > 0 {
+			return fmt.Errorf("invalid label value %q: %s", value, strings.Join(errs, "; "))
+		}
+	}
+
+	for key := range annotations {
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return fmt.Errorf("invalid annotation key %q: %s", key, strings.Join(errs, "; "))
+		}
+	}
+
+	return nil
+}
+
+func evaluateServiceAccount(parameters map[string]interface{}) (string, error) {
+	raw, ok := parameters["service_account"]
+	if !ok {
+		return "", nil
+	}
+
+	serviceAccount, ok := raw.(string)
+	if !ok || len(validation.IsDNS1123Subdomain(serviceAccount)) > 0 {
+		return "", brokerapi.ErrRawParamsInvalid
+	}
+	return serviceAccount, nil
+}
+
+func evaluateMetadata(parameters map[string]interface{}) (map[string]string, map[string]string, error) {
+	labels, err := evaluateStringMap(parameters, "labels")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	annotations, err := evaluateStringMap(parameters, "annotations")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = validateMetadata(labels, annotations)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return labels, annotations, nil
+}
+
+func evaluateStringMap(parameters map[string]interface{}, key string) (map[string]string, error) {
+	raw, ok := parameters[key]
+	if !ok {
+		return nil, nil
+	}
+
+	rawMap, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, brokerapi.ErrRawParamsInvalid
+	}
+
+	result := make(map[string]string, len(rawMap))
+	for k, v := range rawMap {
+		value, ok := v.(string)
+		if !ok {
+			return nil, brokerapi.ErrRawParamsInvalid
+		}
+		result[k] = value
+	}
+	return result, nil
+}
+
+// updateConfig is the parameters of an update: those of a provision, and the
+// name of the instance, which cannot change.
+type updateConfig struct {
+	NfsConfig
+	Name string `json:"name"`
+}
+
+// validateUpdateConfig checks the update parameters against the instance.
+// An instance provisioned with a capacity_range must keep one, and its limit
+// cannot drop below the volume's capacity.
+func validateUpdateConfig(fingerprint *ServiceFingerPrint, configuration updateConfig) error {
+	var errs ValidationErrors
+
+	if configuration.Name != "" && configuration.Name != fingerprint.Name {
+		errs = append(errs, ValidationError{Field: "name", Message: fmt.Sprintf("config \"name\" cannot be changed from %q", fingerprint.Name)})
+	}
+
+	if configuration.CapacityRange == nil {
+		if fingerprint.CapacityRange != nil {
+			errs = append(errs, ValidationError{Field: "capacity_range", Message: "config requires a \"capacity_range\", as the instance was provisioned with one"})
+		}
+	} else if limit := configuration.CapacityRange.LimitBytes; limit > 0 && fingerprint.Volume != nil {
+		capacity := fingerprint.Volume.Spec.Capacity[v1.ResourceStorage]
+		if resource.NewQuantity(limit, resource.BinarySI).Cmp(capacity) < 0 {
+			errs = append(errs, ValidationError{Field: "capacity_range", Message: fmt.Sprintf("config \"capacity_range\" limitBytes must not be less than the volume's capacity of %s", capacity.String())})
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func nfsConfigChanged(volume *v1.PersistentVolume, configuration NfsConfig) bool {
+	if volume.Spec.NFS == nil {
+		return true
+	}
+	return volume.Spec.NFS.Server != configuration.Server || volume.Spec.NFS.Path != configuration.Share
 }
 
-func (b *Broker) deletePersistentVolumeClaim(volumeClaimName string) error {
-	return b.client.CoreV1().PersistentVolumeClaims(b.namespace).Delete(volumeClaimName, &metav1.DeleteOptions{})
+// lastOperationForVolume maps a persistent volume phase onto an OSB operation
+// state. A volume that has not been claimed yet is Available, which is the
+// expected end state of a provision.
+func lastOperationForVolume(volume *v1.PersistentVolume) brokerapi.LastOperation {
+	switch volume.Status.Phase {
+	case v1.VolumeAvailable, v1.VolumeBound:
+		return brokerapi.LastOperation{State: brokerapi.Succeeded}
+	case v1.VolumeFailed:
+		return brokerapi.LastOperation{State: brokerapi.Failed, Description: volume.Status.Message}
+	default:
+		return brokerapi.LastOperation{State: brokerapi.InProgress, Description: volume.Status.Message}
+	}
 }
 
 func evaluateContainerPath(parameters map[string]interface{}, volId string) string {
@@ -462,29 +2942,54 @@ func evaluateContainerPath(parameters map[string]interface{}, volId string) stri
 	return path.Join(DefaultContainerPath, volId)
 }
 
-func evaluateMode(parameters map[string]interface{}) (string, v1.PersistentVolumeAccessMode, error) {
+// evaluateMode maps the readonly parameter with the service's mapper. An
+// access_mode parameter names the claim's access mode itself.
+func evaluateMode(parameters map[string]interface{}, mapper ModeMapper) (string, v1.PersistentVolumeAccessMode, error) {
+	readonly := false
 	if ro, ok := parameters["readonly"]; ok {
-		switch ro := ro.(type) {
-		case bool:
-			if ro {
-				return "r", v1.ReadOnlyMany, nil
-			}
-			break
-		default:
+		ro, ok := ro.(bool)
+		if !ok {
 			return "", "", brokerapi.ErrRawParamsInvalid
 		}
+		readonly = ro
 	}
 
-	return "rw", v1.ReadWriteMany, nil
+	if am, ok := parameters["access_mode"]; ok {
+		am, ok := am.(string)
+		if !ok {
+			return "", "", brokerapi.ErrRawParamsInvalid
+		}
+		mode, ok := accessModes[am]
+		if !ok {
+			return "", "", brokerapi.ErrRawParamsInvalid
+		}
+		if _, ok := parameters["readonly"]; ok && readonly != (mode == v1.ReadOnlyMany) {
+			return "", "", brokerapi.ErrRawParamsInvalid
+		}
+		// the service's driver only supports the modes its mapper gives
+		if _, readWrite, err := mapper.MapMode(false); err == nil && mode == readWrite {
+			return "rw", mode, nil
+		}
+		if _, readOnly, err := mapper.MapMode(true); err == nil && mode == readOnly {
+			return "r", mode, nil
+		}
+		return "", "", ValidationErrors{{Field: "access_mode", Message: fmt.Sprintf("config \"access_mode\" %q is not supported by the service", am)}}
+	}
+
+	return mapper.MapMode(readonly)
 }
 
 func getFingerprint(rawObject interface{}) (*ServiceFingerPrint, error) {
 	fingerprint, ok := rawObject.(*ServiceFingerPrint)
 	if ok {
+		migrateFingerprint(fingerprint)
 		return fingerprint, nil
 	}
 
-	// casting didn't work--try marshalling and unmarshalling as the correct type
+	// casting didn't work--try marshalling and unmarshalling as the correct type.
+	// Unmarshal matches keys case insensitively, so entries written with
+	// lowercase keys by older brokers still load, and are saved back with the
+	// current keys the next time the instance is updated.
 	rawJson, err := json.Marshal(rawObject)
 	if err != nil {
 		return nil, err
@@ -496,5 +3001,24 @@ func getFingerprint(rawObject interface{}) (*ServiceFingerPrint, error) {
 		return nil, err
 	}
 
+	migrateFingerprint(fingerprint)
 	return fingerprint, nil
 }
+
+// migrateFingerprint upgrades a fingerprint written by an older broker to
+// currentFingerprintVersion. Fingerprints without a version predate the
+// reclaim policy and node affinity fields, which are back-filled from the
+// volume.
+func migrateFingerprint(fingerprint *ServiceFingerPrint) {
+	if fingerprint.Version == 0 {
+		if fingerprint.Volume != nil {
+			if fingerprint.ReclaimPolicy == "" {
+				fingerprint.ReclaimPolicy = fingerprint.Volume.Spec.PersistentVolumeReclaimPolicy
+			}
+			if fingerprint.NodeAffinity == nil {
+				fingerprint.NodeAffinity = fingerprint.Volume.Spec.NodeAffinity
+			}
+		}
+		fingerprint.Version = 1
+	}
+}
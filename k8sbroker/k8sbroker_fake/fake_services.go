@@ -2,13 +2,28 @@
 package k8sbroker_fake
 
 import (
+	"context"
+	"encoding/json"
 	"sync"
+	"time"
 
 	"code.cloudfoundry.org/k8sbroker/k8sbroker"
 	"github.com/pivotal-cf/brokerapi"
 )
 
 type FakeServices struct {
+	CanUpgradeStub        func(fromPlanID string, toPlanID string) bool
+	canUpgradeMutex       sync.RWMutex
+	canUpgradeArgsForCall []struct {
+		fromPlanID string
+		toPlanID   string
+	}
+	canUpgradeReturns struct {
+		result1 bool
+	}
+	canUpgradeReturnsOnCall map[int]struct {
+		result1 bool
+	}
 	ListStub        func() []brokerapi.Service
 	listMutex       sync.RWMutex
 	listArgsForCall []struct{}
@@ -18,10 +33,149 @@ type FakeServices struct {
 	listReturnsOnCall map[int]struct {
 		result1 []brokerapi.Service
 	}
+	DriverNameStub        func(serviceID string, planID string) string
+	driverNameMutex       sync.RWMutex
+	driverNameArgsForCall []struct {
+		serviceID string
+		planID    string
+	}
+	driverNameReturns struct {
+		result1 string
+	}
+	driverNameReturnsOnCall map[int]struct {
+		result1 string
+	}
+	ReclaimPolicyStub        func(planID string) string
+	reclaimPolicyMutex       sync.RWMutex
+	reclaimPolicyArgsForCall []struct {
+		planID string
+	}
+	reclaimPolicyReturns struct {
+		result1 string
+	}
+	reclaimPolicyReturnsOnCall map[int]struct {
+		result1 string
+	}
+	ValidateParametersStub        func(serviceID string, rawParameters json.RawMessage) error
+	validateParametersMutex       sync.RWMutex
+	validateParametersArgsForCall []struct {
+		serviceID     string
+		rawParameters json.RawMessage
+	}
+	validateParametersReturns struct {
+		result1 error
+	}
+	validateParametersReturnsOnCall map[int]struct {
+		result1 error
+	}
+	ModeMapperStub        func(serviceID string) k8sbroker.ModeMapper
+	modeMapperMutex       sync.RWMutex
+	modeMapperArgsForCall []struct {
+		serviceID string
+	}
+	modeMapperReturns struct {
+		result1 k8sbroker.ModeMapper
+	}
+	modeMapperReturnsOnCall map[int]struct {
+		result1 k8sbroker.ModeMapper
+	}
+	TestAllConnectionsStub        func(ctx context.Context, timeout time.Duration) error
+	testAllConnectionsMutex       sync.RWMutex
+	testAllConnectionsArgsForCall []struct {
+		ctx     context.Context
+		timeout time.Duration
+	}
+	testAllConnectionsReturns struct {
+		result1 error
+	}
+	testAllConnectionsReturnsOnCall map[int]struct {
+		result1 error
+	}
+	ValidateServiceStub        func(serviceID string, timeout time.Duration) error
+	validateServiceMutex       sync.RWMutex
+	validateServiceArgsForCall []struct {
+		serviceID string
+		timeout   time.Duration
+	}
+	validateServiceReturns struct {
+		result1 error
+	}
+	validateServiceReturnsOnCall map[int]struct {
+		result1 error
+	}
+	ConnectionHealthCheckStub        func() map[string]error
+	connectionHealthCheckMutex       sync.RWMutex
+	connectionHealthCheckArgsForCall []struct{}
+	connectionHealthCheckReturns     struct {
+		result1 map[string]error
+	}
+	connectionHealthCheckReturnsOnCall map[int]struct {
+		result1 map[string]error
+	}
+	ReloadStub        func(pathToServicesConfig string) error
+	reloadMutex       sync.RWMutex
+	reloadArgsForCall []struct {
+		pathToServicesConfig string
+	}
+	reloadReturns struct {
+		result1 error
+	}
+	reloadReturnsOnCall map[int]struct {
+		result1 error
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
 
+func (fake *FakeServices) CanUpgrade(fromPlanID string, toPlanID string) bool {
+	fake.canUpgradeMutex.Lock()
+	ret, specificReturn := fake.canUpgradeReturnsOnCall[len(fake.canUpgradeArgsForCall)]
+	fake.canUpgradeArgsForCall = append(fake.canUpgradeArgsForCall, struct {
+		fromPlanID string
+		toPlanID   string
+	}{fromPlanID, toPlanID})
+	fake.recordInvocation("CanUpgrade", []interface{}{fromPlanID, toPlanID})
+	fake.canUpgradeMutex.Unlock()
+	if fake.CanUpgradeStub != nil {
+		return fake.CanUpgradeStub(fromPlanID, toPlanID)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fake.canUpgradeReturns.result1
+}
+
+func (fake *FakeServices) CanUpgradeCallCount() int {
+	fake.canUpgradeMutex.RLock()
+	defer fake.canUpgradeMutex.RUnlock()
+	return len(fake.canUpgradeArgsForCall)
+}
+
+func (fake *FakeServices) CanUpgradeArgsForCall(i int) (string, string) {
+	fake.canUpgradeMutex.RLock()
+	defer fake.canUpgradeMutex.RUnlock()
+	return fake.canUpgradeArgsForCall[i].fromPlanID, fake.canUpgradeArgsForCall[i].toPlanID
+}
+
+func (fake *FakeServices) CanUpgradeReturns(result1 bool) {
+	fake.CanUpgradeStub = nil
+	fake.canUpgradeReturns = struct {
+		result1 bool
+	}{result1}
+}
+
+func (fake *FakeServices) CanUpgradeReturnsOnCall(i int, result1 bool) {
+	fake.CanUpgradeStub = nil
+	if fake.canUpgradeReturnsOnCall == nil {
+		fake.canUpgradeReturnsOnCall = make(map[int]struct {
+			result1 bool
+		})
+	}
+	fake.canUpgradeReturnsOnCall[i] = struct {
+		result1 bool
+	}{result1}
+}
+
 func (fake *FakeServices) List() []brokerapi.Service {
 	fake.listMutex.Lock()
 	ret, specificReturn := fake.listReturnsOnCall[len(fake.listArgsForCall)]
@@ -62,11 +216,409 @@ func (fake *FakeServices) ListReturnsOnCall(i int, result1 []brokerapi.Service)
 	}{result1}
 }
 
+func (fake *FakeServices) DriverName(serviceID string, planID string) string {
+	fake.driverNameMutex.Lock()
+	ret, specificReturn := fake.driverNameReturnsOnCall[len(fake.driverNameArgsForCall)]
+	fake.driverNameArgsForCall = append(fake.driverNameArgsForCall, struct {
+		serviceID string
+		planID    string
+	}{serviceID, planID})
+	fake.recordInvocation("DriverName", []interface{}{serviceID, planID})
+	fake.driverNameMutex.Unlock()
+	if fake.DriverNameStub != nil {
+		return fake.DriverNameStub(serviceID, planID)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fake.driverNameReturns.result1
+}
+
+func (fake *FakeServices) DriverNameCallCount() int {
+	fake.driverNameMutex.RLock()
+	defer fake.driverNameMutex.RUnlock()
+	return len(fake.driverNameArgsForCall)
+}
+
+func (fake *FakeServices) DriverNameArgsForCall(i int) (string, string) {
+	fake.driverNameMutex.RLock()
+	defer fake.driverNameMutex.RUnlock()
+	return fake.driverNameArgsForCall[i].serviceID, fake.driverNameArgsForCall[i].planID
+}
+
+func (fake *FakeServices) DriverNameReturns(result1 string) {
+	fake.DriverNameStub = nil
+	fake.driverNameReturns = struct {
+		result1 string
+	}{result1}
+}
+
+func (fake *FakeServices) DriverNameReturnsOnCall(i int, result1 string) {
+	fake.DriverNameStub = nil
+	if fake.driverNameReturnsOnCall == nil {
+		fake.driverNameReturnsOnCall = make(map[int]struct {
+			result1 string
+		})
+	}
+	fake.driverNameReturnsOnCall[i] = struct {
+		result1 string
+	}{result1}
+}
+
+func (fake *FakeServices) ReclaimPolicy(planID string) string {
+	fake.reclaimPolicyMutex.Lock()
+	ret, specificReturn := fake.reclaimPolicyReturnsOnCall[len(fake.reclaimPolicyArgsForCall)]
+	fake.reclaimPolicyArgsForCall = append(fake.reclaimPolicyArgsForCall, struct {
+		planID string
+	}{planID})
+	fake.recordInvocation("ReclaimPolicy", []interface{}{planID})
+	fake.reclaimPolicyMutex.Unlock()
+	if fake.ReclaimPolicyStub != nil {
+		return fake.ReclaimPolicyStub(planID)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fake.reclaimPolicyReturns.result1
+}
+
+func (fake *FakeServices) ReclaimPolicyCallCount() int {
+	fake.reclaimPolicyMutex.RLock()
+	defer fake.reclaimPolicyMutex.RUnlock()
+	return len(fake.reclaimPolicyArgsForCall)
+}
+
+func (fake *FakeServices) ReclaimPolicyArgsForCall(i int) string {
+	fake.reclaimPolicyMutex.RLock()
+	defer fake.reclaimPolicyMutex.RUnlock()
+	return fake.reclaimPolicyArgsForCall[i].planID
+}
+
+func (fake *FakeServices) ReclaimPolicyReturns(result1 string) {
+	fake.ReclaimPolicyStub = nil
+	fake.reclaimPolicyReturns = struct {
+		result1 string
+	}{result1}
+}
+
+func (fake *FakeServices) ReclaimPolicyReturnsOnCall(i int, result1 string) {
+	fake.ReclaimPolicyStub = nil
+	if fake.reclaimPolicyReturnsOnCall == nil {
+		fake.reclaimPolicyReturnsOnCall = make(map[int]struct {
+			result1 string
+		})
+	}
+	fake.reclaimPolicyReturnsOnCall[i] = struct {
+		result1 string
+	}{result1}
+}
+
+func (fake *FakeServices) ValidateParameters(serviceID string, rawParameters json.RawMessage) error {
+	fake.validateParametersMutex.Lock()
+	ret, specificReturn := fake.validateParametersReturnsOnCall[len(fake.validateParametersArgsForCall)]
+	fake.validateParametersArgsForCall = append(fake.validateParametersArgsForCall, struct {
+		serviceID     string
+		rawParameters json.RawMessage
+	}{serviceID, rawParameters})
+	fake.recordInvocation("ValidateParameters", []interface{}{serviceID, rawParameters})
+	fake.validateParametersMutex.Unlock()
+	if fake.ValidateParametersStub != nil {
+		return fake.ValidateParametersStub(serviceID, rawParameters)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fake.validateParametersReturns.result1
+}
+
+func (fake *FakeServices) ValidateParametersCallCount() int {
+	fake.validateParametersMutex.RLock()
+	defer fake.validateParametersMutex.RUnlock()
+	return len(fake.validateParametersArgsForCall)
+}
+
+func (fake *FakeServices) ValidateParametersArgsForCall(i int) (string, json.RawMessage) {
+	fake.validateParametersMutex.RLock()
+	defer fake.validateParametersMutex.RUnlock()
+	return fake.validateParametersArgsForCall[i].serviceID, fake.validateParametersArgsForCall[i].rawParameters
+}
+
+func (fake *FakeServices) ValidateParametersReturns(result1 error) {
+	fake.ValidateParametersStub = nil
+	fake.validateParametersReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeServices) ValidateParametersReturnsOnCall(i int, result1 error) {
+	fake.ValidateParametersStub = nil
+	if fake.validateParametersReturnsOnCall == nil {
+		fake.validateParametersReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.validateParametersReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeServices) ModeMapper(serviceID string) k8sbroker.ModeMapper {
+	fake.modeMapperMutex.Lock()
+	ret, specificReturn := fake.modeMapperReturnsOnCall[len(fake.modeMapperArgsForCall)]
+	fake.modeMapperArgsForCall = append(fake.modeMapperArgsForCall, struct {
+		serviceID string
+	}{serviceID})
+	fake.recordInvocation("ModeMapper", []interface{}{serviceID})
+	fake.modeMapperMutex.Unlock()
+	if fake.ModeMapperStub != nil {
+		return fake.ModeMapperStub(serviceID)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fake.modeMapperReturns.result1
+}
+
+func (fake *FakeServices) ModeMapperCallCount() int {
+	fake.modeMapperMutex.RLock()
+	defer fake.modeMapperMutex.RUnlock()
+	return len(fake.modeMapperArgsForCall)
+}
+
+func (fake *FakeServices) ModeMapperArgsForCall(i int) string {
+	fake.modeMapperMutex.RLock()
+	defer fake.modeMapperMutex.RUnlock()
+	return fake.modeMapperArgsForCall[i].serviceID
+}
+
+func (fake *FakeServices) ModeMapperReturns(result1 k8sbroker.ModeMapper) {
+	fake.ModeMapperStub = nil
+	fake.modeMapperReturns = struct {
+		result1 k8sbroker.ModeMapper
+	}{result1}
+}
+
+func (fake *FakeServices) ModeMapperReturnsOnCall(i int, result1 k8sbroker.ModeMapper) {
+	fake.ModeMapperStub = nil
+	if fake.modeMapperReturnsOnCall == nil {
+		fake.modeMapperReturnsOnCall = make(map[int]struct {
+			result1 k8sbroker.ModeMapper
+		})
+	}
+	fake.modeMapperReturnsOnCall[i] = struct {
+		result1 k8sbroker.ModeMapper
+	}{result1}
+}
+
+func (fake *FakeServices) TestAllConnections(ctx context.Context, timeout time.Duration) error {
+	fake.testAllConnectionsMutex.Lock()
+	ret, specificReturn := fake.testAllConnectionsReturnsOnCall[len(fake.testAllConnectionsArgsForCall)]
+	fake.testAllConnectionsArgsForCall = append(fake.testAllConnectionsArgsForCall, struct {
+		ctx     context.Context
+		timeout time.Duration
+	}{ctx, timeout})
+	fake.recordInvocation("TestAllConnections", []interface{}{ctx, timeout})
+	fake.testAllConnectionsMutex.Unlock()
+	if fake.TestAllConnectionsStub != nil {
+		return fake.TestAllConnectionsStub(ctx, timeout)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fake.testAllConnectionsReturns.result1
+}
+
+func (fake *FakeServices) TestAllConnectionsCallCount() int {
+	fake.testAllConnectionsMutex.RLock()
+	defer fake.testAllConnectionsMutex.RUnlock()
+	return len(fake.testAllConnectionsArgsForCall)
+}
+
+func (fake *FakeServices) TestAllConnectionsArgsForCall(i int) (context.Context, time.Duration) {
+	fake.testAllConnectionsMutex.RLock()
+	defer fake.testAllConnectionsMutex.RUnlock()
+	return fake.testAllConnectionsArgsForCall[i].ctx, fake.testAllConnectionsArgsForCall[i].timeout
+}
+
+func (fake *FakeServices) TestAllConnectionsReturns(result1 error) {
+	fake.TestAllConnectionsStub = nil
+	fake.testAllConnectionsReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeServices) TestAllConnectionsReturnsOnCall(i int, result1 error) {
+	fake.TestAllConnectionsStub = nil
+	if fake.testAllConnectionsReturnsOnCall == nil {
+		fake.testAllConnectionsReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.testAllConnectionsReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeServices) ValidateService(serviceID string, timeout time.Duration) error {
+	fake.validateServiceMutex.Lock()
+	ret, specificReturn := fake.validateServiceReturnsOnCall[len(fake.validateServiceArgsForCall)]
+	fake.validateServiceArgsForCall = append(fake.validateServiceArgsForCall, struct {
+		serviceID string
+		timeout   time.Duration
+	}{serviceID, timeout})
+	fake.recordInvocation("ValidateService", []interface{}{serviceID, timeout})
+	fake.validateServiceMutex.Unlock()
+	if fake.ValidateServiceStub != nil {
+		return fake.ValidateServiceStub(serviceID, timeout)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fake.validateServiceReturns.result1
+}
+
+func (fake *FakeServices) ValidateServiceCallCount() int {
+	fake.validateServiceMutex.RLock()
+	defer fake.validateServiceMutex.RUnlock()
+	return len(fake.validateServiceArgsForCall)
+}
+
+func (fake *FakeServices) ValidateServiceArgsForCall(i int) (string, time.Duration) {
+	fake.validateServiceMutex.RLock()
+	defer fake.validateServiceMutex.RUnlock()
+	return fake.validateServiceArgsForCall[i].serviceID, fake.validateServiceArgsForCall[i].timeout
+}
+
+func (fake *FakeServices) ValidateServiceReturns(result1 error) {
+	fake.ValidateServiceStub = nil
+	fake.validateServiceReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeServices) ValidateServiceReturnsOnCall(i int, result1 error) {
+	fake.ValidateServiceStub = nil
+	if fake.validateServiceReturnsOnCall == nil {
+		fake.validateServiceReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.validateServiceReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeServices) ConnectionHealthCheck() map[string]error {
+	fake.connectionHealthCheckMutex.Lock()
+	ret, specificReturn := fake.connectionHealthCheckReturnsOnCall[len(fake.connectionHealthCheckArgsForCall)]
+	fake.connectionHealthCheckArgsForCall = append(fake.connectionHealthCheckArgsForCall, struct{}{})
+	fake.recordInvocation("ConnectionHealthCheck", []interface{}{})
+	fake.connectionHealthCheckMutex.Unlock()
+	if fake.ConnectionHealthCheckStub != nil {
+		return fake.ConnectionHealthCheckStub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fake.connectionHealthCheckReturns.result1
+}
+
+func (fake *FakeServices) ConnectionHealthCheckCallCount() int {
+	fake.connectionHealthCheckMutex.RLock()
+	defer fake.connectionHealthCheckMutex.RUnlock()
+	return len(fake.connectionHealthCheckArgsForCall)
+}
+
+func (fake *FakeServices) ConnectionHealthCheckReturns(result1 map[string]error) {
+	fake.ConnectionHealthCheckStub = nil
+	fake.connectionHealthCheckReturns = struct {
+		result1 map[string]error
+	}{result1}
+}
+
+func (fake *FakeServices) ConnectionHealthCheckReturnsOnCall(i int, result1 map[string]error) {
+	fake.ConnectionHealthCheckStub = nil
+	if fake.connectionHealthCheckReturnsOnCall == nil {
+		fake.connectionHealthCheckReturnsOnCall = make(map[int]struct {
+			result1 map[string]error
+		})
+	}
+	fake.connectionHealthCheckReturnsOnCall[i] = struct {
+		result1 map[string]error
+	}{result1}
+}
+
+func (fake *FakeServices) Reload(pathToServicesConfig string) error {
+	fake.reloadMutex.Lock()
+	ret, specificReturn := fake.reloadReturnsOnCall[len(fake.reloadArgsForCall)]
+	fake.reloadArgsForCall = append(fake.reloadArgsForCall, struct {
+		pathToServicesConfig string
+	}{pathToServicesConfig})
+	fake.recordInvocation("Reload", []interface{}{pathToServicesConfig})
+	fake.reloadMutex.Unlock()
+	if fake.ReloadStub != nil {
+		return fake.ReloadStub(pathToServicesConfig)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fake.reloadReturns.result1
+}
+
+func (fake *FakeServices) ReloadCallCount() int {
+	fake.reloadMutex.RLock()
+	defer fake.reloadMutex.RUnlock()
+	return len(fake.reloadArgsForCall)
+}
+
+func (fake *FakeServices) ReloadArgsForCall(i int) string {
+	fake.reloadMutex.RLock()
+	defer fake.reloadMutex.RUnlock()
+	return fake.reloadArgsForCall[i].pathToServicesConfig
+}
+
+func (fake *FakeServices) ReloadReturns(result1 error) {
+	fake.ReloadStub = nil
+	fake.reloadReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeServices) ReloadReturnsOnCall(i int, result1 error) {
+	fake.ReloadStub = nil
+	if fake.reloadReturnsOnCall == nil {
+		fake.reloadReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.reloadReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeServices) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
+	fake.canUpgradeMutex.RLock()
+	defer fake.canUpgradeMutex.RUnlock()
 	fake.listMutex.RLock()
 	defer fake.listMutex.RUnlock()
+	fake.driverNameMutex.RLock()
+	defer fake.driverNameMutex.RUnlock()
+	fake.reclaimPolicyMutex.RLock()
+	defer fake.reclaimPolicyMutex.RUnlock()
+	fake.validateParametersMutex.RLock()
+	defer fake.validateParametersMutex.RUnlock()
+	fake.modeMapperMutex.RLock()
+	defer fake.modeMapperMutex.RUnlock()
+	fake.testAllConnectionsMutex.RLock()
+	defer fake.testAllConnectionsMutex.RUnlock()
+	fake.validateServiceMutex.RLock()
+	defer fake.validateServiceMutex.RUnlock()
+	fake.connectionHealthCheckMutex.RLock()
+	defer fake.connectionHealthCheckMutex.RUnlock()
+	fake.reloadMutex.RLock()
+	defer fake.reloadMutex.RUnlock()
 	return fake.invocations
 }
 
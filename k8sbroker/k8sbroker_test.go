@@ -4,10 +4,17 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
 
+	"code.cloudfoundry.org/clock/fakeclock"
 	"code.cloudfoundry.org/goshims/osshim/os_fake"
 	"code.cloudfoundry.org/k8sbroker/k8sbroker"
 	"code.cloudfoundry.org/k8sbroker/k8sbroker/k8sbroker_fake"
+	"code.cloudfoundry.org/k8sbroker/requestid"
 	"code.cloudfoundry.org/lager"
 	"code.cloudfoundry.org/lager/lagertest"
 	"code.cloudfoundry.org/service-broker-store/brokerstore"
@@ -16,50 +23,94 @@ import (
 	. "github.com/onsi/gomega"
 	"github.com/pivotal-cf/brokerapi"
 	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
 )
 
 var _ = Describe("Broker", func() {
 	var (
 		broker                        *k8sbroker.Broker
 		fakeOs                        *os_fake.FakeOs
-		logger                        lager.Logger
+		logger                        *lagertest.TestLogger
 		ctx                           context.Context
 		fakeStore                     *brokerstorefakes.FakeStore
 		fakeK8sClient                 *k8sbroker_fake.FakeK8sClient
+		fakeK8sCoreV1                 *k8sbroker_fake.FakeK8sCoreV1
+		fakeK8sNamespaces             *k8sbroker_fake.FakeK8sNamespaces
+		fakeK8sStorageClasses         *k8sbroker_fake.FakeK8sStorageClasses
 		fakeK8sPersistentVolumes      *k8sbroker_fake.FakeK8sPersistentVolumes
 		fakeK8sPersistentVolumeClaims *k8sbroker_fake.FakeK8sPersistentVolumeClaims
+		fakeK8sEvents                 *k8sbroker_fake.FakeK8sEvents
+		fakeK8sRoles                  *k8sbroker_fake.FakeK8sRoles
+		fakeK8sRoleBindings           *k8sbroker_fake.FakeK8sRoleBindings
 		fakeServices                  *k8sbroker_fake.FakeServices
+		fakeClock                     *fakeclock.FakeClock
+		fakeMetrics                   *k8sbroker_fake.FakeMetrics
+		validator                     *k8sbroker.ParameterValidator
+		config                        k8sbroker.Config
 		err                           error
 	)
 
 	BeforeEach(func() {
+		validator, err = k8sbroker.NewParameterValidator("key,nfsvers,hard,timeo,retrans", "")
+		Expect(err).NotTo(HaveOccurred())
+
 		logger = lagertest.NewTestLogger("test-broker")
 		ctx = context.TODO()
 		fakeOs = &os_fake.FakeOs{}
 		fakeStore = &brokerstorefakes.FakeStore{}
 
 		fakeK8sClient = &k8sbroker_fake.FakeK8sClient{}
-		fakeK8sCoreV1 := &k8sbroker_fake.FakeK8sCoreV1{}
+		fakeK8sCoreV1 = &k8sbroker_fake.FakeK8sCoreV1{}
 		fakeK8sPersistentVolumes = &k8sbroker_fake.FakeK8sPersistentVolumes{}
 		fakeK8sPersistentVolumeClaims = &k8sbroker_fake.FakeK8sPersistentVolumeClaims{}
 		fakeK8sClient.CoreV1Returns(fakeK8sCoreV1)
 		fakeK8sCoreV1.PersistentVolumesReturns(fakeK8sPersistentVolumes)
+		fakeK8sPersistentVolumes.WatchReturns(watch.NewEmptyWatch(), nil)
 		fakeK8sCoreV1.PersistentVolumeClaimsReturns(fakeK8sPersistentVolumeClaims)
+		fakeK8sNamespaces = &k8sbroker_fake.FakeK8sNamespaces{}
+		fakeK8sCoreV1.NamespacesReturns(fakeK8sNamespaces)
+		fakeK8sEvents = &k8sbroker_fake.FakeK8sEvents{}
+		fakeK8sCoreV1.EventsReturns(fakeK8sEvents)
+		fakeK8sStorageV1 := &k8sbroker_fake.FakeK8sStorageV1{}
+		fakeK8sStorageClasses = &k8sbroker_fake.FakeK8sStorageClasses{}
+		fakeK8sClient.StorageV1Returns(fakeK8sStorageV1)
+		fakeK8sStorageV1.StorageClassesReturns(fakeK8sStorageClasses)
+		fakeK8sRbacV1 := &k8sbroker_fake.FakeK8sRbacV1{}
+		fakeK8sRoles = &k8sbroker_fake.FakeK8sRoles{}
+		fakeK8sRoleBindings = &k8sbroker_fake.FakeK8sRoleBindings{}
+		fakeK8sClient.RbacV1Returns(fakeK8sRbacV1)
+		fakeK8sRbacV1.RolesReturns(fakeK8sRoles)
+		fakeK8sRbacV1.RoleBindingsReturns(fakeK8sRoleBindings)
 		fakeServices = &k8sbroker_fake.FakeServices{}
+		fakeServices.ModeMapperReturns(k8sbroker.DefaultAccessModes)
+		fakeServices.CanUpgradeReturns(true)
+		fakeClock = fakeclock.NewFakeClock(time.Now())
+		fakeMetrics = &k8sbroker_fake.FakeMetrics{}
+		config = k8sbroker.Config{
+			Namespace:                "some-namespace",
+			PVCNaming:                k8sbroker.PVCNamingVolumeName,
+			ParallelProvisionWorkers: 10,
+			ProvisionWatchTimeout:    time.Minute,
+		}
 	})
 
 	Context("when creating first time", func() {
-		BeforeEach(func() {
+		JustBeforeEach(func() {
 			broker, err = k8sbroker.New(
 				logger,
 				fakeOs,
-				nil,
+				fakeClock,
 				fakeStore,
 				fakeK8sClient,
-				"some-namespace",
+				validator,
 				fakeServices,
+				fakeMetrics,
+				config,
 			)
 			Expect(err).NotTo(HaveOccurred())
 		})
@@ -81,268 +132,2326 @@ var _ = Describe("Broker", func() {
 			})
 		})
 
-		Context(".Provision", func() {
-			var (
-				instanceID       string
-				provisionDetails brokerapi.ProvisionDetails
-				asyncAllowed     bool
-
-				configuration string
-				err           error
-			)
-
+		Context(".ListInstances", func() {
 			BeforeEach(func() {
-				instanceID = "some-instance-id"
-				configuration = `
-        {
-				 "share": "/export/some-share",
-				 "server": "10.0.0.5"
-        }
-        `
-				provisionDetails = brokerapi.ProvisionDetails{PlanID: "nfs", RawParameters: json.RawMessage(configuration)}
-				asyncAllowed = false
-				fakeStore.RetrieveInstanceDetailsReturns(brokerstore.ServiceInstance{}, errors.New("not found"))
-			})
-
-			JustBeforeEach(func() {
-				_, err = broker.Provision(ctx, instanceID, provisionDetails, asyncAllowed)
+				fakeK8sPersistentVolumes.ListReturns(&v1.PersistentVolumeList{
+					Items: []v1.PersistentVolume{
+						{ObjectMeta: metav1.ObjectMeta{Name: "tracked-instance-id"}},
+						{ObjectMeta: metav1.ObjectMeta{Name: "orphaned-instance-id"}},
+					},
+				}, nil)
+				fakeK8sPersistentVolumeClaims.ListReturns(&v1.PersistentVolumeClaimList{}, nil)
+				fakeStore.RetrieveInstanceDetailsStub = func(id string) (brokerstore.ServiceInstance, error) {
+					switch id {
+					case "tracked-instance-id":
+						return brokerstore.ServiceInstance{ServiceID: "some-service-id"}, nil
+					case "dynamic-instance-id":
+						return brokerstore.ServiceInstance{ServiceID: "dynamic-service-id"}, nil
+					}
+					return brokerstore.ServiceInstance{}, errors.New("not found")
+				}
 			})
 
-			It("should not error", func() {
+			It("returns the stored instances of the volumes managed by the broker", func() {
+				instances, err := broker.ListInstances()
 				Expect(err).NotTo(HaveOccurred())
+				Expect(instances).To(Equal(map[string]brokerstore.ServiceInstance{
+					"tracked-instance-id": {ServiceID: "some-service-id"},
+				}))
+				Expect(fakeK8sPersistentVolumes.ListArgsForCall(0).LabelSelector).To(Equal("managed-by=k8sbroker"))
 			})
 
-			It("should not delete the persistent volume", func() {
-				Expect(fakeK8sPersistentVolumes.DeleteCallCount()).To(Equal(0))
+			Context("when claims managed by the broker name their instance", func() {
+				BeforeEach(func() {
+					fakeK8sPersistentVolumeClaims.ListReturns(&v1.PersistentVolumeClaimList{
+						Items: []v1.PersistentVolumeClaim{
+							{ObjectMeta: metav1.ObjectMeta{Name: "dynamic-claim", Namespace: "some-namespace", Labels: map[string]string{"instance-id": "dynamic-instance-id"}}},
+							{ObjectMeta: metav1.ObjectMeta{Name: "other-dynamic-claim", Namespace: "other-namespace", Labels: map[string]string{"instance-id": "dynamic-instance-id"}}},
+							{ObjectMeta: metav1.ObjectMeta{Name: "tracked-claim", Namespace: "some-namespace", Labels: map[string]string{"instance-id": "tracked-instance-id"}}},
+							{ObjectMeta: metav1.ObjectMeta{Name: "unlabelled-claim", Namespace: "some-namespace"}},
+						},
+					}, nil)
+				})
+
+				It("also returns the instances of those claims, such as dynamically provisioned ones", func() {
+					instances, err := broker.ListInstances()
+					Expect(err).NotTo(HaveOccurred())
+					Expect(instances).To(Equal(map[string]brokerstore.ServiceInstance{
+						"tracked-instance-id": {ServiceID: "some-service-id"},
+						"dynamic-instance-id": {ServiceID: "dynamic-service-id"},
+					}))
+					Expect(fakeK8sCoreV1.PersistentVolumeClaimsArgsForCall(0)).To(Equal(""))
+					Expect(fakeK8sPersistentVolumeClaims.ListArgsForCall(0).LabelSelector).To(Equal("managed-by=k8sbroker"))
+					Expect(fakeStore.RetrieveInstanceDetailsCallCount()).To(Equal(3))
+				})
 			})
 
-			It("should write state", func() {
-				Expect(fakeStore.SaveCallCount()).Should(BeNumerically(">", 0))
+			Context("when the claims cannot be listed", func() {
+				BeforeEach(func() {
+					fakeK8sPersistentVolumeClaims.ListReturns(nil, errors.New("claim-list-failed"))
+				})
+
+				It("errors", func() {
+					_, err := broker.ListInstances()
+					Expect(err).To(MatchError("claim-list-failed"))
+				})
 			})
 
-			It("should send the request to the k8s client", func() {
-				expectedQuantity, err := resource.ParseQuantity("5G")
-				Expect(err).NotTo(HaveOccurred())
-				Expect(fakeK8sPersistentVolumes.CreateCallCount()).To(Equal(1))
-				requestVolume := fakeK8sPersistentVolumes.CreateArgsForCall(0)
-				Expect(requestVolume.TypeMeta).To(Equal(metav1.TypeMeta{
-					Kind:       "PersistentVolume",
-					APIVersion: "v1",
-				}))
-				Expect(requestVolume.ObjectMeta).To(Equal(metav1.ObjectMeta{
-					Name:   "some-instance-id",
-					Labels: map[string]string{"name": "some-instance-id"},
-				}))
-				Expect(requestVolume.Spec.AccessModes).To(Equal([]v1.PersistentVolumeAccessMode{v1.ReadWriteMany}))
-				Expect(requestVolume.Spec.Capacity).To(Equal(v1.ResourceList{v1.ResourceName(v1.ResourceStorage): expectedQuantity}))
-				Expect(requestVolume.Spec.PersistentVolumeSource.NFS.Server).To(Equal("10.0.0.5"))
-				Expect(requestVolume.Spec.PersistentVolumeSource.NFS.Path).To(Equal("/export/some-share"))
+			Context("when a label prefix is set", func() {
+				BeforeEach(func() {
+					config.LabelPrefix = "k8sbroker/"
+				})
+
+				It("selects the volumes by the prefixed label", func() {
+					_, err := broker.ListInstances()
+					Expect(err).NotTo(HaveOccurred())
+					Expect(fakeK8sPersistentVolumes.ListArgsForCall(0).LabelSelector).To(Equal("k8sbroker/managed-by=k8sbroker"))
+				})
 			})
 
-			Context("when creating volume returns volume info", func() {
-				var volInfo *v1.PersistentVolume
+			Context("when a resource name prefix is set", func() {
+				BeforeEach(func() {
+					config.ResourceNamePrefix = "team-a-"
+					fakeK8sPersistentVolumes.ListReturns(&v1.PersistentVolumeList{
+						Items: []v1.PersistentVolume{
+							{ObjectMeta: metav1.ObjectMeta{Name: "team-a-tracked-instance-id"}},
+							{ObjectMeta: metav1.ObjectMeta{Name: "orphaned-instance-id"}},
+						},
+					}, nil)
+				})
+
+				It("keys the instances by their ID without the prefix", func() {
+					instances, err := broker.ListInstances()
+					Expect(err).NotTo(HaveOccurred())
+					Expect(instances).To(Equal(map[string]brokerstore.ServiceInstance{
+						"tracked-instance-id": {ServiceID: "some-service-id"},
+					}))
+				})
+			})
 
+			Context("when the volumes cannot be listed", func() {
 				BeforeEach(func() {
-					volInfo = &v1.PersistentVolume{}
-					fakeK8sPersistentVolumes.CreateReturns(volInfo, nil)
+					fakeK8sPersistentVolumes.ListReturns(nil, errors.New("list-failed"))
 				})
 
-				It("should save it", func() {
-					Expect(fakeK8sPersistentVolumes.CreateCallCount()).To(Equal(1))
+				It("errors", func() {
+					_, err := broker.ListInstances()
+					Expect(err).To(MatchError("list-failed"))
+				})
+			})
+		})
 
-					fingerprint := k8sbroker.ServiceFingerPrint{
+		Context(".ListBindings", func() {
+			var (
+				bindings []k8sbroker.BindingSummary
+				err      error
+			)
+
+			BeforeEach(func() {
+				fakeStore.RetrieveInstanceDetailsReturns(brokerstore.ServiceInstance{
+					ServiceFingerPrint: &k8sbroker.ServiceFingerPrint{
 						Name:   "some-instance-id",
-						Volume: volInfo,
+						Volume: &v1.PersistentVolume{ObjectMeta: metav1.ObjectMeta{Name: "some-instance-id"}},
+						Bindings: map[string]k8sbroker.BindingFingerPrint{
+							"binding-b": {ClaimName: "claim-b", Namespace: "namespace-b", AccessMode: "ReadOnlyMany"},
+							"binding-a": {ClaimName: "claim-a", Namespace: "namespace-a", AccessMode: "ReadWriteMany"},
+						},
+					},
+				}, nil)
+				fakeStore.RetrieveBindingDetailsStub = func(id string) (brokerapi.BindDetails, error) {
+					if id == "binding-a" {
+						return brokerapi.BindDetails{AppGUID: "some-app-guid"}, nil
 					}
-
-					expectedServiceInstance := brokerstore.ServiceInstance{
-						PlanID:             "nfs",
-						ServiceFingerPrint: fingerprint,
+					return brokerapi.BindDetails{}, errors.New("not found")
+				}
+				fakeK8sPersistentVolumeClaims.GetStub = func(name string, options metav1.GetOptions) (*v1.PersistentVolumeClaim, error) {
+					if name == "claim-a" {
+						return &v1.PersistentVolumeClaim{Status: v1.PersistentVolumeClaimStatus{Phase: v1.ClaimBound}}, nil
 					}
+					return nil, k8serrors.NewNotFound(v1.Resource("persistentvolumeclaims"), name)
+				}
+			})
 
-					Expect(fakeStore.CreateInstanceDetailsCallCount()).To(Equal(1))
-					fakeInstanceID, fakeServiceInstance := fakeStore.CreateInstanceDetailsArgsForCall(0)
-					Expect(fakeInstanceID).To(Equal(instanceID))
-					Expect(fakeServiceInstance).To(Equal(expectedServiceInstance))
-					Expect(fakeStore.SaveCallCount()).Should(BeNumerically(">", 0))
-				})
+			JustBeforeEach(func() {
+				bindings, err = broker.ListBindings(ctx, "some-instance-id")
 			})
 
-			Context("when the client returns an error", func() {
-				var createErr error
+			It("returns the bindings ordered by ID with the phase of their claims", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(bindings).To(Equal([]k8sbroker.BindingSummary{
+					{BindingID: "binding-a", AppGUID: "some-app-guid", ClaimName: "claim-a", Namespace: "namespace-a", AccessMode: "ReadWriteMany", Phase: "Bound"},
+					{BindingID: "binding-b", ClaimName: "claim-b", Namespace: "namespace-b", AccessMode: "ReadOnlyMany"},
+				}))
+				Expect(fakeK8sCoreV1.PersistentVolumeClaimsArgsForCall(0)).To(Equal("namespace-a"))
+				Expect(fakeK8sCoreV1.PersistentVolumeClaimsArgsForCall(1)).To(Equal("namespace-b"))
+			})
 
+			Context("when the instance has no bindings", func() {
 				BeforeEach(func() {
-					createErr = errors.New("some-error")
-					fakeK8sPersistentVolumes.CreateReturns(nil, createErr)
+					fakeStore.RetrieveInstanceDetailsReturns(brokerstore.ServiceInstance{
+						ServiceFingerPrint: &k8sbroker.ServiceFingerPrint{Name: "some-instance-id"},
+					}, nil)
 				})
 
-				It("should error", func() {
-					Expect(err).To(Equal(createErr))
+				It("returns an empty list", func() {
+					Expect(err).NotTo(HaveOccurred())
+					Expect(bindings).To(BeEmpty())
+					Expect(bindings).NotTo(BeNil())
 				})
 			})
 
-			Context("create-service was given invalid JSON", func() {
+			Context("when the instance does not exist", func() {
 				BeforeEach(func() {
-					badJson := []byte("{this is not json")
-					provisionDetails = brokerapi.ProvisionDetails{PlanID: "CSI", RawParameters: json.RawMessage(badJson)}
+					fakeStore.RetrieveInstanceDetailsReturns(brokerstore.ServiceInstance{}, errors.New("not found"))
 				})
 
 				It("errors", func() {
-					Expect(err).To(Equal(brokerapi.ErrRawParamsInvalid))
+					Expect(err).To(Equal(brokerapi.ErrInstanceDoesNotExist))
 				})
 			})
 
-			Context("create-service was given valid JSON but no 'server' in parameters", func() {
+			Context("when a claim cannot be fetched", func() {
 				BeforeEach(func() {
-					configuration = `
-					{
-						 "share": "/export/some-share"
-					}
-					`
-					provisionDetails = brokerapi.ProvisionDetails{PlanID: "CSI", RawParameters: json.RawMessage(configuration)}
+					fakeK8sPersistentVolumeClaims.GetStub = nil
+					fakeK8sPersistentVolumeClaims.GetReturns(nil, errors.New("get-failed"))
 				})
 
 				It("errors", func() {
-					Expect(err).To(Equal(errors.New("config requires a \"server\"")))
+					Expect(err).To(MatchError("get-failed"))
 				})
 			})
+		})
 
-			Context("create-service was given valid JSON but no 'share' in parameters", func() {
-				BeforeEach(func() {
-					configuration = `
-					{
-						 "server": "10.0.0.5"
+		Context(".DeleteOrphanedVolumes", func() {
+			var err error
+
+			BeforeEach(func() {
+				fakeK8sPersistentVolumes.ListReturns(&v1.PersistentVolumeList{
+					Items: []v1.PersistentVolume{
+						{ObjectMeta: metav1.ObjectMeta{Name: "tracked-instance-id"}},
+						{ObjectMeta: metav1.ObjectMeta{Name: "orphaned-instance-id"}},
+					},
+				}, nil)
+				fakeStore.RetrieveInstanceDetailsStub = func(id string) (brokerstore.ServiceInstance, error) {
+					if id == "tracked-instance-id" {
+						return brokerstore.ServiceInstance{ServiceID: "some-service-id"}, nil
 					}
-					`
-					provisionDetails = brokerapi.ProvisionDetails{PlanID: "CSI", RawParameters: json.RawMessage(configuration)}
-				})
+					return brokerstore.ServiceInstance{}, errors.New("not found")
+				}
+			})
 
-				It("errors", func() {
-					Expect(err).To(Equal(errors.New("config requires a \"share\"")))
-				})
+			JustBeforeEach(func() {
+				err = broker.DeleteOrphanedVolumes(logger)
 			})
 
-			Context("when the service instance already exists with different details", func() {
-				BeforeEach(func() {
-					fakeStore.IsInstanceConflictReturns(true)
-				})
+			It("lists the volumes managed by the broker", func() {
+				Expect(fakeK8sPersistentVolumes.ListCallCount()).To(Equal(1))
+				Expect(fakeK8sPersistentVolumes.ListArgsForCall(0).LabelSelector).To(Equal("managed-by=k8sbroker"))
+			})
 
-				It("should error", func() {
-					Expect(err).To(Equal(brokerapi.ErrInstanceAlreadyExists))
+			It("deletes only the volumes without an instance in the store", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fakeK8sPersistentVolumes.DeleteCallCount()).To(Equal(1))
+				name, _ := fakeK8sPersistentVolumes.DeleteArgsForCall(0)
+				Expect(name).To(Equal("orphaned-instance-id"))
+				Expect(logger.LogMessages()).To(ContainElement("test-broker.delete-orphaned-volumes.deleting-orphaned-persistent-volume"))
+			})
+
+			Context("when the volumes cannot be listed", func() {
+				BeforeEach(func() {
+					fakeK8sPersistentVolumes.ListReturns(nil, errors.New("list-failed"))
 				})
 
-				It("should delete the persistent volume", func() {
-					Expect(fakeK8sPersistentVolumes.DeleteCallCount()).To(Equal(1))
-					volumeName, deleteOptions := fakeK8sPersistentVolumes.DeleteArgsForCall(0)
-					Expect(volumeName).To(Equal("some-instance-id"))
-					Expect(deleteOptions).To(Equal(&metav1.DeleteOptions{
-						TypeMeta: metav1.TypeMeta{
-							Kind:       "PersistentVolume",
-							APIVersion: "v1",
-						},
-					}))
+				It("errors without deleting anything", func() {
+					Expect(err).To(MatchError("list-failed"))
+					Expect(fakeK8sPersistentVolumes.DeleteCallCount()).To(Equal(0))
 				})
 			})
 
-			Context("when the service instance details creation fails", func() {
+			Context("when a volume cannot be deleted", func() {
 				BeforeEach(func() {
-					fakeStore.CreateInstanceDetailsReturns(errors.New("badness"))
-				})
-
-				It("should error", func() {
-					Expect(err).To(HaveOccurred())
+					fakeK8sPersistentVolumes.DeleteReturns(errors.New("delete-failed"))
 				})
 
-				It("should delete the persistent volume", func() {
-					Expect(fakeK8sPersistentVolumes.DeleteCallCount()).To(Equal(1))
-					volumeName, deleteOptions := fakeK8sPersistentVolumes.DeleteArgsForCall(0)
-					Expect(volumeName).To(Equal("some-instance-id"))
-					Expect(deleteOptions).To(Equal(&metav1.DeleteOptions{
-						TypeMeta: metav1.TypeMeta{
-							Kind:       "PersistentVolume",
-							APIVersion: "v1",
-						},
-					}))
+				It("errors", func() {
+					Expect(err).To(MatchError("delete-failed"))
 				})
 			})
 
-			Context("when the save fails", func() {
+			Context("when a volume is already gone", func() {
 				BeforeEach(func() {
-					fakeStore.SaveReturns(errors.New("badness"))
+					fakeK8sPersistentVolumes.DeleteReturns(k8serrors.NewNotFound(schema.GroupResource{Resource: "persistentvolumes"}, "orphaned-instance-id"))
 				})
 
-				It("should error", func() {
-					Expect(err).To(HaveOccurred())
+				It("does not error", func() {
+					Expect(err).NotTo(HaveOccurred())
 				})
 			})
 		})
 
-		Context(".Deprovision", func() {
+		Context(".RecoverPartialProvision", func() {
 			var (
-				instanceID         string
-				asyncAllowed       bool
-				deprovisionDetails brokerapi.DeprovisionDetails
-				err                error
+				err    error
+				minAge time.Duration
 			)
 
 			BeforeEach(func() {
-				instanceID = "some-instance-id"
-				deprovisionDetails = brokerapi.DeprovisionDetails{PlanID: "Existing", ServiceID: "some-service-id"}
-				asyncAllowed = true
+				minAge = 10 * time.Minute
+				created := metav1.NewTime(fakeClock.Now().Add(-time.Hour))
+				fakeK8sPersistentVolumes.ListReturns(&v1.PersistentVolumeList{
+					Items: []v1.PersistentVolume{
+						{ObjectMeta: metav1.ObjectMeta{Name: "tracked-instance-id", CreationTimestamp: created}},
+						{ObjectMeta: metav1.ObjectMeta{Name: "half-created-instance-id", CreationTimestamp: created}, Status: v1.PersistentVolumeStatus{Phase: v1.VolumeAvailable}},
+						{ObjectMeta: metav1.ObjectMeta{Name: "bound-instance-id", CreationTimestamp: created}, Status: v1.PersistentVolumeStatus{Phase: v1.VolumeBound}},
+						{ObjectMeta: metav1.ObjectMeta{Name: "deprovisioned-instance-id", CreationTimestamp: created, Annotations: map[string]string{k8sbroker.DeleteAfterAnnotation: "2020-01-01T00:00:00Z"}}},
+						{ObjectMeta: metav1.ObjectMeta{Name: "provisioning-instance-id", CreationTimestamp: metav1.NewTime(fakeClock.Now().Add(-time.Minute))}},
+					},
+				}, nil)
+				fakeStore.RetrieveInstanceDetailsStub = func(id string) (brokerstore.ServiceInstance, error) {
+					if id == "tracked-instance-id" {
+						return brokerstore.ServiceInstance{ServiceID: "some-service-id"}, nil
+					}
+					return brokerstore.ServiceInstance{}, errors.New("not found")
+				}
 			})
 
 			JustBeforeEach(func() {
-				_, err = broker.Deprovision(ctx, instanceID, deprovisionDetails, asyncAllowed)
+				err = broker.RecoverPartialProvision(logger, minAge)
 			})
 
-			Context("when the instance does not exist", func() {
+			It("lists the volumes managed by the broker", func() {
+				Expect(fakeK8sPersistentVolumes.ListCallCount()).To(Equal(1))
+				Expect(fakeK8sPersistentVolumes.ListArgsForCall(0).LabelSelector).To(Equal("managed-by=k8sbroker"))
+			})
+
+			It("deletes only the old unbound volumes without an instance in the store", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fakeK8sPersistentVolumes.DeleteCallCount()).To(Equal(1))
+				name, _ := fakeK8sPersistentVolumes.DeleteArgsForCall(0)
+				Expect(name).To(Equal("half-created-instance-id"))
+				Expect(logger.LogMessages()).To(ContainElement("test-broker.recover-partial-provision.deleting-partially-provisioned-persistent-volume"))
+				Expect(logger.LogMessages()).To(ContainElement("test-broker.recover-partial-provision.skipping-young-persistent-volume"))
+			})
+
+			Context("when the minimum age is zero", func() {
 				BeforeEach(func() {
-					instanceID = "does-not-exist"
-					fakeStore.RetrieveInstanceDetailsReturns(brokerstore.ServiceInstance{}, brokerapi.ErrInstanceDoesNotExist)
+					minAge = 0
 				})
 
-				It("should fail", func() {
-					Expect(err).To(Equal(brokerapi.ErrInstanceDoesNotExist))
+				It("deletes the young volumes too", func() {
+					Expect(fakeK8sPersistentVolumes.DeleteCallCount()).To(Equal(2))
 				})
 			})
 
-			Context("given an existing instance", func() {
-				var (
-					previousSaveCallCount int
-				)
-
+			Context("when a resource name prefix is set", func() {
 				BeforeEach(func() {
-					asyncAllowed = false
-
-					fingerprint := k8sbroker.ServiceFingerPrint{
-						Name: "some-instance-id",
-						Volume: &v1.PersistentVolume{
-							TypeMeta: metav1.TypeMeta{
-								Kind:       "PersistentVolume",
-								APIVersion: "v1",
-							},
-							ObjectMeta: metav1.ObjectMeta{
-								Name:   "some-instance-id",
-								Labels: map[string]string{"name": "some-instance-id"},
-							},
-						},
-					}
-
-					// simulate untyped data loaded from a data file
-					jsonFingerprint := &map[string]interface{}{}
-					raw, err := json.Marshal(fingerprint)
-					Expect(err).ToNot(HaveOccurred())
-					err = json.Unmarshal(raw, jsonFingerprint)
-					Expect(err).ToNot(HaveOccurred())
-
-					fakeStore.RetrieveInstanceDetailsReturns(brokerstore.ServiceInstance{
-						ServiceID:          "some-service-id",
-						ServiceFingerPrint: jsonFingerprint,
-					}, nil)
-					previousSaveCallCount = fakeStore.SaveCallCount()
+					config.ResourceNamePrefix = "half-"
 				})
 
-				It("should succeed", func() {
+				It("leaves alone the volumes not named with it", func() {
+					Expect(err).NotTo(HaveOccurred())
+					Expect(fakeK8sPersistentVolumes.DeleteCallCount()).To(Equal(1))
+					name, _ := fakeK8sPersistentVolumes.DeleteArgsForCall(0)
+					Expect(name).To(Equal("half-created-instance-id"))
+				})
+			})
+
+			Context("when the volumes cannot be listed", func() {
+				BeforeEach(func() {
+					fakeK8sPersistentVolumes.ListReturns(nil, errors.New("list-failed"))
+				})
+
+				It("errors without deleting anything", func() {
+					Expect(err).To(MatchError("list-failed"))
+					Expect(fakeK8sPersistentVolumes.DeleteCallCount()).To(Equal(0))
+				})
+			})
+
+			Context("when a volume cannot be deleted", func() {
+				BeforeEach(func() {
+					fakeK8sPersistentVolumes.DeleteReturns(errors.New("delete-failed"))
+				})
+
+				It("errors", func() {
+					Expect(err).To(MatchError("delete-failed"))
+				})
+			})
+
+			Context("when a volume is already gone", func() {
+				BeforeEach(func() {
+					fakeK8sPersistentVolumes.DeleteReturns(k8serrors.NewNotFound(schema.GroupResource{Resource: "persistentvolumes"}, "half-created-instance-id"))
+				})
+
+				It("does not error", func() {
+					Expect(err).NotTo(HaveOccurred())
+				})
+			})
+		})
+
+		Context(".PurgeStalePVCs", func() {
+			var (
+				purged int
+				err    error
+			)
+
+			BeforeEach(func() {
+				fakeK8sPersistentVolumeClaims.ListReturns(&v1.PersistentVolumeClaimList{
+					Items: []v1.PersistentVolumeClaim{
+						{ObjectMeta: metav1.ObjectMeta{Name: "bound-claim", Namespace: "some-namespace", Labels: map[string]string{"binding-id": "bound-binding-id"}}},
+						{ObjectMeta: metav1.ObjectMeta{Name: "stale-claim", Namespace: "some-org-namespace", Labels: map[string]string{"binding-id": "stale-binding-id"}}},
+						{ObjectMeta: metav1.ObjectMeta{Name: "unlabelled-claim", Namespace: "some-namespace"}},
+					},
+				}, nil)
+				fakeStore.RetrieveBindingDetailsStub = func(id string) (brokerapi.BindDetails, error) {
+					if id == "bound-binding-id" {
+						return brokerapi.BindDetails{AppGUID: "some-app-guid"}, nil
+					}
+					return brokerapi.BindDetails{}, errors.New("not found")
+				}
+			})
+
+			JustBeforeEach(func() {
+				purged, err = broker.PurgeStalePVCs(context.Background())
+			})
+
+			It("lists the claims managed by the broker in every namespace", func() {
+				Expect(fakeK8sCoreV1.PersistentVolumeClaimsArgsForCall(0)).To(Equal(""))
+				Expect(fakeK8sPersistentVolumeClaims.ListCallCount()).To(Equal(1))
+				Expect(fakeK8sPersistentVolumeClaims.ListArgsForCall(0).LabelSelector).To(Equal("managed-by=k8sbroker"))
+			})
+
+			It("deletes only the claims whose binding is not in the store", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(purged).To(Equal(1))
+				Expect(fakeK8sPersistentVolumeClaims.DeleteCallCount()).To(Equal(1))
+				name, _ := fakeK8sPersistentVolumeClaims.DeleteArgsForCall(0)
+				Expect(name).To(Equal("stale-claim"))
+				Expect(fakeK8sCoreV1.PersistentVolumeClaimsArgsForCall(1)).To(Equal("some-org-namespace"))
+				Expect(logger.LogMessages()).To(ContainElement("test-broker.new-k8s-broker.purge-stale-pvcs.deleting-stale-persistent-volume-claim"))
+			})
+
+			Context("when another binding of the claim's instance still uses it", func() {
+				BeforeEach(func() {
+					fakeK8sPersistentVolumeClaims.ListReturns(&v1.PersistentVolumeClaimList{
+						Items: []v1.PersistentVolumeClaim{
+							{ObjectMeta: metav1.ObjectMeta{Name: "shared-claim", Namespace: "some-org-namespace", Labels: map[string]string{"binding-id": "stale-binding-id", "instance-id": "some-instance-id"}}},
+						},
+					}, nil)
+					fakeStore.RetrieveInstanceDetailsReturns(brokerstore.ServiceInstance{
+						ServiceFingerPrint: &k8sbroker.ServiceFingerPrint{
+							Name: "some-instance-id",
+							Bindings: map[string]k8sbroker.BindingFingerPrint{
+								"other-binding-id": {ClaimName: "shared-claim", Namespace: "some-org-namespace"},
+							},
+						},
+					}, nil)
+				})
+
+				It("keeps the claim", func() {
+					Expect(err).NotTo(HaveOccurred())
+					Expect(purged).To(Equal(0))
+					Expect(fakeStore.RetrieveInstanceDetailsArgsForCall(0)).To(Equal("some-instance-id"))
+					Expect(fakeK8sPersistentVolumeClaims.DeleteCallCount()).To(Equal(0))
+				})
+			})
+
+			Context("when the claims cannot be listed", func() {
+				BeforeEach(func() {
+					fakeK8sPersistentVolumeClaims.ListReturns(nil, errors.New("list-failed"))
+				})
+
+				It("errors without deleting anything", func() {
+					Expect(err).To(MatchError("list-failed"))
+					Expect(purged).To(Equal(0))
+					Expect(fakeK8sPersistentVolumeClaims.DeleteCallCount()).To(Equal(0))
+				})
+			})
+
+			Context("when a claim cannot be deleted", func() {
+				BeforeEach(func() {
+					fakeK8sPersistentVolumeClaims.DeleteReturns(errors.New("delete-failed"))
+				})
+
+				It("errors", func() {
+					Expect(err).To(MatchError("delete-failed"))
+					Expect(purged).To(Equal(0))
+				})
+			})
+
+			Context("when a claim is already gone", func() {
+				BeforeEach(func() {
+					fakeK8sPersistentVolumeClaims.DeleteReturns(k8serrors.NewNotFound(schema.GroupResource{Resource: "persistentvolumeclaims"}, "stale-claim"))
+				})
+
+				It("does not error", func() {
+					Expect(err).NotTo(HaveOccurred())
+				})
+			})
+		})
+
+		Context(".StartDeferredDeletes", func() {
+			var deleteAfter time.Time
+
+			BeforeEach(func() {
+				fakeK8sPersistentVolumes.ListReturns(&v1.PersistentVolumeList{
+					Items: []v1.PersistentVolume{
+						{ObjectMeta: metav1.ObjectMeta{Name: "expired-instance-id"}},
+						{ObjectMeta: metav1.ObjectMeta{Name: "pending-instance-id"}},
+						{ObjectMeta: metav1.ObjectMeta{Name: "live-instance-id"}},
+					},
+				}, nil)
+				deleteAfter = fakeClock.Now().Add(2 * time.Minute)
+				fakeStore.RetrieveInstanceDetailsStub = func(id string) (brokerstore.ServiceInstance, error) {
+					fingerprint := k8sbroker.ServiceFingerPrint{Name: id}
+					switch id {
+					case "expired-instance-id":
+						expired := fakeClock.Now().Add(-time.Second)
+						fingerprint.DeleteAfter = &expired
+					case "pending-instance-id":
+						fingerprint.DeleteAfter = &deleteAfter
+					}
+					return brokerstore.ServiceInstance{ServiceFingerPrint: fingerprint}, nil
+				}
+			})
+
+			JustBeforeEach(func() {
+				broker.StartDeferredDeletes(time.Minute)
+				fakeClock.WaitForWatcherAndIncrement(time.Minute)
+			})
+
+			It("deletes only the volumes whose grace period is over", func() {
+				Eventually(fakeStore.SaveCallCount).Should(Equal(1))
+				Expect(fakeK8sPersistentVolumes.ListArgsForCall(0).LabelSelector).To(Equal("managed-by=k8sbroker"))
+				Expect(fakeK8sPersistentVolumes.DeleteCallCount()).To(Equal(1))
+				name, _ := fakeK8sPersistentVolumes.DeleteArgsForCall(0)
+				Expect(name).To(Equal("expired-instance-id"))
+				Expect(fakeStore.DeleteInstanceDetailsCallCount()).To(Equal(1))
+				Expect(fakeStore.DeleteInstanceDetailsArgsForCall(0)).To(Equal("expired-instance-id"))
+			})
+
+			Context("when a volume cannot be deleted", func() {
+				BeforeEach(func() {
+					fakeK8sPersistentVolumes.DeleteReturns(errors.New("delete-failed"))
+				})
+
+				It("keeps the instance to try again", func() {
+					Eventually(fakeK8sPersistentVolumes.DeleteCallCount).Should(Equal(1))
+					Consistently(fakeStore.DeleteInstanceDetailsCallCount).Should(Equal(0))
+				})
+			})
+		})
+
+		Context(".StartHealthChecks", func() {
+			BeforeEach(func() {
+				fakeK8sPersistentVolumes.ListReturns(&v1.PersistentVolumeList{
+					Items: []v1.PersistentVolume{
+						{
+							ObjectMeta: metav1.ObjectMeta{Name: "healthy-instance-id"},
+							Status:     v1.PersistentVolumeStatus{Phase: v1.VolumeBound},
+						},
+						{
+							ObjectMeta: metav1.ObjectMeta{Name: "failed-instance-id"},
+							Status:     v1.PersistentVolumeStatus{Phase: v1.VolumeFailed, Message: "recycle failed"},
+						},
+					},
+				}, nil)
+			})
+
+			JustBeforeEach(func() {
+				broker.StartHealthChecks(time.Minute)
+				fakeClock.WaitForWatcherAndIncrement(time.Minute)
+			})
+
+			It("lists the volumes managed by the broker", func() {
+				Eventually(fakeK8sPersistentVolumes.ListCallCount).Should(Equal(1))
+				Expect(fakeK8sPersistentVolumes.ListArgsForCall(0).LabelSelector).To(Equal("managed-by=k8sbroker"))
+			})
+
+			It("reports failed volumes", func() {
+				Eventually(fakeMetrics.VolumeHealthCheckFailedCallCount).Should(Equal(1))
+				Expect(logger.LogMessages()).To(ContainElement("test-broker.new-k8s-broker.volume-health-check.volume-failed"))
+			})
+
+			Context("when the volumes cannot be listed", func() {
+				BeforeEach(func() {
+					fakeK8sPersistentVolumes.ListReturns(nil, errors.New("list-failed"))
+				})
+
+				It("logs the error", func() {
+					Eventually(logger.LogMessages).Should(ContainElement("test-broker.new-k8s-broker.volume-health-check.error-listing-persistent-volumes"))
+					Expect(fakeMetrics.VolumeHealthCheckFailedCallCount()).To(Equal(0))
+				})
+			})
+		})
+
+		Context(".StartNamespaceWatch", func() {
+			var namespaceWatcher *watch.FakeWatcher
+
+			BeforeEach(func() {
+				namespaceWatcher = watch.NewFake()
+				fakeK8sNamespaces.ListReturns(&v1.NamespaceList{
+					Items: []v1.Namespace{{ObjectMeta: metav1.ObjectMeta{
+						Name:   "space-namespace",
+						Labels: map[string]string{"cloudfoundry.org/space-guid": "some-space-guid"},
+					}}},
+				}, nil)
+				fakeK8sNamespaces.WatchReturns(namespaceWatcher, nil)
+				fakeK8sPersistentVolumes.ListReturns(&v1.PersistentVolumeList{
+					Items: []v1.PersistentVolume{{ObjectMeta: metav1.ObjectMeta{Name: "some-instance-id"}}},
+				}, nil)
+				fakeK8sPersistentVolumeClaims.ListReturns(&v1.PersistentVolumeClaimList{}, nil)
+				fakeStore.RetrieveInstanceDetailsReturns(brokerstore.ServiceInstance{
+					ServiceID: "some-service-id",
+					ServiceFingerPrint: &k8sbroker.ServiceFingerPrint{
+						Name:   "some-instance-id",
+						Volume: &v1.PersistentVolume{ObjectMeta: metav1.ObjectMeta{Name: "some-instance-id"}},
+						Bindings: map[string]k8sbroker.BindingFingerPrint{
+							"binding-in-space":  {ClaimName: "some-instance-id", Namespace: "space-namespace"},
+							"binding-elsewhere": {ClaimName: "some-instance-id", Namespace: "other-namespace"},
+						},
+					},
+				}, nil)
+			})
+
+			JustBeforeEach(func() {
+				broker.StartNamespaceWatch()
+				Eventually(fakeK8sNamespaces.WatchCallCount).Should(Equal(1))
+			})
+
+			It("watches the namespaces labelled with a CF space", func() {
+				Expect(fakeK8sNamespaces.ListArgsForCall(0).LabelSelector).To(Equal("cloudfoundry.org/space-guid"))
+				Expect(fakeK8sNamespaces.WatchArgsForCall(0).LabelSelector).To(Equal("cloudfoundry.org/space-guid"))
+			})
+
+			It("unbinds the bindings in a deleted namespace", func() {
+				namespaceWatcher.Delete(&v1.Namespace{ObjectMeta: metav1.ObjectMeta{
+					Name:   "space-namespace",
+					Labels: map[string]string{"cloudfoundry.org/space-guid": "some-space-guid"},
+				}})
+
+				Eventually(fakeStore.DeleteBindingDetailsCallCount).Should(Equal(1))
+				Expect(fakeStore.DeleteBindingDetailsArgsForCall(0)).To(Equal("binding-in-space"))
+				Expect(fakeK8sCoreV1.PersistentVolumeClaimsArgsForCall(1)).To(Equal("space-namespace"))
+				Expect(logger.LogMessages()).To(ContainElement("test-broker.new-k8s-broker.namespace-deleted.unbinding"))
+			})
+
+			Context("when a dynamically provisioned instance was bound in the namespace", func() {
+				BeforeEach(func() {
+					fakeK8sPersistentVolumes.ListReturns(&v1.PersistentVolumeList{}, nil)
+					fakeK8sPersistentVolumeClaims.ListReturns(&v1.PersistentVolumeClaimList{
+						Items: []v1.PersistentVolumeClaim{{ObjectMeta: metav1.ObjectMeta{
+							Name:      "dynamic-instance-id",
+							Namespace: "other-namespace",
+							Labels:    map[string]string{"managed-by": "k8sbroker", "instance-id": "dynamic-instance-id"},
+						}}},
+					}, nil)
+					fakeStore.RetrieveInstanceDetailsReturns(brokerstore.ServiceInstance{
+						ServiceID: "some-service-id",
+						ServiceFingerPrint: &k8sbroker.ServiceFingerPrint{
+							Name:                "dynamic-instance-id",
+							DynamicProvisioning: true,
+							StorageClassName:    "nfs-dynamic",
+							ClaimNamespaces:     []string{"space-namespace", "other-namespace"},
+							Bindings: map[string]k8sbroker.BindingFingerPrint{
+								"binding-in-space":  {ClaimName: "dynamic-instance-id", Namespace: "space-namespace"},
+								"binding-elsewhere": {ClaimName: "dynamic-instance-id", Namespace: "other-namespace"},
+							},
+						},
+					}, nil)
+				})
+
+				It("finds the instance through its labelled claims and unbinds the binding in the namespace", func() {
+					namespaceWatcher.Delete(&v1.Namespace{ObjectMeta: metav1.ObjectMeta{
+						Name:   "space-namespace",
+						Labels: map[string]string{"cloudfoundry.org/space-guid": "some-space-guid"},
+					}})
+
+					Eventually(fakeStore.DeleteBindingDetailsCallCount).Should(Equal(1))
+					Expect(fakeStore.DeleteBindingDetailsArgsForCall(0)).To(Equal("binding-in-space"))
+					Expect(fakeK8sCoreV1.PersistentVolumeClaimsArgsForCall(0)).To(Equal(""))
+					Expect(fakeStore.RetrieveInstanceDetailsArgsForCall(0)).To(Equal("dynamic-instance-id"))
+				})
+			})
+
+			It("ignores namespaces that are added", func() {
+				namespaceWatcher.Add(&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "new-space-namespace"}})
+
+				Consistently(fakeStore.DeleteBindingDetailsCallCount).Should(Equal(0))
+			})
+		})
+
+		Context(".Provision", func() {
+			var (
+				instanceID       string
+				provisionDetails brokerapi.ProvisionDetails
+				asyncAllowed     bool
+
+				configuration string
+				spec          brokerapi.ProvisionedServiceSpec
+				err           error
+			)
+
+			BeforeEach(func() {
+				instanceID = "some-instance-id"
+				configuration = `
+        {
+				 "share": "/export/some-share",
+				 "server": "10.0.0.5"
+        }
+        `
+				provisionDetails = brokerapi.ProvisionDetails{PlanID: "nfs", RawParameters: json.RawMessage(configuration)}
+				asyncAllowed = false
+				fakeStore.RetrieveInstanceDetailsReturns(brokerstore.ServiceInstance{}, errors.New("not found"))
+				fakeK8sPersistentVolumes.GetReturns(nil, k8serrors.NewNotFound(schema.GroupResource{Resource: "persistentvolumes"}, "some-instance-id"))
+			})
+
+			JustBeforeEach(func() {
+				spec, err = broker.Provision(ctx, instanceID, provisionDetails, asyncAllowed)
+			})
+
+			Context("when the parameters do not match the service's schema", func() {
+				var schemaErr k8sbroker.ValidationErrors
+
+				BeforeEach(func() {
+					schemaErr = k8sbroker.ValidationErrors{{Field: "capacity_range.requiredBytes", Message: "capacity_range.requiredBytes: Must be greater than or equal to 0"}}
+					fakeServices.ValidateParametersReturns(schemaErr)
+					provisionDetails.ServiceID = "some-service-id"
+				})
+
+				It("rejects them before creating the volume", func() {
+					Expect(err).To(Equal(schemaErr))
+					Expect(fakeServices.ValidateParametersCallCount()).To(Equal(1))
+					serviceID, rawParameters := fakeServices.ValidateParametersArgsForCall(0)
+					Expect(serviceID).To(Equal("some-service-id"))
+					Expect(rawParameters).To(Equal(provisionDetails.RawParameters))
+					Expect(fakeK8sPersistentVolumes.CreateCallCount()).To(Equal(0))
+				})
+			})
+
+			Context("when the persistent volume already exists", func() {
+				var existing *v1.PersistentVolume
+
+				BeforeEach(func() {
+					existing = &v1.PersistentVolume{
+						ObjectMeta: metav1.ObjectMeta{Name: "some-instance-id", ResourceVersion: "7"},
+						Spec: v1.PersistentVolumeSpec{
+							PersistentVolumeSource: v1.PersistentVolumeSource{
+								NFS: &v1.NFSVolumeSource{Server: "10.0.0.5", Path: "/export/some-share"},
+							},
+						},
+					}
+					fakeK8sPersistentVolumes.GetReturns(existing, nil)
+				})
+
+				It("reuses it and stores the instance", func() {
+					Expect(err).NotTo(HaveOccurred())
+					Expect(fakeK8sPersistentVolumes.CreateCallCount()).To(Equal(0))
+					Expect(fakeStore.CreateInstanceDetailsCallCount()).To(Equal(1))
+					_, details := fakeStore.CreateInstanceDetailsArgsForCall(0)
+					Expect(details.ServiceFingerPrint.(k8sbroker.ServiceFingerPrint).Volume).To(Equal(existing))
+				})
+
+				Context("when it has a different source", func() {
+					BeforeEach(func() {
+						existing.Spec.NFS.Path = "/export/other-share"
+					})
+
+					It("errors with a conflict", func() {
+						Expect(err).To(Equal(brokerapi.ErrInstanceAlreadyExists))
+						Expect(fakeK8sPersistentVolumes.CreateCallCount()).To(Equal(0))
+					})
+				})
+
+				Context("when storing the instance fails", func() {
+					var storeErr error
+
+					BeforeEach(func() {
+						storeErr = errors.New("store-failed")
+						fakeStore.CreateInstanceDetailsReturns(storeErr)
+					})
+
+					It("returns the store's error", func() {
+						Expect(errors.Is(err, storeErr)).To(BeTrue())
+					})
+
+					It("does not delete the volume it did not create", func() {
+						Expect(err).To(HaveOccurred())
+						Expect(fakeK8sPersistentVolumes.DeleteCallCount()).To(Equal(0))
+					})
+				})
+			})
+
+			Context("when a provision timeout is set", func() {
+				var phases []v1.PersistentVolumePhase
+
+				BeforeEach(func() {
+					config.ProvisionTimeout = time.Minute
+					phases = []v1.PersistentVolumePhase{v1.VolumePending, v1.VolumeAvailable}
+					fakeK8sPersistentVolumes.GetStub = func(name string, options metav1.GetOptions) (*v1.PersistentVolume, error) {
+						if fakeK8sPersistentVolumes.GetCallCount() == 1 {
+							return nil, k8serrors.NewNotFound(schema.GroupResource{Resource: "persistentvolumes"}, name)
+						}
+						phase := phases[0]
+						if len(phases) > 1 {
+							phases = phases[1:]
+							go fakeClock.WaitForWatcherAndIncrement(time.Second)
+						} else if phase == v1.VolumePending {
+							fakeClock.Increment(config.ProvisionTimeout)
+						}
+						return &v1.PersistentVolume{
+							ObjectMeta: metav1.ObjectMeta{Name: name},
+							Status:     v1.PersistentVolumeStatus{Phase: phase},
+						}, nil
+					}
+				})
+
+				It("waits for the persistent volume to become available", func() {
+					Expect(err).NotTo(HaveOccurred())
+					Expect(fakeK8sPersistentVolumes.GetCallCount()).To(Equal(3))
+					_, details := fakeStore.CreateInstanceDetailsArgsForCall(0)
+					Expect(details.ServiceFingerPrint.(k8sbroker.ServiceFingerPrint).Volume.Status.Phase).To(Equal(v1.VolumeAvailable))
+				})
+
+				Context("when the persistent volume stays pending", func() {
+					BeforeEach(func() {
+						phases = []v1.PersistentVolumePhase{v1.VolumePending}
+					})
+
+					It("deletes the persistent volume and errors with its last phase", func() {
+						Expect(err).To(MatchError(`persistent volume some-instance-id did not become available within 1m0s, last phase "Pending"`))
+						Expect(fakeK8sPersistentVolumes.DeleteCallCount()).To(Equal(1))
+						name, _ := fakeK8sPersistentVolumes.DeleteArgsForCall(0)
+						Expect(name).To(Equal("some-instance-id"))
+						Expect(fakeStore.CreateInstanceDetailsCallCount()).To(Equal(0))
+					})
+				})
+
+				Context("when the persistent volume fails", func() {
+					BeforeEach(func() {
+						phases = []v1.PersistentVolumePhase{v1.VolumeFailed}
+					})
+
+					It("deletes the persistent volume and errors", func() {
+						Expect(err).To(MatchError(ContainSubstring("persistent volume some-instance-id failed")))
+						Expect(fakeK8sPersistentVolumes.DeleteCallCount()).To(Equal(1))
+					})
+				})
+			})
+
+			It("should not error", func() {
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("should not delete the persistent volume", func() {
+				Expect(fakeK8sPersistentVolumes.DeleteCallCount()).To(Equal(0))
+			})
+
+			It("should write state", func() {
+				Expect(fakeStore.SaveCallCount()).Should(BeNumerically(">", 0))
+			})
+
+			It("records the operation", func() {
+				Expect(fakeMetrics.ObserveCallCount()).To(Equal(1))
+				operation, _, observedErr := fakeMetrics.ObserveArgsForCall(0)
+				Expect(operation).To(Equal("provision"))
+				Expect(observedErr).NotTo(HaveOccurred())
+			})
+
+			It("should send the request to the k8s client", func() {
+				expectedQuantity, err := resource.ParseQuantity("5G")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fakeK8sPersistentVolumes.CreateCallCount()).To(Equal(1))
+				requestVolume := fakeK8sPersistentVolumes.CreateArgsForCall(0)
+				Expect(requestVolume.TypeMeta).To(Equal(metav1.TypeMeta{
+					Kind:       "PersistentVolume",
+					APIVersion: "v1",
+				}))
+				Expect(requestVolume.ObjectMeta).To(Equal(metav1.ObjectMeta{
+					Name:   "some-instance-id",
+					Labels: map[string]string{"name": "some-instance-id", "managed-by": "k8sbroker"},
+				}))
+				Expect(requestVolume.Spec.AccessModes).To(Equal([]v1.PersistentVolumeAccessMode{v1.ReadWriteMany}))
+				Expect(requestVolume.Spec.Capacity).To(Equal(v1.ResourceList{v1.ResourceName(v1.ResourceStorage): expectedQuantity}))
+				Expect(requestVolume.Spec.PersistentVolumeSource.NFS.Server).To(Equal("10.0.0.5"))
+				Expect(requestVolume.Spec.PersistentVolumeSource.NFS.Path).To(Equal("/export/some-share"))
+			})
+
+			Context("when a label prefix is set", func() {
+				BeforeEach(func() {
+					config.LabelPrefix = "k8sbroker/"
+				})
+
+				It("prefixes the keys of the volume's labels", func() {
+					Expect(fakeK8sPersistentVolumes.CreateCallCount()).To(Equal(1))
+					requestVolume := fakeK8sPersistentVolumes.CreateArgsForCall(0)
+					Expect(requestVolume.Labels).To(Equal(map[string]string{"k8sbroker/name": "some-instance-id", "k8sbroker/managed-by": "k8sbroker"}))
+				})
+			})
+
+			Context("when a resource name prefix is set", func() {
+				BeforeEach(func() {
+					config.ResourceNamePrefix = "team-a-"
+				})
+
+				It("prefixes the name of the volume", func() {
+					Expect(fakeK8sPersistentVolumes.CreateCallCount()).To(Equal(1))
+					requestVolume := fakeK8sPersistentVolumes.CreateArgsForCall(0)
+					Expect(requestVolume.Name).To(Equal("team-a-some-instance-id"))
+					Expect(requestVolume.Labels).To(HaveKeyWithValue("name", "team-a-some-instance-id"))
+				})
+
+				It("records the prefix with the instance", func() {
+					instanceID, details := fakeStore.CreateInstanceDetailsArgsForCall(0)
+					Expect(instanceID).To(Equal("some-instance-id"))
+					stored := details.ServiceFingerPrint.(k8sbroker.ServiceFingerPrint)
+					Expect(stored.Name).To(Equal("some-instance-id"))
+					Expect(stored.NamePrefix).To(Equal("team-a-"))
+				})
+			})
+
+			Context("when creating volume returns volume info", func() {
+				var volInfo *v1.PersistentVolume
+
+				BeforeEach(func() {
+					volInfo = &v1.PersistentVolume{}
+					fakeK8sPersistentVolumes.CreateReturns(volInfo, nil)
+				})
+
+				It("should save it", func() {
+					Expect(fakeK8sPersistentVolumes.CreateCallCount()).To(Equal(1))
+
+					fingerprint := k8sbroker.ServiceFingerPrint{
+						Version: 1,
+						Name:    "some-instance-id",
+						Volume:  volInfo,
+					}
+
+					expectedServiceInstance := brokerstore.ServiceInstance{
+						PlanID:             "nfs",
+						ServiceFingerPrint: fingerprint,
+					}
+
+					Expect(fakeStore.CreateInstanceDetailsCallCount()).To(Equal(1))
+					fakeInstanceID, fakeServiceInstance := fakeStore.CreateInstanceDetailsArgsForCall(0)
+					Expect(fakeInstanceID).To(Equal(instanceID))
+					Expect(fakeServiceInstance).To(Equal(expectedServiceInstance))
+					Expect(fakeStore.SaveCallCount()).Should(BeNumerically(">", 0))
+				})
+
+				Context("when the volume is a csi volume", func() {
+					BeforeEach(func() {
+						volInfo.Spec.CSI = &v1.CSIPersistentVolumeSource{
+							Driver:           "some-csi-driver",
+							VolumeHandle:     "some-volume-handle",
+							VolumeAttributes: map[string]string{"share": "/export/some-share"},
+						}
+					})
+
+					It("records its volume context", func() {
+						_, fakeServiceInstance := fakeStore.CreateInstanceDetailsArgsForCall(0)
+						fingerprint := fakeServiceInstance.ServiceFingerPrint.(k8sbroker.ServiceFingerPrint)
+						Expect(fingerprint.VolumeContext).To(Equal(map[string]string{"share": "/export/some-share"}))
+					})
+
+					Context("when extra volume attributes are set", func() {
+						BeforeEach(func() {
+							config.ExtraVolumeAttributes = map[string]string{"provisioner": "nfs.csi.k8s.io", "share": "/export/other-share"}
+						})
+
+						It("adds them under the volume's own attributes", func() {
+							_, fakeServiceInstance := fakeStore.CreateInstanceDetailsArgsForCall(0)
+							fingerprint := fakeServiceInstance.ServiceFingerPrint.(k8sbroker.ServiceFingerPrint)
+							Expect(fingerprint.VolumeContext).To(Equal(map[string]string{
+								"provisioner": "nfs.csi.k8s.io",
+								"share":       "/export/some-share",
+							}))
+						})
+					})
+				})
+
+				Context("when extra volume attributes are set", func() {
+					BeforeEach(func() {
+						config.ExtraVolumeAttributes = map[string]string{"provisioner": "nfs.csi.k8s.io"}
+					})
+
+					It("records them as the volume context", func() {
+						_, fakeServiceInstance := fakeStore.CreateInstanceDetailsArgsForCall(0)
+						fingerprint := fakeServiceInstance.ServiceFingerPrint.(k8sbroker.ServiceFingerPrint)
+						Expect(fingerprint.VolumeContext).To(Equal(map[string]string{"provisioner": "nfs.csi.k8s.io"}))
+					})
+				})
+			})
+
+			Context("when the client returns an error", func() {
+				var createErr error
+
+				BeforeEach(func() {
+					createErr = errors.New("some-error")
+					fakeK8sPersistentVolumes.CreateReturns(nil, createErr)
+				})
+
+				It("should error", func() {
+					Expect(err).To(Equal(createErr))
+				})
+
+				It("records the failure", func() {
+					Expect(fakeMetrics.ObserveCallCount()).To(Equal(1))
+					operation, _, observedErr := fakeMetrics.ObserveArgsForCall(0)
+					Expect(operation).To(Equal("provision"))
+					Expect(observedErr).To(Equal(createErr))
+				})
+
+				It("logs the error without a kubernetes reason", func() {
+					var found bool
+					for _, log := range logger.Logs() {
+						if log.Message == "test-broker.new-k8s-broker.provision.error-creating-persistent-volume" {
+							found = true
+							Expect(log.Data).To(HaveKeyWithValue("pv_name", "some-instance-id"))
+							Expect(log.Data).To(HaveKeyWithValue("k8s_error_code", BeEquivalentTo(0)))
+							Expect(log.Data).To(HaveKeyWithValue("k8s_reason", ""))
+						}
+					}
+					Expect(found).To(BeTrue())
+				})
+
+				Context("when the API server rejects the volume", func() {
+					BeforeEach(func() {
+						createErr = k8serrors.NewForbidden(schema.GroupResource{Resource: "persistentvolumes"}, "some-instance-id", errors.New("not allowed"))
+						fakeK8sPersistentVolumes.CreateReturns(nil, createErr)
+					})
+
+					It("logs the status code and reason of the error", func() {
+						var found bool
+						for _, log := range logger.Logs() {
+							if log.Message == "test-broker.new-k8s-broker.provision.error-creating-persistent-volume" {
+								found = true
+								Expect(log.Data).To(HaveKeyWithValue("pv_name", "some-instance-id"))
+								Expect(log.Data).To(HaveKeyWithValue("k8s_error_code", BeEquivalentTo(403)))
+								Expect(log.Data).To(HaveKeyWithValue("k8s_reason", "Forbidden"))
+							}
+						}
+						Expect(found).To(BeTrue())
+					})
+				})
+
+				It("looks for events about the volume in the default namespace", func() {
+					Expect(fakeK8sEvents.ListCallCount()).To(Equal(1))
+					Expect(fakeK8sCoreV1.EventsArgsForCall(0)).To(Equal("default"))
+					opts := fakeK8sEvents.ListArgsForCall(0)
+					Expect(opts.FieldSelector).To(Equal("involvedObject.kind=PersistentVolume,involvedObject.name=some-instance-id"))
+				})
+
+				Context("when the events take too long to list", func() {
+					var release chan struct{}
+
+					BeforeEach(func() {
+						release = make(chan struct{})
+						fakeK8sEvents.ListStub = func(metav1.ListOptions) (*v1.EventList, error) {
+							<-release
+							return &v1.EventList{Items: []v1.Event{{Message: "too late"}}}, nil
+						}
+						go fakeClock.WaitForWatcherAndIncrement(3 * time.Second)
+					})
+
+					AfterEach(func() {
+						close(release)
+					})
+
+					It("returns the original error without waiting for them", func() {
+						Expect(err).To(Equal(createErr))
+					})
+				})
+
+				Context("when there are events about the volume", func() {
+					BeforeEach(func() {
+						now := time.Now()
+						fakeK8sEvents.ListReturns(&v1.EventList{
+							Items: []v1.Event{
+								{Message: "older", LastTimestamp: metav1.NewTime(now.Add(-time.Minute))},
+								{Message: "exceeded quota: pv-quota", LastTimestamp: metav1.NewTime(now)},
+								{Message: "oldest", LastTimestamp: metav1.NewTime(now.Add(-time.Hour))},
+							},
+						}, nil)
+					})
+
+					It("includes the most recent event in the error", func() {
+						Expect(err).To(MatchError("some-error: exceeded quota: pv-quota"))
+						Expect(errors.Is(err, createErr)).To(BeTrue())
+					})
+				})
+
+				Context("when the events cannot be listed", func() {
+					BeforeEach(func() {
+						fakeK8sEvents.ListReturns(nil, errors.New("forbidden"))
+					})
+
+					It("returns the original error", func() {
+						Expect(err).To(Equal(createErr))
+					})
+				})
+			})
+
+			It("does not return a dashboard URL without a template", func() {
+				Expect(spec.DashboardURL).To(BeEmpty())
+			})
+
+			Context("when a dashboard URL template is set", func() {
+				BeforeEach(func() {
+					config.DashboardURL, err = k8sbroker.NewDashboardURLTemplate("https://dashboard.example.com/#/persistentvolumes/{{.Volume.Name}}")
+					Expect(err).NotTo(HaveOccurred())
+				})
+
+				It("returns the rendered dashboard URL", func() {
+					Expect(err).NotTo(HaveOccurred())
+					Expect(spec.DashboardURL).To(Equal("https://dashboard.example.com/#/persistentvolumes/some-instance-id"))
+				})
+
+				Context("when async is allowed", func() {
+					BeforeEach(func() {
+						asyncAllowed = true
+					})
+
+					It("returns the rendered dashboard URL", func() {
+						Expect(spec.IsAsync).To(BeTrue())
+						Expect(spec.DashboardURL).To(Equal("https://dashboard.example.com/#/persistentvolumes/some-instance-id"))
+					})
+				})
+			})
+
+			It("does not count existing volumes without a quota", func() {
+				Expect(fakeK8sPersistentVolumes.ListCallCount()).To(Equal(0))
+			})
+
+			Context("when a volume quota is set", func() {
+				BeforeEach(func() {
+					config.Quotas.MaxPVCount = 2
+					fakeK8sPersistentVolumes.ListReturns(&v1.PersistentVolumeList{
+						Items: []v1.PersistentVolume{
+							{ObjectMeta: metav1.ObjectMeta{Name: "other-instance-id"}},
+						},
+					}, nil)
+				})
+
+				It("counts the volumes managed by the broker", func() {
+					Expect(err).NotTo(HaveOccurred())
+					Expect(fakeK8sPersistentVolumes.ListCallCount()).To(Equal(1))
+					Expect(fakeK8sPersistentVolumes.ListArgsForCall(0).LabelSelector).To(Equal("managed-by=k8sbroker"))
+					Expect(fakeK8sPersistentVolumes.CreateCallCount()).To(Equal(1))
+				})
+
+				Context("when the quota is used up", func() {
+					BeforeEach(func() {
+						config.Quotas.MaxPVCount = 1
+					})
+
+					It("errors without creating a volume", func() {
+						Expect(err).To(Equal(brokerapi.ErrPlanQuotaExceeded))
+						Expect(fakeK8sPersistentVolumes.CreateCallCount()).To(Equal(0))
+					})
+
+					Context("when async is allowed", func() {
+						BeforeEach(func() {
+							asyncAllowed = true
+						})
+
+						It("errors without storing the instance", func() {
+							Expect(err).To(Equal(brokerapi.ErrPlanQuotaExceeded))
+							Expect(fakeStore.CreateInstanceDetailsCallCount()).To(Equal(0))
+						})
+					})
+				})
+
+				Context("when the instance's volume is already counted", func() {
+					BeforeEach(func() {
+						config.Quotas.MaxPVCount = 1
+						fakeK8sPersistentVolumes.ListReturns(&v1.PersistentVolumeList{
+							Items: []v1.PersistentVolume{
+								{ObjectMeta: metav1.ObjectMeta{Name: "some-instance-id"}},
+							},
+						}, nil)
+					})
+
+					It("does not error", func() {
+						Expect(err).NotTo(HaveOccurred())
+					})
+				})
+
+				Context("when the volumes cannot be listed", func() {
+					BeforeEach(func() {
+						fakeK8sPersistentVolumes.ListReturns(nil, errors.New("list-failed"))
+					})
+
+					It("errors", func() {
+						Expect(err).To(MatchError("list-failed"))
+						Expect(fakeK8sPersistentVolumes.CreateCallCount()).To(Equal(0))
+					})
+				})
+			})
+
+			Context("when async is allowed", func() {
+				var (
+					volInfo    *v1.PersistentVolume
+					storeMutex sync.Mutex
+					instances  map[string]brokerstore.ServiceInstance
+				)
+
+				BeforeEach(func() {
+					asyncAllowed = true
+					volInfo = &v1.PersistentVolume{ObjectMeta: metav1.ObjectMeta{Name: "some-instance-id"}}
+					fakeK8sPersistentVolumes.CreateReturns(volInfo, nil)
+
+					// the background provision reads back the instance it stored
+					instances = map[string]brokerstore.ServiceInstance{}
+					fakeStore.RetrieveInstanceDetailsStub = func(id string) (brokerstore.ServiceInstance, error) {
+						storeMutex.Lock()
+						defer storeMutex.Unlock()
+						details, ok := instances[id]
+						if !ok {
+							return brokerstore.ServiceInstance{}, errors.New("not found")
+						}
+						return details, nil
+					}
+					fakeStore.CreateInstanceDetailsStub = func(id string, details brokerstore.ServiceInstance) error {
+						storeMutex.Lock()
+						defer storeMutex.Unlock()
+						instances[id] = details
+						return nil
+					}
+				})
+
+				It("returns an async response", func() {
+					Expect(err).NotTo(HaveOccurred())
+					Expect(spec.IsAsync).To(BeTrue())
+					Expect(spec.OperationData).To(Equal(k8sbroker.OperationProvision))
+				})
+
+				It("stores the instance as in progress", func() {
+					Expect(fakeStore.CreateInstanceDetailsCallCount()).To(BeNumerically(">=", 1))
+					_, details := fakeStore.CreateInstanceDetailsArgsForCall(0)
+					fingerprint := details.ServiceFingerPrint.(k8sbroker.ServiceFingerPrint)
+					Expect(fingerprint.ProvisionState).To(Equal(&k8sbroker.ProvisionState{Status: k8sbroker.ProvisionInProgress}))
+				})
+
+				It("creates the persistent volume in the background and records success", func() {
+					Eventually(fakeK8sPersistentVolumes.CreateCallCount).Should(Equal(1))
+					Eventually(fakeStore.CreateInstanceDetailsCallCount).Should(Equal(2))
+
+					Expect(fakeStore.DeleteInstanceDetailsCallCount()).To(Equal(1))
+					_, details := fakeStore.CreateInstanceDetailsArgsForCall(1)
+					fingerprint := details.ServiceFingerPrint.(k8sbroker.ServiceFingerPrint)
+					Expect(fingerprint.Volume).To(Equal(volInfo))
+					Expect(fingerprint.ProvisionState).To(Equal(&k8sbroker.ProvisionState{Status: k8sbroker.ProvisionSucceeded}))
+				})
+
+				Context("while the persistent volume is being created", func() {
+					var release chan struct{}
+
+					BeforeEach(func() {
+						release = make(chan struct{})
+						fakeK8sPersistentVolumes.CreateStub = func(volume *v1.PersistentVolume) (*v1.PersistentVolume, error) {
+							<-release
+							return volInfo, nil
+						}
+					})
+
+					AfterEach(func() {
+						close(release)
+					})
+
+					It("refuses to bind the instance", func() {
+						_, err = broker.Bind(ctx, "some-instance-id", "binding-id", brokerapi.BindDetails{AppGUID: "guid", ServiceID: "some-service-id"})
+						Expect(err).To(Equal(brokerapi.ErrConcurrentInstanceAccess))
+					})
+
+					It("refuses to update the instance", func() {
+						_, err = broker.Update(ctx, "some-instance-id", brokerapi.UpdateDetails{ServiceID: "some-service-id"}, false)
+						Expect(err).To(Equal(brokerapi.ErrConcurrentInstanceAccess))
+					})
+
+					It("refuses to deprovision the instance", func() {
+						_, err = broker.Deprovision(ctx, "some-instance-id", brokerapi.DeprovisionDetails{}, false)
+						Expect(err).To(Equal(brokerapi.ErrConcurrentInstanceAccess))
+						Expect(fakeK8sPersistentVolumes.DeleteCallCount()).To(Equal(0))
+					})
+
+					It("records the outcome on the instance details stored when it finishes", func() {
+						Eventually(fakeK8sPersistentVolumes.CreateCallCount).Should(Equal(1))
+						storeMutex.Lock()
+						details := instances["some-instance-id"]
+						fingerprint := details.ServiceFingerPrint.(k8sbroker.ServiceFingerPrint)
+						fingerprint.Name = "renamed-instance"
+						details.ServiceFingerPrint = fingerprint
+						instances["some-instance-id"] = details
+						storeMutex.Unlock()
+
+						release <- struct{}{}
+
+						Eventually(fakeStore.CreateInstanceDetailsCallCount).Should(Equal(2))
+						_, details = fakeStore.CreateInstanceDetailsArgsForCall(1)
+						fingerprint = details.ServiceFingerPrint.(k8sbroker.ServiceFingerPrint)
+						Expect(fingerprint.Name).To(Equal("renamed-instance"))
+						Expect(fingerprint.ProvisionState).To(Equal(&k8sbroker.ProvisionState{Status: k8sbroker.ProvisionSucceeded}))
+					})
+				})
+
+				Context("when the volume watch sees the volume become available", func() {
+					var fakeWatcher *watch.FakeWatcher
+
+					BeforeEach(func() {
+						fakeWatcher = watch.NewFakeWithChanSize(2, false)
+						fakeK8sPersistentVolumes.WatchReturns(fakeWatcher, nil)
+						fakeStore.RetrieveInstanceDetailsReturns(brokerstore.ServiceInstance{
+							ServiceFingerPrint: k8sbroker.ServiceFingerPrint{Name: "some-instance-id", Volume: volInfo},
+						}, nil)
+					})
+
+					It("watches the volume by name", func() {
+						Eventually(fakeK8sPersistentVolumes.WatchCallCount).Should(Equal(1))
+						options := fakeK8sPersistentVolumes.WatchArgsForCall(0)
+						Expect(options.FieldSelector).To(Equal("metadata.name=some-instance-id"))
+						Expect(*options.TimeoutSeconds).To(Equal(int64(60)))
+					})
+
+					Context("when the broker has a separate watch client", func() {
+						var watchVolumes *k8sbroker_fake.FakeK8sPersistentVolumes
+
+						BeforeEach(func() {
+							watchVolumes = &k8sbroker_fake.FakeK8sPersistentVolumes{}
+							watchVolumes.WatchReturns(fakeWatcher, nil)
+							watchCoreV1 := &k8sbroker_fake.FakeK8sCoreV1{}
+							watchCoreV1.PersistentVolumesReturns(watchVolumes)
+							watchClient := &k8sbroker_fake.FakeK8sClient{}
+							watchClient.CoreV1Returns(watchCoreV1)
+							config.WatchClient = watchClient
+						})
+
+						It("watches the volume through it", func() {
+							Eventually(watchVolumes.WatchCallCount).Should(Equal(1))
+							Consistently(fakeK8sPersistentVolumes.WatchCallCount).Should(Equal(0))
+						})
+					})
+
+					It("answers last_operation from the watch", func() {
+						Eventually(fakeStore.CreateInstanceDetailsCallCount).Should(Equal(2))
+						// the API server still reports the volume as pending
+						fakeK8sPersistentVolumes.GetReturns(&v1.PersistentVolume{Status: v1.PersistentVolumeStatus{Phase: v1.VolumePending}}, nil)
+
+						fakeWatcher.Add(&v1.PersistentVolume{Status: v1.PersistentVolumeStatus{Phase: v1.VolumePending}})
+						fakeWatcher.Modify(&v1.PersistentVolume{Status: v1.PersistentVolumeStatus{Phase: v1.VolumeAvailable}})
+
+						Eventually(func() brokerapi.LastOperationState {
+							op, err := broker.LastOperation(ctx, "some-instance-id", k8sbroker.OperationProvision)
+							Expect(err).NotTo(HaveOccurred())
+							return op.State
+						}).Should(Equal(brokerapi.Succeeded))
+					})
+
+					It("gets the volume itself until the watch has an outcome", func() {
+						Eventually(fakeStore.CreateInstanceDetailsCallCount).Should(Equal(2))
+						fakeK8sPersistentVolumes.GetReturns(&v1.PersistentVolume{Status: v1.PersistentVolumeStatus{Phase: v1.VolumePending}}, nil)
+						getCalls := fakeK8sPersistentVolumes.GetCallCount()
+						fakeWatcher.Add(&v1.PersistentVolume{Status: v1.PersistentVolumeStatus{Phase: v1.VolumePending}})
+
+						op, err := broker.LastOperation(ctx, "some-instance-id", k8sbroker.OperationProvision)
+						Expect(err).NotTo(HaveOccurred())
+						Expect(op.State).To(Equal(brokerapi.InProgress))
+						Expect(fakeK8sPersistentVolumes.GetCallCount()).To(Equal(getCalls + 1))
+					})
+
+					Context("when the watch times out", func() {
+						It("stops watching", func() {
+							Eventually(fakeK8sPersistentVolumes.WatchCallCount).Should(Equal(1))
+							Eventually(fakeClock.WatcherCount).Should(Equal(1))
+							fakeClock.Increment(config.ProvisionWatchTimeout)
+							Eventually(fakeWatcher.IsStopped).Should(BeTrue())
+						})
+					})
+				})
+
+				Context("when the background creation fails", func() {
+					BeforeEach(func() {
+						fakeK8sPersistentVolumes.CreateReturns(nil, errors.New("some-error"))
+					})
+
+					It("records the failure", func() {
+						Eventually(fakeStore.CreateInstanceDetailsCallCount).Should(Equal(2))
+
+						_, details := fakeStore.CreateInstanceDetailsArgsForCall(1)
+						fingerprint := details.ServiceFingerPrint.(k8sbroker.ServiceFingerPrint)
+						Expect(fingerprint.ProvisionState).To(Equal(&k8sbroker.ProvisionState{Status: k8sbroker.ProvisionFailed, Description: "some-error"}))
+					})
+				})
+
+				Context("when the API server times out", func() {
+					BeforeEach(func() {
+						fakeK8sPersistentVolumes.CreateReturnsOnCall(0, nil, k8serrors.NewServerTimeout(schema.GroupResource{Resource: "persistentvolumes"}, "create", 1))
+						fakeK8sPersistentVolumes.CreateReturnsOnCall(1, volInfo, nil)
+					})
+
+					It("retries after backing off", func() {
+						Eventually(fakeK8sPersistentVolumes.CreateCallCount).Should(Equal(1))
+						fakeClock.WaitForWatcherAndIncrement(time.Second)
+
+						Eventually(fakeStore.CreateInstanceDetailsCallCount).Should(Equal(2))
+						Expect(fakeK8sPersistentVolumes.CreateCallCount()).To(Equal(2))
+						_, details := fakeStore.CreateInstanceDetailsArgsForCall(1)
+						fingerprint := details.ServiceFingerPrint.(k8sbroker.ServiceFingerPrint)
+						Expect(fingerprint.ProvisionState.Status).To(Equal(k8sbroker.ProvisionSucceeded))
+					})
+
+					Context("on every attempt", func() {
+						BeforeEach(func() {
+							fakeK8sPersistentVolumes.CreateReturnsOnCall(1, nil, k8serrors.NewServerTimeout(schema.GroupResource{Resource: "persistentvolumes"}, "create", 1))
+							fakeK8sPersistentVolumes.CreateReturnsOnCall(2, nil, k8serrors.NewServerTimeout(schema.GroupResource{Resource: "persistentvolumes"}, "create", 1))
+						})
+
+						It("gives up after three attempts", func() {
+							fakeClock.WaitForWatcherAndIncrement(time.Second)
+							fakeClock.WaitForWatcherAndIncrement(2 * time.Second)
+
+							Eventually(fakeStore.CreateInstanceDetailsCallCount).Should(Equal(2))
+							Expect(fakeK8sPersistentVolumes.CreateCallCount()).To(Equal(3))
+							_, details := fakeStore.CreateInstanceDetailsArgsForCall(1)
+							fingerprint := details.ServiceFingerPrint.(k8sbroker.ServiceFingerPrint)
+							Expect(fingerprint.ProvisionState.Status).To(Equal(k8sbroker.ProvisionFailed))
+						})
+					})
+				})
+
+				Context("when every provision worker is busy", func() {
+					var (
+						release chan struct{}
+						errs    chan error
+					)
+
+					BeforeEach(func() {
+						config.ParallelProvisionWorkers = 2
+						release = make(chan struct{})
+						outerInstanceID, blocked := instanceID, release
+						fakeK8sPersistentVolumes.CreateStub = func(volume *v1.PersistentVolume) (*v1.PersistentVolume, error) {
+							if volume.Name != outerInstanceID {
+								<-blocked
+							}
+							return volume, nil
+						}
+					})
+
+					JustBeforeEach(func() {
+						// the provision made by the outer JustBeforeEach holds a
+						// worker until it completes
+						Eventually(fakeStore.CreateInstanceDetailsCallCount).Should(Equal(2))
+
+						errs = make(chan error, config.ParallelProvisionWorkers+1)
+						for i := 1; i <= config.ParallelProvisionWorkers+1; i++ {
+							go func(id string) {
+								defer GinkgoRecover()
+								_, err := broker.Provision(ctx, id, provisionDetails, true)
+								errs <- err
+							}(fmt.Sprintf("instance-%d", i))
+						}
+					})
+
+					AfterEach(func() {
+						close(release)
+					})
+
+					It("turns exactly one of n+1 concurrent provisions away with 429", func() {
+						busy := 0
+						for i := 0; i <= config.ParallelProvisionWorkers; i++ {
+							var err error
+							Eventually(errs).Should(Receive(&err))
+							if err != nil {
+								Expect(err).To(Equal(k8sbroker.ErrProvisionWorkersBusy))
+								Expect(err.(*brokerapi.FailureResponse).ValidatedStatusCode(logger)).To(Equal(http.StatusTooManyRequests))
+								busy++
+							}
+						}
+						Expect(busy).To(Equal(1))
+						Expect(fakeStore.CreateInstanceDetailsCallCount()).To(Equal(2 + config.ParallelProvisionWorkers))
+					})
+
+					It("accepts provisions again once a worker finishes", func() {
+						for i := 0; i <= config.ParallelProvisionWorkers; i++ {
+							Eventually(errs).Should(Receive())
+						}
+						release <- struct{}{}
+						Eventually(fakeStore.CreateInstanceDetailsCallCount).Should(Equal(2 + config.ParallelProvisionWorkers + 1))
+
+						_, err := broker.Provision(ctx, "another-instance-id", provisionDetails, true)
+						Expect(err).NotTo(HaveOccurred())
+					})
+				})
+
+				Context("when the persistent volume already exists in Kubernetes", func() {
+					BeforeEach(func() {
+						fakeK8sPersistentVolumes.CreateReturns(nil, k8serrors.NewAlreadyExists(schema.GroupResource{Resource: "persistentvolumes"}, "some-instance-id"))
+					})
+
+					It("does not retry", func() {
+						Eventually(fakeStore.CreateInstanceDetailsCallCount).Should(Equal(2))
+						Expect(fakeK8sPersistentVolumes.CreateCallCount()).To(Equal(1))
+					})
+				})
+
+				Context("when the service instance already exists with different details", func() {
+					BeforeEach(func() {
+						fakeStore.IsInstanceConflictReturns(true)
+					})
+
+					It("should error", func() {
+						Expect(err).To(Equal(brokerapi.ErrInstanceAlreadyExists))
+					})
+
+					It("does not create the persistent volume", func() {
+						Consistently(fakeK8sPersistentVolumes.CreateCallCount).Should(Equal(0))
+					})
+				})
+			})
+
+			Context("create-service was given invalid JSON", func() {
+				BeforeEach(func() {
+					badJson := []byte("{this is not json")
+					provisionDetails = brokerapi.ProvisionDetails{PlanID: "CSI", RawParameters: json.RawMessage(badJson)}
+				})
+
+				It("errors", func() {
+					Expect(err).To(Equal(brokerapi.ErrRawParamsInvalid))
+				})
+			})
+
+			Context("create-service was given valid JSON but no 'server' in parameters", func() {
+				BeforeEach(func() {
+					configuration = `
+					{
+						 "share": "/export/some-share"
+					}
+					`
+					provisionDetails = brokerapi.ProvisionDetails{PlanID: "CSI", RawParameters: json.RawMessage(configuration)}
+				})
+
+				It("errors", func() {
+					Expect(err).To(Equal(k8sbroker.ValidationErrors{
+						{Field: "server", Message: "config requires a \"server\""},
+					}))
+				})
+			})
+
+			Context("create-service was given valid JSON but no 'share' in parameters", func() {
+				BeforeEach(func() {
+					configuration = `
+					{
+						 "server": "10.0.0.5"
+					}
+					`
+					provisionDetails = brokerapi.ProvisionDetails{PlanID: "CSI", RawParameters: json.RawMessage(configuration)}
+				})
+
+				It("errors", func() {
+					Expect(err).To(Equal(k8sbroker.ValidationErrors{
+						{Field: "share", Message: "config requires a \"share\""},
+					}))
+				})
+			})
+
+			Context("create-service was given a path_template instead of a share", func() {
+				BeforeEach(func() {
+					configuration = `
+					{
+						 "server": "10.0.0.5",
+						 "path_template": "/export/{{.OrganizationGUID}}/{{.InstanceID}}/data"
+					}
+					`
+					provisionDetails = brokerapi.ProvisionDetails{PlanID: "CSI", OrganizationGUID: "some-org", RawParameters: json.RawMessage(configuration)}
+				})
+
+				It("uses the rendered path as the share", func() {
+					Expect(err).NotTo(HaveOccurred())
+					requestVolume := fakeK8sPersistentVolumes.CreateArgsForCall(0)
+					Expect(requestVolume.Spec.NFS.Path).To(Equal("/export/some-org/some-instance-id/data"))
+				})
+
+				Context("when a share is given as well", func() {
+					BeforeEach(func() {
+						configuration = `{"server": "10.0.0.5", "share": "/export", "path_template": "/export/{{.InstanceID}}"}`
+						provisionDetails.RawParameters = json.RawMessage(configuration)
+					})
+
+					It("errors", func() {
+						Expect(err).To(Equal(k8sbroker.ValidationErrors{
+							{Field: "path_template", Message: "config cannot have both \"share\" and \"path_template\""},
+						}))
+						Expect(fakeK8sPersistentVolumes.CreateCallCount()).To(Equal(0))
+					})
+				})
+
+				Context("when the rendered path steps out of the export", func() {
+					BeforeEach(func() {
+						configuration = `{"server": "10.0.0.5", "path_template": "/export/{{.SpaceGUID}}/../other"}`
+						provisionDetails.RawParameters = json.RawMessage(configuration)
+					})
+
+					It("errors", func() {
+						Expect(err).To(Equal(k8sbroker.ValidationErrors{
+							{Field: "path_template", Message: "rendered path \"/export//../other\" contains \"..\""},
+						}))
+						Expect(fakeK8sPersistentVolumes.CreateCallCount()).To(Equal(0))
+					})
+				})
+			})
+
+			Context("create-service was given valid JSON but neither 'server' nor 'share'", func() {
+				BeforeEach(func() {
+					configuration = `
+					{
+						 "capacity_range": {"requiredBytes": -1}
+					}
+					`
+					provisionDetails = brokerapi.ProvisionDetails{PlanID: "CSI", RawParameters: json.RawMessage(configuration)}
+				})
+
+				It("reports every invalid field at once", func() {
+					Expect(err).To(Equal(k8sbroker.ValidationErrors{
+						{Field: "server", Message: "config requires a \"server\""},
+						{Field: "share", Message: "config requires a \"share\""},
+						{Field: "capacity_range", Message: "config \"capacity_range\" must not be negative"},
+					}))
+					Expect(err).To(MatchError("config requires a \"server\"; config requires a \"share\"; config \"capacity_range\" must not be negative"))
+				})
+			})
+
+			Context("create-service was given a reclaim_policy", func() {
+				BeforeEach(func() {
+					configuration = `
+					{
+						 "share": "/export/some-share",
+						 "server": "10.0.0.5",
+						 "reclaim_policy": "Delete"
+					}
+					`
+					provisionDetails = brokerapi.ProvisionDetails{PlanID: "nfs", RawParameters: json.RawMessage(configuration)}
+				})
+
+				It("sets the reclaim policy on the persistent volume", func() {
+					Expect(fakeK8sPersistentVolumes.CreateCallCount()).To(Equal(1))
+					requestVolume := fakeK8sPersistentVolumes.CreateArgsForCall(0)
+					Expect(requestVolume.Spec.PersistentVolumeReclaimPolicy).To(Equal(v1.PersistentVolumeReclaimDelete))
+				})
+
+				It("records the reclaim policy in the fingerprint", func() {
+					Expect(fakeStore.CreateInstanceDetailsCallCount()).To(Equal(1))
+					_, fakeServiceInstance := fakeStore.CreateInstanceDetailsArgsForCall(0)
+					fingerprint := fakeServiceInstance.ServiceFingerPrint.(k8sbroker.ServiceFingerPrint)
+					Expect(fingerprint.ReclaimPolicy).To(Equal(v1.PersistentVolumeReclaimDelete))
+				})
+
+				Context("when the plan has a reclaim policy", func() {
+					BeforeEach(func() {
+						fakeServices.ReclaimPolicyReturns("Retain")
+					})
+
+					It("uses the reclaim policy from the parameters", func() {
+						requestVolume := fakeK8sPersistentVolumes.CreateArgsForCall(0)
+						Expect(requestVolume.Spec.PersistentVolumeReclaimPolicy).To(Equal(v1.PersistentVolumeReclaimDelete))
+					})
+				})
+			})
+
+			Context("the plan has a reclaim policy", func() {
+				BeforeEach(func() {
+					fakeServices.ReclaimPolicyReturns("Delete")
+					provisionDetails = brokerapi.ProvisionDetails{PlanID: "some-plan-id", RawParameters: json.RawMessage(`{"share": "/export/some-share", "server": "10.0.0.5"}`)}
+				})
+
+				It("defaults the persistent volume's reclaim policy to the plan's", func() {
+					Expect(fakeServices.ReclaimPolicyCallCount()).To(Equal(1))
+					Expect(fakeServices.ReclaimPolicyArgsForCall(0)).To(Equal("some-plan-id"))
+
+					Expect(fakeK8sPersistentVolumes.CreateCallCount()).To(Equal(1))
+					requestVolume := fakeK8sPersistentVolumes.CreateArgsForCall(0)
+					Expect(requestVolume.Spec.PersistentVolumeReclaimPolicy).To(Equal(v1.PersistentVolumeReclaimDelete))
+				})
+			})
+
+			Context("create-service was given mount_options", func() {
+				BeforeEach(func() {
+					configuration = `
+					{
+						 "share": "/export/some-share",
+						 "server": "10.0.0.5",
+						 "mount_options": ["nfsvers=4", "hard", "timeo=600", "retrans=2"]
+					}
+					`
+					provisionDetails = brokerapi.ProvisionDetails{PlanID: "nfs", RawParameters: json.RawMessage(configuration)}
+				})
+
+				It("sets the mount options on the persistent volume", func() {
+					Expect(fakeK8sPersistentVolumes.CreateCallCount()).To(Equal(1))
+					requestVolume := fakeK8sPersistentVolumes.CreateArgsForCall(0)
+					Expect(requestVolume.Spec.MountOptions).To(Equal([]string{"nfsvers=4", "hard", "timeo=600", "retrans=2"}))
+				})
+
+				Context("when an option is not allowed", func() {
+					BeforeEach(func() {
+						configuration = `
+						{
+							 "share": "/export/some-share",
+							 "server": "10.0.0.5",
+							 "mount_options": ["hard", "sec=krb5"]
+						}
+						`
+						provisionDetails = brokerapi.ProvisionDetails{PlanID: "nfs", RawParameters: json.RawMessage(configuration)}
+					})
+
+					It("errors", func() {
+						Expect(err).To(Equal(errors.New("mount option \"sec=krb5\" is not allowed")))
+					})
+
+					It("does not create the persistent volume", func() {
+						Expect(fakeK8sPersistentVolumes.CreateCallCount()).To(Equal(0))
+					})
+				})
+			})
+
+			It("does not look up a storage class", func() {
+				Expect(fakeK8sStorageClasses.GetCallCount()).To(Equal(0))
+			})
+
+			It("leaves the volume mode to default", func() {
+				requestVolume := fakeK8sPersistentVolumes.CreateArgsForCall(0)
+				Expect(requestVolume.Spec.VolumeMode).To(BeNil())
+			})
+
+			Context("create-service was given a volume_mode", func() {
+				BeforeEach(func() {
+					configuration = `
+					{
+						 "share": "/export/some-share",
+						 "server": "10.0.0.5",
+						 "volume_mode": "Block"
+					}
+					`
+					provisionDetails = brokerapi.ProvisionDetails{PlanID: "nfs", RawParameters: json.RawMessage(configuration)}
+				})
+
+				It("sets the volume mode on the persistent volume", func() {
+					Expect(err).NotTo(HaveOccurred())
+					requestVolume := fakeK8sPersistentVolumes.CreateArgsForCall(0)
+					Expect(requestVolume.Spec.VolumeMode).NotTo(BeNil())
+					Expect(*requestVolume.Spec.VolumeMode).To(Equal(v1.PersistentVolumeBlock))
+				})
+
+				Context("when the volume mode is not recognized", func() {
+					BeforeEach(func() {
+						configuration = `
+						{
+							 "share": "/export/some-share",
+							 "server": "10.0.0.5",
+							 "volume_mode": "block"
+						}
+						`
+						provisionDetails = brokerapi.ProvisionDetails{PlanID: "nfs", RawParameters: json.RawMessage(configuration)}
+					})
+
+					It("errors without creating the persistent volume", func() {
+						Expect(err).To(Equal(k8sbroker.ValidationErrors{
+							{Field: "volume_mode", Message: "config \"volume_mode\" must be \"Filesystem\" or \"Block\""},
+						}))
+						Expect(fakeK8sPersistentVolumes.CreateCallCount()).To(Equal(0))
+					})
+				})
+			})
+
+			Context("create-service was given a storage_class_name", func() {
+				BeforeEach(func() {
+					configuration = `
+					{
+						 "share": "/export/some-share",
+						 "server": "10.0.0.5",
+						 "storage_class_name": "nfs-retain"
+					}
+					`
+					provisionDetails = brokerapi.ProvisionDetails{PlanID: "nfs", RawParameters: json.RawMessage(configuration)}
+				})
+
+				It("checks the storage class exists", func() {
+					Expect(fakeK8sStorageClasses.GetCallCount()).To(Equal(1))
+					name, _ := fakeK8sStorageClasses.GetArgsForCall(0)
+					Expect(name).To(Equal("nfs-retain"))
+				})
+
+				It("sets the storage class on the persistent volume", func() {
+					Expect(fakeK8sPersistentVolumes.CreateCallCount()).To(Equal(1))
+					requestVolume := fakeK8sPersistentVolumes.CreateArgsForCall(0)
+					Expect(requestVolume.Spec.StorageClassName).To(Equal("nfs-retain"))
+				})
+
+				Context("when the storage class does not exist", func() {
+					BeforeEach(func() {
+						fakeK8sStorageClasses.GetReturns(nil, k8serrors.NewNotFound(schema.GroupResource{Group: "storage.k8s.io", Resource: "storageclasses"}, "nfs-retain"))
+					})
+
+					It("errors", func() {
+						Expect(err).To(Equal(errors.New("storage class \"nfs-retain\" does not exist")))
+					})
+
+					It("does not create the persistent volume", func() {
+						Expect(fakeK8sPersistentVolumes.CreateCallCount()).To(Equal(0))
+					})
+				})
+
+				Context("when the storage class cannot be looked up", func() {
+					BeforeEach(func() {
+						fakeK8sStorageClasses.GetReturns(nil, errors.New("forbidden"))
+					})
+
+					It("errors", func() {
+						Expect(err).To(MatchError("forbidden"))
+					})
+				})
+			})
+
+			Context("create-service asked for dynamic provisioning", func() {
+				BeforeEach(func() {
+					configuration = `
+					{
+						 "dynamic_provisioning": true,
+						 "storage_class_name": "nfs-dynamic",
+						 "labels": {"team": "storage"},
+						 "capacity_range": {"requiredBytes": 1073741824}
+					}
+					`
+					provisionDetails = brokerapi.ProvisionDetails{PlanID: "nfs", RawParameters: json.RawMessage(configuration)}
+					asyncAllowed = true
+				})
+
+				It("succeeds synchronously", func() {
+					Expect(err).NotTo(HaveOccurred())
+					Expect(spec.IsAsync).To(BeFalse())
+				})
+
+				It("checks the storage class exists", func() {
+					Expect(fakeK8sStorageClasses.GetCallCount()).To(Equal(1))
+					name, _ := fakeK8sStorageClasses.GetArgsForCall(0)
+					Expect(name).To(Equal("nfs-dynamic"))
+				})
+
+				It("does not create a persistent volume", func() {
+					Expect(fakeK8sPersistentVolumes.GetCallCount()).To(Equal(0))
+					Expect(fakeK8sPersistentVolumes.CreateCallCount()).To(Equal(0))
+				})
+
+				It("records the instance as dynamically provisioned", func() {
+					Expect(fakeStore.CreateInstanceDetailsCallCount()).To(Equal(1))
+					id, details := fakeStore.CreateInstanceDetailsArgsForCall(0)
+					Expect(id).To(Equal(instanceID))
+					Expect(details.ServiceFingerPrint).To(Equal(k8sbroker.ServiceFingerPrint{
+						Version:             1,
+						Name:                "some-instance-id",
+						Labels:              map[string]string{"team": "storage"},
+						CapacityRange:       &k8sbroker.CapacityRange{RequiredBytes: 1073741824},
+						DynamicProvisioning: true,
+						StorageClassName:    "nfs-dynamic",
+					}))
+					Expect(fakeStore.SaveCallCount()).To(Equal(1))
+				})
+
+				Context("without a storage class", func() {
+					BeforeEach(func() {
+						provisionDetails.RawParameters = json.RawMessage(`{"dynamic_provisioning": true}`)
+					})
+
+					It("errors", func() {
+						Expect(err).To(Equal(k8sbroker.ValidationErrors{
+							{Field: "storage_class_name", Message: "config with \"dynamic_provisioning\" requires a \"storage_class_name\""},
+						}))
+						Expect(fakeStore.CreateInstanceDetailsCallCount()).To(Equal(0))
+					})
+				})
+			})
+
+			Context("create-service was given a node_affinity", func() {
+				var expectedAffinity *v1.VolumeNodeAffinity
+
+				BeforeEach(func() {
+					configuration = `
+					{
+						 "share": "/export/some-share",
+						 "server": "10.0.0.5",
+						 "node_affinity": {
+							 "required": {
+								 "nodeSelectorTerms": [{
+									 "matchExpressions": [{
+										 "key": "failure-domain.beta.kubernetes.io/zone",
+										 "operator": "In",
+										 "values": ["us-east-1a"]
+									 }]
+								 }]
+							 }
+						 }
+					}
+					`
+					provisionDetails = brokerapi.ProvisionDetails{PlanID: "nfs", RawParameters: json.RawMessage(configuration)}
+
+					expectedAffinity = &v1.VolumeNodeAffinity{
+						Required: &v1.NodeSelector{
+							NodeSelectorTerms: []v1.NodeSelectorTerm{{
+								MatchExpressions: []v1.NodeSelectorRequirement{{
+									Key:      "failure-domain.beta.kubernetes.io/zone",
+									Operator: v1.NodeSelectorOpIn,
+									Values:   []string{"us-east-1a"},
+								}},
+							}},
+						},
+					}
+				})
+
+				It("sets the node affinity on the persistent volume", func() {
+					Expect(fakeK8sPersistentVolumes.CreateCallCount()).To(Equal(1))
+					requestVolume := fakeK8sPersistentVolumes.CreateArgsForCall(0)
+					Expect(requestVolume.Spec.NodeAffinity).To(Equal(expectedAffinity))
+				})
+
+				It("records the node affinity in the fingerprint", func() {
+					Expect(fakeStore.CreateInstanceDetailsCallCount()).To(Equal(1))
+					_, fakeServiceInstance := fakeStore.CreateInstanceDetailsArgsForCall(0)
+					fingerprint := fakeServiceInstance.ServiceFingerPrint.(k8sbroker.ServiceFingerPrint)
+					Expect(fingerprint.NodeAffinity).To(Equal(expectedAffinity))
+				})
+
+				Context("when it has no node selector terms", func() {
+					BeforeEach(func() {
+						configuration = `
+						{
+							 "share": "/export/some-share",
+							 "server": "10.0.0.5",
+							 "node_affinity": {"required": {"nodeSelectorTerms": []}}
+						}
+						`
+						provisionDetails = brokerapi.ProvisionDetails{PlanID: "nfs", RawParameters: json.RawMessage(configuration)}
+					})
+
+					It("errors", func() {
+						Expect(err).To(Equal(k8sbroker.ValidationErrors{
+							{Field: "node_affinity", Message: "config \"node_affinity\" requires at least one \"nodeSelectorTerms\" entry"},
+						}))
+						Expect(fakeK8sPersistentVolumes.CreateCallCount()).To(Equal(0))
+					})
+				})
+			})
+
+			Context("create-service was given labels and annotations", func() {
+				BeforeEach(func() {
+					configuration = `
+					{
+						 "share": "/export/some-share",
+						 "server": "10.0.0.5",
+						 "labels": {"team": "storage", "name": "overridden"},
+						 "annotations": {"example.com/cost-center": "1234"}
+					}
+					`
+					provisionDetails = brokerapi.ProvisionDetails{PlanID: "nfs", RawParameters: json.RawMessage(configuration)}
+				})
+
+				It("merges them with the broker's labels", func() {
+					Expect(fakeK8sPersistentVolumes.CreateCallCount()).To(Equal(1))
+					requestVolume := fakeK8sPersistentVolumes.CreateArgsForCall(0)
+					Expect(requestVolume.Labels).To(Equal(map[string]string{"team": "storage", "name": "some-instance-id", "managed-by": "k8sbroker"}))
+					Expect(requestVolume.Annotations).To(Equal(map[string]string{"example.com/cost-center": "1234"}))
+				})
+
+				It("records the user supplied labels in the fingerprint", func() {
+					Expect(fakeStore.CreateInstanceDetailsCallCount()).To(Equal(1))
+					_, fakeServiceInstance := fakeStore.CreateInstanceDetailsArgsForCall(0)
+					fingerprint := fakeServiceInstance.ServiceFingerPrint.(k8sbroker.ServiceFingerPrint)
+					Expect(fingerprint.Labels).To(Equal(map[string]string{"team": "storage", "name": "overridden"}))
+					Expect(fingerprint.Annotations).To(Equal(map[string]string{"example.com/cost-center": "1234"}))
+				})
+
+				Context("when extra persistent volume labels are set", func() {
+					BeforeEach(func() {
+						config.ExtraPVLabels = map[string]string{"team": "platform", "env": "prod", "managed-by": "someone-else"}
+					})
+
+					It("adds them under the user supplied and broker labels", func() {
+						requestVolume := fakeK8sPersistentVolumes.CreateArgsForCall(0)
+						Expect(requestVolume.Labels).To(Equal(map[string]string{"team": "storage", "env": "prod", "name": "some-instance-id", "managed-by": "k8sbroker"}))
+					})
+
+					It("does not record them in the fingerprint", func() {
+						_, fakeServiceInstance := fakeStore.CreateInstanceDetailsArgsForCall(0)
+						fingerprint := fakeServiceInstance.ServiceFingerPrint.(k8sbroker.ServiceFingerPrint)
+						Expect(fingerprint.Labels).To(Equal(map[string]string{"team": "storage", "name": "overridden"}))
+					})
+				})
+
+				Context("when a label key is invalid", func() {
+					BeforeEach(func() {
+						configuration = `
+						{
+							 "share": "/export/some-share",
+							 "server": "10.0.0.5",
+							 "labels": {"not a key!": "value"}
+						}
+						`
+						provisionDetails = brokerapi.ProvisionDetails{PlanID: "nfs", RawParameters: json.RawMessage(configuration)}
+					})
+
+					It("errors without creating the persistent volume", func() {
+						Expect(err).To(MatchError(ContainSubstring(`invalid label key "not a key!"`)))
+						Expect(fakeK8sPersistentVolumes.CreateCallCount()).To(Equal(0))
+					})
+				})
+			})
+
+			Context("create-service was given a capacity_range", func() {
+				BeforeEach(func() {
+					configuration = `
+					{
+						 "share": "/export/some-share",
+						 "server": "10.0.0.5",
+						 "capacity_range": {"requiredBytes": 1073741824, "limitBytes": 2147483648}
+					}
+					`
+					provisionDetails = brokerapi.ProvisionDetails{PlanID: "nfs", RawParameters: json.RawMessage(configuration)}
+				})
+
+				It("requests the required capacity", func() {
+					Expect(fakeK8sPersistentVolumes.CreateCallCount()).To(Equal(1))
+					requestVolume := fakeK8sPersistentVolumes.CreateArgsForCall(0)
+					capacity := requestVolume.Spec.Capacity[v1.ResourceStorage]
+					Expect(capacity.Value()).To(Equal(int64(1073741824)))
+				})
+
+				It("annotates the persistent volume with the limit", func() {
+					requestVolume := fakeK8sPersistentVolumes.CreateArgsForCall(0)
+					Expect(requestVolume.Annotations).To(HaveKeyWithValue(k8sbroker.CapacityLimitAnnotation, "2Gi"))
+				})
+
+				It("records the range in the fingerprint", func() {
+					Expect(fakeStore.CreateInstanceDetailsCallCount()).To(Equal(1))
+					_, fakeServiceInstance := fakeStore.CreateInstanceDetailsArgsForCall(0)
+					fingerprint := fakeServiceInstance.ServiceFingerPrint.(k8sbroker.ServiceFingerPrint)
+					Expect(fingerprint.CapacityRange).To(Equal(&k8sbroker.CapacityRange{RequiredBytes: 1073741824, LimitBytes: 2147483648}))
+				})
+
+				Context("when the limit is less than the required bytes", func() {
+					BeforeEach(func() {
+						configuration = `
+						{
+							 "share": "/export/some-share",
+							 "server": "10.0.0.5",
+							 "capacity_range": {"requiredBytes": 2147483648, "limitBytes": 1073741824}
+						}
+						`
+						provisionDetails = brokerapi.ProvisionDetails{PlanID: "nfs", RawParameters: json.RawMessage(configuration)}
+					})
+
+					It("errors without creating the persistent volume", func() {
+						Expect(err).To(Equal(k8sbroker.ValidationErrors{
+							{Field: "capacity_range", Message: "config \"capacity_range\" limitBytes must not be less than requiredBytes"},
+						}))
+						Expect(fakeK8sPersistentVolumes.CreateCallCount()).To(Equal(0))
+					})
+				})
+
+				Context("when only the required bytes are given", func() {
+					BeforeEach(func() {
+						configuration = `
+						{
+							 "share": "/export/some-share",
+							 "server": "10.0.0.5",
+							 "capacity_range": {"requiredBytes": 1073741824}
+						}
+						`
+						provisionDetails = brokerapi.ProvisionDetails{PlanID: "nfs", RawParameters: json.RawMessage(configuration)}
+					})
+
+					It("does not annotate a limit", func() {
+						Expect(err).NotTo(HaveOccurred())
+						requestVolume := fakeK8sPersistentVolumes.CreateArgsForCall(0)
+						Expect(requestVolume.Annotations).NotTo(HaveKey(k8sbroker.CapacityLimitAnnotation))
+					})
+				})
+			})
+
+			Context("create-service was given the deprecated Recycle reclaim_policy", func() {
+				BeforeEach(func() {
+					configuration = `
+					{
+						 "share": "/export/some-share",
+						 "server": "10.0.0.5",
+						 "reclaim_policy": "Recycle"
+					}
+					`
+					provisionDetails = brokerapi.ProvisionDetails{PlanID: "nfs", RawParameters: json.RawMessage(configuration)}
+				})
+
+				It("errors", func() {
+					Expect(err).To(Equal(k8sbroker.ErrInvalidReclaimPolicy{Policy: "Recycle"}))
+				})
+			})
+
+			Context("create-service was given an unknown reclaim_policy", func() {
+				BeforeEach(func() {
+					configuration = `
+					{
+						 "share": "/export/some-share",
+						 "server": "10.0.0.5",
+						 "reclaim_policy": "Shred"
+					}
+					`
+					provisionDetails = brokerapi.ProvisionDetails{PlanID: "nfs", RawParameters: json.RawMessage(configuration)}
+				})
+
+				It("errors", func() {
+					Expect(err).To(Equal(k8sbroker.ErrInvalidReclaimPolicy{Policy: "Shred"}))
+					Expect(errors.Is(err, brokerapi.ErrRawParamsInvalid)).To(BeTrue())
+				})
+
+				It("does not create the persistent volume", func() {
+					Expect(fakeK8sPersistentVolumes.CreateCallCount()).To(Equal(0))
+				})
+			})
+
+			Context("when the service instance already exists with different details", func() {
+				BeforeEach(func() {
+					fakeStore.IsInstanceConflictReturns(true)
+				})
+
+				It("should error", func() {
+					Expect(err).To(Equal(brokerapi.ErrInstanceAlreadyExists))
+				})
+
+				It("should delete the persistent volume", func() {
+					Expect(fakeK8sPersistentVolumes.DeleteCallCount()).To(Equal(1))
+					volumeName, deleteOptions := fakeK8sPersistentVolumes.DeleteArgsForCall(0)
+					Expect(volumeName).To(Equal("some-instance-id"))
+					Expect(deleteOptions).To(Equal(&metav1.DeleteOptions{
+						TypeMeta: metav1.TypeMeta{
+							Kind:       "PersistentVolume",
+							APIVersion: "v1",
+						},
+					}))
+				})
+			})
+
+			Context("when the service instance details creation fails", func() {
+				BeforeEach(func() {
+					fakeStore.CreateInstanceDetailsReturns(errors.New("badness"))
+				})
+
+				It("should error", func() {
+					Expect(err).To(HaveOccurred())
+				})
+
+				Context("when the request was cancelled after the volume was created", func() {
+					BeforeEach(func() {
+						var cancel context.CancelFunc
+						ctx, cancel = context.WithCancel(ctx)
+						fakeK8sPersistentVolumes.CreateStub = func(volume *v1.PersistentVolume) (*v1.PersistentVolume, error) {
+							cancel()
+							return volume, nil
+						}
+					})
+
+					It("still deletes the persistent volume", func() {
+						Expect(fakeK8sPersistentVolumes.DeleteCallCount()).To(Equal(1))
+					})
+				})
+
+				It("should delete the persistent volume", func() {
+					Expect(fakeK8sPersistentVolumes.DeleteCallCount()).To(Equal(1))
+					volumeName, deleteOptions := fakeK8sPersistentVolumes.DeleteArgsForCall(0)
+					Expect(volumeName).To(Equal("some-instance-id"))
+					Expect(deleteOptions).To(Equal(&metav1.DeleteOptions{
+						TypeMeta: metav1.TypeMeta{
+							Kind:       "PersistentVolume",
+							APIVersion: "v1",
+						},
+					}))
+				})
+			})
+
+			Context("when the save fails", func() {
+				BeforeEach(func() {
+					fakeStore.SaveReturns(errors.New("badness"))
+				})
+
+				It("should error", func() {
+					Expect(err).To(HaveOccurred())
+				})
+			})
+		})
+
+		Context(".Deprovision", func() {
+			var (
+				instanceID         string
+				asyncAllowed       bool
+				deprovisionDetails brokerapi.DeprovisionDetails
+				err                error
+			)
+
+			BeforeEach(func() {
+				instanceID = "some-instance-id"
+				deprovisionDetails = brokerapi.DeprovisionDetails{PlanID: "Existing", ServiceID: "some-service-id"}
+				asyncAllowed = true
+			})
+
+			JustBeforeEach(func() {
+				_, err = broker.Deprovision(ctx, instanceID, deprovisionDetails, asyncAllowed)
+			})
+
+			Context("when the instance does not exist", func() {
+				BeforeEach(func() {
+					instanceID = "does-not-exist"
+					fakeStore.RetrieveInstanceDetailsReturns(brokerstore.ServiceInstance{}, brokerapi.ErrInstanceDoesNotExist)
+				})
+
+				It("should fail", func() {
+					Expect(err).To(Equal(brokerapi.ErrInstanceDoesNotExist))
+				})
+
+				It("logs it at info level", func() {
+					var found bool
+					for _, log := range logger.Logs() {
+						if log.Message == "test-broker.new-k8s-broker.deprovision.instance-does-not-exist" {
+							found = true
+							Expect(log.LogLevel).To(Equal(lager.INFO))
+						}
+					}
+					Expect(found).To(BeTrue())
+				})
+			})
+
+			Context("when the instance is dynamically provisioned", func() {
+				BeforeEach(func() {
+					asyncAllowed = false
+					config.DeprovisionGracePeriod = time.Hour
+					fakeStore.RetrieveInstanceDetailsReturns(brokerstore.ServiceInstance{
+						ServiceID: "some-service-id",
+						ServiceFingerPrint: &k8sbroker.ServiceFingerPrint{
+							Name:                "some-instance-id",
+							DynamicProvisioning: true,
+							StorageClassName:    "nfs-dynamic",
+							ClaimNamespaces:     []string{"some-namespace", "other-namespace"},
+						},
+					}, nil)
+				})
+
+				It("deletes the instance's claims instead of a volume", func() {
+					Expect(err).NotTo(HaveOccurred())
+					Expect(fakeK8sPersistentVolumes.DeleteCallCount()).To(Equal(0))
+					Expect(fakeK8sPersistentVolumes.PatchCallCount()).To(Equal(0))
+
+					Expect(fakeK8sPersistentVolumeClaims.DeleteCallCount()).To(Equal(2))
+					Expect(fakeK8sCoreV1.PersistentVolumeClaimsArgsForCall(0)).To(Equal("some-namespace"))
+					Expect(fakeK8sCoreV1.PersistentVolumeClaimsArgsForCall(1)).To(Equal("other-namespace"))
+					claimName, _ := fakeK8sPersistentVolumeClaims.DeleteArgsForCall(0)
+					Expect(claimName).To(Equal("some-instance-id"))
+				})
+
+				It("forgets the instance", func() {
+					Expect(fakeStore.DeleteInstanceDetailsCallCount()).To(Equal(1))
+					Expect(fakeStore.DeleteInstanceDetailsArgsForCall(0)).To(Equal("some-instance-id"))
+					Expect(fakeStore.SaveCallCount()).To(Equal(1))
+				})
+
+				Context("when the instance was provisioned with a resource name prefix", func() {
+					BeforeEach(func() {
+						fakeStore.RetrieveInstanceDetailsReturns(brokerstore.ServiceInstance{
+							ServiceID: "some-service-id",
+							ServiceFingerPrint: &k8sbroker.ServiceFingerPrint{
+								Name:                "some-instance-id",
+								NamePrefix:          "team-a-",
+								DynamicProvisioning: true,
+								StorageClassName:    "nfs-dynamic",
+								ClaimNamespaces:     []string{"some-namespace"},
+							},
+						}, nil)
+					})
+
+					It("deletes the prefixed claims", func() {
+						Expect(err).NotTo(HaveOccurred())
+						claimName, _ := fakeK8sPersistentVolumeClaims.DeleteArgsForCall(0)
+						Expect(claimName).To(Equal("team-a-some-instance-id"))
+					})
+				})
+
+				Context("when the request has been cancelled", func() {
+					BeforeEach(func() {
+						var cancel context.CancelFunc
+						ctx, cancel = context.WithCancel(ctx)
+						cancel()
+					})
+
+					It("errors without deleting the claims", func() {
+						Expect(err).To(Equal(context.Canceled))
+						Expect(fakeK8sPersistentVolumeClaims.DeleteCallCount()).To(Equal(0))
+						Expect(fakeStore.DeleteInstanceDetailsCallCount()).To(Equal(0))
+					})
+				})
+
+				Context("when a claim is already gone", func() {
+					BeforeEach(func() {
+						fakeK8sPersistentVolumeClaims.DeleteReturnsOnCall(0, k8serrors.NewNotFound(schema.GroupResource{Resource: "persistentvolumeclaims"}, "some-instance-id"))
+					})
+
+					It("deletes the others", func() {
+						Expect(err).NotTo(HaveOccurred())
+						Expect(fakeK8sPersistentVolumeClaims.DeleteCallCount()).To(Equal(2))
+						Expect(fakeStore.DeleteInstanceDetailsCallCount()).To(Equal(1))
+					})
+				})
+
+				Context("when a claim cannot be deleted", func() {
+					BeforeEach(func() {
+						fakeK8sPersistentVolumeClaims.DeleteReturns(errors.New("forbidden"))
+					})
+
+					It("errors and keeps the instance", func() {
+						Expect(err).To(MatchError("forbidden"))
+						Expect(fakeStore.DeleteInstanceDetailsCallCount()).To(Equal(0))
+					})
+				})
+			})
+
+			Context("given an existing instance", func() {
+				var (
+					previousSaveCallCount int
+				)
+
+				BeforeEach(func() {
+					asyncAllowed = false
+
+					fingerprint := k8sbroker.ServiceFingerPrint{
+						Name: "some-instance-id",
+						Volume: &v1.PersistentVolume{
+							TypeMeta: metav1.TypeMeta{
+								Kind:       "PersistentVolume",
+								APIVersion: "v1",
+							},
+							ObjectMeta: metav1.ObjectMeta{
+								Name:   "some-instance-id",
+								Labels: map[string]string{"name": "some-instance-id"},
+							},
+						},
+					}
+
+					// simulate untyped data loaded from a data file
+					jsonFingerprint := &map[string]interface{}{}
+					raw, err := json.Marshal(fingerprint)
+					Expect(err).ToNot(HaveOccurred())
+					err = json.Unmarshal(raw, jsonFingerprint)
+					Expect(err).ToNot(HaveOccurred())
+
+					fakeStore.RetrieveInstanceDetailsReturns(brokerstore.ServiceInstance{
+						ServiceID:          "some-service-id",
+						ServiceFingerPrint: jsonFingerprint,
+					}, nil)
+					previousSaveCallCount = fakeStore.SaveCallCount()
+				})
+
+				It("should succeed", func() {
 					Expect(err).NotTo(HaveOccurred())
 				})
 
@@ -350,321 +2459,2390 @@ var _ = Describe("Broker", func() {
 					Expect(fakeStore.SaveCallCount()).To(Equal(previousSaveCallCount + 1))
 				})
 
-				It("should send the request to the k8s client", func() {
-					Expect(fakeK8sPersistentVolumes.DeleteCallCount()).To(Equal(1))
-					volumeName, deleteOptions := fakeK8sPersistentVolumes.DeleteArgsForCall(0)
-					Expect(volumeName).To(Equal("some-instance-id"))
-					Expect(deleteOptions).To(Equal(&metav1.DeleteOptions{
-						TypeMeta: metav1.TypeMeta{
-							Kind:       "PersistentVolume",
-							APIVersion: "v1",
-						},
-					}))
+				It("records the operation", func() {
+					Expect(fakeMetrics.ObserveCallCount()).To(Equal(1))
+					operation, _, observedErr := fakeMetrics.ObserveArgsForCall(0)
+					Expect(operation).To(Equal("deprovision"))
+					Expect(observedErr).NotTo(HaveOccurred())
+				})
+
+				It("should send the request to the k8s client", func() {
+					Expect(fakeK8sPersistentVolumes.DeleteCallCount()).To(Equal(1))
+					volumeName, deleteOptions := fakeK8sPersistentVolumes.DeleteArgsForCall(0)
+					Expect(volumeName).To(Equal("some-instance-id"))
+					Expect(deleteOptions).To(Equal(&metav1.DeleteOptions{
+						TypeMeta: metav1.TypeMeta{
+							Kind:       "PersistentVolume",
+							APIVersion: "v1",
+						},
+					}))
+				})
+
+				Context("when the instance has user annotations", func() {
+					var (
+						deletedAt         time.Time
+						deletedAfterPatch bool
+					)
+
+					BeforeEach(func() {
+						fakeStore.RetrieveInstanceDetailsReturns(brokerstore.ServiceInstance{
+							ServiceID: "some-service-id",
+							ServiceFingerPrint: k8sbroker.ServiceFingerPrint{
+								Name:        "some-instance-id",
+								Volume:      &v1.PersistentVolume{ObjectMeta: metav1.ObjectMeta{Name: "some-instance-id"}},
+								Annotations: map[string]string{"backup.velero.io/backup-volumes": "data"},
+							},
+						}, nil)
+						deletedAfterPatch = false
+						fakeK8sPersistentVolumes.DeleteStub = func(name string, options *metav1.DeleteOptions) error {
+							deletedAt = fakeClock.Now()
+							deletedAfterPatch = fakeK8sPersistentVolumes.PatchCallCount() == 1
+							return nil
+						}
+					})
+
+					It("re-applies them before deleting the volume", func() {
+						Expect(err).NotTo(HaveOccurred())
+						Expect(fakeK8sPersistentVolumes.PatchCallCount()).To(Equal(1))
+						name, patchType, data, _ := fakeK8sPersistentVolumes.PatchArgsForCall(0)
+						Expect(name).To(Equal("some-instance-id"))
+						Expect(patchType).To(Equal(types.MergePatchType))
+						Expect(data).To(MatchJSON(`{"metadata": {"annotations": {"backup.velero.io/backup-volumes": "data"}}}`))
+						Expect(deletedAfterPatch).To(BeTrue())
+					})
+
+					Context("with an annotation propagation delay", func() {
+						var start time.Time
+
+						BeforeEach(func() {
+							config.AnnotationPropagationDelay = time.Minute
+							start = fakeClock.Now()
+							go fakeClock.WaitForWatcherAndIncrement(time.Minute)
+						})
+
+						It("waits for the delay before deleting the volume", func() {
+							Expect(err).NotTo(HaveOccurred())
+							Expect(fakeK8sPersistentVolumes.DeleteCallCount()).To(Equal(1))
+							Expect(deletedAt.Equal(start.Add(time.Minute))).To(BeTrue())
+						})
+					})
+
+					Context("when the volume is already gone", func() {
+						BeforeEach(func() {
+							fakeK8sPersistentVolumes.PatchReturns(nil, k8serrors.NewNotFound(schema.GroupResource{Resource: "persistentvolumes"}, "some-instance-id"))
+							fakeK8sPersistentVolumes.DeleteStub = nil
+							fakeK8sPersistentVolumes.DeleteReturns(k8serrors.NewNotFound(schema.GroupResource{Resource: "persistentvolumes"}, "some-instance-id"))
+						})
+
+						It("deprovisions", func() {
+							Expect(err).NotTo(HaveOccurred())
+							Expect(fakeStore.DeleteInstanceDetailsCallCount()).To(Equal(1))
+						})
+					})
+
+					Context("when re-applying them fails", func() {
+						BeforeEach(func() {
+							fakeK8sPersistentVolumes.PatchReturns(nil, errors.New("patch-failed"))
+						})
+
+						It("errors without deleting the volume", func() {
+							Expect(err).To(MatchError("patch-failed"))
+							Expect(fakeK8sPersistentVolumes.DeleteCallCount()).To(Equal(0))
+							Expect(fakeStore.DeleteInstanceDetailsCallCount()).To(Equal(0))
+						})
+					})
+
+					Context("with a grace period", func() {
+						BeforeEach(func() {
+							config.DeprovisionGracePeriod = time.Hour
+						})
+
+						It("re-applies them along with the deletion time", func() {
+							Expect(fakeK8sPersistentVolumes.PatchCallCount()).To(Equal(1))
+							_, _, data, _ := fakeK8sPersistentVolumes.PatchArgsForCall(0)
+							deleteAfter := fakeClock.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+							Expect(data).To(MatchJSON(`{"metadata": {"annotations": {"backup.velero.io/backup-volumes": "data", "` + k8sbroker.DeleteAfterAnnotation + `": "` + deleteAfter + `"}}}`))
+						})
+					})
+				})
+
+				Context("with a grace period", func() {
+					BeforeEach(func() {
+						config.DeprovisionGracePeriod = time.Hour
+					})
+
+					It("annotates the volume instead of deleting it", func() {
+						Expect(err).NotTo(HaveOccurred())
+						Expect(fakeK8sPersistentVolumes.DeleteCallCount()).To(Equal(0))
+						Expect(fakeK8sPersistentVolumes.PatchCallCount()).To(Equal(1))
+						name, patchType, data, _ := fakeK8sPersistentVolumes.PatchArgsForCall(0)
+						Expect(name).To(Equal("some-instance-id"))
+						Expect(patchType).To(Equal(types.MergePatchType))
+						deleteAfter := fakeClock.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+						Expect(data).To(MatchJSON(`{"metadata": {"annotations": {"` + k8sbroker.DeleteAfterAnnotation + `": "` + deleteAfter + `"}}}`))
+					})
+
+					It("keeps the instance with its deletion time", func() {
+						Expect(fakeStore.DeleteInstanceDetailsCallCount()).To(Equal(1))
+						Expect(fakeStore.CreateInstanceDetailsCallCount()).To(Equal(1))
+						id, details := fakeStore.CreateInstanceDetailsArgsForCall(0)
+						Expect(id).To(Equal("some-instance-id"))
+						fingerprint := details.ServiceFingerPrint.(k8sbroker.ServiceFingerPrint)
+						Expect(fingerprint.DeleteAfter).NotTo(BeNil())
+						Expect(fingerprint.DeleteAfter.Equal(fakeClock.Now().Add(time.Hour))).To(BeTrue())
+						Expect(fakeStore.SaveCallCount()).To(Equal(previousSaveCallCount + 1))
+					})
+
+					Context("when annotating the volume fails", func() {
+						BeforeEach(func() {
+							fakeK8sPersistentVolumes.PatchReturns(nil, errors.New("patch-failed"))
+						})
+
+						It("errors and keeps the instance as it was", func() {
+							Expect(err).To(MatchError("patch-failed"))
+							Expect(fakeStore.DeleteInstanceDetailsCallCount()).To(Equal(0))
+						})
+					})
+
+					Context("when the volume is reclaimed by kubernetes", func() {
+						BeforeEach(func() {
+							fakeStore.RetrieveInstanceDetailsReturns(brokerstore.ServiceInstance{
+								ServiceID: "some-service-id",
+								ServiceFingerPrint: k8sbroker.ServiceFingerPrint{
+									Name:          "some-instance-id",
+									Volume:        &v1.PersistentVolume{ObjectMeta: metav1.ObjectMeta{Name: "some-instance-id"}},
+									ReclaimPolicy: v1.PersistentVolumeReclaimDelete,
+									Bindings: map[string]k8sbroker.BindingFingerPrint{
+										"binding-id": {ClaimName: "some-claim", Namespace: "some-org-namespace"},
+									},
+								},
+							}, nil)
+						})
+
+						It("removes the instance immediately", func() {
+							Expect(err).NotTo(HaveOccurred())
+							Expect(fakeK8sPersistentVolumes.PatchCallCount()).To(Equal(0))
+							Expect(fakeStore.DeleteInstanceDetailsCallCount()).To(Equal(1))
+							Expect(fakeStore.CreateInstanceDetailsCallCount()).To(Equal(0))
+						})
+					})
+				})
+
+				Context("when the instance is already waiting for deletion", func() {
+					BeforeEach(func() {
+						deleteAfter := time.Now().Add(time.Hour)
+						fakeStore.RetrieveInstanceDetailsReturns(brokerstore.ServiceInstance{
+							ServiceID: "some-service-id",
+							ServiceFingerPrint: k8sbroker.ServiceFingerPrint{
+								Name:        "some-instance-id",
+								Volume:      &v1.PersistentVolume{ObjectMeta: metav1.ObjectMeta{Name: "some-instance-id"}},
+								DeleteAfter: &deleteAfter,
+							},
+						}, nil)
+					})
+
+					It("reports the instance as gone", func() {
+						Expect(err).To(Equal(brokerapi.ErrInstanceDoesNotExist))
+						Expect(fakeK8sPersistentVolumes.DeleteCallCount()).To(Equal(0))
+					})
+				})
+
+				Context("when the volume is already gone", func() {
+					BeforeEach(func() {
+						fakeK8sPersistentVolumes.DeleteReturns(k8serrors.NewNotFound(schema.GroupResource{Resource: "persistentvolumes"}, "some-instance-id"))
+					})
+
+					It("deprovisions", func() {
+						Expect(err).NotTo(HaveOccurred())
+						Expect(fakeStore.DeleteInstanceDetailsCallCount()).To(Equal(1))
+						Expect(fakeStore.DeleteInstanceDetailsArgsForCall(0)).To(Equal("some-instance-id"))
+					})
+				})
+
+				Context("when the client returns an error", func() {
+					var deleteErr error
+
+					BeforeEach(func() {
+						deleteErr = errors.New("some-error")
+						fakeK8sPersistentVolumes.DeleteReturns(deleteErr)
+					})
+
+					It("should error", func() {
+						Expect(err).To(Equal(deleteErr))
+					})
+				})
+
+				Context("when deletion of the instance fails", func() {
+					var storeErr error
+
+					BeforeEach(func() {
+						storeErr = errors.New("some-error")
+						fakeStore.DeleteInstanceDetailsReturns(storeErr)
+					})
+
+					It("should error", func() {
+						Expect(err).To(Equal(storeErr))
+					})
+				})
+
+				Context("when the save fails", func() {
+					var storeErr error
+
+					BeforeEach(func() {
+						storeErr = errors.New("some-error")
+						fakeStore.SaveReturns(storeErr)
+					})
+
+					It("should error", func() {
+						Expect(err).To(Equal(storeErr))
+					})
+				})
+
+				Context("when the volume has a Delete reclaim policy", func() {
+					BeforeEach(func() {
+						fakeStore.RetrieveInstanceDetailsReturns(brokerstore.ServiceInstance{
+							ServiceID: "some-service-id",
+							ServiceFingerPrint: &k8sbroker.ServiceFingerPrint{
+								Name: "some-instance-id",
+								Volume: &v1.PersistentVolume{
+									ObjectMeta: metav1.ObjectMeta{Name: "some-instance-id"},
+								},
+								ReclaimPolicy: v1.PersistentVolumeReclaimDelete,
+							},
+						}, nil)
+					})
+
+					It("deletes the persistent volume, as no claim is bound to it", func() {
+						Expect(err).NotTo(HaveOccurred())
+						Expect(fakeK8sPersistentVolumes.DeleteCallCount()).To(Equal(1))
+						Expect(fakeStore.DeleteInstanceDetailsCallCount()).To(Equal(1))
+					})
+
+					Context("when a claim is still bound to the volume", func() {
+						BeforeEach(func() {
+							fakeStore.RetrieveInstanceDetailsReturns(brokerstore.ServiceInstance{
+								ServiceID: "some-service-id",
+								ServiceFingerPrint: &k8sbroker.ServiceFingerPrint{
+									Name: "some-instance-id",
+									Volume: &v1.PersistentVolume{
+										ObjectMeta: metav1.ObjectMeta{Name: "some-instance-id"},
+									},
+									ReclaimPolicy: v1.PersistentVolumeReclaimDelete,
+									Bindings: map[string]k8sbroker.BindingFingerPrint{
+										"binding-id": {ClaimName: "some-claim", Namespace: "some-org-namespace"},
+									},
+								},
+							}, nil)
+						})
+
+						It("should succeed", func() {
+							Expect(err).NotTo(HaveOccurred())
+						})
+
+						It("leaves the persistent volume to kubernetes", func() {
+							Expect(fakeK8sPersistentVolumes.DeleteCallCount()).To(Equal(0))
+						})
+
+						It("deletes the instance details", func() {
+							Expect(fakeStore.DeleteInstanceDetailsCallCount()).To(Equal(1))
+						})
+					})
+				})
+
+				Context("delete-service was given no instance id", func() {
+					BeforeEach(func() {
+						instanceID = ""
+					})
+
+					It("errors", func() {
+						Expect(err).To(Equal(errors.New("volume deletion requires instance ID")))
+					})
+				})
+			})
+		})
+
+		Context(".Update", func() {
+			var (
+				instanceID    string
+				updateDetails brokerapi.UpdateDetails
+				err           error
+			)
+
+			BeforeEach(func() {
+				instanceID = "some-instance-id"
+				updateDetails = brokerapi.UpdateDetails{
+					ServiceID:     "some-service-id",
+					PlanID:        "some-plan-id",
+					RawParameters: json.RawMessage(`{"server": "10.0.0.5", "share": "/export/some-share"}`),
+				}
+
+				fakeServices.ListReturns([]brokerapi.Service{
+					{
+						ID: "some-service-id",
+						Plans: []brokerapi.ServicePlan{
+							{ID: "some-plan-id"},
+							{ID: "other-plan-id"},
+						},
+					},
+				})
+
+				fingerprint := k8sbroker.ServiceFingerPrint{
+					Name: "some-instance-id",
+					Volume: &v1.PersistentVolume{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:   "some-instance-id",
+							Labels: map[string]string{"name": "some-instance-id"},
+						},
+						Spec: v1.PersistentVolumeSpec{
+							PersistentVolumeSource: v1.PersistentVolumeSource{
+								NFS: &v1.NFSVolumeSource{
+									Server: "10.0.0.5",
+									Path:   "/export/some-share",
+								},
+							},
+						},
+					},
+				}
+
+				// simulate untyped data loaded from a data file
+				jsonFingerprint := &map[string]interface{}{}
+				raw, err := json.Marshal(fingerprint)
+				Expect(err).ToNot(HaveOccurred())
+				err = json.Unmarshal(raw, jsonFingerprint)
+				Expect(err).ToNot(HaveOccurred())
+
+				fakeStore.RetrieveInstanceDetailsReturns(brokerstore.ServiceInstance{
+					ServiceID:          "some-service-id",
+					PlanID:             "some-plan-id",
+					ServiceFingerPrint: jsonFingerprint,
+				}, nil)
+
+			})
+
+			JustBeforeEach(func() {
+				_, err = broker.Update(ctx, instanceID, updateDetails, false)
+			})
+
+			It("should not error", func() {
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("does not update the persistent volume", func() {
+				Expect(fakeK8sPersistentVolumes.UpdateCallCount()).To(Equal(0))
+			})
+
+			It("replaces the stored instance details", func() {
+				Expect(fakeStore.DeleteInstanceDetailsCallCount()).To(Equal(1))
+				Expect(fakeStore.DeleteInstanceDetailsArgsForCall(0)).To(Equal(instanceID))
+
+				Expect(fakeStore.CreateInstanceDetailsCallCount()).To(Equal(1))
+				id, details := fakeStore.CreateInstanceDetailsArgsForCall(0)
+				Expect(id).To(Equal(instanceID))
+				Expect(details.PlanID).To(Equal("some-plan-id"))
+				fingerprint := details.ServiceFingerPrint.(k8sbroker.ServiceFingerPrint)
+				Expect(fingerprint.Name).To(Equal("some-instance-id"))
+				Expect(fingerprint.Volume.Spec.NFS.Server).To(Equal("10.0.0.5"))
+				Expect(fingerprint.Volume.Spec.NFS.Path).To(Equal("/export/some-share"))
+			})
+
+			It("should write state", func() {
+				Expect(fakeStore.SaveCallCount()).To(Equal(1))
+			})
+
+			Context("when the server or share changes", func() {
+				BeforeEach(func() {
+					updateDetails.RawParameters = json.RawMessage(`{"server": "10.0.0.6", "share": "/export/other-share"}`)
+				})
+
+				It("errors with a 422, as the nfs source of a persistent volume cannot change", func() {
+					Expect(err).To(Equal(k8sbroker.ErrNfsSourceChange))
+					Expect(err.(*brokerapi.FailureResponse).ValidatedStatusCode(logger)).To(Equal(http.StatusUnprocessableEntity))
+				})
+
+				It("touches neither the persistent volume nor the store", func() {
+					Expect(fakeK8sPersistentVolumes.UpdateCallCount()).To(Equal(0))
+					Expect(fakeStore.CreateInstanceDetailsCallCount()).To(Equal(0))
+				})
+			})
+
+			Context("when the instance's name is given", func() {
+				BeforeEach(func() {
+					updateDetails.RawParameters = json.RawMessage(`{"name": "some-instance-id", "server": "10.0.0.5", "share": "/export/some-share"}`)
+				})
+
+				It("does not error", func() {
+					Expect(err).NotTo(HaveOccurred())
+				})
+
+				Context("when it is another name", func() {
+					BeforeEach(func() {
+						updateDetails.RawParameters = json.RawMessage(`{"name": "other-name", "server": "10.0.0.5", "share": "/export/some-share"}`)
+					})
+
+					It("errors without storing the instance details", func() {
+						Expect(err).To(Equal(k8sbroker.ValidationErrors{
+							{Field: "name", Message: `config "name" cannot be changed from "some-instance-id"`},
+						}))
+						Expect(fakeStore.CreateInstanceDetailsCallCount()).To(Equal(0))
+					})
+				})
+			})
+
+			Context("when the instance was provisioned with a capacity range", func() {
+				BeforeEach(func() {
+					fakeStore.RetrieveInstanceDetailsReturns(brokerstore.ServiceInstance{
+						ServiceID: "some-service-id",
+						PlanID:    "some-plan-id",
+						ServiceFingerPrint: &k8sbroker.ServiceFingerPrint{
+							Name:          "some-instance-id",
+							CapacityRange: &k8sbroker.CapacityRange{RequiredBytes: 1073741824},
+							Volume: &v1.PersistentVolume{
+								ObjectMeta: metav1.ObjectMeta{Name: "some-instance-id"},
+								Spec: v1.PersistentVolumeSpec{
+									Capacity: v1.ResourceList{v1.ResourceStorage: resource.MustParse("1Gi")},
+									PersistentVolumeSource: v1.PersistentVolumeSource{
+										NFS: &v1.NFSVolumeSource{Server: "10.0.0.5", Path: "/export/some-share"},
+									},
+								},
+							},
+						},
+					}, nil)
+				})
+
+				Context("when the update has none", func() {
+					It("errors", func() {
+						Expect(err).To(Equal(k8sbroker.ValidationErrors{
+							{Field: "capacity_range", Message: `config requires a "capacity_range", as the instance was provisioned with one`},
+						}))
+					})
+				})
+
+				Context("when its limit is below the volume's capacity", func() {
+					BeforeEach(func() {
+						updateDetails.RawParameters = json.RawMessage(`{"server": "10.0.0.5", "share": "/export/some-share", "capacity_range": {"requiredBytes": 536870912, "limitBytes": 536870912}}`)
+					})
+
+					It("errors", func() {
+						Expect(err).To(Equal(k8sbroker.ValidationErrors{
+							{Field: "capacity_range", Message: `config "capacity_range" limitBytes must not be less than the volume's capacity of 1Gi`},
+						}))
+					})
+				})
+
+				Context("when it is kept", func() {
+					BeforeEach(func() {
+						updateDetails.RawParameters = json.RawMessage(`{"server": "10.0.0.5", "share": "/export/some-share", "capacity_range": {"requiredBytes": 1073741824}}`)
+					})
+
+					It("does not error", func() {
+						Expect(err).NotTo(HaveOccurred())
+					})
+				})
+			})
+
+			Context("when no parameters are given", func() {
+				BeforeEach(func() {
+					updateDetails.RawParameters = nil
+				})
+
+				It("does not update the persistent volume", func() {
+					Expect(err).NotTo(HaveOccurred())
+					Expect(fakeK8sPersistentVolumes.UpdateCallCount()).To(Equal(0))
+				})
+			})
+
+			It("does not check plan upgrades when the plan is unchanged", func() {
+				Expect(fakeServices.CanUpgradeCallCount()).To(Equal(0))
+			})
+
+			Context("when the plan changes to a known plan", func() {
+				BeforeEach(func() {
+					updateDetails.PlanID = "other-plan-id"
+				})
+
+				It("stores the new plan", func() {
+					Expect(err).NotTo(HaveOccurred())
+					_, details := fakeStore.CreateInstanceDetailsArgsForCall(0)
+					Expect(details.PlanID).To(Equal("other-plan-id"))
+				})
+
+				It("checks the instance may move to the new plan", func() {
+					Expect(fakeServices.CanUpgradeCallCount()).To(Equal(1))
+					fromPlanID, toPlanID := fakeServices.CanUpgradeArgsForCall(0)
+					Expect(fromPlanID).To(Equal("some-plan-id"))
+					Expect(toPlanID).To(Equal("other-plan-id"))
+				})
+
+				Context("when the new plan does not accept instances from the old one", func() {
+					BeforeEach(func() {
+						fakeServices.CanUpgradeReturns(false)
+					})
+
+					It("errors without touching the persistent volume or the store", func() {
+						Expect(err).To(Equal(brokerapi.ErrPlanChangeNotSupported))
+						Expect(fakeK8sPersistentVolumes.UpdateCallCount()).To(Equal(0))
+						Expect(fakeStore.CreateInstanceDetailsCallCount()).To(Equal(0))
+					})
+				})
+			})
+
+			Context("when the plan changes to an unknown plan", func() {
+				BeforeEach(func() {
+					updateDetails.PlanID = "unknown-plan-id"
+				})
+
+				It("errors with a 400", func() {
+					Expect(err).To(MatchError(k8sbroker.ErrInvalidPlan{PlanID: "unknown-plan-id"}.Error()))
+					Expect(err.(*brokerapi.FailureResponse).ValidatedStatusCode(logger)).To(Equal(http.StatusBadRequest))
+				})
+
+				It("does not touch the persistent volume", func() {
+					Expect(fakeK8sPersistentVolumes.UpdateCallCount()).To(Equal(0))
+				})
+			})
+
+			Context("when the parameters are not valid JSON", func() {
+				BeforeEach(func() {
+					updateDetails.RawParameters = json.RawMessage("{this is not json")
+				})
+
+				It("errors", func() {
+					Expect(err).To(Equal(brokerapi.ErrRawParamsInvalid))
+				})
+			})
+
+			Context("when the parameters have no 'share'", func() {
+				BeforeEach(func() {
+					updateDetails.RawParameters = json.RawMessage(`{"server": "10.0.0.6"}`)
+				})
+
+				It("errors", func() {
+					Expect(err).To(Equal(k8sbroker.ValidationErrors{
+						{Field: "share", Message: "config requires a \"share\""},
+					}))
+				})
+			})
+
+			Context("when the capacity range grows", func() {
+				BeforeEach(func() {
+					updateDetails.RawParameters = json.RawMessage(`{"server": "10.0.0.5", "share": "/export/some-share", "capacity_range": {"requiredBytes": 2147483648}}`)
+
+					fakeStore.RetrieveInstanceDetailsReturns(brokerstore.ServiceInstance{
+						ServiceID: "some-service-id",
+						PlanID:    "some-plan-id",
+						ServiceFingerPrint: &k8sbroker.ServiceFingerPrint{
+							Name: "some-instance-id",
+							Volume: &v1.PersistentVolume{
+								ObjectMeta: metav1.ObjectMeta{Name: "some-instance-id"},
+								Spec: v1.PersistentVolumeSpec{
+									Capacity:         v1.ResourceList{v1.ResourceStorage: resource.MustParse("1Gi")},
+									StorageClassName: "expandable",
+									PersistentVolumeSource: v1.PersistentVolumeSource{
+										NFS: &v1.NFSVolumeSource{Server: "10.0.0.5", Path: "/export/some-share"},
+									},
+								},
+							},
+							Bindings: map[string]k8sbroker.BindingFingerPrint{
+								"binding-id": {ClaimName: "some-claim", Namespace: "some-org-namespace"},
+							},
+						},
+					}, nil)
+				})
+
+				It("refuses to expand the NFS volume", func() {
+					Expect(err).To(Equal(k8sbroker.ErrVolumeExpansionUnsupported))
+					Expect(fakeK8sStorageClasses.GetCallCount()).To(Equal(0))
+					Expect(fakeK8sPersistentVolumeClaims.PatchCallCount()).To(Equal(0))
+					Expect(fakeK8sPersistentVolumes.PatchCallCount()).To(Equal(0))
+					Expect(fakeStore.CreateInstanceDetailsCallCount()).To(Equal(0))
+				})
+
+				Context("when the capacity does not grow", func() {
+					BeforeEach(func() {
+						updateDetails.RawParameters = json.RawMessage(`{"server": "10.0.0.5", "share": "/export/some-share", "capacity_range": {"requiredBytes": 1073741824}}`)
+					})
+
+					It("stores the capacity range without touching the volume", func() {
+						Expect(err).NotTo(HaveOccurred())
+						Expect(fakeK8sPersistentVolumes.PatchCallCount()).To(Equal(0))
+
+						_, details := fakeStore.CreateInstanceDetailsArgsForCall(0)
+						fingerprint := details.ServiceFingerPrint.(k8sbroker.ServiceFingerPrint)
+						Expect(fingerprint.CapacityRange).To(Equal(&k8sbroker.CapacityRange{RequiredBytes: 1073741824}))
+					})
+				})
+			})
+
+			Context("when the instance does not exist", func() {
+				BeforeEach(func() {
+					fakeStore.RetrieveInstanceDetailsReturns(brokerstore.ServiceInstance{}, errors.New("not found"))
+				})
+
+				It("errors", func() {
+					Expect(err).To(Equal(brokerapi.ErrInstanceDoesNotExist))
+				})
+			})
+
+			Context("when storing the instance details fails", func() {
+				var storeErr error
+
+				BeforeEach(func() {
+					storeErr = errors.New("badness")
+					fakeStore.CreateInstanceDetailsReturnsOnCall(0, storeErr)
+				})
+
+				It("returns the store's error", func() {
+					Expect(err).To(MatchError("failed to store instance details some-instance-id: badness"))
+					Expect(errors.Is(err, storeErr)).To(BeTrue())
+				})
+
+				It("puts the old instance details back", func() {
+					Expect(fakeStore.CreateInstanceDetailsCallCount()).To(Equal(2))
+					previous, _ := fakeStore.RetrieveInstanceDetails(instanceID)
+					id, details := fakeStore.CreateInstanceDetailsArgsForCall(1)
+					Expect(id).To(Equal(instanceID))
+					Expect(details).To(Equal(previous))
+				})
+			})
+
+			Context("when the instance is dynamically provisioned", func() {
+				BeforeEach(func() {
+					fakeStore.RetrieveInstanceDetailsReturns(brokerstore.ServiceInstance{
+						ServiceID: "some-service-id",
+						PlanID:    "some-plan-id",
+						ServiceFingerPrint: &k8sbroker.ServiceFingerPrint{
+							Name:                "some-instance-id",
+							DynamicProvisioning: true,
+							StorageClassName:    "nfs-dynamic",
+						},
+					}, nil)
+				})
+
+				It("refuses new parameters", func() {
+					Expect(err).To(Equal(k8sbroker.ErrDynamicInstanceUpdate))
+					Expect(fakeStore.CreateInstanceDetailsCallCount()).To(Equal(0))
+				})
+
+				Context("when only the plan changes", func() {
+					BeforeEach(func() {
+						updateDetails.PlanID = "other-plan-id"
+						updateDetails.RawParameters = nil
+					})
+
+					It("updates the plan", func() {
+						Expect(err).NotTo(HaveOccurred())
+						_, details := fakeStore.CreateInstanceDetailsArgsForCall(0)
+						Expect(details.PlanID).To(Equal("other-plan-id"))
+					})
+				})
+			})
+		})
+
+		Context(".LastOperation", func() {
+			var (
+				fingerprint   k8sbroker.ServiceFingerPrint
+				operationData string
+				storeErr      error
+				op            brokerapi.LastOperation
+				err           error
+			)
+
+			BeforeEach(func() {
+				operationData = k8sbroker.OperationProvision
+				storeErr = nil
+				fingerprint = k8sbroker.ServiceFingerPrint{
+					Name: "some-instance-id",
+					Volume: &v1.PersistentVolume{
+						ObjectMeta: metav1.ObjectMeta{Name: "some-instance-id"},
+					},
+				}
+			})
+
+			JustBeforeEach(func() {
+				// simulate untyped data loaded from a data file
+				jsonFingerprint := &map[string]interface{}{}
+				raw, marshalErr := json.Marshal(fingerprint)
+				Expect(marshalErr).ToNot(HaveOccurred())
+				Expect(json.Unmarshal(raw, jsonFingerprint)).To(Succeed())
+
+				fakeStore.RetrieveInstanceDetailsReturns(brokerstore.ServiceInstance{
+					ServiceID:          "some-service-id",
+					ServiceFingerPrint: jsonFingerprint,
+				}, storeErr)
+
+				op, err = broker.LastOperation(ctx, "some-instance-id", operationData)
+			})
+
+			Context("when the operation data is not recognized", func() {
+				BeforeEach(func() {
+					operationData = "some-instance-id"
+				})
+
+				It("errors", func() {
+					Expect(err).To(HaveOccurred())
+				})
+			})
+
+			Context("when the instance is not in the store", func() {
+				BeforeEach(func() {
+					operationData = k8sbroker.OperationDeprovision
+					storeErr = errors.New("not found")
+				})
+
+				It("reports that the instance is gone", func() {
+					Expect(err).To(Equal(brokerapi.ErrInstanceDoesNotExist))
+				})
+			})
+
+			Context("when deprovisioning", func() {
+				BeforeEach(func() {
+					operationData = k8sbroker.OperationDeprovision
+				})
+
+				Context("and the persistent volume is gone", func() {
+					BeforeEach(func() {
+						fakeK8sPersistentVolumes.GetReturns(nil, k8serrors.NewNotFound(schema.GroupResource{Resource: "persistentvolumes"}, "some-instance-id"))
+					})
+
+					It("succeeds", func() {
+						Expect(err).NotTo(HaveOccurred())
+						Expect(op.State).To(Equal(brokerapi.Succeeded))
+					})
+				})
+
+				Context("and the persistent volume still exists", func() {
+					BeforeEach(func() {
+						fakeK8sPersistentVolumes.GetReturns(&v1.PersistentVolume{Status: v1.PersistentVolumeStatus{Phase: v1.VolumeReleased}}, nil)
+					})
+
+					It("is in progress", func() {
+						Expect(err).NotTo(HaveOccurred())
+						Expect(op.State).To(Equal(brokerapi.InProgress))
+					})
+				})
+			})
+
+			Context("when the provision is still in progress", func() {
+				BeforeEach(func() {
+					fingerprint.ProvisionState = &k8sbroker.ProvisionState{Status: k8sbroker.ProvisionInProgress}
+				})
+
+				It("reports in progress without asking kubernetes", func() {
+					Expect(err).NotTo(HaveOccurred())
+					Expect(op.State).To(Equal(brokerapi.InProgress))
+					Expect(fakeK8sPersistentVolumes.GetCallCount()).To(Equal(0))
+				})
+			})
+
+			Context("when the provision failed", func() {
+				BeforeEach(func() {
+					fingerprint.ProvisionState = &k8sbroker.ProvisionState{Status: k8sbroker.ProvisionFailed, Description: "some-error"}
+				})
+
+				It("reports the failure", func() {
+					Expect(err).NotTo(HaveOccurred())
+					Expect(op).To(Equal(brokerapi.LastOperation{State: brokerapi.Failed, Description: "some-error"}))
+				})
+			})
+
+			Context("when the persistent volume exists", func() {
+				var phase v1.PersistentVolumePhase
+
+				JustBeforeEach(func() {
+					Expect(fakeK8sPersistentVolumes.GetCallCount()).To(Equal(1))
+					name, _ := fakeK8sPersistentVolumes.GetArgsForCall(0)
+					Expect(name).To(Equal("some-instance-id"))
+				})
+
+				BeforeEach(func() {
+					fakeK8sPersistentVolumes.GetStub = func(string, metav1.GetOptions) (*v1.PersistentVolume, error) {
+						return &v1.PersistentVolume{Status: v1.PersistentVolumeStatus{Phase: phase}}, nil
+					}
+				})
+
+				Context("and is bound", func() {
+					BeforeEach(func() {
+						phase = v1.VolumeBound
+					})
+
+					It("succeeds", func() {
+						Expect(op.State).To(Equal(brokerapi.Succeeded))
+					})
+				})
+
+				Context("and is available", func() {
+					BeforeEach(func() {
+						phase = v1.VolumeAvailable
+					})
+
+					It("succeeds", func() {
+						Expect(op.State).To(Equal(brokerapi.Succeeded))
+					})
+				})
+
+				Context("and is pending", func() {
+					BeforeEach(func() {
+						phase = v1.VolumePending
+					})
+
+					It("is in progress", func() {
+						Expect(op.State).To(Equal(brokerapi.InProgress))
+					})
+				})
+
+				Context("and has failed", func() {
+					BeforeEach(func() {
+						phase = v1.VolumeFailed
+					})
+
+					It("fails", func() {
+						Expect(op.State).To(Equal(brokerapi.Failed))
+					})
+				})
+			})
+
+			Context("when the persistent volume cannot be retrieved", func() {
+				BeforeEach(func() {
+					fakeK8sPersistentVolumes.GetReturns(nil, errors.New("some-error"))
+				})
+
+				It("errors", func() {
+					Expect(err).To(Equal(errors.New("some-error")))
+				})
+			})
+
+			Context("when the instance is dynamically provisioned", func() {
+				BeforeEach(func() {
+					operationData = k8sbroker.OperationDeprovision
+					fingerprint = k8sbroker.ServiceFingerPrint{
+						Name:                "some-instance-id",
+						DynamicProvisioning: true,
+						StorageClassName:    "nfs-dynamic",
+					}
+				})
+
+				It("succeeds without looking up a volume", func() {
+					Expect(err).NotTo(HaveOccurred())
+					Expect(op.State).To(Equal(brokerapi.Succeeded))
+					Expect(fakeK8sPersistentVolumes.GetCallCount()).To(Equal(0))
+				})
+			})
+		})
+
+		Context(".Bind", func() {
+			var (
+				serviceID     string
+				bindDetails   brokerapi.BindDetails
+				rawParameters json.RawMessage
+				params        map[string]interface{}
+				err           error
+				binding       brokerapi.Binding
+			)
+
+			BeforeEach(func() {
+				serviceID = "ServiceOne.ID"
+				params = make(map[string]interface{})
+				params["key"] = "value"
+				rawParameters, err = json.Marshal(params)
+
+				bindDetails = brokerapi.BindDetails{
+					AppGUID:       "guid",
+					ServiceID:     serviceID,
+					RawParameters: rawParameters,
+				}
+			})
+
+			JustBeforeEach(func() {
+				binding, err = broker.Bind(ctx, "some-instance-id", "binding-id", bindDetails)
+			})
+
+			Context("when service instance does not exist", func() {
+				BeforeEach(func() {
+					fakeStore.RetrieveInstanceDetailsReturns(brokerstore.ServiceInstance{}, errors.New("Awesome!"))
+				})
+
+				It("errors", func() {
+					Expect(err).To(Equal(brokerapi.ErrInstanceDoesNotExist))
+				})
+			})
+
+			Context("when service instance contains invalid service fingerprint", func() {
+				BeforeEach(func() {
+					fakeStore.RetrieveInstanceDetailsReturns(brokerstore.ServiceInstance{
+						ServiceID:          serviceID,
+						ServiceFingerPrint: "invalid-json",
+					}, nil)
+				})
+
+				It("errors", func() {
+					Expect(err).To(HaveOccurred())
+				})
+			})
+
+			Context("when the instance is dynamically provisioned", func() {
+				var fingerprint *k8sbroker.ServiceFingerPrint
+
+				BeforeEach(func() {
+					fingerprint = &k8sbroker.ServiceFingerPrint{
+						Name:                "some-instance-id",
+						Labels:              map[string]string{"team": "storage"},
+						CapacityRange:       &k8sbroker.CapacityRange{RequiredBytes: 1073741824},
+						DynamicProvisioning: true,
+						StorageClassName:    "nfs-dynamic",
+					}
+					fakeStore.RetrieveInstanceDetailsReturns(brokerstore.ServiceInstance{
+						ServiceID:          serviceID,
+						ServiceFingerPrint: fingerprint,
+					}, nil)
+					fakeK8sPersistentVolumeClaims.GetReturns(nil, k8serrors.NewNotFound(schema.GroupResource{Resource: "persistentvolumeclaims"}, "some-instance-id"))
+					fakeK8sPersistentVolumeClaims.CreateStub = func(claim *v1.PersistentVolumeClaim) (*v1.PersistentVolumeClaim, error) {
+						return claim, nil
+					}
+				})
+
+				It("claims a volume from the storage class without selecting one", func() {
+					Expect(err).NotTo(HaveOccurred())
+					Expect(fakeK8sPersistentVolumeClaims.CreateCallCount()).To(Equal(1))
+					claim := fakeK8sPersistentVolumeClaims.CreateArgsForCall(0)
+					Expect(claim.Name).To(Equal("some-instance-id"))
+					Expect(claim.Namespace).To(Equal("some-namespace"))
+					Expect(*claim.Spec.StorageClassName).To(Equal("nfs-dynamic"))
+					Expect(claim.Spec.Selector).To(BeNil())
+					Expect(claim.Spec.Resources.Requests[v1.ResourceStorage]).To(Equal(*resource.NewQuantity(1073741824, resource.BinarySI)))
+					Expect(claim.Labels).To(Equal(map[string]string{"team": "storage", "managed-by": "k8sbroker", "instance-id": "some-instance-id"}))
+				})
+
+				It("labels the claim with the instance but not the binding, as the claim is shared", func() {
+					claim := fakeK8sPersistentVolumeClaims.CreateArgsForCall(0)
+					Expect(claim.Labels).To(HaveKeyWithValue("instance-id", "some-instance-id"))
+					Expect(claim.Labels).NotTo(HaveKey("binding-id"))
+				})
+
+				It("lists the instance through its claim", func() {
+					claim := fakeK8sPersistentVolumeClaims.CreateArgsForCall(0)
+					fakeK8sPersistentVolumes.ListReturns(&v1.PersistentVolumeList{}, nil)
+					fakeK8sPersistentVolumeClaims.ListReturns(&v1.PersistentVolumeClaimList{Items: []v1.PersistentVolumeClaim{*claim}}, nil)
+
+					instances, err := broker.ListInstances()
+					Expect(err).NotTo(HaveOccurred())
+					Expect(instances).To(HaveKey("some-instance-id"))
+					Expect(fakeK8sPersistentVolumeClaims.ListArgsForCall(0).LabelSelector).To(Equal("managed-by=k8sbroker"))
+				})
+
+				Context("when extra persistent volume claim labels are set", func() {
+					BeforeEach(func() {
+						config.ExtraPVCLabels = map[string]string{"team": "platform", "env": "prod"}
+					})
+
+					It("adds them under the instance's labels", func() {
+						claim := fakeK8sPersistentVolumeClaims.CreateArgsForCall(0)
+						Expect(claim.Labels).To(Equal(map[string]string{"team": "storage", "env": "prod", "managed-by": "k8sbroker", "instance-id": "some-instance-id"}))
+					})
+				})
+
+				It("mounts the claim", func() {
+					Expect(binding.VolumeMounts[0].Device.MountConfig).To(HaveKeyWithValue("name", "some-instance-id"))
+				})
+
+				It("records the claim's namespace", func() {
+					_, details := fakeStore.CreateInstanceDetailsArgsForCall(0)
+					stored := details.ServiceFingerPrint.(k8sbroker.ServiceFingerPrint)
+					Expect(stored.ClaimNamespaces).To(Equal([]string{"some-namespace"}))
+					Expect(stored.Bindings["binding-id"].ClaimName).To(Equal("some-instance-id"))
+				})
+
+				Context("when the instance was provisioned in block mode", func() {
+					BeforeEach(func() {
+						fingerprint.VolumeMode = v1.PersistentVolumeBlock
+					})
+
+					It("claims a block volume and binds it as a block device", func() {
+						claim := fakeK8sPersistentVolumeClaims.CreateArgsForCall(0)
+						Expect(*claim.Spec.VolumeMode).To(Equal(v1.PersistentVolumeBlock))
+						Expect(binding.VolumeMounts[0].DeviceType).To(Equal("block"))
+					})
+				})
+
+				Context("when claims are named after bindings", func() {
+					BeforeEach(func() {
+						config.PVCNaming = k8sbroker.PVCNamingBindingID
+					})
+
+					It("still names the claim after the instance", func() {
+						claim := fakeK8sPersistentVolumeClaims.CreateArgsForCall(0)
+						Expect(claim.Name).To(Equal("some-instance-id"))
+					})
+				})
+
+				Context("when the instance was provisioned with a resource name prefix", func() {
+					BeforeEach(func() {
+						fingerprint.NamePrefix = "team-a-"
+					})
+
+					It("names the claim after the prefixed instance", func() {
+						claim := fakeK8sPersistentVolumeClaims.CreateArgsForCall(0)
+						Expect(claim.Name).To(Equal("team-a-some-instance-id"))
+						Expect(binding.VolumeMounts[0].Device.MountConfig).To(HaveKeyWithValue("name", "team-a-some-instance-id"))
+					})
+				})
+
+				Context("when an earlier binding already claimed a volume in the namespace", func() {
+					BeforeEach(func() {
+						fingerprint.ClaimNamespaces = []string{"some-namespace"}
+						storageClassName := "nfs-dynamic"
+						fakeK8sPersistentVolumeClaims.GetReturns(&v1.PersistentVolumeClaim{
+							ObjectMeta: metav1.ObjectMeta{Name: "some-instance-id", Namespace: "some-namespace"},
+							Spec: v1.PersistentVolumeClaimSpec{
+								AccessModes:      []v1.PersistentVolumeAccessMode{v1.ReadWriteMany},
+								Resources:        v1.ResourceRequirements{Requests: v1.ResourceList{v1.ResourceStorage: *resource.NewQuantity(1073741824, resource.BinarySI)}},
+								StorageClassName: &storageClassName,
+								VolumeName:       "pvc-1234",
+							},
+						}, nil)
+					})
+
+					It("shares the claim", func() {
+						Expect(err).NotTo(HaveOccurred())
+						Expect(fakeK8sPersistentVolumeClaims.CreateCallCount()).To(Equal(0))
+						_, details := fakeStore.CreateInstanceDetailsArgsForCall(0)
+						stored := details.ServiceFingerPrint.(k8sbroker.ServiceFingerPrint)
+						Expect(stored.ClaimNamespaces).To(Equal([]string{"some-namespace"}))
+					})
+
+					Context("when the bind fails", func() {
+						BeforeEach(func() {
+							fakeStore.CreateBindingDetailsReturns(errors.New("store-failed"))
+						})
+
+						It("keeps the shared claim", func() {
+							Expect(err).To(MatchError("store-failed"))
+							Expect(fakeK8sPersistentVolumeClaims.DeleteCallCount()).To(Equal(0))
+						})
+					})
+				})
+			})
+
+			Context("when service instance exists", func() {
+				var quantity resource.Quantity
+
+				BeforeEach(func() {
+					quantity, err = resource.ParseQuantity("2")
+					Expect(err).NotTo(HaveOccurred())
+					fingerprint := k8sbroker.ServiceFingerPrint{
+						Name: "some-instance-id",
+						Volume: &v1.PersistentVolume{
+							TypeMeta: metav1.TypeMeta{
+								Kind:       "PersistentVolume",
+								APIVersion: "v1",
+							},
+							ObjectMeta: metav1.ObjectMeta{
+								Name:   "some-instance-id",
+								Labels: map[string]string{"name": "some-instance-id"},
+							},
+							Spec: v1.PersistentVolumeSpec{
+								AccessModes: []v1.PersistentVolumeAccessMode{v1.ReadWriteMany},
+								Capacity:    v1.ResourceList{v1.ResourceStorage: quantity},
+								PersistentVolumeSource: v1.PersistentVolumeSource{
+									CSI: &v1.CSIPersistentVolumeSource{
+										VolumeHandle:     "data-id",
+										VolumeAttributes: map[string]string{"share": "/export/some-share", "name": "volume-name"},
+									},
+								},
+							},
+						},
+					}
+
+					// simulate untyped data loaded from a data file
+					jsonFingerprint := &map[string]interface{}{}
+					raw, err := json.Marshal(fingerprint)
+					Expect(err).ToNot(HaveOccurred())
+					err = json.Unmarshal(raw, jsonFingerprint)
+					Expect(err).ToNot(HaveOccurred())
+					fakeStore.RetrieveInstanceDetailsReturns(brokerstore.ServiceInstance{
+						ServiceID:          serviceID,
+						PlanID:             "some-plan-id",
+						ServiceFingerPrint: jsonFingerprint,
+					}, nil)
+					fakeServices.DriverNameReturns("csi")
+
+					fakeK8sPersistentVolumeClaims.GetReturns(nil, k8serrors.NewNotFound(schema.GroupResource{Resource: "persistentvolumeclaims"}, "some-instance-id"))
+					fakeK8sPersistentVolumeClaims.CreateReturns(&v1.PersistentVolumeClaim{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "k8s-volume-claim",
+						},
+					}, nil)
+				})
+
+				It("should not error", func() {
+					Expect(err).NotTo(HaveOccurred())
+				})
+
+				It("looks for an existing claim before creating one", func() {
+					Expect(fakeK8sPersistentVolumeClaims.GetCallCount()).To(Equal(1))
+					name, _ := fakeK8sPersistentVolumeClaims.GetArgsForCall(0)
+					Expect(name).To(Equal("some-instance-id"))
+					Expect(fakeK8sPersistentVolumeClaims.CreateCallCount()).To(Equal(1))
+				})
+
+				Context("when a matching claim already exists", func() {
+					BeforeEach(func() {
+						fakeK8sPersistentVolumeClaims.GetReturns(&v1.PersistentVolumeClaim{
+							ObjectMeta: metav1.ObjectMeta{Name: "some-instance-id"},
+							Spec: v1.PersistentVolumeClaimSpec{
+								AccessModes: []v1.PersistentVolumeAccessMode{v1.ReadWriteMany},
+								Resources:   v1.ResourceRequirements{Requests: v1.ResourceList{v1.ResourceStorage: resource.MustParse("2")}},
+								Selector: &metav1.LabelSelector{
+									MatchExpressions: []metav1.LabelSelectorRequirement{
+										{Key: "name", Operator: metav1.LabelSelectorOpIn, Values: []string{"some-instance-id"}},
+									},
+								},
+							},
+						}, nil)
+					})
+
+					It("reuses the claim", func() {
+						Expect(err).NotTo(HaveOccurred())
+						Expect(fakeK8sPersistentVolumeClaims.CreateCallCount()).To(Equal(0))
+						Expect(binding.VolumeMounts[0].Device.MountConfig["name"]).To(Equal("some-instance-id"))
+					})
+				})
+
+				Context("when a claim with a different spec already exists", func() {
+					BeforeEach(func() {
+						fakeK8sPersistentVolumeClaims.GetReturns(&v1.PersistentVolumeClaim{
+							ObjectMeta: metav1.ObjectMeta{Name: "some-instance-id"},
+							Spec: v1.PersistentVolumeClaimSpec{
+								AccessModes: []v1.PersistentVolumeAccessMode{v1.ReadOnlyMany},
+							},
+						}, nil)
+					})
+
+					It("errors with a conflict", func() {
+						Expect(err).To(Equal(k8sbroker.ErrClaimConflict{Namespace: "some-namespace", Name: "some-instance-id"}))
+						Expect(errors.Is(err, brokerapi.ErrBindingAlreadyExists)).To(BeTrue())
+						Expect(fakeK8sPersistentVolumeClaims.CreateCallCount()).To(Equal(0))
+					})
+
+					It("does not delete the existing claim", func() {
+						Expect(fakeK8sPersistentVolumeClaims.DeleteCallCount()).To(Equal(0))
+					})
+				})
+
+				Context("when another instance's claim has the same name", func() {
+					BeforeEach(func() {
+						fakeK8sPersistentVolumeClaims.GetReturns(&v1.PersistentVolumeClaim{
+							ObjectMeta: metav1.ObjectMeta{
+								Name:   "some-instance-id",
+								Labels: map[string]string{"managed-by": "k8sbroker", "instance-id": "other-instance-id"},
+							},
+							Spec: v1.PersistentVolumeClaimSpec{
+								AccessModes: []v1.PersistentVolumeAccessMode{v1.ReadWriteMany},
+								Resources:   v1.ResourceRequirements{Requests: v1.ResourceList{v1.ResourceStorage: quantity}},
+								Selector: &metav1.LabelSelector{
+									MatchExpressions: []metav1.LabelSelectorRequirement{
+										{Key: "name", Operator: metav1.LabelSelectorOpIn, Values: []string{"some-instance-id"}},
+									},
+								},
+							},
+						}, nil)
+					})
+
+					It("errors with a conflict naming the other instance", func() {
+						Expect(err).To(Equal(k8sbroker.ErrClaimOwnedByOtherInstance{Namespace: "some-namespace", Name: "some-instance-id", InstanceID: "other-instance-id"}))
+						Expect(errors.Is(err, brokerapi.ErrBindingAlreadyExists)).To(BeTrue())
+						Expect(err.Error()).To(ContainSubstring("give the volumes unique names with --resourceNamePrefix"))
+					})
+
+					It("neither creates nor deletes a claim", func() {
+						Expect(fakeK8sPersistentVolumeClaims.CreateCallCount()).To(Equal(0))
+						Expect(fakeK8sPersistentVolumeClaims.DeleteCallCount()).To(Equal(0))
+					})
+				})
+
+				Context("when the instance's own labelled claim already exists", func() {
+					BeforeEach(func() {
+						fakeK8sPersistentVolumeClaims.GetReturns(&v1.PersistentVolumeClaim{
+							ObjectMeta: metav1.ObjectMeta{
+								Name:   "some-instance-id",
+								Labels: map[string]string{"managed-by": "k8sbroker", "instance-id": "some-instance-id"},
+							},
+							Spec: v1.PersistentVolumeClaimSpec{
+								AccessModes: []v1.PersistentVolumeAccessMode{v1.ReadWriteMany},
+								Resources:   v1.ResourceRequirements{Requests: v1.ResourceList{v1.ResourceStorage: quantity}},
+								Selector: &metav1.LabelSelector{
+									MatchExpressions: []metav1.LabelSelectorRequirement{
+										{Key: "name", Operator: metav1.LabelSelectorOpIn, Values: []string{"some-instance-id"}},
+									},
+								},
+							},
+						}, nil)
+					})
+
+					It("reuses it", func() {
+						Expect(err).NotTo(HaveOccurred())
+						Expect(fakeK8sPersistentVolumeClaims.CreateCallCount()).To(Equal(0))
+					})
+				})
+
+				Context("when a claim of the same name is labelled with another binding", func() {
+					BeforeEach(func() {
+						fakeK8sPersistentVolumeClaims.GetReturns(&v1.PersistentVolumeClaim{
+							ObjectMeta: metav1.ObjectMeta{
+								Name:   "some-instance-id",
+								Labels: map[string]string{"managed-by": "k8sbroker", "instance-id": "some-instance-id", "binding-id": "other-binding-id"},
+							},
+							Spec: v1.PersistentVolumeClaimSpec{
+								AccessModes: []v1.PersistentVolumeAccessMode{v1.ReadWriteMany},
+								Resources:   v1.ResourceRequirements{Requests: v1.ResourceList{v1.ResourceStorage: quantity}},
+								Selector: &metav1.LabelSelector{
+									MatchExpressions: []metav1.LabelSelectorRequirement{
+										{Key: "name", Operator: metav1.LabelSelectorOpIn, Values: []string{"some-instance-id"}},
+									},
+								},
+							},
+						}, nil)
+					})
+
+					It("errors with a conflict when the instance has no such binding", func() {
+						Expect(err).To(Equal(k8sbroker.ErrClaimConflict{Namespace: "some-namespace", Name: "some-instance-id"}))
+						Expect(fakeK8sPersistentVolumeClaims.CreateCallCount()).To(Equal(0))
+						Expect(fakeK8sPersistentVolumeClaims.DeleteCallCount()).To(Equal(0))
+					})
+
+					Context("when the instance records that binding as using the claim", func() {
+						BeforeEach(func() {
+							instanceDetails, _ := fakeStore.RetrieveInstanceDetails("some-instance-id")
+							fingerprint := *instanceDetails.ServiceFingerPrint.(*map[string]interface{})
+							fingerprint["Bindings"] = map[string]interface{}{
+								"other-binding-id": map[string]interface{}{"ClaimName": "some-instance-id", "Namespace": "some-namespace"},
+							}
+						})
+
+						It("shares the claim", func() {
+							Expect(err).NotTo(HaveOccurred())
+							Expect(fakeK8sPersistentVolumeClaims.CreateCallCount()).To(Equal(0))
+							_, details := fakeStore.CreateInstanceDetailsArgsForCall(0)
+							stored := details.ServiceFingerPrint.(k8sbroker.ServiceFingerPrint)
+							Expect(stored.Bindings).To(HaveKey("other-binding-id"))
+							Expect(stored.Bindings["binding-id"].ClaimName).To(Equal("some-instance-id"))
+						})
+
+						Context("when the bind fails", func() {
+							BeforeEach(func() {
+								fakeStore.CreateBindingDetailsReturns(errors.New("store-failed"))
+							})
+
+							It("keeps the claim the other binding uses", func() {
+								Expect(err).To(MatchError("store-failed"))
+								Expect(fakeK8sPersistentVolumeClaims.DeleteCallCount()).To(Equal(0))
+							})
+						})
+					})
+				})
+
+				Context("when looking up the claim fails", func() {
+					BeforeEach(func() {
+						fakeK8sPersistentVolumeClaims.GetReturns(nil, errors.New("get-failed"))
+					})
+
+					It("errors without creating a claim", func() {
+						Expect(err).To(MatchError("get-failed"))
+						Expect(fakeK8sPersistentVolumeClaims.CreateCallCount()).To(Equal(0))
+					})
+				})
+
+				Context("when mode is not a boolean", func() {
+					BeforeEach(func() {
+						params["readonly"] = ""
+						bindDetails.RawParameters, err = json.Marshal(params)
+						Expect(err).NotTo(HaveOccurred())
+					})
+
+					It("errors", func() {
+						Expect(err).To(Equal(brokerapi.ErrRawParamsInvalid))
+					})
+				})
+
+				Context("when a parameter is not allowed", func() {
+					BeforeEach(func() {
+						params["uid"] = "1000"
+						bindDetails.RawParameters, err = json.Marshal(params)
+						Expect(err).NotTo(HaveOccurred())
+					})
+
+					It("errors", func() {
+						Expect(err).To(Equal(k8sbroker.ErrParameterNotAllowed{Key: "uid"}))
+					})
+
+					It("does not create a persistent volume claim", func() {
+						Expect(fakeK8sPersistentVolumeClaims.CreateCallCount()).To(Equal(0))
+					})
+				})
+
+				It("creates the claim in the broker's namespace", func() {
+					Expect(fakeK8sCoreV1.PersistentVolumeClaimsCallCount()).To(Equal(1))
+					Expect(fakeK8sCoreV1.PersistentVolumeClaimsArgsForCall(0)).To(Equal("some-namespace"))
+				})
+
+				It("records the claim created for the binding", func() {
+					Expect(fakeStore.CreateInstanceDetailsCallCount()).To(Equal(1))
+					id, details := fakeStore.CreateInstanceDetailsArgsForCall(0)
+					Expect(id).To(Equal("some-instance-id"))
+					fingerprint := details.ServiceFingerPrint.(k8sbroker.ServiceFingerPrint)
+					Expect(fingerprint.Bindings).To(Equal(map[string]k8sbroker.BindingFingerPrint{
+						"binding-id": {
+							ClaimName:  "k8s-volume-claim",
+							Namespace:  "some-namespace",
+							AccessMode: "ReadWriteMany",
+						},
+					}))
+				})
+
+				Context("when storing the binding fingerprint fails", func() {
+					BeforeEach(func() {
+						fakeStore.CreateInstanceDetailsReturns(errors.New("badness"))
+					})
+
+					It("errors and deletes the claim", func() {
+						Expect(err).To(MatchError("badness"))
+						Expect(fakeK8sPersistentVolumeClaims.DeleteCallCount()).To(Equal(1))
+					})
+
+					Context("when the request was cancelled after the claim was created", func() {
+						BeforeEach(func() {
+							var cancel context.CancelFunc
+							ctx, cancel = context.WithCancel(ctx)
+							fakeK8sPersistentVolumeClaims.CreateStub = func(claim *v1.PersistentVolumeClaim) (*v1.PersistentVolumeClaim, error) {
+								cancel()
+								return claim, nil
+							}
+						})
+
+						It("still deletes the claim", func() {
+							Expect(fakeK8sPersistentVolumeClaims.DeleteCallCount()).To(Equal(1))
+						})
+					})
+				})
+
+				Context("when claims are named after the volume and the binding", func() {
+					BeforeEach(func() {
+						config.PVCNaming = k8sbroker.PVCNamingVolumeBindingID
+						fakeK8sPersistentVolumeClaims.CreateStub = func(claim *v1.PersistentVolumeClaim) (*v1.PersistentVolumeClaim, error) {
+							return claim, nil
+						}
+					})
+
+					It("creates a claim for each binding", func() {
+						claim := fakeK8sPersistentVolumeClaims.CreateArgsForCall(0)
+						Expect(claim.Name).To(Equal("some-instance-id-binding"))
+						_, details := fakeStore.CreateInstanceDetailsArgsForCall(0)
+						fingerprint := details.ServiceFingerPrint.(k8sbroker.ServiceFingerPrint)
+						Expect(fingerprint.Bindings["binding-id"].ClaimName).To(Equal("some-instance-id-binding"))
+					})
+				})
+
+				Context("when claims are named after the binding", func() {
+					BeforeEach(func() {
+						config.PVCNaming = k8sbroker.PVCNamingBindingID
+						fakeK8sPersistentVolumeClaims.CreateStub = func(claim *v1.PersistentVolumeClaim) (*v1.PersistentVolumeClaim, error) {
+							return claim, nil
+						}
+					})
+
+					It("creates the claim with the binding ID", func() {
+						Expect(fakeK8sPersistentVolumeClaims.GetCallCount()).To(Equal(1))
+						name, _ := fakeK8sPersistentVolumeClaims.GetArgsForCall(0)
+						Expect(name).To(Equal("binding-id"))
+						claim := fakeK8sPersistentVolumeClaims.CreateArgsForCall(0)
+						Expect(claim.Name).To(Equal("binding-id"))
+						Expect(claim.Spec.Selector.MatchExpressions[0].Values).To(Equal([]string{"some-instance-id"}))
+					})
+
+					It("records the claim name for unbind", func() {
+						_, details := fakeStore.CreateInstanceDetailsArgsForCall(0)
+						fingerprint := details.ServiceFingerPrint.(k8sbroker.ServiceFingerPrint)
+						Expect(fingerprint.Bindings["binding-id"].ClaimName).To(Equal("binding-id"))
+					})
+
+					Context("when the instance was provisioned with a resource name prefix", func() {
+						BeforeEach(func() {
+							instance, err := fakeStore.RetrieveInstanceDetails("some-instance-id")
+							Expect(err).NotTo(HaveOccurred())
+							(*instance.ServiceFingerPrint.(*map[string]interface{}))["NamePrefix"] = "team-a-"
+						})
+
+						It("prefixes the claim name", func() {
+							claim := fakeK8sPersistentVolumeClaims.CreateArgsForCall(0)
+							Expect(claim.Name).To(Equal("team-a-binding-id"))
+							_, details := fakeStore.CreateInstanceDetailsArgsForCall(0)
+							fingerprint := details.ServiceFingerPrint.(k8sbroker.ServiceFingerPrint)
+							Expect(fingerprint.Bindings["binding-id"].ClaimName).To(Equal("team-a-binding-id"))
+						})
+					})
+
+					Context("when storing the binding fingerprint fails", func() {
+						BeforeEach(func() {
+							fakeStore.CreateInstanceDetailsReturns(errors.New("badness"))
+						})
+
+						It("deletes the claim it created", func() {
+							Expect(fakeK8sPersistentVolumeClaims.DeleteCallCount()).To(Equal(1))
+							name, _ := fakeK8sPersistentVolumeClaims.DeleteArgsForCall(0)
+							Expect(name).To(Equal("binding-id"))
+						})
+					})
+				})
+
+				Context("when a namespace is given", func() {
+					BeforeEach(func() {
+						params["namespace"] = "some-org-namespace"
+						bindDetails.RawParameters, err = json.Marshal(params)
+						Expect(err).NotTo(HaveOccurred())
+					})
+
+					It("creates the claim in that namespace", func() {
+						Expect(fakeK8sNamespaces.GetCallCount()).To(Equal(1))
+						name, _ := fakeK8sNamespaces.GetArgsForCall(0)
+						Expect(name).To(Equal("some-org-namespace"))
+						Expect(fakeK8sNamespaces.CreateCallCount()).To(Equal(0))
+						Expect(fakeK8sCoreV1.PersistentVolumeClaimsArgsForCall(0)).To(Equal("some-org-namespace"))
+					})
+
+					Context("when the namespace does not exist", func() {
+						BeforeEach(func() {
+							fakeK8sNamespaces.GetReturns(nil, k8serrors.NewNotFound(schema.GroupResource{Resource: "namespaces"}, "some-org-namespace"))
+						})
+
+						It("creates it", func() {
+							Expect(err).NotTo(HaveOccurred())
+							Expect(fakeK8sNamespaces.CreateCallCount()).To(Equal(1))
+							namespace := fakeK8sNamespaces.CreateArgsForCall(0)
+							Expect(namespace.Name).To(Equal("some-org-namespace"))
+							Expect(namespace.Labels).To(HaveKeyWithValue("managed-by", "k8sbroker"))
+						})
+
+						Context("when creating it fails", func() {
+							BeforeEach(func() {
+								fakeK8sNamespaces.CreateReturns(nil, errors.New("badness"))
+							})
+
+							It("errors without creating the claim", func() {
+								Expect(err).To(MatchError("badness"))
+								Expect(fakeK8sPersistentVolumeClaims.CreateCallCount()).To(Equal(0))
+							})
+						})
+					})
+
+					Context("when namespaces are restricted", func() {
+						BeforeEach(func() {
+							config.AllowedNamespaces = []string{"other-namespace", "some-org-namespace"}
+						})
+
+						It("allows a listed namespace", func() {
+							Expect(err).NotTo(HaveOccurred())
+							Expect(fakeK8sCoreV1.PersistentVolumeClaimsArgsForCall(0)).To(Equal("some-org-namespace"))
+						})
+
+						Context("and the namespace is not listed", func() {
+							BeforeEach(func() {
+								config.AllowedNamespaces = []string{"other-namespace"}
+							})
+
+							It("forbids the bind before creating anything", func() {
+								Expect(err).To(MatchError(`namespace "some-org-namespace" is not allowed`))
+								failure, ok := err.(*brokerapi.FailureResponse)
+								Expect(ok).To(BeTrue())
+								Expect(failure.ValidatedStatusCode(logger)).To(Equal(http.StatusForbidden))
+								Expect(fakeK8sNamespaces.GetCallCount()).To(Equal(0))
+								Expect(fakeK8sPersistentVolumeClaims.CreateCallCount()).To(Equal(0))
+							})
+						})
+					})
+
+					Context("when the namespace is not a string", func() {
+						BeforeEach(func() {
+							params["namespace"] = 42
+							bindDetails.RawParameters, err = json.Marshal(params)
+							Expect(err).NotTo(HaveOccurred())
+						})
+
+						It("errors", func() {
+							Expect(err).To(Equal(brokerapi.ErrRawParamsInvalid))
+						})
+					})
+				})
+
+				Context("when labels and annotations are given", func() {
+					BeforeEach(func() {
+						params["labels"] = map[string]string{"app": "pora"}
+						params["annotations"] = map[string]string{"example.com/owner": "someone"}
+						bindDetails.RawParameters, err = json.Marshal(params)
+						Expect(err).NotTo(HaveOccurred())
+					})
+
+					It("sets them on the persistent volume claim", func() {
+						claim := fakeK8sPersistentVolumeClaims.CreateArgsForCall(0)
+						Expect(claim.Labels).To(Equal(map[string]string{
+							"app":         "pora",
+							"managed-by":  "k8sbroker",
+							"instance-id": "some-instance-id",
+							"binding-id":  "binding-id",
+						}))
+						Expect(claim.Annotations).To(Equal(map[string]string{"example.com/owner": "someone"}))
+					})
+				})
+
+				Context("when extra persistent volume claim labels are set", func() {
+					BeforeEach(func() {
+						config.ExtraPVCLabels = map[string]string{"app": "default", "env": "prod", "binding-id": "other-binding-id"}
+						params["labels"] = map[string]string{"app": "pora"}
+						bindDetails.RawParameters, err = json.Marshal(params)
+						Expect(err).NotTo(HaveOccurred())
+					})
+
+					It("adds them under the bind's labels and the broker's", func() {
+						claim := fakeK8sPersistentVolumeClaims.CreateArgsForCall(0)
+						Expect(claim.Labels).To(Equal(map[string]string{
+							"app":         "pora",
+							"env":         "prod",
+							"managed-by":  "k8sbroker",
+							"instance-id": "some-instance-id",
+							"binding-id":  "binding-id",
+						}))
+					})
+				})
+
+				It("labels the persistent volume claim with its binding", func() {
+					claim := fakeK8sPersistentVolumeClaims.CreateArgsForCall(0)
+					Expect(claim.Labels).To(HaveKeyWithValue("managed-by", "k8sbroker"))
+					Expect(claim.Labels).To(HaveKeyWithValue("instance-id", "some-instance-id"))
+					Expect(claim.Labels).To(HaveKeyWithValue("binding-id", "binding-id"))
+				})
+
+				Context("when labels are not a map of strings", func() {
+					BeforeEach(func() {
+						params["labels"] = map[string]int{"app": 1}
+						bindDetails.RawParameters, err = json.Marshal(params)
+						Expect(err).NotTo(HaveOccurred())
+					})
+
+					It("errors", func() {
+						Expect(err).To(Equal(brokerapi.ErrRawParamsInvalid))
+					})
+				})
+
+				Context("when a service account is given", func() {
+					BeforeEach(func() {
+						params["service_account"] = "app-sa"
+						bindDetails.RawParameters, err = json.Marshal(params)
+						Expect(err).NotTo(HaveOccurred())
+					})
+
+					It("annotates the persistent volume claim", func() {
+						claim := fakeK8sPersistentVolumeClaims.CreateArgsForCall(0)
+						Expect(claim.Annotations).To(HaveKeyWithValue(k8sbroker.ServiceAccountAnnotation, "app-sa"))
+					})
+
+					It("creates a role that can read the claim", func() {
+						Expect(fakeK8sRoles.CreateCallCount()).To(Equal(1))
+						role := fakeK8sRoles.CreateArgsForCall(0)
+						Expect(role.Name).To(Equal("k8sbroker-binding-id"))
+						Expect(role.Rules).To(HaveLen(1))
+						Expect(role.Rules[0].Resources).To(Equal([]string{"persistentvolumeclaims"}))
+						Expect(role.Rules[0].ResourceNames).To(Equal([]string{"k8s-volume-claim"}))
+						Expect(role.Rules[0].Verbs).To(ConsistOf("get", "list"))
+					})
+
+					It("binds the role to the service account", func() {
+						Expect(fakeK8sRoleBindings.CreateCallCount()).To(Equal(1))
+						roleBinding := fakeK8sRoleBindings.CreateArgsForCall(0)
+						Expect(roleBinding.Name).To(Equal("k8sbroker-binding-id"))
+						Expect(roleBinding.Subjects).To(HaveLen(1))
+						Expect(roleBinding.Subjects[0].Kind).To(Equal("ServiceAccount"))
+						Expect(roleBinding.Subjects[0].Name).To(Equal("app-sa"))
+						Expect(roleBinding.Subjects[0].Namespace).To(Equal("some-namespace"))
+						Expect(roleBinding.RoleRef.Kind).To(Equal("Role"))
+						Expect(roleBinding.RoleRef.Name).To(Equal("k8sbroker-binding-id"))
+					})
+
+					It("records the service account on the binding", func() {
+						_, details := fakeStore.CreateInstanceDetailsArgsForCall(0)
+						fingerprint := details.ServiceFingerPrint.(k8sbroker.ServiceFingerPrint)
+						Expect(fingerprint.Bindings["binding-id"].ServiceAccount).To(Equal("app-sa"))
+					})
+
+					Context("when the service account is not a valid name", func() {
+						BeforeEach(func() {
+							params["service_account"] = "App_SA"
+							bindDetails.RawParameters, err = json.Marshal(params)
+							Expect(err).NotTo(HaveOccurred())
+						})
+
+						It("errors", func() {
+							Expect(err).To(Equal(brokerapi.ErrRawParamsInvalid))
+							Expect(fakeK8sPersistentVolumeClaims.CreateCallCount()).To(Equal(0))
+						})
+					})
+
+					Context("when the role binding cannot be created", func() {
+						BeforeEach(func() {
+							fakeK8sRoleBindings.CreateReturns(nil, errors.New("forbidden"))
+						})
+
+						It("errors and removes the role and the claim", func() {
+							Expect(err).To(MatchError("forbidden"))
+							Expect(fakeK8sRoles.DeleteCallCount()).To(Equal(1))
+							name, _ := fakeK8sRoles.DeleteArgsForCall(0)
+							Expect(name).To(Equal("k8sbroker-binding-id"))
+							Expect(fakeK8sPersistentVolumeClaims.DeleteCallCount()).To(Equal(1))
+						})
+					})
+
+					Context("when saving the binding fails", func() {
+						BeforeEach(func() {
+							fakeStore.CreateBindingDetailsReturns(errors.New("badness"))
+						})
+
+						It("revokes the access it granted", func() {
+							Expect(err).To(HaveOccurred())
+							Expect(fakeK8sRoleBindings.DeleteCallCount()).To(Equal(1))
+							Expect(fakeK8sRoles.DeleteCallCount()).To(Equal(1))
+						})
+					})
+				})
+
+				Context("when no service account is given", func() {
+					It("does not create a role", func() {
+						Expect(fakeK8sRoles.CreateCallCount()).To(Equal(0))
+						Expect(fakeK8sRoleBindings.CreateCallCount()).To(Equal(0))
+					})
+				})
+
+				Context("when a claim quota is set", func() {
+					BeforeEach(func() {
+						config.Quotas.MaxPVCPerInstance = 1
+					})
+
+					It("binds the first claim", func() {
+						Expect(err).NotTo(HaveOccurred())
+					})
+
+					Context("when the instance already has that many bindings", func() {
+						BeforeEach(func() {
+							fakeStore.RetrieveInstanceDetailsReturns(brokerstore.ServiceInstance{
+								ServiceID: serviceID,
+								ServiceFingerPrint: k8sbroker.ServiceFingerPrint{
+									Name: "some-instance-id",
+									Volume: &v1.PersistentVolume{
+										ObjectMeta: metav1.ObjectMeta{Name: "some-instance-id"},
+									},
+									Bindings: map[string]k8sbroker.BindingFingerPrint{
+										"other-binding-id": {ClaimName: "some-instance-id", Namespace: "some-namespace"},
+									},
+								},
+							}, nil)
+						})
+
+						It("errors without creating a claim", func() {
+							Expect(err).To(Equal(brokerapi.ErrPlanQuotaExceeded))
+							Expect(fakeK8sPersistentVolumeClaims.CreateCallCount()).To(Equal(0))
+						})
+					})
+				})
+
+				Context("when an identical binding already exists", func() {
+					BeforeEach(func() {
+						fakeStore.IsBindingConflictReturns(false)
+					})
+
+					It("doesn't error when binding the same details", func() {
+						Expect(err).NotTo(HaveOccurred())
+					})
+				})
+
+				Context("when the binding already exists with different details", func() {
+					BeforeEach(func() {
+						fakeStore.IsBindingConflictReturns(true)
+					})
+
+					It("errors", func() {
+						Expect(err).To(Equal(brokerapi.ErrBindingAlreadyExists))
+					})
+				})
+
+				Context("when it fails to create persistent volume claim", func() {
+					var createErr error
+
+					BeforeEach(func() {
+						createErr = errors.New("failed-to-create")
+						fakeK8sPersistentVolumeClaims.CreateReturns(nil, createErr)
+					})
+
+					It("returns an error", func() {
+						Expect(err).To(Equal(createErr))
+					})
+				})
+
+				It("creates a persistent volume claim", func() {
+					Expect(fakeK8sPersistentVolumeClaims.CreateCallCount()).To(Equal(1), "PVC.Create not called")
+					spec := fakeK8sPersistentVolumeClaims.CreateArgsForCall(0)
+					Expect(spec).To(Equal(&v1.PersistentVolumeClaim{
+						TypeMeta: metav1.TypeMeta{
+							Kind:       "PersistentVolumeClaim",
+							APIVersion: "v1",
+						},
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "some-instance-id",
+						},
+
+						Spec: v1.PersistentVolumeClaimSpec{
+							AccessModes: []v1.PersistentVolumeAccessMode{v1.ReadWriteMany},
+							Resources:   v1.ResourceRequirements{Requests: v1.ResourceList{v1.ResourceStorage: quantity}},
+							Selector: &metav1.LabelSelector{
+								MatchExpressions: []metav1.LabelSelectorRequirement{
+									{
+										Key:      "name",
+										Operator: metav1.LabelSelectorOpIn,
+										Values:   []string{"some-instance-id"},
+									},
+								},
+							},
+						},
+					}))
+				})
+
+				It("creates the binding detail", func() {
+					Expect(fakeStore.CreateBindingDetailsCallCount()).To(Equal(1))
+					id, details := fakeStore.CreateBindingDetailsArgsForCall(0)
+					Expect(id).To(Equal("binding-id"))
+					Expect(details).To(Equal(bindDetails))
+				})
+
+				It("includes empty credentials to prevent CAPI crash", func() {
+					Expect(binding.Credentials).NotTo(BeNil())
+				})
+
+				It("uses the instance id in the default container path", func() {
+					Expect(binding.VolumeMounts[0].ContainerDir).To(Equal("/var/vcap/data/some-instance-id"))
+				})
+
+				Context("when there is a mount path in the params", func() {
+					BeforeEach(func() {
+						params["mount"] = "/var/vcap/otherdir/something"
+						bindDetails.RawParameters, err = json.Marshal(params)
+						Expect(err).NotTo(HaveOccurred())
+					})
+
+					It("flows container path through", func() {
+						Expect(binding.VolumeMounts[0].ContainerDir).To(Equal("/var/vcap/otherdir/something"))
+					})
+				})
+
+				It("uses rw as its default mode", func() {
+					Expect(binding.VolumeMounts[0].Mode).To(Equal("rw"))
+				})
+
+				Context("when readonly is set", func() {
+					BeforeEach(func() {
+						params["readonly"] = true
+						bindDetails.RawParameters, err = json.Marshal(params)
+						Expect(err).NotTo(HaveOccurred())
+					})
+
+					It("claims the volume read only", func() {
+						Expect(binding.VolumeMounts[0].Mode).To(Equal("r"))
+						claim := fakeK8sPersistentVolumeClaims.CreateArgsForCall(0)
+						Expect(claim.Spec.AccessModes).To(Equal([]v1.PersistentVolumeAccessMode{v1.ReadOnlyMany}))
+					})
+				})
+
+				Context("when the service maps modes itself", func() {
+					BeforeEach(func() {
+						fakeServices.ModeMapperReturns(k8sbroker.AccessModes{ReadWrite: "RWO"})
+					})
+
+					It("uses the service's access mode", func() {
+						Expect(err).NotTo(HaveOccurred())
+						Expect(fakeServices.ModeMapperArgsForCall(0)).To(Equal("ServiceOne.ID"))
+						claim := fakeK8sPersistentVolumeClaims.CreateArgsForCall(0)
+						Expect(claim.Spec.AccessModes).To(Equal([]v1.PersistentVolumeAccessMode{v1.ReadWriteOnce}))
+						Expect(binding.VolumeMounts[0].Mode).To(Equal("rw"))
+					})
+
+					Context("when readonly is set", func() {
+						BeforeEach(func() {
+							params["readonly"] = true
+							bindDetails.RawParameters, err = json.Marshal(params)
+							Expect(err).NotTo(HaveOccurred())
+						})
+
+						It("errors without creating a claim", func() {
+							Expect(err).To(Equal(k8sbroker.ErrReadOnlyUnsupported))
+							Expect(fakeK8sPersistentVolumeClaims.CreateCallCount()).To(Equal(0))
+						})
+					})
+				})
+
+				Context("when an access mode is given", func() {
+					BeforeEach(func() {
+						fakeServices.ModeMapperReturns(k8sbroker.AccessModes{ReadWrite: "RWO", ReadOnly: "ROX"})
+						params["access_mode"] = "RWO"
+						bindDetails.RawParameters, err = json.Marshal(params)
+						Expect(err).NotTo(HaveOccurred())
+					})
+
+					It("uses it in the persistent volume claim", func() {
+						claim := fakeK8sPersistentVolumeClaims.CreateArgsForCall(0)
+						Expect(claim.Spec.AccessModes).To(Equal([]v1.PersistentVolumeAccessMode{v1.ReadWriteOnce}))
+						Expect(binding.VolumeMounts[0].Mode).To(Equal("rw"))
+					})
+
+					Context("when the service does not support it", func() {
+						BeforeEach(func() {
+							fakeServices.ModeMapperReturns(k8sbroker.DefaultAccessModes)
+						})
+
+						It("errors without creating a claim", func() {
+							Expect(err).To(Equal(k8sbroker.ValidationErrors{
+								{Field: "access_mode", Message: "config \"access_mode\" \"RWO\" is not supported by the service"},
+							}))
+							Expect(fakeK8sPersistentVolumeClaims.CreateCallCount()).To(Equal(0))
+						})
+					})
+				})
+
+				Context("when the access mode is ROX", func() {
+					BeforeEach(func() {
+						params["access_mode"] = "ROX"
+						bindDetails.RawParameters, err = json.Marshal(params)
+						Expect(err).NotTo(HaveOccurred())
+					})
+
+					It("mounts read only", func() {
+						claim := fakeK8sPersistentVolumeClaims.CreateArgsForCall(0)
+						Expect(claim.Spec.AccessModes).To(Equal([]v1.PersistentVolumeAccessMode{v1.ReadOnlyMany}))
+						Expect(binding.VolumeMounts[0].Mode).To(Equal("r"))
+					})
+				})
+
+				Context("when the access mode is RWOP", func() {
+					BeforeEach(func() {
+						fakeServices.ModeMapperReturns(k8sbroker.AccessModes{ReadWrite: "RWOP"})
+						params["access_mode"] = "RWOP"
+						bindDetails.RawParameters, err = json.Marshal(params)
+						Expect(err).NotTo(HaveOccurred())
+					})
+
+					It("mounts read write", func() {
+						claim := fakeK8sPersistentVolumeClaims.CreateArgsForCall(0)
+						Expect(claim.Spec.AccessModes).To(Equal([]v1.PersistentVolumeAccessMode{k8sbroker.ReadWriteOncePod}))
+						Expect(binding.VolumeMounts[0].Mode).To(Equal("rw"))
+					})
+				})
+
+				Context("when the access mode is not recognized", func() {
+					BeforeEach(func() {
+						params["access_mode"] = "RWXYZ"
+						bindDetails.RawParameters, err = json.Marshal(params)
+						Expect(err).NotTo(HaveOccurred())
+					})
+
+					It("errors", func() {
+						Expect(err).To(Equal(brokerapi.ErrRawParamsInvalid))
+					})
+				})
+
+				Context("when the access mode contradicts readonly", func() {
+					BeforeEach(func() {
+						params["access_mode"] = "RWX"
+						params["readonly"] = true
+						bindDetails.RawParameters, err = json.Marshal(params)
+						Expect(err).NotTo(HaveOccurred())
+					})
+
+					It("errors", func() {
+						Expect(err).To(Equal(brokerapi.ErrRawParamsInvalid))
+					})
 				})
 
-				Context("when the client returns an error", func() {
-					var deleteErr error
+				It("fills in the driver name", func() {
+					Expect(binding.VolumeMounts[0].Driver).To(Equal("csi"))
+				})
 
+				Context("when the request has an ID", func() {
 					BeforeEach(func() {
-						deleteErr = errors.New("some-error")
-						fakeK8sPersistentVolumes.DeleteReturns(deleteErr)
+						ctx = requestid.NewContext(ctx, "some-request-id")
 					})
 
-					It("should error", func() {
-						Expect(err).To(Equal(deleteErr))
+					It("logs it with every bind message", func() {
+						var bindLogs int
+						for _, log := range logger.Logs() {
+							if strings.HasPrefix(log.Message, "test-broker.new-k8s-broker.bind.") {
+								bindLogs++
+								Expect(log.Data).To(HaveKeyWithValue("request_id", "some-request-id"))
+							}
+						}
+						Expect(bindLogs).NotTo(BeZero())
 					})
 				})
 
-				Context("when deletion of the instance fails", func() {
-					var storeErr error
+				It("looks up the driver name of the instance's plan", func() {
+					Expect(fakeServices.DriverNameCallCount()).To(Equal(1))
+					id, planID := fakeServices.DriverNameArgsForCall(0)
+					Expect(id).To(Equal(serviceID))
+					Expect(planID).To(Equal("some-plan-id"))
+				})
 
+				Context("when the service has no driver name", func() {
 					BeforeEach(func() {
-						storeErr = errors.New("some-error")
-						fakeStore.DeleteInstanceDetailsReturns(storeErr)
+						fakeServices.DriverNameReturns("")
 					})
 
-					It("should error", func() {
-						Expect(err).To(Equal(storeErr))
+					It("uses the nfs driver", func() {
+						Expect(binding.VolumeMounts[0].Driver).To(Equal("nfs"))
 					})
 				})
 
-				Context("when the save fails", func() {
-					var storeErr error
+				It("fills in the device type", func() {
+					Expect(binding.VolumeMounts[0].DeviceType).To(Equal("shared"))
+				})
+
+				It("leaves the claim's volume mode to default", func() {
+					claim := fakeK8sPersistentVolumeClaims.CreateArgsForCall(0)
+					Expect(claim.Spec.VolumeMode).To(BeNil())
+				})
 
+				Context("when the volume is a raw block volume", func() {
 					BeforeEach(func() {
-						storeErr = errors.New("some-error")
-						fakeStore.SaveReturns(storeErr)
+						volumeMode := v1.PersistentVolumeBlock
+						fakeStore.RetrieveInstanceDetailsReturns(brokerstore.ServiceInstance{
+							ServiceID: serviceID,
+							PlanID:    "some-plan-id",
+							ServiceFingerPrint: k8sbroker.ServiceFingerPrint{
+								Name: "some-instance-id",
+								Volume: &v1.PersistentVolume{
+									ObjectMeta: metav1.ObjectMeta{
+										Name:   "some-instance-id",
+										Labels: map[string]string{"name": "some-instance-id"},
+									},
+									Spec: v1.PersistentVolumeSpec{
+										Capacity:   v1.ResourceList{v1.ResourceStorage: quantity},
+										VolumeMode: &volumeMode,
+									},
+								},
+							},
+						}, nil)
+					})
+
+					It("claims it in block mode", func() {
+						Expect(err).NotTo(HaveOccurred())
+						claim := fakeK8sPersistentVolumeClaims.CreateArgsForCall(0)
+						Expect(claim.Spec.VolumeMode).NotTo(BeNil())
+						Expect(*claim.Spec.VolumeMode).To(Equal(v1.PersistentVolumeBlock))
+					})
+
+					It("binds it as a block device", func() {
+						Expect(binding.VolumeMounts[0].DeviceType).To(Equal("block"))
+					})
+				})
+
+				It("includes csi volume info in the service binding", func() {
+					Expect(binding.VolumeMounts).To(HaveLen(1))
+					Expect(binding.VolumeMounts[0].Device.VolumeId).To(Equal("some-instance-id-volume"))
+					Expect(binding.VolumeMounts[0].Device.MountConfig).To(HaveKeyWithValue("name", "k8s-volume-claim"))
+				})
+
+				It("passes the csi volume context through in the mount config", func() {
+					Expect(binding.VolumeMounts[0].Device.MountConfig).To(Equal(map[string]interface{}{
+						"name":  "k8s-volume-claim",
+						"share": "/export/some-share",
+					}))
+				})
+
+				It("should write state", func() {
+					Expect(fakeStore.SaveCallCount()).To(Equal(1))
+				})
+
+				It("records the operation", func() {
+					Expect(fakeMetrics.ObserveCallCount()).To(Equal(1))
+					operation, _, observedErr := fakeMetrics.ObserveArgsForCall(0)
+					Expect(operation).To(Equal("bind"))
+					Expect(observedErr).NotTo(HaveOccurred())
+				})
+
+				Context("when the details are not provided", func() {
+					BeforeEach(func() {
+						bindDetails.RawParameters = nil
+					})
+
+					It("succeeds", func() {
+						Expect(err).NotTo(HaveOccurred())
+					})
+				})
+
+				Context("when the binding cannot be stored", func() {
+					BeforeEach(func() {
+						fakeStore.CreateBindingDetailsReturns(errors.New("badness"))
 					})
 
 					It("should error", func() {
-						Expect(err).To(Equal(storeErr))
+						Expect(err).To(HaveOccurred())
 					})
 				})
 
-				Context("delete-service was given no instance id", func() {
+				Context("when the save fails", func() {
 					BeforeEach(func() {
-						instanceID = ""
+						fakeStore.SaveReturns(errors.New("badness"))
 					})
 
-					It("errors", func() {
-						Expect(err).To(Equal(errors.New("volume deletion requires instance ID")))
+					It("should error", func() {
+						Expect(err).To(HaveOccurred())
 					})
 				})
 			})
 		})
 
-		Context(".Bind", func() {
+		Context(".GetInstance", func() {
 			var (
-				serviceID     string
-				bindDetails   brokerapi.BindDetails
-				rawParameters json.RawMessage
-				params        map[string]interface{}
-				err           error
-				binding       brokerapi.Binding
+				instance k8sbroker.InstanceDetailsSpec
+				err      error
 			)
 
 			BeforeEach(func() {
-				serviceID = "ServiceOne.ID"
-				params = make(map[string]interface{})
-				params["key"] = "value"
-				rawParameters, err = json.Marshal(params)
-
-				bindDetails = brokerapi.BindDetails{
-					AppGUID:       "guid",
-					ServiceID:     serviceID,
-					RawParameters: rawParameters,
-				}
+				fakeStore.RetrieveInstanceDetailsReturns(brokerstore.ServiceInstance{
+					ServiceID: "some-service-id",
+					PlanID:    "some-plan-id",
+					ServiceFingerPrint: &k8sbroker.ServiceFingerPrint{
+						Name: "some-instance-id",
+						Volume: &v1.PersistentVolume{
+							ObjectMeta: metav1.ObjectMeta{Name: "some-instance-id"},
+						},
+						Labels:        map[string]string{"team": "storage"},
+						CapacityRange: &k8sbroker.CapacityRange{RequiredBytes: 1073741824},
+					},
+				}, nil)
+				fakeK8sPersistentVolumes.GetReturns(&v1.PersistentVolume{
+					ObjectMeta: metav1.ObjectMeta{Name: "some-instance-id"},
+					Spec: v1.PersistentVolumeSpec{
+						PersistentVolumeSource: v1.PersistentVolumeSource{
+							NFS: &v1.NFSVolumeSource{Server: "10.0.0.6", Path: "/export/moved-share"},
+						},
+						PersistentVolumeReclaimPolicy: v1.PersistentVolumeReclaimRetain,
+						MountOptions:                  []string{"hard"},
+					},
+				}, nil)
 			})
 
 			JustBeforeEach(func() {
-				binding, err = broker.Bind(ctx, "some-instance-id", "binding-id", bindDetails)
+				instance, err = broker.GetInstance(ctx, "some-instance-id")
 			})
 
-			Context("when service instance does not exist", func() {
-				BeforeEach(func() {
-					fakeStore.RetrieveInstanceDetailsReturns(brokerstore.ServiceInstance{}, errors.New("Awesome!"))
-				})
+			It("returns the instance with parameters from the current volume", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fakeK8sPersistentVolumes.GetCallCount()).To(Equal(1))
+				name, _ := fakeK8sPersistentVolumes.GetArgsForCall(0)
+				Expect(name).To(Equal("some-instance-id"))
 
-				It("errors", func() {
-					Expect(err).To(Equal(brokerapi.ErrInstanceDoesNotExist))
-				})
+				Expect(instance).To(Equal(k8sbroker.InstanceDetailsSpec{
+					ServiceID: "some-service-id",
+					PlanID:    "some-plan-id",
+					Parameters: map[string]interface{}{
+						"server":         "10.0.0.6",
+						"share":          "/export/moved-share",
+						"reclaim_policy": "Retain",
+						"mount_options":  []string{"hard"},
+						"labels":         map[string]string{"team": "storage"},
+						"capacity_range": &k8sbroker.CapacityRange{RequiredBytes: 1073741824},
+					},
+				}))
 			})
 
-			Context("when service instance contains invalid service fingerprint", func() {
+			Context("when a dashboard URL template is set", func() {
 				BeforeEach(func() {
-					fakeStore.RetrieveInstanceDetailsReturns(brokerstore.ServiceInstance{
-						ServiceID:          serviceID,
-						ServiceFingerPrint: "invalid-json",
-					}, nil)
+					config.DashboardURL, err = k8sbroker.NewDashboardURLTemplate("https://dashboard.example.com/{{.Name}}")
+					Expect(err).NotTo(HaveOccurred())
 				})
 
-				It("errors", func() {
-					Expect(err).To(HaveOccurred())
+				It("returns the dashboard URL", func() {
+					Expect(instance.DashboardURL).To(Equal("https://dashboard.example.com/some-instance-id"))
 				})
 			})
 
-			Context("when service instance exists", func() {
-				var quantity resource.Quantity
-
+			Context("when the instance is still provisioning", func() {
 				BeforeEach(func() {
-					quantity, err = resource.ParseQuantity("2")
-					Expect(err).NotTo(HaveOccurred())
-					fingerprint := k8sbroker.ServiceFingerPrint{
-						Name: "some-instance-id",
-						Volume: &v1.PersistentVolume{
-							TypeMeta: metav1.TypeMeta{
-								Kind:       "PersistentVolume",
-								APIVersion: "v1",
-							},
-							ObjectMeta: metav1.ObjectMeta{
-								Name:   "some-instance-id",
-								Labels: map[string]string{"name": "some-instance-id"},
-							},
-							Spec: v1.PersistentVolumeSpec{
-								AccessModes: []v1.PersistentVolumeAccessMode{v1.ReadWriteMany},
-								Capacity:    v1.ResourceList{v1.ResourceStorage: quantity},
-								PersistentVolumeSource: v1.PersistentVolumeSource{
-									CSI: &v1.CSIPersistentVolumeSource{
-										VolumeHandle: "data-id",
-									},
-								},
-							},
-						},
-					}
-
-					// simulate untyped data loaded from a data file
-					jsonFingerprint := &map[string]interface{}{}
-					raw, err := json.Marshal(fingerprint)
-					Expect(err).ToNot(HaveOccurred())
-					err = json.Unmarshal(raw, jsonFingerprint)
-					Expect(err).ToNot(HaveOccurred())
 					fakeStore.RetrieveInstanceDetailsReturns(brokerstore.ServiceInstance{
-						ServiceID:          serviceID,
-						ServiceFingerPrint: jsonFingerprint,
-					}, nil)
-
-					fakeK8sPersistentVolumeClaims.CreateReturns(&v1.PersistentVolumeClaim{
-						ObjectMeta: metav1.ObjectMeta{
-							Name: "k8s-volume-claim",
+						ServiceFingerPrint: &k8sbroker.ServiceFingerPrint{
+							Name:           "some-instance-id",
+							Volume:         &v1.PersistentVolume{ObjectMeta: metav1.ObjectMeta{Name: "some-instance-id"}},
+							ProvisionState: &k8sbroker.ProvisionState{Status: k8sbroker.ProvisionInProgress},
 						},
 					}, nil)
 				})
 
-				It("should not error", func() {
-					Expect(err).NotTo(HaveOccurred())
+				It("does not exist yet", func() {
+					Expect(err).To(Equal(brokerapi.ErrInstanceDoesNotExist))
+					Expect(fakeK8sPersistentVolumes.GetCallCount()).To(Equal(0))
 				})
+			})
 
-				Context("when mode is not a boolean", func() {
-					BeforeEach(func() {
-						params["readonly"] = ""
-						bindDetails.RawParameters, err = json.Marshal(params)
-						Expect(err).NotTo(HaveOccurred())
-					})
-
-					It("errors", func() {
-						Expect(err).To(Equal(brokerapi.ErrRawParamsInvalid))
-					})
+			Context("when the instance does not exist", func() {
+				BeforeEach(func() {
+					fakeStore.RetrieveInstanceDetailsReturns(brokerstore.ServiceInstance{}, errors.New("not found"))
 				})
 
-				Context("when an identical binding already exists", func() {
-					BeforeEach(func() {
-						fakeStore.IsBindingConflictReturns(false)
-					})
-
-					It("doesn't error when binding the same details", func() {
-						Expect(err).NotTo(HaveOccurred())
-					})
+				It("errors", func() {
+					Expect(err).To(Equal(brokerapi.ErrInstanceDoesNotExist))
 				})
+			})
 
-				Context("when the binding already exists with different details", func() {
-					BeforeEach(func() {
-						fakeStore.IsBindingConflictReturns(true)
-					})
-
-					It("errors", func() {
-						Expect(err).To(Equal(brokerapi.ErrBindingAlreadyExists))
-					})
+			Context("when the volume cannot be fetched", func() {
+				BeforeEach(func() {
+					fakeK8sPersistentVolumes.GetReturns(nil, errors.New("get-failed"))
 				})
 
-				Context("when it fails to create persistent volume claim", func() {
-					var createErr error
+				It("errors", func() {
+					Expect(err).To(MatchError("get-failed"))
+				})
+			})
 
-					BeforeEach(func() {
-						createErr = errors.New("failed-to-create")
-						fakeK8sPersistentVolumeClaims.CreateReturns(nil, createErr)
-					})
+			Context("when the instance is dynamically provisioned", func() {
+				BeforeEach(func() {
+					fakeStore.RetrieveInstanceDetailsReturns(brokerstore.ServiceInstance{
+						ServiceID: "some-service-id",
+						PlanID:    "some-plan-id",
+						ServiceFingerPrint: &k8sbroker.ServiceFingerPrint{
+							Name:                "some-instance-id",
+							DynamicProvisioning: true,
+							StorageClassName:    "nfs-dynamic",
+							Labels:              map[string]string{"team": "storage"},
+						},
+					}, nil)
+				})
 
-					It("returns an error", func() {
-						Expect(err).To(Equal(createErr))
-					})
+				It("returns the parameters from the fingerprint", func() {
+					Expect(err).NotTo(HaveOccurred())
+					Expect(fakeK8sPersistentVolumes.GetCallCount()).To(Equal(0))
+					Expect(instance.Parameters).To(Equal(map[string]interface{}{
+						"dynamic_provisioning": true,
+						"storage_class_name":   "nfs-dynamic",
+						"labels":               map[string]string{"team": "storage"},
+					}))
 				})
+			})
+		})
 
-				It("creates a persistent volume claim", func() {
-					Expect(fakeK8sPersistentVolumeClaims.CreateCallCount()).To(Equal(1), "PVC.Create not called")
-					spec := fakeK8sPersistentVolumeClaims.CreateArgsForCall(0)
-					Expect(spec).To(Equal(&v1.PersistentVolumeClaim{
-						TypeMeta: metav1.TypeMeta{
-							Kind:       "PersistentVolumeClaim",
-							APIVersion: "v1",
+		Context(".GetBinding", func() {
+			var (
+				binding brokerapi.Binding
+				err     error
+			)
+
+			BeforeEach(func() {
+				fakeStore.RetrieveInstanceDetailsReturns(brokerstore.ServiceInstance{
+					ServiceID: "some-service-id",
+					PlanID:    "some-plan-id",
+					ServiceFingerPrint: &k8sbroker.ServiceFingerPrint{
+						Name: "some-instance-id",
+						Volume: &v1.PersistentVolume{
+							ObjectMeta: metav1.ObjectMeta{Name: "some-instance-id"},
+						},
+						Bindings: map[string]k8sbroker.BindingFingerPrint{
+							"binding-id": {ClaimName: "some-claim", Namespace: "some-org-namespace", AccessMode: "ReadOnlyMany"},
 						},
-						ObjectMeta: metav1.ObjectMeta{
-							Name: "some-instance-id",
+					},
+				}, nil)
+				fakeStore.RetrieveBindingDetailsReturns(brokerapi.BindDetails{
+					AppGUID:       "guid",
+					RawParameters: json.RawMessage(`{"mount": "/data", "readonly": true}`),
+				}, nil)
+				fakeServices.DriverNameReturns("csi")
+			})
+
+			JustBeforeEach(func() {
+				binding, err = broker.GetBinding(ctx, "some-instance-id", "binding-id")
+			})
+
+			It("returns the volume mount that bind returned", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(binding).To(Equal(brokerapi.Binding{
+					Credentials: struct{}{},
+					VolumeMounts: []brokerapi.VolumeMount{{
+						ContainerDir: "/data",
+						Mode:         "r",
+						Driver:       "csi",
+						DeviceType:   "shared",
+						Device: brokerapi.SharedDevice{
+							VolumeId:    "some-instance-id-volume",
+							MountConfig: map[string]interface{}{"name": "some-claim"},
 						},
+					}},
+				}))
+			})
 
-						Spec: v1.PersistentVolumeClaimSpec{
-							AccessModes: []v1.PersistentVolumeAccessMode{v1.ReadWriteMany},
-							Resources:   v1.ResourceRequirements{Requests: v1.ResourceList{v1.ResourceStorage: quantity}},
-							Selector: &metav1.LabelSelector{
-								MatchExpressions: []metav1.LabelSelectorRequirement{
-									{
-										Key:      "name",
-										Operator: metav1.LabelSelectorOpIn,
-										Values:   []string{"some-instance-id"},
-									},
-								},
+			It("looks up the driver of the instance's plan", func() {
+				serviceID, planID := fakeServices.DriverNameArgsForCall(0)
+				Expect(serviceID).To(Equal("some-service-id"))
+				Expect(planID).To(Equal("some-plan-id"))
+			})
+
+			Context("when the binding has no fingerprint", func() {
+				BeforeEach(func() {
+					fakeStore.RetrieveInstanceDetailsReturns(brokerstore.ServiceInstance{
+						ServiceFingerPrint: &k8sbroker.ServiceFingerPrint{
+							Name: "some-instance-id",
+							Volume: &v1.PersistentVolume{
+								ObjectMeta: metav1.ObjectMeta{Name: "some-instance-id"},
 							},
 						},
-					}))
+					}, nil)
 				})
 
-				It("creates the binding detail", func() {
-					Expect(fakeStore.CreateBindingDetailsCallCount()).To(Equal(1))
-					id, details := fakeStore.CreateBindingDetailsArgsForCall(0)
-					Expect(id).To(Equal("binding-id"))
-					Expect(details).To(Equal(bindDetails))
+				It("uses the claim named after the volume", func() {
+					Expect(binding.VolumeMounts[0].Device.MountConfig).To(Equal(map[string]interface{}{"name": "some-instance-id"}))
 				})
+			})
 
-				It("includes empty credentials to prevent CAPI crash", func() {
-					Expect(binding.Credentials).NotTo(BeNil())
+			Context("when the instance does not exist", func() {
+				BeforeEach(func() {
+					fakeStore.RetrieveInstanceDetailsReturns(brokerstore.ServiceInstance{}, errors.New("not found"))
 				})
 
-				It("uses the instance id in the default container path", func() {
-					Expect(binding.VolumeMounts[0].ContainerDir).To(Equal("/var/vcap/data/some-instance-id"))
+				It("errors", func() {
+					Expect(err).To(Equal(brokerapi.ErrInstanceDoesNotExist))
 				})
+			})
 
-				Context("when there is a mount path in the params", func() {
-					BeforeEach(func() {
-						params["mount"] = "/var/vcap/otherdir/something"
-						bindDetails.RawParameters, err = json.Marshal(params)
-						Expect(err).NotTo(HaveOccurred())
-					})
-
-					It("flows container path through", func() {
-						Expect(binding.VolumeMounts[0].ContainerDir).To(Equal("/var/vcap/otherdir/something"))
-					})
+			Context("when the binding does not exist", func() {
+				BeforeEach(func() {
+					fakeStore.RetrieveBindingDetailsReturns(brokerapi.BindDetails{}, errors.New("not found"))
 				})
 
-				It("uses rw as its default mode", func() {
-					Expect(binding.VolumeMounts[0].Mode).To(Equal("rw"))
+				It("errors", func() {
+					Expect(err).To(Equal(brokerapi.ErrBindingDoesNotExist))
 				})
+			})
+		})
 
-				It("fills in the driver name", func() {
-					Expect(binding.VolumeMounts[0].Driver).To(Equal("csi"))
-				})
+		Context("when provisions, binds and unbinds run concurrently", func() {
+			const workers = 20
 
-				It("fills in the device type", func() {
-					Expect(binding.VolumeMounts[0].DeviceType).To(Equal("shared"))
-				})
+			var (
+				storeMutex sync.Mutex
+				instances  map[string]brokerstore.ServiceInstance
+				bindings   map[string]brokerapi.BindDetails
+				provisions int
+				stored     map[string]bool
+			)
 
-				It("includes csi volume info in the service binding", func() {
-					Expect(binding.VolumeMounts).To(HaveLen(1))
-					Expect(binding.VolumeMounts[0].Device.VolumeId).To(Equal("some-instance-id-volume"))
-					Expect(binding.VolumeMounts[0].Device.MountConfig).To(HaveKeyWithValue("name", "k8s-volume-claim"))
-				})
+			BeforeEach(func() {
+				instances = map[string]brokerstore.ServiceInstance{}
+				bindings = map[string]brokerapi.BindDetails{}
+				provisions = 0
+				stored = map[string]bool{}
 
-				It("should write state", func() {
-					Expect(fakeStore.SaveCallCount()).To(Equal(1))
-				})
+				fakeStore.RetrieveInstanceDetailsStub = func(id string) (brokerstore.ServiceInstance, error) {
+					storeMutex.Lock()
+					defer storeMutex.Unlock()
+					details, ok := instances[id]
+					if !ok {
+						return brokerstore.ServiceInstance{}, errors.New("not found")
+					}
+					return details, nil
+				}
+				fakeStore.CreateInstanceDetailsStub = func(id string, details brokerstore.ServiceInstance) error {
+					storeMutex.Lock()
+					defer storeMutex.Unlock()
+					instances[id] = details
+					stored[id] = true
+					return nil
+				}
+				fakeStore.DeleteInstanceDetailsStub = func(id string) error {
+					storeMutex.Lock()
+					defer storeMutex.Unlock()
+					delete(instances, id)
+					return nil
+				}
+				fakeStore.RetrieveBindingDetailsStub = func(id string) (brokerapi.BindDetails, error) {
+					storeMutex.Lock()
+					defer storeMutex.Unlock()
+					details, ok := bindings[id]
+					if !ok {
+						return brokerapi.BindDetails{}, errors.New("not found")
+					}
+					return details, nil
+				}
+				fakeStore.CreateBindingDetailsStub = func(id string, details brokerapi.BindDetails) error {
+					storeMutex.Lock()
+					defer storeMutex.Unlock()
+					bindings[id] = details
+					return nil
+				}
+				fakeStore.DeleteBindingDetailsStub = func(id string) error {
+					storeMutex.Lock()
+					defer storeMutex.Unlock()
+					delete(bindings, id)
+					return nil
+				}
 
-				Context("when the details are not provided", func() {
-					BeforeEach(func() {
-						bindDetails.RawParameters = nil
-					})
+				fakeK8sPersistentVolumes.GetReturns(nil, k8serrors.NewNotFound(schema.GroupResource{Resource: "persistentvolumes"}, "some-instance-id"))
+				fakeK8sPersistentVolumes.CreateStub = func(volume *v1.PersistentVolume) (*v1.PersistentVolume, error) {
+					return volume, nil
+				}
+				fakeK8sPersistentVolumeClaims.GetReturns(nil, k8serrors.NewNotFound(schema.GroupResource{Resource: "persistentvolumeclaims"}, "some-instance-id"))
+				fakeK8sPersistentVolumeClaims.CreateStub = func(claim *v1.PersistentVolumeClaim) (*v1.PersistentVolumeClaim, error) {
+					return claim, nil
+				}
+			})
 
-					It("succeeds", func() {
-						Expect(err).NotTo(HaveOccurred())
-					})
-				})
+			// run with go test -race to catch unguarded access to the store and
+			// the client
+			It("provisions, binds and unbinds every instance", func() {
+				var (
+					wg          sync.WaitGroup
+					resultMutex sync.Mutex
+					errs        []error
+				)
 
-				Context("when the binding cannot be stored", func() {
-					BeforeEach(func() {
-						fakeStore.CreateBindingDetailsReturns(errors.New("badness"))
-					})
+				for i := 0; i < workers; i++ {
+					wg.Add(1)
+					go func(i int) {
+						defer wg.Done()
+						instanceID := fmt.Sprintf("instance-%d", i)
+						bindingID := fmt.Sprintf("binding-%d", i)
 
-					It("should error", func() {
-						Expect(err).To(HaveOccurred())
-					})
-				})
+						_, err := broker.Provision(ctx, instanceID, brokerapi.ProvisionDetails{
+							ServiceID:     "some-service-id",
+							PlanID:        "nfs",
+							RawParameters: json.RawMessage(`{"share": "/export/some-share", "server": "10.0.0.5"}`),
+						}, false)
+						if err == nil {
+							resultMutex.Lock()
+							provisions++
+							resultMutex.Unlock()
 
-				Context("when the save fails", func() {
-					BeforeEach(func() {
-						fakeStore.SaveReturns(errors.New("badness"))
-					})
+							_, err = broker.Bind(ctx, instanceID, bindingID, brokerapi.BindDetails{AppGUID: "guid", ServiceID: "some-service-id"})
+						}
+						if err == nil {
+							err = broker.Unbind(ctx, instanceID, bindingID, brokerapi.UnbindDetails{})
+						}
 
-					It("should error", func() {
-						Expect(err).To(HaveOccurred())
-					})
-				})
+						if err != nil {
+							resultMutex.Lock()
+							errs = append(errs, fmt.Errorf("%s: %s", instanceID, err.Error()))
+							resultMutex.Unlock()
+						}
+					}(i)
+				}
+				wg.Wait()
+
+				Expect(errs).To(BeEmpty())
+				Expect(provisions).To(Equal(workers))
+				Expect(stored).To(HaveLen(provisions))
+				Expect(instances).To(HaveLen(workers))
+				Expect(bindings).To(BeEmpty())
+				Expect(fakeK8sPersistentVolumes.CreateCallCount()).To(Equal(workers))
+				Expect(fakeK8sPersistentVolumeClaims.CreateCallCount()).To(Equal(workers))
+				Expect(fakeK8sPersistentVolumeClaims.DeleteCallCount()).To(Equal(workers))
 			})
 		})
 
@@ -718,6 +4896,271 @@ var _ = Describe("Broker", func() {
 				Expect(fakeStore.SaveCallCount()).To(Equal(1))
 			})
 
+			It("records the operation", func() {
+				Expect(fakeMetrics.ObserveCallCount()).To(Equal(1))
+				operation, _, observedErr := fakeMetrics.ObserveArgsForCall(0)
+				Expect(operation).To(Equal("unbind"))
+				Expect(observedErr).NotTo(HaveOccurred())
+			})
+
+			It("deletes the claim from the broker's namespace", func() {
+				Expect(fakeK8sCoreV1.PersistentVolumeClaimsArgsForCall(0)).To(Equal("some-namespace"))
+			})
+
+			Context("when another binding uses the same claim", func() {
+				BeforeEach(func() {
+					fakeStore.RetrieveInstanceDetailsReturns(brokerstore.ServiceInstance{
+						ServiceID: "some-service-id",
+						ServiceFingerPrint: &k8sbroker.ServiceFingerPrint{
+							Name:   "some-instance-id",
+							Volume: &v1.PersistentVolume{ObjectMeta: metav1.ObjectMeta{Name: "some-instance-id"}},
+							Bindings: map[string]k8sbroker.BindingFingerPrint{
+								"binding-id":       {ClaimName: "some-instance-id", Namespace: "some-namespace"},
+								"other-binding-id": {ClaimName: "some-instance-id", Namespace: "some-namespace"},
+							},
+						},
+					}, nil)
+				})
+
+				It("keeps the claim until the last binding is unbound", func() {
+					Expect(err).NotTo(HaveOccurred())
+					Expect(fakeK8sPersistentVolumeClaims.DeleteCallCount()).To(Equal(0))
+					Expect(logger.LogMessages()).To(ContainElement("test-broker.new-k8s-broker.unbind.keeping-shared-claim"))
+				})
+
+				It("forgets only this binding", func() {
+					_, details := fakeStore.CreateInstanceDetailsArgsForCall(0)
+					fingerprint := details.ServiceFingerPrint.(k8sbroker.ServiceFingerPrint)
+					Expect(fingerprint.Bindings).To(HaveLen(1))
+					Expect(fingerprint.Bindings).To(HaveKey("other-binding-id"))
+				})
+			})
+
+			Context("when the instance is dynamically provisioned", func() {
+				BeforeEach(func() {
+					fakeStore.RetrieveInstanceDetailsReturns(brokerstore.ServiceInstance{
+						ServiceID: "some-service-id",
+						ServiceFingerPrint: &k8sbroker.ServiceFingerPrint{
+							Name:                "some-instance-id",
+							DynamicProvisioning: true,
+							StorageClassName:    "nfs-dynamic",
+							ClaimNamespaces:     []string{"some-org-namespace"},
+							Bindings: map[string]k8sbroker.BindingFingerPrint{
+								"binding-id": {ClaimName: "some-instance-id", Namespace: "some-org-namespace", AccessMode: "ReadWriteMany"},
+							},
+						},
+					}, nil)
+				})
+
+				It("keeps the claim, which holds the instance's data", func() {
+					Expect(err).NotTo(HaveOccurred())
+					Expect(fakeK8sPersistentVolumeClaims.DeleteCallCount()).To(Equal(0))
+				})
+
+				It("forgets the binding but not the claim", func() {
+					_, details := fakeStore.CreateInstanceDetailsArgsForCall(0)
+					fingerprint := details.ServiceFingerPrint.(k8sbroker.ServiceFingerPrint)
+					Expect(fingerprint.Bindings).To(BeEmpty())
+					Expect(fingerprint.ClaimNamespaces).To(Equal([]string{"some-org-namespace"}))
+				})
+			})
+
+			Context("when the request has been cancelled", func() {
+				BeforeEach(func() {
+					var cancel context.CancelFunc
+					ctx, cancel = context.WithCancel(ctx)
+					cancel()
+				})
+
+				It("errors without deleting the claim", func() {
+					Expect(err).To(Equal(context.Canceled))
+					Expect(fakeK8sPersistentVolumeClaims.DeleteCallCount()).To(Equal(0))
+					Expect(fakeStore.DeleteBindingDetailsCallCount()).To(Equal(0))
+				})
+			})
+
+			Context("when the persistent volume claim is already gone", func() {
+				BeforeEach(func() {
+					fakeK8sPersistentVolumeClaims.DeleteReturns(k8serrors.NewNotFound(schema.GroupResource{Resource: "persistentvolumeclaims"}, "some-instance-id"))
+				})
+
+				It("unbinds", func() {
+					Expect(err).NotTo(HaveOccurred())
+					Expect(fakeStore.DeleteBindingDetailsCallCount()).To(Equal(1))
+				})
+			})
+
+			Context("when the persistent volume claim cannot be deleted", func() {
+				BeforeEach(func() {
+					fakeK8sPersistentVolumeClaims.DeleteReturns(errors.New("forbidden"))
+				})
+
+				It("errors and keeps the binding", func() {
+					Expect(err).To(MatchError("forbidden"))
+					Expect(fakeStore.DeleteBindingDetailsCallCount()).To(Equal(0))
+				})
+			})
+
+			Context("when the binding has a fingerprint", func() {
+				BeforeEach(func() {
+					fakeStore.RetrieveInstanceDetailsReturns(brokerstore.ServiceInstance{
+						ServiceID: "some-service-id",
+						ServiceFingerPrint: &k8sbroker.ServiceFingerPrint{
+							Name: "some-instance-id",
+							Volume: &v1.PersistentVolume{
+								ObjectMeta: metav1.ObjectMeta{Name: "some-instance-id"},
+							},
+							Bindings: map[string]k8sbroker.BindingFingerPrint{
+								"binding-id": {
+									ClaimName:  "some-claim",
+									Namespace:  "some-org-namespace",
+									AccessMode: "ReadWriteMany",
+								},
+							},
+						},
+					}, nil)
+				})
+
+				It("deletes the recorded claim", func() {
+					Expect(fakeK8sCoreV1.PersistentVolumeClaimsArgsForCall(0)).To(Equal("some-org-namespace"))
+					claimName, _ := fakeK8sPersistentVolumeClaims.DeleteArgsForCall(0)
+					Expect(claimName).To(Equal("some-claim"))
+				})
+
+				It("forgets the binding", func() {
+					Expect(fakeStore.CreateInstanceDetailsCallCount()).To(Equal(1))
+					_, details := fakeStore.CreateInstanceDetailsArgsForCall(0)
+					fingerprint := details.ServiceFingerPrint.(k8sbroker.ServiceFingerPrint)
+					Expect(fingerprint.Bindings).To(BeEmpty())
+				})
+
+				It("does not touch roles", func() {
+					Expect(fakeK8sRoleBindings.DeleteCallCount()).To(Equal(0))
+					Expect(fakeK8sRoles.DeleteCallCount()).To(Equal(0))
+				})
+			})
+
+			Context("when the binding granted a service account access", func() {
+				BeforeEach(func() {
+					fakeStore.RetrieveInstanceDetailsReturns(brokerstore.ServiceInstance{
+						ServiceID: "some-service-id",
+						ServiceFingerPrint: &k8sbroker.ServiceFingerPrint{
+							Name: "some-instance-id",
+							Volume: &v1.PersistentVolume{
+								ObjectMeta: metav1.ObjectMeta{Name: "some-instance-id"},
+							},
+							Bindings: map[string]k8sbroker.BindingFingerPrint{
+								"binding-id": {
+									ClaimName:      "some-claim",
+									Namespace:      "some-org-namespace",
+									AccessMode:     "ReadWriteMany",
+									ServiceAccount: "app-sa",
+								},
+							},
+						},
+					}, nil)
+				})
+
+				It("deletes the role binding and the role", func() {
+					Expect(err).NotTo(HaveOccurred())
+					Expect(fakeK8sRoleBindings.DeleteCallCount()).To(Equal(1))
+					name, _ := fakeK8sRoleBindings.DeleteArgsForCall(0)
+					Expect(name).To(Equal("k8sbroker-binding-id"))
+					Expect(fakeK8sRoles.DeleteCallCount()).To(Equal(1))
+					name, _ = fakeK8sRoles.DeleteArgsForCall(0)
+					Expect(name).To(Equal("k8sbroker-binding-id"))
+				})
+
+				Context("when the role binding is already gone", func() {
+					BeforeEach(func() {
+						fakeK8sRoleBindings.DeleteReturns(k8serrors.NewNotFound(schema.GroupResource{Resource: "rolebindings"}, "k8sbroker-binding-id"))
+					})
+
+					It("still deletes the role", func() {
+						Expect(err).NotTo(HaveOccurred())
+						Expect(fakeK8sRoles.DeleteCallCount()).To(Equal(1))
+					})
+				})
+
+				Context("when the role binding cannot be deleted", func() {
+					BeforeEach(func() {
+						fakeK8sRoleBindings.DeleteReturns(errors.New("forbidden"))
+					})
+
+					It("errors and keeps the binding", func() {
+						Expect(err).To(MatchError("forbidden"))
+						Expect(fakeStore.DeleteBindingDetailsCallCount()).To(Equal(0))
+					})
+				})
+			})
+
+			Context("when the fingerprint has no version", func() {
+				BeforeEach(func() {
+					fakeStore.RetrieveInstanceDetailsReturns(brokerstore.ServiceInstance{
+						ServiceID: "some-service-id",
+						ServiceFingerPrint: &k8sbroker.ServiceFingerPrint{
+							Name: "some-instance-id",
+							Volume: &v1.PersistentVolume{
+								ObjectMeta: metav1.ObjectMeta{Name: "some-instance-id"},
+								Spec: v1.PersistentVolumeSpec{
+									PersistentVolumeReclaimPolicy: v1.PersistentVolumeReclaimRetain,
+									NodeAffinity: &v1.VolumeNodeAffinity{
+										Required: &v1.NodeSelector{NodeSelectorTerms: []v1.NodeSelectorTerm{{}}},
+									},
+								},
+							},
+							Bindings: map[string]k8sbroker.BindingFingerPrint{
+								"binding-id": {ClaimName: "some-claim", Namespace: "some-org-namespace"},
+							},
+						},
+					}, nil)
+				})
+
+				It("migrates it to the current version", func() {
+					Expect(err).NotTo(HaveOccurred())
+					_, details := fakeStore.CreateInstanceDetailsArgsForCall(0)
+					fingerprint := details.ServiceFingerPrint.(k8sbroker.ServiceFingerPrint)
+					Expect(fingerprint.Version).To(Equal(1))
+					Expect(fingerprint.ReclaimPolicy).To(Equal(v1.PersistentVolumeReclaimRetain))
+					Expect(fingerprint.NodeAffinity).To(Equal(fingerprint.Volume.Spec.NodeAffinity))
+				})
+			})
+
+			Context("when the fingerprint was stored with lowercase keys", func() {
+				BeforeEach(func() {
+					legacyFingerprint := map[string]interface{}{}
+					err := json.Unmarshal([]byte(`{
+						"name": "some-instance-id",
+						"volume": {"metadata": {"name": "some-instance-id"}},
+						"bindings": {
+							"binding-id": {"claimName": "some-claim", "namespace": "some-org-namespace", "accessMode": "ReadWriteMany"}
+						}
+					}`), &legacyFingerprint)
+					Expect(err).NotTo(HaveOccurred())
+
+					fakeStore.RetrieveInstanceDetailsReturns(brokerstore.ServiceInstance{
+						ServiceID:          "some-service-id",
+						ServiceFingerPrint: legacyFingerprint,
+					}, nil)
+				})
+
+				It("reads the legacy entry", func() {
+					Expect(err).NotTo(HaveOccurred())
+					Expect(fakeK8sCoreV1.PersistentVolumeClaimsArgsForCall(0)).To(Equal("some-org-namespace"))
+					claimName, _ := fakeK8sPersistentVolumeClaims.DeleteArgsForCall(0)
+					Expect(claimName).To(Equal("some-claim"))
+				})
+
+				It("saves the fingerprint back with the current keys", func() {
+					Expect(fakeStore.CreateInstanceDetailsCallCount()).To(Equal(1))
+					_, details := fakeStore.CreateInstanceDetailsArgsForCall(0)
+					raw, err := json.Marshal(details.ServiceFingerPrint)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(string(raw)).To(ContainSubstring(`"Name":"some-instance-id"`))
+					Expect(string(raw)).To(ContainSubstring(`"Volume":{`))
+				})
+			})
+
 			Context("when trying to unbind a instance that has not been provisioned", func() {
 				BeforeEach(func() {
 					fakeStore.RetrieveInstanceDetailsReturns(brokerstore.ServiceInstance{}, errors.New("Shazaam!"))
@@ -759,4 +5202,22 @@ var _ = Describe("Broker", func() {
 			})
 		})
 	})
+
+	Context("when the label prefix is invalid", func() {
+		It("refuses to create the broker", func() {
+			config.LabelPrefix = "not a prefix/"
+			broker, err = k8sbroker.New(
+				logger,
+				fakeOs,
+				fakeClock,
+				fakeStore,
+				fakeK8sClient,
+				validator,
+				fakeServices,
+				fakeMetrics,
+				config,
+			)
+			Expect(err).To(MatchError(ContainSubstring(`invalid label prefix "not a prefix/"`)))
+		})
+	})
 })
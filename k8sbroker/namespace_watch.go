@@ -0,0 +1,117 @@
+package k8sbroker
+
+import (
+	"context"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/pivotal-cf/brokerapi"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+)
+
+// SpaceLabel holds the CF space GUID on the namespaces the broker creates.
+const SpaceLabel = "cloudfoundry.org/space-guid"
+
+// StartNamespaceWatch watches the namespaces labelled with a CF space and,
+// when one is deleted, unbinds every binding whose claim was in it. Bindings
+// are found through the volumes and claims labelled as managed by the broker,
+// so a dynamically provisioned instance whose only claims were in the deleted
+// namespace is left for the platform to unbind.
+func (b *Broker) StartNamespaceWatch() {
+	informer := cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				options.LabelSelector = SpaceLabel
+				return b.client.CoreV1().Namespaces().List(options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				options.LabelSelector = SpaceLabel
+				return b.watchClient.CoreV1().Namespaces().Watch(options)
+			},
+		},
+		&v1.Namespace{},
+		0,
+		cache.Indexers{},
+	)
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if namespace, ok := obj.(*v1.Namespace); ok {
+				b.unbindNamespace(namespace)
+			}
+		},
+	})
+
+	go informer.Run(make(chan struct{}))
+}
+
+func (b *Broker) unbindNamespace(namespace *v1.Namespace) {
+	logger := b.logger.Session("namespace-deleted", lager.Data{"namespace": namespace.Name, "space": namespace.Labels[SpaceLabel]})
+	logger.Info("start")
+	defer logger.Info("end")
+
+	unbinds, err := b.namespaceBindings(namespace.Name)
+	if err != nil {
+		logger.Error("error-listing-managed-resources", err, k8sErrorData(err, nil))
+		return
+	}
+
+	for _, unbind := range unbinds {
+		logger.Info("unbinding", lager.Data{"instance_id": unbind.instanceID, "binding_id": unbind.bindingID})
+		err = b.Unbind(context.Background(), unbind.instanceID, unbind.bindingID, unbind.details)
+		if err != nil {
+			logger.Error("error-unbinding", err, lager.Data{"instance_id": unbind.instanceID, "binding_id": unbind.bindingID})
+		}
+	}
+}
+
+type namespaceBinding struct {
+	instanceID string
+	bindingID  string
+	details    brokerapi.UnbindDetails
+}
+
+// namespaceBindings returns the stored bindings whose claim is in namespace.
+// It holds the broker's lock while reading the store, which Unbind takes
+// again for each binding.
+func (b *Broker) namespaceBindings(namespace string) ([]namespaceBinding, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	instanceIDs, err := b.managedInstanceIDs()
+	if err != nil {
+		return nil, err
+	}
+
+	var bindings []namespaceBinding
+	for _, instanceID := range instanceIDs {
+		instanceDetails, err := b.store.RetrieveInstanceDetails(instanceID)
+		if err != nil {
+			continue
+		}
+		fingerprint, err := getFingerprint(instanceDetails.ServiceFingerPrint)
+		if err != nil {
+			continue
+		}
+
+		for bindingID, binding := range fingerprint.Bindings {
+			if binding.Namespace != namespace {
+				continue
+			}
+			bindings = append(bindings, namespaceBinding{
+				instanceID: instanceID,
+				bindingID:  bindingID,
+				details: brokerapi.UnbindDetails{
+					ServiceID: instanceDetails.ServiceID,
+					PlanID:    instanceDetails.PlanID,
+				},
+			})
+		}
+	}
+	return bindings, nil
+}
@@ -0,0 +1,89 @@
+package k8sbroker
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pivotal-cf/brokerapi"
+)
+
+// bindParameters are interpreted by the broker itself and are always allowed.
+var bindParameters = []string{"mount", "readonly", "access_mode", "namespace", "labels", "annotations", "service_account"}
+
+type ErrParameterNotAllowed struct {
+	Key string
+}
+
+func (e ErrParameterNotAllowed) Error() string {
+	return fmt.Sprintf("%s: parameter %q is not allowed", brokerapi.ErrRawParamsInvalid.Error(), e.Key)
+}
+
+func (e ErrParameterNotAllowed) Unwrap() error {
+	return brokerapi.ErrRawParamsInvalid
+}
+
+type ParameterValidator struct {
+	allowed  map[string]bool
+	defaults map[string]interface{}
+}
+
+// NewParameterValidator parses a comma separated list of allowed keys, e.g.
+// "uid,gid", and a comma separated list of key:value defaults, e.g.
+// "auto_cache:true". Default values are decoded as JSON where possible so
+// that "true" becomes a bool; anything else is kept as a string.
+func NewParameterValidator(allowedOptions string, defaultOptions string) (*ParameterValidator, error) {
+	v := &ParameterValidator{
+		allowed:  map[string]bool{},
+		defaults: map[string]interface{}{},
+	}
+
+	for _, key := range strings.Split(allowedOptions, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			v.allowed[key] = true
+		}
+	}
+
+	for _, option := range strings.Split(defaultOptions, ",") {
+		if option = strings.TrimSpace(option); option == "" {
+			continue
+		}
+		kv := strings.SplitN(option, ":", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, fmt.Errorf("invalid default option %q, expected param:value", option)
+		}
+
+		var value interface{}
+		if err := json.Unmarshal([]byte(kv[1]), &value); err != nil {
+			value = kv[1]
+		}
+		v.defaults[kv[0]] = value
+	}
+
+	return v, nil
+}
+
+func (v *ParameterValidator) Allowed(key string) bool {
+	return v.allowed[key]
+}
+
+// Validate rejects keys that are not allowed and returns a new map with the
+// defaults filled in for any key that was not given.
+func (v *ParameterValidator) Validate(params map[string]interface{}) (map[string]interface{}, error) {
+	merged := make(map[string]interface{}, len(params)+len(v.defaults))
+
+	for key, value := range params {
+		if !v.allowed[key] && !contains(bindParameters, key) {
+			return nil, ErrParameterNotAllowed{Key: key}
+		}
+		merged[key] = value
+	}
+
+	for key, value := range v.defaults {
+		if _, ok := merged[key]; !ok {
+			merged[key] = value
+		}
+	}
+
+	return merged, nil
+}
@@ -0,0 +1,78 @@
+package k8sbroker_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/brokerapi"
+
+	. "code.cloudfoundry.org/k8sbroker/k8sbroker"
+)
+
+var _ = Describe("ParameterValidator", func() {
+	var (
+		validator *ParameterValidator
+		err       error
+	)
+
+	BeforeEach(func() {
+		validator, err = NewParameterValidator("auto_cache,uid,gid", "auto_cache:true,uid:1000,sloppy_mount:true")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	Describe("NewParameterValidator", func() {
+		It("errors when a default is not param:value", func() {
+			_, err = NewParameterValidator("uid", "uid")
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("accepts empty lists", func() {
+			validator, err = NewParameterValidator("", "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(validator.Allowed("uid")).To(BeFalse())
+		})
+	})
+
+	Describe("Validate", func() {
+		It("applies defaults for missing keys", func() {
+			params, err := validator.Validate(map[string]interface{}{"gid": "2000"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(params).To(Equal(map[string]interface{}{
+				"gid":          "2000",
+				"auto_cache":   true,
+				"uid":          float64(1000),
+				"sloppy_mount": true,
+			}))
+		})
+
+		It("lets allowed keys override defaults", func() {
+			params, err := validator.Validate(map[string]interface{}{"uid": "2000"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(params).To(HaveKeyWithValue("uid", "2000"))
+		})
+
+		It("does not modify the given params", func() {
+			given := map[string]interface{}{"gid": "2000"}
+			_, err := validator.Validate(given)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(given).To(Equal(map[string]interface{}{"gid": "2000"}))
+		})
+
+		It("rejects keys that are not allowed", func() {
+			_, err := validator.Validate(map[string]interface{}{"key": "value"})
+			Expect(err).To(Equal(ErrParameterNotAllowed{Key: "key"}))
+			Expect(errors.Is(err, brokerapi.ErrRawParamsInvalid)).To(BeTrue())
+		})
+
+		It("rejects defaults that are not allowed to be overridden", func() {
+			_, err := validator.Validate(map[string]interface{}{"sloppy_mount": false})
+			Expect(err).To(Equal(ErrParameterNotAllowed{Key: "sloppy_mount"}))
+		})
+
+		It("always allows the broker's own bind parameters", func() {
+			_, err := validator.Validate(map[string]interface{}{"mount": "/data", "readonly": true, "access_mode": "ROX"})
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,40 @@
+package k8sbroker
+
+import (
+	"errors"
+	"net"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+const (
+	retryAttempts = 3
+	retryBackoff  = time.Second
+)
+
+// withRetry calls fn up to attempts times, sleeping backoff between the first
+// two calls and doubling it after each one. Only errors that a restarting or
+// overloaded API server returns are retried.
+func (b *Broker) withRetry(attempts int, backoff time.Duration, fn func() error) error {
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = fn()
+		if err == nil || attempt >= attempts || !isTransient(err) {
+			return err
+		}
+
+		b.logger.Info("retrying-transient-error", lager.Data{"attempt": attempt, "error": err.Error()})
+		b.clock.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+func isTransient(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return k8serrors.IsServerTimeout(err) || k8serrors.IsServiceUnavailable(err)
+}
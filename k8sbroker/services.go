@@ -1,36 +1,481 @@
 package k8sbroker
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/pivotal-cf/brokerapi"
+	"github.com/xeipuuv/gojsonschema"
+	"google.golang.org/grpc/credentials"
+	v1 "k8s.io/api/core/v1"
 )
 
+// PlanUpgradeChecker reports whether an instance may move from one plan to
+// another.
+type PlanUpgradeChecker interface {
+	CanUpgrade(fromPlanID, toPlanID string) bool
+}
+
 //go:generate counterfeiter -o k8sbroker_fake/fake_services.go . Services
 type Services interface {
+	PlanUpgradeChecker
 	List() []brokerapi.Service
+	DriverName(serviceID, planID string) string
+	ReclaimPolicy(planID string) string
+	ValidateParameters(serviceID string, rawParameters json.RawMessage) error
+	ModeMapper(serviceID string) ModeMapper
+	TestAllConnections(ctx context.Context, timeout time.Duration) error
+	ValidateService(serviceID string, timeout time.Duration) error
+	ConnectionHealthCheck() map[string]error
+	Reload(pathToServicesConfig string) error
+}
+
+type ErrDuplicateServiceID struct {
+	ID string
+}
+
+func (e ErrDuplicateServiceID) Error() string {
+	return fmt.Sprintf("duplicate service id %s", e.ID)
+}
+
+type ErrDuplicatePlanID struct {
+	ServiceID string
+	PlanID    string
+}
+
+func (e ErrDuplicatePlanID) Error() string {
+	return fmt.Sprintf("duplicate plan id %s in service %s", e.PlanID, e.ServiceID)
+}
+
+// ErrPlanIDInUse is a plan ID used by more than one service. The settings in
+// a plan's plan_metadata are looked up by plan ID alone, so plan IDs must be
+// unique across the catalog, as the Open Service Broker API requires.
+type ErrPlanIDInUse struct {
+	PlanID         string
+	ServiceID      string
+	OtherServiceID string
+}
+
+func (e ErrPlanIDInUse) Error() string {
+	return fmt.Sprintf("plan id %s of service %s is also used by service %s", e.PlanID, e.ServiceID, e.OtherServiceID)
+}
+
+type ErrInvalidPlanReclaimPolicy struct {
+	PlanID string
+	Policy string
+}
+
+func (e ErrInvalidPlanReclaimPolicy) Error() string {
+	return fmt.Sprintf("invalid reclaim_policy %q in plan %s, must be one of Retain or Delete", e.Policy, e.PlanID)
+}
+
+type ErrInvalidAccessMode struct {
+	ServiceID string
+	Mode      string
+}
+
+func (e ErrInvalidAccessMode) Error() string {
+	return fmt.Sprintf("invalid access mode %q in service %s, must be one of RWX, ROX, RWO or RWOP", e.Mode, e.ServiceID)
+}
+
+// planConfig reads the broker specific settings of a plan, which
+// brokerapi.ServicePlan does not keep.
+type planConfig struct {
+	ID           string `json:"id"`
+	PlanMetadata struct {
+		DriverName      string   `json:"driver_name"`
+		ReclaimPolicy   string   `json:"reclaim_policy"`
+		UpgradeableFrom []string `json:"upgradeable_from"`
+	} `json:"plan_metadata"`
+}
+
+type catalog struct {
+	services           []brokerapi.Service
+	serviceDriverNames map[string]string
+	planDriverNames    map[string]string
+	planReclaimPolicy  map[string]string
+	planUpgrades       map[string][]string
+	parameterSchemas   map[string]*gojsonschema.Schema
+	connAddrs          map[string]string
+	connCredentials    map[string]credentials.TransportCredentials
+	accessModes        map[string]AccessModes
+}
+
+// serviceConfig is a service read from a services config together with the
+// broker specific settings of its plans.
+type serviceConfig struct {
+	Service
+	plans []planConfig
 }
 
 type services struct {
-	services []brokerapi.Service
+	mutex   sync.RWMutex
+	fs      http.FileSystem
+	dir     string
+	catalog catalog
 }
 
 func NewServicesFromConfig(pathToServicesConfig string) (Services, error) {
-	contents, err := ioutil.ReadFile(pathToServicesConfig)
+	return NewServicesFromFS(osFileSystem{}, pathToServicesConfig)
+}
+
+// NewServicesFromConfigDir merges the services of every *.json file in
+// servicesConfigDir with those of pathToServicesConfig, either of which may be
+// empty. A service ID may appear in more than one file only if every
+// definition is the same. Reload reads the directory again.
+func NewServicesFromConfigDir(pathToServicesConfig, servicesConfigDir string) (Services, error) {
+	c, err := readServicesConfig(osFileSystem{}, pathToServicesConfig, servicesConfigDir)
 	if err != nil {
 		return nil, err
 	}
 
-	var s []brokerapi.Service
-	err = json.Unmarshal(contents, &s)
+	return &services{fs: osFileSystem{}, dir: servicesConfigDir, catalog: c}, nil
+}
+
+// NewServicesFromFS reads the services config at path from fs, e.g. a config
+// embedded in the binary. Reload reads from the same fs.
+func NewServicesFromFS(fs http.FileSystem, path string) (Services, error) {
+	c, err := readServicesConfig(fs, path, "")
 	if err != nil {
 		return nil, err
 	}
 
-	return &services{s}, nil
+	return &services{fs: fs, catalog: c}, nil
+}
+
+// osFileSystem opens paths as given, where http.Dir would confine them to a
+// root directory.
+type osFileSystem struct{}
+
+func (osFileSystem) Open(name string) (http.File, error) {
+	return os.Open(name)
 }
 
 func (s *services) List() []brokerapi.Service {
-	return s.services
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.catalog.services
+}
+
+// DriverName returns the plan's driver_name from its plan_metadata, falling
+// back to the service's driver_name.
+func (s *services) DriverName(serviceID, planID string) string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if driverName, ok := s.catalog.planDriverNames[planID]; ok {
+		return driverName
+	}
+	return s.catalog.serviceDriverNames[serviceID]
+}
+
+// ReclaimPolicy returns the plan's reclaim_policy from its plan_metadata,
+// which provision uses unless the parameters set one.
+func (s *services) ReclaimPolicy(planID string) string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.catalog.planReclaimPolicy[planID]
+}
+
+// CanUpgrade reports whether an instance may move to toPlanID from
+// fromPlanID, which the upgradeable_from list in the plan_metadata of
+// toPlanID must then name. A plan without the list accepts instances from any
+// plan.
+func (s *services) CanUpgrade(fromPlanID, toPlanID string) bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	upgradeableFrom, ok := s.catalog.planUpgrades[toPlanID]
+	if !ok || fromPlanID == toPlanID {
+		return true
+	}
+	return contains(upgradeableFrom, fromPlanID)
+}
+
+// ValidateParameters checks provision parameters against the JSON schema at
+// the service's parameters_schema_path, returning every violation as
+// ValidationErrors. Services without a schema accept any parameters.
+func (s *services) ValidateParameters(serviceID string, rawParameters json.RawMessage) error {
+	s.mutex.RLock()
+	schema, ok := s.catalog.parameterSchemas[serviceID]
+	s.mutex.RUnlock()
+	if !ok {
+		return nil
+	}
+
+	if len(rawParameters) == 0 {
+		rawParameters = json.RawMessage("{}")
+	}
+	result, err := schema.Validate(gojsonschema.NewBytesLoader(rawParameters))
+	if err != nil {
+		return brokerapi.ErrRawParamsInvalid
+	}
+	if result.Valid() {
+		return nil
+	}
+
+	var errs ValidationErrors
+	for _, resultError := range result.Errors() {
+		errs = append(errs, ValidationError{Field: resultError.Field(), Message: resultError.String()})
+	}
+	return errs
+}
+
+// ModeMapper returns the service's access_modes, or DefaultAccessModes for a
+// service without them.
+func (s *services) ModeMapper(serviceID string) ModeMapper {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if modes, ok := s.catalog.accessModes[serviceID]; ok {
+		return modes
+	}
+	return DefaultAccessModes
+}
+
+// Reload re-reads the services config. The current services are kept if the
+// config cannot be read.
+func (s *services) Reload(pathToServicesConfig string) error {
+	loaded, err := readServicesConfig(s.fs, pathToServicesConfig, s.dir)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.catalog = loaded
+	return nil
+}
+
+func readServicesConfig(fs http.FileSystem, pathToServicesConfig, servicesConfigDir string) (catalog, error) {
+	var configs []serviceConfig
+	if pathToServicesConfig != "" {
+		var err error
+		configs, err = readServicesFile(fs, pathToServicesConfig)
+		if err != nil {
+			return catalog{}, err
+		}
+	}
+
+	if servicesConfigDir != "" {
+		paths, err := listServicesFiles(fs, servicesConfigDir)
+		if err != nil {
+			return catalog{}, err
+		}
+		for _, p := range paths {
+			fileConfigs, err := readServicesFile(fs, p)
+			if err != nil {
+				return catalog{}, err
+			}
+			configs, err = mergeServices(configs, fileConfigs)
+			if err != nil {
+				return catalog{}, err
+			}
+		}
+	}
+
+	c := catalog{
+		serviceDriverNames: map[string]string{},
+		planDriverNames:    map[string]string{},
+		planReclaimPolicy:  map[string]string{},
+		planUpgrades:       map[string][]string{},
+		parameterSchemas:   map[string]*gojsonschema.Schema{},
+		connAddrs:          map[string]string{},
+		connCredentials:    map[string]credentials.TransportCredentials{},
+		accessModes:        map[string]AccessModes{},
+	}
+	planServices := map[string]string{}
+	for _, service := range configs {
+		c.services = append(c.services, service.Service.Service)
+		if service.DriverName != "" {
+			c.serviceDriverNames[service.ID] = service.DriverName
+		}
+		if service.ConnAddr != "" {
+			c.connAddrs[service.ID] = service.ConnAddr
+			creds, err := readTransportCredentials(fs, service.Service)
+			if err != nil {
+				return catalog{}, err
+			}
+			if creds != nil {
+				c.connCredentials[service.ID] = creds
+			}
+		}
+		if service.AccessModes != nil {
+			c.accessModes[service.ID] = *service.AccessModes
+		}
+		if service.ParametersSchemaPath != "" {
+			schema, err := readParametersSchema(fs, service.ParametersSchemaPath)
+			if err != nil {
+				return catalog{}, err
+			}
+			c.parameterSchemas[service.ID] = schema
+		}
+		for _, plan := range service.plans {
+			if other, ok := planServices[plan.ID]; ok && other != service.ID {
+				return catalog{}, ErrPlanIDInUse{PlanID: plan.ID, ServiceID: service.ID, OtherServiceID: other}
+			}
+			planServices[plan.ID] = service.ID
+
+			if plan.PlanMetadata.DriverName != "" {
+				c.planDriverNames[plan.ID] = plan.PlanMetadata.DriverName
+			}
+			if plan.PlanMetadata.UpgradeableFrom != nil {
+				c.planUpgrades[plan.ID] = plan.PlanMetadata.UpgradeableFrom
+			}
+			switch v1.PersistentVolumeReclaimPolicy(plan.PlanMetadata.ReclaimPolicy) {
+			case "":
+			case v1.PersistentVolumeReclaimRetain, v1.PersistentVolumeReclaimDelete:
+				c.planReclaimPolicy[plan.ID] = plan.PlanMetadata.ReclaimPolicy
+			default:
+				return catalog{}, ErrInvalidPlanReclaimPolicy{PlanID: plan.ID, Policy: plan.PlanMetadata.ReclaimPolicy}
+			}
+		}
+	}
+
+	return c, nil
+}
+
+func readServicesFile(fs http.FileSystem, pathToServicesConfig string) ([]serviceConfig, error) {
+	file, err := fs.Open(pathToServicesConfig)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	contents, err := ioutil.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var s []Service
+	err = json.Unmarshal(contents, &s)
+	if err != nil {
+		return nil, err
+	}
+
+	err = validateServices(s)
+	if err != nil {
+		return nil, err
+	}
+
+	var plans []struct {
+		Plans []planConfig `json:"plans"`
+	}
+	err = json.Unmarshal(contents, &plans)
+	if err != nil {
+		return nil, err
+	}
+
+	configs := make([]serviceConfig, len(s))
+	for i, service := range s {
+		configs[i] = serviceConfig{Service: service, plans: plans[i].Plans}
+	}
+	return configs, nil
+}
+
+// listServicesFiles returns the *.json files in dir in name order, so that
+// the catalog order does not depend on the filesystem.
+func listServicesFiles(fs http.FileSystem, dir string) ([]string, error) {
+	file, err := fs.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	infos, err := file.Readdir(-1)
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for _, info := range infos {
+		if !info.IsDir() && strings.HasSuffix(info.Name(), ".json") {
+			paths = append(paths, path.Join(dir, info.Name()))
+		}
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// mergeServices appends the services of another file, dropping exact
+// duplicates of services that were already read.
+func mergeServices(configs, more []serviceConfig) ([]serviceConfig, error) {
+	for _, service := range more {
+		duplicate := false
+		for _, existing := range configs {
+			if existing.ID != service.ID {
+				continue
+			}
+			if !reflect.DeepEqual(existing, service) {
+				return nil, ErrDuplicateServiceID{ID: service.ID}
+			}
+			duplicate = true
+		}
+		if !duplicate {
+			configs = append(configs, service)
+		}
+	}
+	return configs, nil
+}
+
+// readParametersSchema opens the schema on the same filesystem as the services
+// config.
+func readParametersSchema(fs http.FileSystem, path string) (*gojsonschema.Schema, error) {
+	file, err := fs.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	contents, err := ioutil.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+
+	schema, err := gojsonschema.NewSchema(gojsonschema.NewBytesLoader(contents))
+	if err != nil {
+		return nil, fmt.Errorf("invalid parameters schema %s: %s", path, err)
+	}
+	return schema, nil
+}
+
+func validateServices(s []Service) error {
+	seenServices := map[string]bool{}
+	for _, service := range s {
+		if seenServices[service.ID] {
+			return ErrDuplicateServiceID{ID: service.ID}
+		}
+		seenServices[service.ID] = true
+
+		if modes := service.AccessModes; modes != nil {
+			if _, ok := accessModes[modes.ReadWrite]; !ok {
+				return ErrInvalidAccessMode{ServiceID: service.ID, Mode: modes.ReadWrite}
+			}
+			if _, ok := accessModes[modes.ReadOnly]; !ok && modes.ReadOnly != "" {
+				return ErrInvalidAccessMode{ServiceID: service.ID, Mode: modes.ReadOnly}
+			}
+		}
+
+		seenPlans := map[string]bool{}
+		for _, plan := range service.Plans {
+			if seenPlans[plan.ID] {
+				return ErrDuplicatePlanID{ServiceID: service.ID, PlanID: plan.ID}
+			}
+			seenPlans[plan.ID] = true
+		}
+	}
+	return nil
 }
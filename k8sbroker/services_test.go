@@ -1,9 +1,17 @@
 package k8sbroker_test
 
 import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing/fstest"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"github.com/pivotal-cf/brokerapi"
+	v1 "k8s.io/api/core/v1"
 
 	. "code.cloudfoundry.org/k8sbroker/k8sbroker"
 )
@@ -19,6 +27,143 @@ var _ = Describe("Services", func() {
 		Expect(err).NotTo(HaveOccurred())
 	})
 
+	Describe("NewServicesFromConfig", func() {
+		It("rejects duplicate service IDs", func() {
+			_, err := NewServicesFromConfig("fixtures/duplicate_service_ids.json")
+			Expect(err).To(Equal(ErrDuplicateServiceID{ID: "db404fc5-97fb-4806-9827-07e0e8d3bd51"}))
+		})
+
+		It("rejects duplicate plan IDs within a service", func() {
+			_, err := NewServicesFromConfig("fixtures/duplicate_plan_ids.json")
+			Expect(err).To(Equal(ErrDuplicatePlanID{
+				ServiceID: "db404fc5-97fb-4806-9827-07e0e8d3bd51",
+				PlanID:    "190de554-4fc1-4008-ace9-5d3796140b48",
+			}))
+		})
+
+		It("rejects a plan ID used by two services", func() {
+			_, err := NewServicesFromFS(http.FS(fstest.MapFS{
+				"services.json": &fstest.MapFile{Data: []byte(`[
+					{"id": "nfs-service-id", "name": "nfs", "plans": [{"id": "shared-plan-id", "name": "Existing", "plan_metadata": {"reclaim_policy": "Retain"}}]},
+					{"id": "csi-service-id", "name": "csi", "plans": [{"id": "shared-plan-id", "name": "Existing", "plan_metadata": {"reclaim_policy": "Delete"}}]}
+				]`)},
+			}), "services.json")
+			Expect(err).To(Equal(ErrPlanIDInUse{PlanID: "shared-plan-id", ServiceID: "csi-service-id", OtherServiceID: "nfs-service-id"}))
+			Expect(err).To(MatchError("plan id shared-plan-id of service csi-service-id is also used by service nfs-service-id"))
+		})
+	})
+
+	Describe("NewServicesFromFS", func() {
+		var fs http.FileSystem
+
+		BeforeEach(func() {
+			fs = http.FS(fstest.MapFS{
+				"config/services.json": &fstest.MapFile{Data: []byte(`[{
+					"id": "embedded-service-id",
+					"name": "nfs",
+					"plans": [{"id": "embedded-plan-id", "name": "Embedded", "plan_metadata": {"driver_name": "embedded-driver"}}]
+				}]`)},
+				"config/reloaded.json": &fstest.MapFile{Data: []byte(`[{"id": "reloaded-service-id", "name": "nfs"}]`)},
+			})
+		})
+
+		It("reads the services config from the filesystem", func() {
+			services, err := NewServicesFromFS(fs, "config/services.json")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(services.List()).To(HaveLen(1))
+			Expect(services.List()[0].ID).To(Equal("embedded-service-id"))
+			Expect(services.DriverName("embedded-service-id", "embedded-plan-id")).To(Equal("embedded-driver"))
+		})
+
+		It("reloads from the same filesystem", func() {
+			services, err := NewServicesFromFS(fs, "config/services.json")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(services.Reload("config/reloaded.json")).To(Succeed())
+			Expect(services.List()[0].ID).To(Equal("reloaded-service-id"))
+		})
+
+		It("errors when the config is not in the filesystem", func() {
+			_, err := NewServicesFromFS(fs, "config/missing.json")
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		})
+	})
+
+	Describe("NewServicesFromConfigDir", func() {
+		var (
+			dir string
+			err error
+		)
+
+		writeConfig := func(name, contents string) {
+			Expect(ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0644)).To(Succeed())
+		}
+
+		BeforeEach(func() {
+			dir, err = ioutil.TempDir("", "services-config-dir")
+			Expect(err).NotTo(HaveOccurred())
+
+			writeConfig("b.json", `[{"id": "service-b", "name": "nfs-b", "plans": [{"id": "plan-b", "name": "B", "plan_metadata": {"driver_name": "driver-b"}}]}]`)
+			writeConfig("a.json", `[{"id": "service-a", "name": "nfs-a"}]`)
+			writeConfig("README.md", `not a services config`)
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(dir)
+		})
+
+		It("merges the json files in name order", func() {
+			services, err = NewServicesFromConfigDir("", dir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(services.List()).To(HaveLen(2))
+			Expect(services.List()[0].ID).To(Equal("service-a"))
+			Expect(services.List()[1].ID).To(Equal("service-b"))
+			Expect(services.DriverName("service-b", "plan-b")).To(Equal("driver-b"))
+		})
+
+		It("adds the services of the config file first", func() {
+			services, err = NewServicesFromConfigDir("../default_services.json", dir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(services.List()).To(HaveLen(3))
+			Expect(services.List()[0].ID).To(Equal("db404fc5-97fb-4806-9827-07e0e8d3bd51"))
+		})
+
+		It("keeps one copy of a service defined identically in two files", func() {
+			writeConfig("c.json", `[{"id": "service-a", "name": "nfs-a"}]`)
+
+			services, err = NewServicesFromConfigDir("", dir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(services.List()).To(HaveLen(2))
+		})
+
+		It("rejects a service ID defined differently in two files", func() {
+			writeConfig("c.json", `[{"id": "service-a", "name": "other-nfs"}]`)
+
+			_, err = NewServicesFromConfigDir("", dir)
+			Expect(err).To(Equal(ErrDuplicateServiceID{ID: "service-a"}))
+		})
+
+		It("rejects a plan ID used by services in two files", func() {
+			writeConfig("c.json", `[{"id": "service-c", "name": "nfs-c", "plans": [{"id": "plan-b", "name": "C"}]}]`)
+
+			_, err = NewServicesFromConfigDir("", dir)
+			Expect(err).To(Equal(ErrPlanIDInUse{PlanID: "plan-b", ServiceID: "service-c", OtherServiceID: "service-b"}))
+		})
+
+		It("reads the directory again on reload", func() {
+			services, err = NewServicesFromConfigDir("", dir)
+			Expect(err).NotTo(HaveOccurred())
+
+			writeConfig("c.json", `[{"id": "service-c", "name": "nfs-c"}]`)
+			Expect(services.Reload("")).To(Succeed())
+			Expect(services.List()).To(HaveLen(3))
+		})
+
+		It("errors when the directory does not exist", func() {
+			_, err = NewServicesFromConfigDir("", filepath.Join(dir, "missing"))
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		})
+	})
+
 	Describe("List", func() {
 		It("returns the list of services", func() {
 			Expect(services.List()).To(Equal([]brokerapi.Service{
@@ -42,4 +187,294 @@ var _ = Describe("Services", func() {
 			}))
 		})
 	})
+
+	Describe("DriverName", func() {
+		var configPath string
+
+		BeforeEach(func() {
+			configPath = filepath.Join(os.TempDir(), "driver-services.json")
+			Expect(ioutil.WriteFile(configPath, []byte(`[{
+				"id": "some-service-id",
+				"name": "nfs",
+				"driver_name": "service-driver",
+				"plans": [
+					{"id": "in-cluster-plan-id", "name": "InCluster"},
+					{"id": "cloud-plan-id", "name": "Cloud", "plan_metadata": {"driver_name": "cloud-driver"}}
+				]
+			}]`), 0644)).To(Succeed())
+
+			var err error
+			services, err = NewServicesFromConfig(configPath)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			os.Remove(configPath)
+		})
+
+		It("uses the plan's driver name", func() {
+			Expect(services.DriverName("some-service-id", "cloud-plan-id")).To(Equal("cloud-driver"))
+		})
+
+		It("falls back to the service's driver name", func() {
+			Expect(services.DriverName("some-service-id", "in-cluster-plan-id")).To(Equal("service-driver"))
+		})
+
+		It("is empty for an unknown service", func() {
+			Expect(services.DriverName("other-service-id", "other-plan-id")).To(BeEmpty())
+		})
+
+		It("still lists the plans", func() {
+			Expect(services.List()[0].Plans).To(HaveLen(2))
+		})
+	})
+
+	Describe("ReclaimPolicy", func() {
+		var (
+			configPath string
+			err        error
+		)
+
+		BeforeEach(func() {
+			configPath = filepath.Join(os.TempDir(), "reclaim-services.json")
+			Expect(ioutil.WriteFile(configPath, []byte(`[{
+				"id": "some-service-id",
+				"name": "nfs",
+				"plans": [
+					{"id": "default-plan-id", "name": "Default"},
+					{"id": "scratch-plan-id", "name": "Scratch", "plan_metadata": {"reclaim_policy": "Delete"}}
+				]
+			}]`), 0644)).To(Succeed())
+		})
+
+		JustBeforeEach(func() {
+			services, err = NewServicesFromConfig(configPath)
+		})
+
+		AfterEach(func() {
+			os.Remove(configPath)
+		})
+
+		It("returns the plan's reclaim policy", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(services.ReclaimPolicy("scratch-plan-id")).To(Equal("Delete"))
+		})
+
+		It("is empty for a plan without one", func() {
+			Expect(services.ReclaimPolicy("default-plan-id")).To(BeEmpty())
+		})
+
+		Context("when a plan's reclaim policy is invalid", func() {
+			BeforeEach(func() {
+				Expect(ioutil.WriteFile(configPath, []byte(`[{
+					"id": "some-service-id",
+					"name": "nfs",
+					"plans": [{"id": "scratch-plan-id", "name": "Scratch", "plan_metadata": {"reclaim_policy": "Shred"}}]
+				}]`), 0644)).To(Succeed())
+			})
+
+			It("rejects the config", func() {
+				Expect(err).To(Equal(ErrInvalidPlanReclaimPolicy{PlanID: "scratch-plan-id", Policy: "Shred"}))
+			})
+		})
+
+		Context("when a plan's reclaim policy is the deprecated Recycle", func() {
+			BeforeEach(func() {
+				Expect(ioutil.WriteFile(configPath, []byte(`[{
+					"id": "some-service-id",
+					"name": "nfs",
+					"plans": [{"id": "scratch-plan-id", "name": "Scratch", "plan_metadata": {"reclaim_policy": "Recycle"}}]
+				}]`), 0644)).To(Succeed())
+			})
+
+			It("rejects the config", func() {
+				Expect(err).To(Equal(ErrInvalidPlanReclaimPolicy{PlanID: "scratch-plan-id", Policy: "Recycle"}))
+			})
+		})
+	})
+
+	Describe("CanUpgrade", func() {
+		BeforeEach(func() {
+			var err error
+			services, err = NewServicesFromFS(http.FS(fstest.MapFS{
+				"services.json": &fstest.MapFile{Data: []byte(`[{
+					"id": "some-service-id",
+					"name": "nfs",
+					"plans": [
+						{"id": "small-plan-id", "name": "Small"},
+						{"id": "large-plan-id", "name": "Large", "plan_metadata": {"upgradeable_from": ["small-plan-id"]}},
+						{"id": "block-plan-id", "name": "Block", "plan_metadata": {"upgradeable_from": []}}
+					]
+				}]`)},
+			}), "services.json")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("allows moving from a plan the new plan lists", func() {
+			Expect(services.CanUpgrade("small-plan-id", "large-plan-id")).To(BeTrue())
+		})
+
+		It("refuses moving from a plan the new plan does not list", func() {
+			Expect(services.CanUpgrade("block-plan-id", "large-plan-id")).To(BeFalse())
+			Expect(services.CanUpgrade("small-plan-id", "block-plan-id")).To(BeFalse())
+		})
+
+		It("allows moving to a plan without upgradeable_from", func() {
+			Expect(services.CanUpgrade("large-plan-id", "small-plan-id")).To(BeTrue())
+		})
+
+		It("allows staying on the same plan", func() {
+			Expect(services.CanUpgrade("block-plan-id", "block-plan-id")).To(BeTrue())
+		})
+	})
+
+	Describe("ModeMapper", func() {
+		var (
+			servicesJSON string
+			err          error
+		)
+
+		BeforeEach(func() {
+			servicesJSON = `[
+				{"id": "block-service-id", "name": "block", "access_modes": {"read_write": "RWO"}},
+				{"id": "nfs-service-id", "name": "nfs"}
+			]`
+		})
+
+		JustBeforeEach(func() {
+			services, err = NewServicesFromFS(http.FS(fstest.MapFS{
+				"services.json": &fstest.MapFile{Data: []byte(servicesJSON)},
+			}), "services.json")
+		})
+
+		It("maps bindings with the service's access modes", func() {
+			Expect(err).NotTo(HaveOccurred())
+			mode, accessMode, err := services.ModeMapper("block-service-id").MapMode(false)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mode).To(Equal("rw"))
+			Expect(accessMode).To(Equal(v1.ReadWriteOnce))
+
+			_, _, err = services.ModeMapper("block-service-id").MapMode(true)
+			Expect(err).To(Equal(ErrReadOnlyUnsupported))
+		})
+
+		It("maps bindings of services without access modes to RWX and ROX", func() {
+			mode, accessMode, err := services.ModeMapper("nfs-service-id").MapMode(true)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mode).To(Equal("r"))
+			Expect(accessMode).To(Equal(v1.ReadOnlyMany))
+			Expect(services.ModeMapper("other-service-id")).To(Equal(DefaultAccessModes))
+		})
+
+		Context("when an access mode is invalid", func() {
+			BeforeEach(func() {
+				servicesJSON = `[{"id": "block-service-id", "name": "block", "access_modes": {"read_write": "RWO", "read_only": "RO"}}]`
+			})
+
+			It("rejects the config", func() {
+				Expect(err).To(Equal(ErrInvalidAccessMode{ServiceID: "block-service-id", Mode: "RO"}))
+			})
+		})
+	})
+
+	Describe("ValidateParameters", func() {
+		BeforeEach(func() {
+			var err error
+			services, err = NewServicesFromConfig("fixtures/services_with_parameters_schema.json")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("accepts parameters that match the service's schema", func() {
+			Expect(services.ValidateParameters("db404fc5-97fb-4806-9827-07e0e8d3bd51", json.RawMessage(`{"share": "/export/some-share", "capacity_range": {"requiredBytes": 1024}}`))).To(Succeed())
+		})
+
+		It("accepts missing parameters when the schema allows them", func() {
+			Expect(services.ValidateParameters("db404fc5-97fb-4806-9827-07e0e8d3bd51", nil)).To(Succeed())
+		})
+
+		It("lists every schema violation", func() {
+			err := services.ValidateParameters("db404fc5-97fb-4806-9827-07e0e8d3bd51", json.RawMessage(`{"share": "export", "capacity_range": {"requiredBytes": -1}}`))
+			Expect(err).To(BeAssignableToTypeOf(ValidationErrors{}))
+
+			fields := []string{}
+			for _, validationError := range err.(ValidationErrors) {
+				fields = append(fields, validationError.Field)
+			}
+			Expect(fields).To(ConsistOf("share", "capacity_range.requiredBytes"))
+		})
+
+		It("accepts any parameters for a service without a schema", func() {
+			Expect(services.ValidateParameters("other-service-id", json.RawMessage(`{"share": "export"}`))).To(Succeed())
+		})
+
+		Context("when the schema file does not exist", func() {
+			var configPath string
+
+			BeforeEach(func() {
+				configPath = filepath.Join(os.TempDir(), "missing-schema-services.json")
+				Expect(ioutil.WriteFile(configPath, []byte(`[{"id": "some-service-id", "name": "nfs", "parameters_schema_path": "fixtures/missing.json"}]`), 0644)).To(Succeed())
+			})
+
+			AfterEach(func() {
+				os.Remove(configPath)
+			})
+
+			It("rejects the config", func() {
+				_, err := NewServicesFromConfig(configPath)
+				Expect(os.IsNotExist(err)).To(BeTrue())
+			})
+		})
+	})
+
+	Describe("Reload", func() {
+		var (
+			configPath string
+			err        error
+		)
+
+		BeforeEach(func() {
+			configPath = filepath.Join(os.TempDir(), "reload-services.json")
+			Expect(ioutil.WriteFile(configPath, []byte(`[{"id": "other-service-id", "name": "other", "plans": [{"id": "other-plan-id", "name": "Other"}]}]`), 0644)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			os.Remove(configPath)
+		})
+
+		JustBeforeEach(func() {
+			err = services.Reload(configPath)
+		})
+
+		It("replaces the list of services", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(services.List()).To(Equal([]brokerapi.Service{
+				{
+					ID:    "other-service-id",
+					Name:  "other",
+					Plans: []brokerapi.ServicePlan{{ID: "other-plan-id", Name: "Other"}},
+				},
+			}))
+		})
+
+		Context("when the config is invalid", func() {
+			BeforeEach(func() {
+				Expect(ioutil.WriteFile(configPath, []byte(`not-json`), 0644)).To(Succeed())
+			})
+
+			It("errors and keeps the current services", func() {
+				Expect(err).To(HaveOccurred())
+				Expect(services.List()[0].Name).To(Equal("nfs"))
+			})
+		})
+
+		Context("when the config does not exist", func() {
+			BeforeEach(func() {
+				configPath = filepath.Join(os.TempDir(), "missing-services.json")
+			})
+
+			It("errors", func() {
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
 })
@@ -0,0 +1,311 @@
+package k8sbroker
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// PVOption sets an optional field of the volume built by
+// BuildPersistentVolume.
+type PVOption func(*v1.PersistentVolume)
+
+// BuildPersistentVolume returns the ReadWriteMany NFS volume the broker
+// provisions for an instance. It is labelled with the instance name and as
+// managed by the broker, under keys starting with labelPrefix; labels given
+// with WithLabels cannot override those.
+func BuildPersistentVolume(name, labelPrefix, server, share string, capacity resource.Quantity, opts ...PVOption) *v1.PersistentVolume {
+	volume := &v1.PersistentVolume{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "PersistentVolume",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: map[string]string{labelPrefix + NameLabel: name, labelPrefix + ManagedByLabel: "k8sbroker"},
+		},
+
+		Spec: v1.PersistentVolumeSpec{
+			AccessModes: []v1.PersistentVolumeAccessMode{v1.ReadWriteMany},
+			Capacity:    v1.ResourceList{v1.ResourceName(v1.ResourceStorage): capacity},
+			PersistentVolumeSource: v1.PersistentVolumeSource{
+				NFS: &v1.NFSVolumeSource{
+					Server: server,
+					Path:   share,
+				},
+			},
+		},
+	}
+
+	for _, opt := range opts {
+		opt(volume)
+	}
+	return volume
+}
+
+func WithMountOptions(mountOptions []string) PVOption {
+	return func(volume *v1.PersistentVolume) {
+		volume.Spec.MountOptions = mountOptions
+	}
+}
+
+func WithNodeAffinity(nodeAffinity *v1.VolumeNodeAffinity) PVOption {
+	return func(volume *v1.PersistentVolume) {
+		volume.Spec.NodeAffinity = nodeAffinity
+	}
+}
+
+func WithReclaimPolicy(reclaimPolicy v1.PersistentVolumeReclaimPolicy) PVOption {
+	return func(volume *v1.PersistentVolume) {
+		volume.Spec.PersistentVolumeReclaimPolicy = reclaimPolicy
+	}
+}
+
+func WithLabels(labels map[string]string) PVOption {
+	return func(volume *v1.PersistentVolume) {
+		volume.Labels = mergeMetadata(labels, volume.Labels)
+	}
+}
+
+func WithAnnotations(annotations map[string]string) PVOption {
+	return func(volume *v1.PersistentVolume) {
+		volume.Annotations = annotations
+	}
+}
+
+func WithStorageClassName(storageClassName string) PVOption {
+	return func(volume *v1.PersistentVolume) {
+		volume.Spec.StorageClassName = storageClassName
+	}
+}
+
+// WithVolumeMode sets the volume's mode, leaving it to default to Filesystem
+// when empty.
+func WithVolumeMode(volumeMode v1.PersistentVolumeMode) PVOption {
+	return func(volume *v1.PersistentVolume) {
+		if volumeMode != "" {
+			volume.Spec.VolumeMode = &volumeMode
+		}
+	}
+}
+
+// PVCOption sets an optional field of the claim built by
+// BuildPersistentVolumeClaim.
+type PVCOption func(*v1.PersistentVolumeClaim)
+
+// BuildPersistentVolumeClaim returns a claim on the instance's volume in the
+// given namespace. The claim selects the volume by its name label and requests
+// the volume's whole capacity.
+func BuildPersistentVolumeClaim(name, namespace, labelPrefix string, fingerprint *ServiceFingerPrint, mode v1.PersistentVolumeAccessMode, opts ...PVCOption) *v1.PersistentVolumeClaim {
+	claim := &v1.PersistentVolumeClaim{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "PersistentVolumeClaim",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+
+		Spec: v1.PersistentVolumeClaimSpec{
+			AccessModes: []v1.PersistentVolumeAccessMode{mode},
+			Resources:   v1.ResourceRequirements{Requests: fingerprint.Volume.Spec.Capacity},
+			Selector: &metav1.LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{
+						Key:      nameLabelKey(fingerprint.Volume, labelPrefix),
+						Operator: metav1.LabelSelectorOpIn,
+						Values:   []string{fingerprint.Volume.Name},
+					},
+				},
+			},
+		},
+	}
+
+	for _, opt := range opts {
+		opt(claim)
+	}
+	return claim
+}
+
+// nameLabelKey is the key of the volume's name label. Volumes provisioned
+// before the label prefix was changed keep the key they were created with.
+func nameLabelKey(volume *v1.PersistentVolume, labelPrefix string) string {
+	if _, ok := volume.Labels[labelPrefix+NameLabel]; ok {
+		return labelPrefix + NameLabel
+	}
+	if _, ok := volume.Labels[NameLabel]; ok {
+		return NameLabel
+	}
+	return labelPrefix + NameLabel
+}
+
+// BuildDynamicPersistentVolumeClaim returns a claim for a dynamically
+// provisioned instance. The claim names the instance's storage class and
+// selects no volume, so that the class's provisioner creates one of the
+// requested capacity.
+func BuildDynamicPersistentVolumeClaim(name, namespace string, fingerprint *ServiceFingerPrint, mode v1.PersistentVolumeAccessMode, opts ...PVCOption) *v1.PersistentVolumeClaim {
+	storageClassName := fingerprint.StorageClassName
+	claim := &v1.PersistentVolumeClaim{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "PersistentVolumeClaim",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+
+		Spec: v1.PersistentVolumeClaimSpec{
+			AccessModes:      []v1.PersistentVolumeAccessMode{mode},
+			Resources:        v1.ResourceRequirements{Requests: v1.ResourceList{v1.ResourceStorage: requestedCapacity(fingerprint.CapacityRange)}},
+			StorageClassName: &storageClassName,
+		},
+	}
+
+	for _, opt := range opts {
+		opt(claim)
+	}
+	return claim
+}
+
+// requestedCapacity is the capacity given by a capacity range's
+// requiredBytes, or 5G when none is required.
+func requestedCapacity(capacityRange *CapacityRange) resource.Quantity {
+	if capacityRange != nil && capacityRange.RequiredBytes > 0 {
+		return *resource.NewQuantity(capacityRange.RequiredBytes, resource.BinarySI)
+	}
+	return resource.MustParse("5G")
+}
+
+// WithClaimStorageClass sets the claim's storage class, even when it is
+// empty: an empty class stops Kubernetes from assigning the default one.
+func WithClaimStorageClass(storageClassName string) PVCOption {
+	return func(claim *v1.PersistentVolumeClaim) {
+		claim.Spec.StorageClassName = &storageClassName
+	}
+}
+
+func WithClaimAnnotations(annotations map[string]string) PVCOption {
+	return func(claim *v1.PersistentVolumeClaim) {
+		claim.Annotations = annotations
+	}
+}
+
+// WithClaimVolumeMode sets the claim's mode, which must match the mode of the
+// volume it binds. An empty mode is left to default to Filesystem.
+func WithClaimVolumeMode(volumeMode v1.PersistentVolumeMode) PVCOption {
+	return func(claim *v1.PersistentVolumeClaim) {
+		if volumeMode != "" {
+			claim.Spec.VolumeMode = &volumeMode
+		}
+	}
+}
+
+func WithClaimLabels(labels map[string]string) PVCOption {
+	return func(claim *v1.PersistentVolumeClaim) {
+		claim.Labels = labels
+	}
+}
+
+// csiVolumeContext returns a copy of the attributes of a CSI volume, which the
+// node plugin needs at node-publish, or nil when the volume is not CSI.
+func csiVolumeContext(volume *v1.PersistentVolume) map[string]string {
+	if volume == nil || volume.Spec.CSI == nil || len(volume.Spec.CSI.VolumeAttributes) == 0 {
+		return nil
+	}
+
+	volumeContext := make(map[string]string, len(volume.Spec.CSI.VolumeAttributes))
+	for key, value := range volume.Spec.CSI.VolumeAttributes {
+		volumeContext[key] = value
+	}
+	return volumeContext
+}
+
+// reservedVolumeAttributes are the keys of the NFS volume source, which extra
+// volume attributes may not set.
+var reservedVolumeAttributes = []string{"server", "share"}
+
+// ParseExtraVolumeAttributes parses a comma separated list of key:value volume
+// attributes, e.g. "provisioner:nfs.csi.k8s.io", that the broker adds to the
+// volume context of every instance.
+func ParseExtraVolumeAttributes(value string) (map[string]string, error) {
+	attributes := map[string]string{}
+	for _, attribute := range strings.Split(value, ",") {
+		if attribute = strings.TrimSpace(attribute); attribute == "" {
+			continue
+		}
+		kv := strings.SplitN(attribute, ":", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, fmt.Errorf("invalid volume attribute %q, expected key:value", attribute)
+		}
+		if contains(reservedVolumeAttributes, kv[0]) {
+			return nil, fmt.Errorf("volume attribute %q is reserved", kv[0])
+		}
+		attributes[kv[0]] = kv[1]
+	}
+	return attributes, nil
+}
+
+// ParseExtraLabels parses a comma separated list of key=value labels, e.g.
+// "team=storage,cost-center=1234", that the broker adds to every persistent
+// volume or claim it creates.
+func ParseExtraLabels(value string) (map[string]string, error) {
+	labels := map[string]string{}
+	for _, label := range strings.Split(value, ",") {
+		if label = strings.TrimSpace(label); label == "" {
+			continue
+		}
+		kv := strings.SplitN(label, "=", 2)
+		if len(kv) != 2 || kv[1] == "" {
+			return nil, fmt.Errorf("invalid label %q, expected key=value", label)
+		}
+		if errs := validation.IsQualifiedName(kv[0]); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid label key %q: %s", kv[0], strings.Join(errs, "; "))
+		}
+		if errs := validation.IsValidLabelValue(kv[1]); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid label value %q: %s", kv[1], strings.Join(errs, "; "))
+		}
+		labels[kv[0]] = kv[1]
+	}
+	return labels, nil
+}
+
+// PathTemplateData is what a path_template provision parameter is rendered
+// with, e.g. "/export/{{.InstanceID}}/data".
+type PathTemplateData struct {
+	InstanceID       string
+	PlanID           string
+	OrganizationGUID string
+	SpaceGUID        string
+}
+
+// RenderPathTemplate renders a path_template into the share of a volume. The
+// share must be absolute and must not step out of its directory with "..".
+func RenderPathTemplate(text string, data PathTemplateData) (string, error) {
+	tmpl, err := template.New("path-template").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	var share bytes.Buffer
+	err = tmpl.Execute(&share, data)
+	if err != nil {
+		return "", err
+	}
+
+	path := share.String()
+	if !strings.HasPrefix(path, "/") {
+		return "", fmt.Errorf("rendered path %q is not absolute", path)
+	}
+	if contains(strings.Split(path, "/"), "..") {
+		return "", fmt.Errorf("rendered path %q contains \"..\"", path)
+	}
+	return path, nil
+}
@@ -0,0 +1,336 @@
+package k8sbroker_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	. "code.cloudfoundry.org/k8sbroker/k8sbroker"
+)
+
+var _ = Describe("BuildPersistentVolume", func() {
+	var capacity resource.Quantity
+
+	BeforeEach(func() {
+		capacity = resource.MustParse("5G")
+	})
+
+	It("builds a ReadWriteMany NFS volume managed by the broker", func() {
+		volume := BuildPersistentVolume("some-instance-id", "", "10.0.0.5", "/export/some-share", capacity)
+
+		Expect(volume.Name).To(Equal("some-instance-id"))
+		Expect(volume.Labels).To(Equal(map[string]string{"name": "some-instance-id", ManagedByLabel: "k8sbroker"}))
+		Expect(volume.Spec.AccessModes).To(Equal([]v1.PersistentVolumeAccessMode{v1.ReadWriteMany}))
+		Expect(volume.Spec.Capacity[v1.ResourceStorage]).To(Equal(capacity))
+		Expect(volume.Spec.NFS).To(Equal(&v1.NFSVolumeSource{Server: "10.0.0.5", Path: "/export/some-share"}))
+		Expect(volume.Spec.MountOptions).To(BeNil())
+		Expect(volume.Spec.NodeAffinity).To(BeNil())
+		Expect(volume.Spec.PersistentVolumeReclaimPolicy).To(BeEmpty())
+	})
+
+	It("prefixes the keys of the broker's labels", func() {
+		volume := BuildPersistentVolume("some-instance-id", "k8sbroker/", "10.0.0.5", "/export/some-share", capacity,
+			WithLabels(map[string]string{"name": "user-name"}),
+		)
+
+		Expect(volume.Labels).To(Equal(map[string]string{
+			"name":                 "user-name",
+			"k8sbroker/name":       "some-instance-id",
+			"k8sbroker/managed-by": "k8sbroker",
+		}))
+	})
+
+	nodeAffinity := &v1.VolumeNodeAffinity{
+		Required: &v1.NodeSelector{
+			NodeSelectorTerms: []v1.NodeSelectorTerm{{
+				MatchExpressions: []v1.NodeSelectorRequirement{{
+					Key:      "zone",
+					Operator: v1.NodeSelectorOpIn,
+					Values:   []string{"z1"},
+				}},
+			}},
+		},
+	}
+
+	cases := []struct {
+		description string
+		opts        []PVOption
+		expected    func(*v1.PersistentVolume)
+	}{
+		{
+			description: "mount options",
+			opts:        []PVOption{WithMountOptions([]string{"nfsvers=4.1"})},
+			expected: func(volume *v1.PersistentVolume) {
+				volume.Spec.MountOptions = []string{"nfsvers=4.1"}
+			},
+		},
+		{
+			description: "node affinity",
+			opts:        []PVOption{WithNodeAffinity(nodeAffinity)},
+			expected: func(volume *v1.PersistentVolume) {
+				volume.Spec.NodeAffinity = nodeAffinity
+			},
+		},
+		{
+			description: "a reclaim policy",
+			opts:        []PVOption{WithReclaimPolicy(v1.PersistentVolumeReclaimRetain)},
+			expected: func(volume *v1.PersistentVolume) {
+				volume.Spec.PersistentVolumeReclaimPolicy = v1.PersistentVolumeReclaimRetain
+			},
+		},
+		{
+			description: "labels, keeping the broker's own",
+			opts:        []PVOption{WithLabels(map[string]string{"team": "storage", "name": "other"})},
+			expected: func(volume *v1.PersistentVolume) {
+				volume.Labels["team"] = "storage"
+			},
+		},
+		{
+			description: "every option",
+			opts: []PVOption{
+				WithMountOptions([]string{"nfsvers=4.1"}),
+				WithNodeAffinity(nodeAffinity),
+				WithReclaimPolicy(v1.PersistentVolumeReclaimRetain),
+				WithLabels(map[string]string{"team": "storage"}),
+			},
+			expected: func(volume *v1.PersistentVolume) {
+				volume.Spec.MountOptions = []string{"nfsvers=4.1"}
+				volume.Spec.NodeAffinity = nodeAffinity
+				volume.Spec.PersistentVolumeReclaimPolicy = v1.PersistentVolumeReclaimRetain
+				volume.Labels["team"] = "storage"
+			},
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+		It("sets "+c.description, func() {
+			expected := BuildPersistentVolume("some-instance-id", "", "10.0.0.5", "/export/some-share", capacity)
+			c.expected(expected)
+
+			Expect(BuildPersistentVolume("some-instance-id", "", "10.0.0.5", "/export/some-share", capacity, c.opts...)).To(Equal(expected))
+		})
+	}
+})
+
+var _ = Describe("BuildPersistentVolumeClaim", func() {
+	var fingerprint *ServiceFingerPrint
+
+	BeforeEach(func() {
+		fingerprint = &ServiceFingerPrint{
+			Name:   "some-instance-id",
+			Volume: BuildPersistentVolume("some-instance-id", "", "10.0.0.5", "/export/some-share", resource.MustParse("5G")),
+		}
+	})
+
+	It("claims the whole instance volume in the namespace", func() {
+		claim := BuildPersistentVolumeClaim("some-claim", "some-namespace", "", fingerprint, v1.ReadOnlyMany)
+
+		Expect(claim.Name).To(Equal("some-claim"))
+		Expect(claim.Namespace).To(Equal("some-namespace"))
+		Expect(claim.Spec.AccessModes).To(Equal([]v1.PersistentVolumeAccessMode{v1.ReadOnlyMany}))
+		Expect(claim.Spec.Resources.Requests).To(Equal(fingerprint.Volume.Spec.Capacity))
+		Expect(claim.Spec.Selector.MatchExpressions[0].Values).To(Equal([]string{"some-instance-id"}))
+		Expect(claim.Spec.StorageClassName).To(BeNil())
+		Expect(claim.Labels).To(BeNil())
+		Expect(claim.Annotations).To(BeNil())
+	})
+
+	It("selects the volume by its prefixed name label", func() {
+		fingerprint.Volume = BuildPersistentVolume("some-instance-id", "k8sbroker/", "10.0.0.5", "/export/some-share", resource.MustParse("5G"))
+
+		claim := BuildPersistentVolumeClaim("some-claim", "some-namespace", "k8sbroker/", fingerprint, v1.ReadOnlyMany)
+
+		Expect(claim.Spec.Selector.MatchExpressions[0].Key).To(Equal("k8sbroker/name"))
+		Expect(claim.Spec.Selector.MatchExpressions[0].Values).To(Equal([]string{"some-instance-id"}))
+	})
+
+	Context("when the volume was labelled without the prefix", func() {
+		It("selects it by the unprefixed name label", func() {
+			claim := BuildPersistentVolumeClaim("some-claim", "some-namespace", "k8sbroker/", fingerprint, v1.ReadOnlyMany)
+
+			Expect(claim.Spec.Selector.MatchExpressions[0].Key).To(Equal("name"))
+		})
+	})
+
+	emptyClass := ""
+	someClass := "some-class"
+
+	cases := []struct {
+		description string
+		opts        []PVCOption
+		expected    func(*v1.PersistentVolumeClaim)
+	}{
+		{
+			description: "a storage class",
+			opts:        []PVCOption{WithClaimStorageClass("some-class")},
+			expected: func(claim *v1.PersistentVolumeClaim) {
+				claim.Spec.StorageClassName = &someClass
+			},
+		},
+		{
+			description: "an empty storage class",
+			opts:        []PVCOption{WithClaimStorageClass("")},
+			expected: func(claim *v1.PersistentVolumeClaim) {
+				claim.Spec.StorageClassName = &emptyClass
+			},
+		},
+		{
+			description: "annotations",
+			opts:        []PVCOption{WithClaimAnnotations(map[string]string{"example.com/owner": "someone"})},
+			expected: func(claim *v1.PersistentVolumeClaim) {
+				claim.Annotations = map[string]string{"example.com/owner": "someone"}
+			},
+		},
+		{
+			description: "labels",
+			opts:        []PVCOption{WithClaimLabels(map[string]string{"app": "pora"})},
+			expected: func(claim *v1.PersistentVolumeClaim) {
+				claim.Labels = map[string]string{"app": "pora"}
+			},
+		},
+		{
+			description: "every option",
+			opts: []PVCOption{
+				WithClaimStorageClass("some-class"),
+				WithClaimAnnotations(map[string]string{"example.com/owner": "someone"}),
+				WithClaimLabels(map[string]string{"app": "pora"}),
+			},
+			expected: func(claim *v1.PersistentVolumeClaim) {
+				claim.Spec.StorageClassName = &someClass
+				claim.Annotations = map[string]string{"example.com/owner": "someone"}
+				claim.Labels = map[string]string{"app": "pora"}
+			},
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+		It("sets "+c.description, func() {
+			expected := BuildPersistentVolumeClaim("some-claim", "some-namespace", "", fingerprint, v1.ReadWriteMany)
+			c.expected(expected)
+
+			Expect(BuildPersistentVolumeClaim("some-claim", "some-namespace", "", fingerprint, v1.ReadWriteMany, c.opts...)).To(Equal(expected))
+		})
+	}
+})
+
+var _ = Describe("BuildDynamicPersistentVolumeClaim", func() {
+	var fingerprint *ServiceFingerPrint
+
+	BeforeEach(func() {
+		fingerprint = &ServiceFingerPrint{
+			Name:                "some-instance-id",
+			DynamicProvisioning: true,
+			StorageClassName:    "nfs-dynamic",
+		}
+	})
+
+	It("claims a 5G volume from the instance's storage class without selecting one", func() {
+		claim := BuildDynamicPersistentVolumeClaim("some-claim", "some-namespace", fingerprint, v1.ReadWriteMany)
+
+		Expect(claim.Name).To(Equal("some-claim"))
+		Expect(claim.Namespace).To(Equal("some-namespace"))
+		Expect(claim.Spec.AccessModes).To(Equal([]v1.PersistentVolumeAccessMode{v1.ReadWriteMany}))
+		Expect(claim.Spec.Resources.Requests[v1.ResourceStorage]).To(Equal(resource.MustParse("5G")))
+		Expect(*claim.Spec.StorageClassName).To(Equal("nfs-dynamic"))
+		Expect(claim.Spec.Selector).To(BeNil())
+	})
+
+	It("requests the required capacity", func() {
+		fingerprint.CapacityRange = &CapacityRange{RequiredBytes: 1073741824}
+
+		claim := BuildDynamicPersistentVolumeClaim("some-claim", "some-namespace", fingerprint, v1.ReadWriteMany)
+
+		Expect(claim.Spec.Resources.Requests[v1.ResourceStorage]).To(Equal(*resource.NewQuantity(1073741824, resource.BinarySI)))
+	})
+})
+
+var _ = Describe("ParseExtraVolumeAttributes", func() {
+	It("parses key:value pairs", func() {
+		attributes, err := ParseExtraVolumeAttributes("provisioner:nfs.csi.k8s.io, mountPermissions:0777")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(attributes).To(Equal(map[string]string{"provisioner": "nfs.csi.k8s.io", "mountPermissions": "0777"}))
+	})
+
+	It("returns no attributes for an empty list", func() {
+		attributes, err := ParseExtraVolumeAttributes("")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(attributes).To(BeEmpty())
+	})
+
+	It("rejects a pair without a value", func() {
+		_, err := ParseExtraVolumeAttributes("provisioner")
+		Expect(err).To(MatchError(`invalid volume attribute "provisioner", expected key:value`))
+	})
+
+	It("rejects the keys of the NFS volume source", func() {
+		_, err := ParseExtraVolumeAttributes("share:/export")
+		Expect(err).To(MatchError(`volume attribute "share" is reserved`))
+	})
+})
+
+var _ = Describe("ParseExtraLabels", func() {
+	It("parses key=value pairs", func() {
+		labels, err := ParseExtraLabels("team=storage, example.com/cost-center=1234")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(labels).To(Equal(map[string]string{"team": "storage", "example.com/cost-center": "1234"}))
+	})
+
+	It("returns no labels for an empty list", func() {
+		labels, err := ParseExtraLabels("")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(labels).To(BeEmpty())
+	})
+
+	It("rejects a label without a value", func() {
+		_, err := ParseExtraLabels("team=")
+		Expect(err).To(MatchError(`invalid label "team=", expected key=value`))
+	})
+
+	It("rejects an invalid key", func() {
+		_, err := ParseExtraLabels("not a key=storage")
+		Expect(err).To(MatchError(ContainSubstring(`invalid label key "not a key"`)))
+	})
+
+	It("rejects an invalid value", func() {
+		_, err := ParseExtraLabels("team=not a value")
+		Expect(err).To(MatchError(ContainSubstring(`invalid label value "not a value"`)))
+	})
+})
+
+var _ = Describe("RenderPathTemplate", func() {
+	var data PathTemplateData
+
+	BeforeEach(func() {
+		data = PathTemplateData{InstanceID: "some-instance-id", PlanID: "some-plan-id", OrganizationGUID: "some-org", SpaceGUID: "some-space"}
+	})
+
+	It("renders the instance into the path", func() {
+		path, err := RenderPathTemplate("/export/{{.OrganizationGUID}}/{{.SpaceGUID}}/{{.InstanceID}}-{{.PlanID}}", data)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(path).To(Equal("/export/some-org/some-space/some-instance-id-some-plan-id"))
+	})
+
+	It("rejects a relative path", func() {
+		_, err := RenderPathTemplate("{{.InstanceID}}/data", data)
+		Expect(err).To(MatchError(`rendered path "some-instance-id/data" is not absolute`))
+	})
+
+	It("rejects a path with a .. component", func() {
+		data.InstanceID = ".."
+		_, err := RenderPathTemplate("/export/{{.InstanceID}}/etc", data)
+		Expect(err).To(MatchError(`rendered path "/export/../etc" contains ".."`))
+	})
+
+	It("allows dots within a component", func() {
+		_, err := RenderPathTemplate("/export/{{.InstanceID}}..bak", data)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("rejects unknown fields", func() {
+		_, err := RenderPathTemplate("/export/{{.Name}}", data)
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tedsuo/ifrit"
+	"github.com/tedsuo/ifrit/ginkgomon"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const (
+	kindClusterName = "k8sbroker-integration"
+	nfsServiceID    = "db404fc5-97fb-4806-9827-07e0e8d3bd51"
+	nfsPlanID       = "190de554-4fc1-4008-ace9-5d3796140b48"
+)
+
+const nfsServerPod = `apiVersion: v1
+kind: Pod
+metadata:
+  name: nfs-server
+  namespace: default
+spec:
+  containers:
+  - name: nfs-server
+    image: itsthenetwork/nfs-server-alpine:12
+    env:
+    - name: SHARED_DIRECTORY
+      value: /exports
+    securityContext:
+      privileged: true
+    volumeMounts:
+    - name: exports
+      mountPath: /exports
+  volumes:
+  - name: exports
+    emptyDir: {}
+`
+
+// run executes a command and returns its output, failing the test when the
+// command does.
+func run(cmd *exec.Cmd) string {
+	output, err := cmd.CombinedOutput()
+	ExpectWithOffset(1, err).NotTo(HaveOccurred(), fmt.Sprintf("%s: %s", strings.Join(cmd.Args, " "), output))
+	return string(output)
+}
+
+var _ = Describe("k8sbroker against a kind cluster", func() {
+	var (
+		kubeConfig         string
+		listenAddr         string
+		username, password string
+		tempDir            string
+		process            ifrit.Process
+	)
+
+	kubectl := func(args ...string) *exec.Cmd {
+		return exec.Command("kubectl", append([]string{"--kubeconfig", kubeConfig}, args...)...)
+	}
+
+	brokerRequest := func(method, endpoint, body string) *http.Response {
+		req, err := http.NewRequest(method, "http://"+listenAddr+endpoint, bytes.NewBufferString(body))
+		Expect(err).NotTo(HaveOccurred())
+		req.Header.Add("X-Broker-Api-Version", "2.14")
+		req.Header.Add("Content-Type", "application/json")
+		req.SetBasicAuth(username, password)
+
+		resp, err := http.DefaultClient.Do(req)
+		Expect(err).NotTo(HaveOccurred())
+		return resp
+	}
+
+	BeforeEach(func() {
+		if os.Getenv("TEST_WITH_KIND") != "true" {
+			Skip("set TEST_WITH_KIND=true to run the kind integration test")
+		}
+
+		var err error
+		tempDir, err = ioutil.TempDir("", "k8sbroker-kind")
+		Expect(err).NotTo(HaveOccurred())
+
+		kubeConfig = filepath.Join(tempDir, "kubeconfig")
+		run(exec.Command("kind", "create", "cluster", "--name", kindClusterName, "--kubeconfig", kubeConfig, "--wait", "120s"))
+
+		apply := kubectl("apply", "-f", "-")
+		apply.Stdin = strings.NewReader(nfsServerPod)
+		run(apply)
+		run(kubectl("wait", "--for=condition=Ready", "pod/nfs-server", "--namespace", "default", "--timeout=120s"))
+
+		listenAddr = "127.0.0.1:" + strconv.Itoa(8799+GinkgoParallelNode())
+		username = "admin"
+		password = "password"
+		os.Setenv("USERNAME", username)
+		os.Setenv("PASSWORD", password)
+
+		volmanRunner := ginkgomon.New(ginkgomon.Config{
+			Name: "k8sbroker",
+			Command: exec.Command(binaryPath,
+				"-listenAddr", listenAddr,
+				"-metricsAddr", "127.0.0.1:"+strconv.Itoa(9002+GinkgoParallelNode()),
+				"-dataDir", tempDir,
+				"-servicesConfig", "./default_services.json",
+				"-kubeConfig", kubeConfig,
+				"-kubeNamespace", "k8sbroker-integration",
+			),
+			StartCheck: "started",
+		})
+		process = ginkgomon.Invoke(volmanRunner)
+	})
+
+	AfterEach(func() {
+		if os.Getenv("TEST_WITH_KIND") != "true" {
+			return
+		}
+
+		ginkgomon.Kill(process)
+		exec.Command("kind", "delete", "cluster", "--name", kindClusterName).Run()
+		os.RemoveAll(tempDir)
+	})
+
+	It("provisions, binds, unbinds and deprovisions an NFS volume", func() {
+		serverIP := strings.TrimSpace(run(kubectl("get", "pod", "nfs-server", "--namespace", "default", "-o", "jsonpath={.status.podIP}")))
+		Expect(serverIP).NotTo(BeEmpty())
+
+		resp := brokerRequest("PUT", "/v2/service_instances/kind-instance", fmt.Sprintf(`{
+			"service_id": %q,
+			"plan_id": %q,
+			"organization_guid": "some-org",
+			"space_guid": "some-space",
+			"parameters": {"server": %q, "share": "/"}
+		}`, nfsServiceID, nfsPlanID, serverIP))
+		Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+
+		run(kubectl("get", "pv", "kind-instance"))
+
+		resp = brokerRequest("PUT", "/v2/service_instances/kind-instance/service_bindings/kind-binding", fmt.Sprintf(`{
+			"service_id": %q,
+			"plan_id": %q,
+			"app_guid": "some-app",
+			"bind_resource": {"app_guid": "some-app"}
+		}`, nfsServiceID, nfsPlanID))
+		Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+
+		Eventually(func() string {
+			output, _ := kubectl("get", "pvc", "kind-instance", "--namespace", "k8sbroker-integration", "-o", "jsonpath={.status.phase}").Output()
+			return string(output)
+		}, 60*time.Second, time.Second).Should(Equal("Bound"))
+
+		query := fmt.Sprintf("?service_id=%s&plan_id=%s", nfsServiceID, nfsPlanID)
+		resp = brokerRequest("DELETE", "/v2/service_instances/kind-instance/service_bindings/kind-binding"+query, "")
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		Eventually(func() error {
+			return kubectl("get", "pvc", "kind-instance", "--namespace", "k8sbroker-integration").Run()
+		}, 60*time.Second, time.Second).Should(HaveOccurred())
+
+		resp = brokerRequest("DELETE", "/v2/service_instances/kind-instance"+query, "")
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		Eventually(func() error {
+			return kubectl("get", "pv", "kind-instance").Run()
+		}, 60*time.Second, time.Second).Should(HaveOccurred())
+	})
+})
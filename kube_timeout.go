@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	"k8s.io/client-go/rest"
+)
+
+// withRequestTimeout returns a copy of config whose requests each fail after
+// timeout. Unlike rest.Config.Timeout, which would also cut off watches, the
+// limit is applied per request through its context and ends once the
+// response body is closed. Watches must go through a client without it.
+func withRequestTimeout(config *rest.Config, timeout time.Duration) *rest.Config {
+	config = rest.CopyConfig(config)
+	if timeout <= 0 {
+		return config
+	}
+
+	wrap := config.WrapTransport
+	config.WrapTransport = func(rt http.RoundTripper) http.RoundTripper {
+		if wrap != nil {
+			rt = wrap(rt)
+		}
+		return &timeoutTransport{next: rt, timeout: timeout}
+	}
+	return config
+}
+
+type timeoutTransport struct {
+	next    http.RoundTripper
+	timeout time.Duration
+}
+
+func (t *timeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(req.Context(), t.timeout)
+	resp, err := t.next.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// cancelOnClose releases a request's timeout once its body has been read.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"k8s.io/client-go/rest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("withRequestTimeout", func() {
+	var (
+		server  *httptest.Server
+		release chan struct{}
+		config  *rest.Config
+	)
+
+	BeforeEach(func() {
+		release = make(chan struct{})
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/slow" {
+				select {
+				case <-release:
+				case <-r.Context().Done():
+				}
+			}
+			w.Write([]byte("ok"))
+		}))
+		config = &rest.Config{Host: server.URL}
+	})
+
+	AfterEach(func() {
+		close(release)
+		server.Close()
+	})
+
+	get := func(config *rest.Config, path string) (string, error) {
+		transport, err := rest.TransportFor(config)
+		Expect(err).NotTo(HaveOccurred())
+		resp, err := (&http.Client{Transport: transport}).Get(server.URL + path)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		return string(body), err
+	}
+
+	It("fails a request that outlasts the timeout", func() {
+		_, err := get(withRequestTimeout(config, 50*time.Millisecond), "/slow")
+		Expect(err).To(MatchError(ContainSubstring("context deadline exceeded")))
+	})
+
+	It("lets a quick request read its body", func() {
+		body, err := get(withRequestTimeout(config, time.Second), "/")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(body).To(Equal("ok"))
+	})
+
+	It("keeps calling the config's own transport wrapper", func() {
+		wrapped := false
+		config.WrapTransport = func(rt http.RoundTripper) http.RoundTripper {
+			wrapped = true
+			return rt
+		}
+
+		_, err := get(withRequestTimeout(config, time.Second), "/")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(wrapped).To(BeTrue())
+	})
+
+	It("leaves the config it was given without a limit", func() {
+		withRequestTimeout(config, 50*time.Millisecond)
+
+		go func() {
+			time.Sleep(100 * time.Millisecond)
+			release <- struct{}{}
+		}()
+		body, err := get(config, "/slow")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(body).To(Equal("ok"))
+	})
+})
@@ -2,16 +2,34 @@ package main
 
 import (
 	// "errors"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"flag"
 	"fmt"
 	"io/ioutil"
+	"net/http"
 	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	"code.cloudfoundry.org/clock"
 	"code.cloudfoundry.org/debugserver"
 	"code.cloudfoundry.org/goshims/osshim"
+	"code.cloudfoundry.org/k8sbroker/admin"
+	"code.cloudfoundry.org/k8sbroker/audit"
+	"code.cloudfoundry.org/k8sbroker/health"
 	"code.cloudfoundry.org/k8sbroker/k8sbroker"
+	"code.cloudfoundry.org/k8sbroker/metrics"
+	"code.cloudfoundry.org/k8sbroker/pagination"
+	"code.cloudfoundry.org/k8sbroker/ratelimit"
+	"code.cloudfoundry.org/k8sbroker/requestid"
 	"code.cloudfoundry.org/k8sbroker/utils"
+	"code.cloudfoundry.org/k8sbroker/vault"
+	"code.cloudfoundry.org/k8sbroker/vcap"
 	"code.cloudfoundry.org/lager"
 	"code.cloudfoundry.org/lager/lagerflags"
 
@@ -20,13 +38,14 @@ import (
 	// "encoding/json"
 
 	"code.cloudfoundry.org/service-broker-store/brokerstore"
-	"github.com/go-sql-driver/mysql"
-	"github.com/lib/pq"
 	"github.com/pivotal-cf/brokerapi"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/tedsuo/ifrit"
 	"github.com/tedsuo/ifrit/grouper"
 	"github.com/tedsuo/ifrit/http_server"
+	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 )
 
@@ -45,7 +64,13 @@ var atAddress = flag.String(
 var servicesConfig = flag.String(
 	"servicesConfig",
 	"",
-	"[REQUIRED] - Path to services config to register with cloud controller",
+	"[REQUIRED unless servicesConfigDir is given] - Path to services config to register with cloud controller",
+)
+
+var servicesConfigDir = flag.String(
+	"servicesConfigDir",
+	"",
+	"(optional) Directory of services configs (*.json) to merge with servicesConfig",
 )
 
 var dbDriver = flag.String(
@@ -77,6 +102,12 @@ var dbCACertPath = flag.String(
 	"(optional) Path to CA Cert for database SSL connection",
 )
 
+var dbSSLMode = flag.String(
+	"dbSSLMode",
+	"",
+	"(optional) sslmode of the postgres connection: disable, verify-ca or verify-full. Defaults to verify-ca when dbCACertPath is set and to disable otherwise",
+)
+
 var cfServiceName = flag.String(
 	"cfServiceName",
 	"",
@@ -131,10 +162,262 @@ var storeID = flag.String(
 	"(optional) Store ID used to namespace instance details and bindings (credhub only)",
 )
 
+var storeEncryptionKey = flag.String(
+	"storeEncryptionKey",
+	"",
+	"(optional) 64 hex digit AES-256 key to encrypt instance fingerprints and binding parameters with before they are stored",
+)
+
+var metricsAddr = flag.String(
+	"metricsAddr",
+	"0.0.0.0:9102",
+	"(optional) host:port to serve prometheus metrics on, without broker authentication",
+)
+
+var maxProvisionPerSecond = flag.Int(
+	"maxProvisionPerSecond",
+	10,
+	"(optional) maximum provision requests per second, 0 for no limit",
+)
+
+var maxBindPerSecond = flag.Int(
+	"maxBindPerSecond",
+	50,
+	"(optional) maximum bind requests per second, 0 for no limit",
+)
+
+var maxDeprovisionPerSecond = flag.Int(
+	"maxDeprovisionPerSecond",
+	10,
+	"(optional) maximum deprovision requests per second, 0 for no limit",
+)
+
+var maxPVCount = flag.Int(
+	"maxPVCount",
+	0,
+	"(optional) maximum number of persistent volumes the broker provisions, 0 for no limit",
+)
+
+var maxPVCPerInstance = flag.Int(
+	"maxPVCPerInstance",
+	0,
+	"(optional) maximum number of bindings per service instance, 0 for no limit",
+)
+
+var gcOrphanedPVsOnStart = flag.Bool(
+	"gcOrphanedPVsOnStart",
+	false,
+	"(optional) Delete persistent volumes created by the broker that have no instance in the store when starting",
+)
+
+var recoverPartialProvisionOnStart = flag.Bool(
+	"recoverPartialProvisionOnStart",
+	false,
+	"(optional) Delete unbound persistent volumes a provision created without storing their instance when starting",
+)
+
+var recoverPartialProvisionMinAge = flag.Duration(
+	"recoverPartialProvisionMinAge",
+	10*time.Minute,
+	"(optional) minimum age of the persistent volumes deleted by --recoverPartialProvisionOnStart",
+)
+
+var purgeStaleOnStart = flag.Bool(
+	"purgeStaleOnStart",
+	false,
+	"(optional) Delete persistent volume claims created by the broker that have no binding in the store when starting",
+)
+
+var parallelProvisionWorkers = flag.Int(
+	"parallelProvisionWorkers",
+	10,
+	"(optional) maximum number of asynchronous provisions creating volumes at once, 0 for no limit",
+)
+
+var annotationPropagationDelay = flag.Duration(
+	"annotationPropagationDelay",
+	0,
+	"(optional) How long to wait after re-applying an instance's annotations to its persistent volume before deprovision deletes it",
+)
+
+var provisionWatchTimeout = flag.Duration(
+	"provisionWatchTimeout",
+	10*time.Minute,
+	"(optional) How long to watch the persistent volume of an asynchronous provision for its outcome before last_operation asks the API server again",
+)
+
+var provisionTimeout = flag.Duration(
+	"provisionTimeout",
+	30*time.Second,
+	"(optional) How long a synchronous provision waits for its new persistent volume to become Available before deleting it and failing, 0 to not wait. Keep it under the platform's 60s broker timeout",
+)
+
+var deprovisionGracePeriodSeconds = flag.Int(
+	"deprovisionGracePeriodSeconds",
+	0,
+	"(optional) Seconds to keep a deprovisioned instance's persistent volume before deleting it, 0 to delete it immediately",
+)
+
+var auditLogFile = flag.String(
+	"auditLogFile",
+	"",
+	"(optional) File to append the audit log of broker API requests to, instead of the broker's log",
+)
+
+var maxConnectionRetries = flag.Int(
+	"maxConnectionRetries",
+	5,
+	"(optional) How many times to retry reaching the Kubernetes API at startup before exiting",
+)
+
+var connectionRetryInterval = flag.Duration(
+	"connectionRetryInterval",
+	5*time.Second,
+	"(optional) How long to wait between attempts to reach the Kubernetes API at startup",
+)
+
+var csiConnectionTimeout = flag.Duration(
+	"csiConnectionTimeout",
+	5*time.Second,
+	"(optional) How long each service's CSI endpoint has to answer at startup",
+)
+
+var failOnCSIConnectionError = flag.Bool(
+	"failOnCSIConnectionError",
+	false,
+	"(optional) Exit when a service's CSI endpoint cannot be reached at startup, instead of starting degraded",
+)
+
+var validateCSIOnStart = flag.Bool(
+	"validateCSIOnStart",
+	false,
+	"(optional) Exit unless every service's CSI endpoint answers at startup with the service's driver_name",
+)
+
+var shutdownGracePeriod = flag.Duration(
+	"shutdownGracePeriod",
+	30*time.Second,
+	"(optional) How long to wait for in-flight broker requests to finish when stopping",
+)
+
+var volumeHealthCheckInterval = flag.Duration(
+	"volumeHealthCheckInterval",
+	0,
+	"(optional) How often to check the broker's persistent volumes for failures, e.g. 5m, 0 to disable",
+)
+
+var watchNamespace = flag.Bool(
+	"watchNamespace",
+	false,
+	"(optional) Unbind the bindings in a namespace labelled with a CF space when the namespace is deleted",
+)
+
+var dashboardURLTemplate = flag.String(
+	"dashboardURLTemplate",
+	"",
+	"(optional) Go template for the dashboard URL of a provisioned instance, rendered with its service fingerprint, e.g. https://dashboard.example.com/#/persistentvolumes/{{.Name}}",
+)
+
+var tlsCert = flag.String(
+	"tlsCert",
+	"",
+	"(optional) Path to the certificate used to serve the broker API over HTTPS, requires tlsKey",
+)
+
+var tlsKey = flag.String(
+	"tlsKey",
+	"",
+	"(optional) Path to the private key used to serve the broker API over HTTPS, requires tlsCert",
+)
+
+var tlsClientCA = flag.String(
+	"tlsClientCA",
+	"",
+	"(optional) Path to a CA cert that client certificates must be signed by, enables mutual TLS",
+)
+
 var kubeConfig = flag.String(
 	"kubeConfig",
 	"",
-	"[REQUIRED] Path to the kube config file",
+	"(optional) Path to the kube config file, required unless kubeInCluster is set",
+)
+
+var kubeInCluster = flag.Bool(
+	"kubeInCluster",
+	false,
+	"(optional) authenticate with the service account of the pod the broker runs in instead of a kube config file",
+)
+
+var cfKubeServiceName = flag.String(
+	"cfKubeServiceName",
+	"",
+	"(optional) For CF pushed apps, the service name in VCAP_SERVICES whose host, token and ca_cert credentials give access to the Kubernetes API, instead of kubeConfig",
+)
+
+var kubeContextName = flag.String(
+	"kubeContextName",
+	"",
+	"(optional) name of the context in the kube config file to use, defaults to the file's current context",
+)
+
+var kubeQPS = flag.Float64(
+	"kubeQPS",
+	20,
+	"(optional) queries per second the Kubernetes client may send before it throttles itself",
+)
+
+var kubeMaxBurst = flag.Int(
+	"kubeMaxBurst",
+	40,
+	"(optional) burst of queries the Kubernetes client may send above kubeQPS",
+)
+
+var extraVolumeAttributes = flag.String(
+	"extraVolumeAttributes",
+	"",
+	"(optional) A comma separated list of key:value volume attributes to add to the volume context of every instance, e.g. provisioner:nfs.csi.k8s.io",
+)
+
+var extraPVLabels = flag.String(
+	"extraPVLabels",
+	"",
+	"(optional) A comma separated list of key=value labels to add to every persistent volume the broker creates, e.g. team=storage",
+)
+
+var extraPVCLabels = flag.String(
+	"extraPVCLabels",
+	"",
+	"(optional) A comma separated list of key=value labels to add to every persistent volume claim the broker creates, e.g. team=storage",
+)
+
+var allowedNamespaces = flag.String(
+	"allowedNamespaces",
+	"",
+	"(optional) A comma separated list of the namespaces a bind may target with the namespace parameter, empty to allow any",
+)
+
+var labelPrefix = flag.String(
+	"labelPrefix",
+	"k8sbroker/",
+	"(optional) prefix of the keys of the labels the broker puts on the Kubernetes objects it manages",
+)
+
+var resourceNamePrefix = flag.String(
+	"resourceNamePrefix",
+	"",
+	"(optional) prefix of the names of the persistent volumes and claims the broker creates, lowercase alphanumerics and hyphens",
+)
+
+var pvcNamingStrategy = flag.String(
+	"pvcNamingStrategy",
+	string(k8sbroker.PVCNamingVolumeName),
+	"(optional) How bind names its persistent volume claims: volume-name (one claim per instance and namespace), binding-id or volume-binding-id",
+)
+
+var requestTimeout = flag.Duration(
+	"requestTimeout",
+	30*time.Second,
+	"(optional) Maximum time to wait for each Kubernetes API request other than a watch, 0 for no limit",
 )
 
 var kubeNamespace = flag.String(
@@ -143,13 +426,41 @@ var kubeNamespace = flag.String(
 	"(optional) Kubernetes namespace to create the PVCs in",
 )
 
+var vaultAddress = flag.String(
+	"vaultAddress",
+	"",
+	"(optional) Vault address to read the broker credentials from instead of the USERNAME and PASSWORD environment variables",
+)
+
+var vaultToken = flag.String(
+	"vaultToken",
+	"",
+	"(optional) Vault token used to read the broker credentials",
+)
+
+var vaultPath = flag.String(
+	"vaultPath",
+	"",
+	"(optional) Path of the Vault secret holding the username and password keys, e.g. secret/k8sbroker",
+)
+
+var vaultCredentialTTL = flag.Duration(
+	"vaultCredentialTTL",
+	0,
+	"(optional) How often to read the broker credentials from Vault again, e.g. 1h, 0 to read them only at startup",
+)
+
 var (
-	username   string
-	password   string
-	dbUsername string
-	dbPassword string
+	username      string
+	password      string
+	dbUsername    string
+	dbPassword    string
+	adminUsername string
+	adminPassword string
 )
 
+var resourceNamePrefixPattern = regexp.MustCompile(`^[a-z0-9][-a-z0-9]*$`)
+
 func main() {
 	parseCommandLine()
 	parseEnvironment()
@@ -170,15 +481,20 @@ func main() {
 	logger.Info("starting")
 	defer logger.Info("ends")
 
-	server := createServer(logger)
+	registry := prometheus.NewRegistry()
+	members := grouper.Members{
+		{"metrics-server", http_server.New(*metricsAddr, metrics.Handler(registry))},
+		{"broker-api", createServer(logger, metrics.New(registry))},
+	}
 
 	if dbgAddr := debugserver.DebugAddress(flag.CommandLine); dbgAddr != "" {
-		server = utils.ProcessRunnerFor(grouper.Members{
+		members = append(grouper.Members{
 			{"debug-server", debugserver.Runner(dbgAddr, logSink)},
-			{"broker-api", server},
-		})
+		}, members...)
 	}
 
+	server := utils.ProcessRunnerFor(members)
+
 	process := ifrit.Invoke(server)
 	logger.Info("started")
 	utils.UntilTerminated(logger, process)
@@ -195,6 +511,8 @@ func parseEnvironment() {
 	password, _ = os.LookupEnv("PASSWORD")
 	dbUsername, _ = os.LookupEnv("DB_USERNAME")
 	dbPassword, _ = os.LookupEnv("DB_PASSWORD")
+	adminUsername, _ = os.LookupEnv("ADMIN_USERNAME")
+	adminPassword, _ = os.LookupEnv("ADMIN_PASSWORD")
 }
 
 func checkParams() {
@@ -204,24 +522,80 @@ func checkParams() {
 		os.Exit(1)
 	}
 
-	if *servicesConfig == "" {
-		fmt.Fprint(os.Stderr, "\nERROR: servicesConfig parameter must be provided.\n\n")
+	if *servicesConfig == "" && *servicesConfigDir == "" {
+		fmt.Fprint(os.Stderr, "\nERROR: Either servicesConfigDir or servicesConfig parameter must be provided.\n\n")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if (*tlsCert == "") != (*tlsKey == "") {
+		fmt.Fprint(os.Stderr, "\nERROR: Both tlsCert and tlsKey parameters must be provided to serve over TLS.\n\n")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if *tlsClientCA != "" && *tlsCert == "" {
+		fmt.Fprint(os.Stderr, "\nERROR: tlsClientCA parameter requires tlsCert and tlsKey.\n\n")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if *resourceNamePrefix != "" && !resourceNamePrefixPattern.MatchString(*resourceNamePrefix) {
+		fmt.Fprint(os.Stderr, "\nERROR: resourceNamePrefix parameter must start with a lowercase letter or digit and contain only lowercase letters, digits and hyphens.\n\n")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	switch k8sbroker.PVCNamingStrategy(*pvcNamingStrategy) {
+	case k8sbroker.PVCNamingVolumeName, k8sbroker.PVCNamingBindingID, k8sbroker.PVCNamingVolumeBindingID:
+	default:
+		fmt.Fprint(os.Stderr, "\nERROR: pvcNamingStrategy parameter must be volume-name, binding-id or volume-binding-id.\n\n")
 		flag.Usage()
 		os.Exit(1)
 	}
-}
 
-func getByAlias(data map[string]interface{}, keys ...string) interface{} {
-	for _, key := range keys {
-		value, ok := data[key]
-		if ok {
-			return value
+	if *dbSSLMode != "" {
+		if *dbDriver != "postgres" {
+			fmt.Fprint(os.Stderr, "\nERROR: dbSSLMode parameter requires dbDriver postgres.\n\n")
+			flag.Usage()
+			os.Exit(1)
+		}
+
+		switch *dbSSLMode {
+		case "disable":
+		case "verify-ca", "verify-full":
+			if *dbCACertPath == "" {
+				fmt.Fprintf(os.Stderr, "\nERROR: dbSSLMode %s requires dbCACertPath.\n\n", *dbSSLMode)
+				flag.Usage()
+				os.Exit(1)
+			}
+		default:
+			fmt.Fprint(os.Stderr, "\nERROR: dbSSLMode parameter must be disable, verify-ca or verify-full.\n\n")
+			flag.Usage()
+			os.Exit(1)
+		}
+	}
+
+	if *vaultAddress != "" && *vaultPath == "" {
+		fmt.Fprint(os.Stderr, "\nERROR: vaultPath parameter must be provided with vaultAddress.\n\n")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	kubeSources := 0
+	for _, given := range []bool{*kubeConfig != "", *kubeInCluster, *cfKubeServiceName != ""} {
+		if given {
+			kubeSources++
 		}
 	}
-	return nil
+	if kubeSources != 1 {
+		fmt.Fprint(os.Stderr, "\nERROR: Exactly one of kubeConfig, kubeInCluster or cfKubeServiceName parameters must be provided.\n\n")
+		flag.Usage()
+		os.Exit(1)
+	}
 }
 
-func createServer(logger lager.Logger) ifrit.Runner {
+func createServer(logger lager.Logger, brokerMetrics k8sbroker.Metrics) ifrit.Runner {
 	fileName := filepath.Join(*dataDir, fmt.Sprintf("k8s-services.json"))
 
 	var dbCACert string
@@ -232,6 +606,20 @@ func createServer(logger lager.Logger) ifrit.Runner {
 		}
 		dbCACert = string(b)
 	}
+	if *cfServiceName != "" {
+		var vcapCACert string
+		var err error
+		dbUsername, dbPassword, *dbHostname, *dbPort, *dbName, vcapCACert, err = vcap.ParseVCAPServices(os.Getenv("VCAP_SERVICES"), *dbDriver, *cfServiceName)
+		if err != nil {
+			logger.Fatal("cannot-read-db-credentials-from-vcap-services", err, lager.Data{"service": *cfServiceName})
+		}
+		if vcapCACert != "" {
+			dbCACert = vcapCACert
+		}
+	}
+	if *dbSSLMode == "disable" {
+		dbCACert = ""
+	}
 
 	var credhubCACert string
 	if *credhubCACertPath != "" {
@@ -260,7 +648,7 @@ func createServer(logger lager.Logger) ifrit.Runner {
 		*dbPort,
 		*dbName,
 		dbCACert,
-		false,
+		*dbSSLMode == "verify-full",
 		*credhubURL,
 		credhubCACert,
 		*uaaClientID,
@@ -270,47 +658,349 @@ func createServer(logger lager.Logger) ifrit.Runner {
 		*storeID,
 	)
 
-	services, err := k8sbroker.NewServicesFromConfig(*servicesConfig)
+	store, err := NewEncryptingStore(store, *storeEncryptionKey)
+	if err != nil {
+		logger.Fatal("invalid-store-encryption-key", err)
+	}
+
+	services, err := k8sbroker.NewServicesFromConfigDir(*servicesConfig, *servicesConfigDir)
 	if err != nil {
 		logger.Fatal("loading-services-config-error", err)
 	}
 
-	logger.Info(fmt.Sprintf("Using kubeconfig %s", *kubeConfig))
-	kubeConfigForClient, err := clientcmd.BuildConfigFromFlags("", *kubeConfig)
+	if *servicesConfig != "" || *servicesConfigDir != "" {
+		_, err = watchServicesConfig(logger, services, *servicesConfig, *servicesConfigDir)
+		if err != nil {
+			logger.Error("watching-services-config-error", err)
+		}
+	}
+
+	err = services.TestAllConnections(context.Background(), *csiConnectionTimeout)
+	if err != nil {
+		if *failOnCSIConnectionError {
+			logger.Error("failed-to-reach-csi-endpoints", err)
+			os.Exit(1)
+		}
+		logger.Info("csi-endpoints-unreachable", lager.Data{"error": err.Error()})
+	}
+
+	if *validateCSIOnStart {
+		var invalid bool
+		for _, service := range services.List() {
+			err = services.ValidateService(service.ID, *csiConnectionTimeout)
+			if err != nil {
+				logger.Error("invalid-csi-service", err, lager.Data{"service_id": service.ID})
+				invalid = true
+			}
+		}
+		if invalid {
+			os.Exit(1)
+		}
+	}
+
+	validator, err := k8sbroker.NewParameterValidator(*allowedOptions, *defaultOptions)
+	if err != nil {
+		logger.Fatal("parsing-options-error", err)
+	}
+
+	extraAttributes, err := k8sbroker.ParseExtraVolumeAttributes(*extraVolumeAttributes)
+	if err != nil {
+		logger.Fatal("parsing-extra-volume-attributes-error", err)
+	}
+
+	pvLabels, err := k8sbroker.ParseExtraLabels(*extraPVLabels)
+	if err != nil {
+		logger.Fatal("parsing-extra-pv-labels-error", err)
+	}
+
+	pvcLabels, err := k8sbroker.ParseExtraLabels(*extraPVCLabels)
+	if err != nil {
+		logger.Fatal("parsing-extra-pvc-labels-error", err)
+	}
+
+	dashboardURL, err := k8sbroker.NewDashboardURLTemplate(*dashboardURLTemplate)
+	if err != nil {
+		logger.Fatal("parsing-dashboard-url-template-error", err)
+	}
+
+	var kubeConfigForClient *rest.Config
+	if *kubeInCluster {
+		logger.Info("Using in-cluster kube config")
+		kubeConfigForClient, err = rest.InClusterConfig()
+	} else if *cfKubeServiceName != "" {
+		logger.Info(fmt.Sprintf("Using kube credentials of service %s", *cfKubeServiceName))
+		kubeConfigForClient, err = kubeConfigFromVCAPServices(os.Getenv("VCAP_SERVICES"), *cfKubeServiceName)
+	} else {
+		logger.Info(fmt.Sprintf("Using kubeconfig %s", *kubeConfig))
+		kubeConfigForClient, err = buildKubeConfig(logger, *kubeConfig, *kubeContextName)
+	}
 	if err != nil {
 		logger.Error("failed-to-create-kube-config", err)
 		os.Exit(1)
 	}
 
-	kubeClient, err := kubernetes.NewForConfig(kubeConfigForClient)
+	kubeConfigForClient.QPS = float32(*kubeQPS)
+	kubeConfigForClient.Burst = *kubeMaxBurst
+	logger.Info("kube-client-rate-limits", lager.Data{"qps": kubeConfigForClient.QPS, "burst": kubeConfigForClient.Burst})
+
+	kubeClient, err := kubernetes.NewForConfig(withRequestTimeout(kubeConfigForClient, *requestTimeout))
+	if err != nil {
+		logger.Error("failed-to-create-kube-client", err)
+		os.Exit(1)
+	}
+
+	watchClient, err := kubernetes.NewForConfig(kubeConfigForClient)
 	if err != nil {
 		logger.Error("failed-to-create-kube-client", err)
 		os.Exit(1)
 	}
 
+	err = waitForKubeAPI(logger, kubeClient.Discovery(), *maxConnectionRetries, *connectionRetryInterval)
+	if err != nil {
+		logger.Error("failed-to-reach-kube-api", err)
+		os.Exit(1)
+	}
+
+	gracePeriod := time.Duration(*deprovisionGracePeriodSeconds) * time.Second
+
 	serviceBroker, err := k8sbroker.New(
 		logger,
 		&osshim.OsShim{},
 		clock.NewClock(),
-		store,
+		errorConvertingStore{store},
 		kubeClient,
-		*kubeNamespace,
+		validator,
 		services,
+		brokerMetrics,
+		k8sbroker.Config{
+			Namespace:         *kubeNamespace,
+			AllowedNamespaces: splitList(*allowedNamespaces),
+			Quotas: k8sbroker.Quotas{
+				MaxPVCount:        *maxPVCount,
+				MaxPVCPerInstance: *maxPVCPerInstance,
+			},
+			DashboardURL:               dashboardURL,
+			DeprovisionGracePeriod:     gracePeriod,
+			PVCNaming:                  k8sbroker.PVCNamingStrategy(*pvcNamingStrategy),
+			AnnotationPropagationDelay: *annotationPropagationDelay,
+			ParallelProvisionWorkers:   *parallelProvisionWorkers,
+			LabelPrefix:                *labelPrefix,
+			ResourceNamePrefix:         *resourceNamePrefix,
+			ExtraVolumeAttributes:      extraAttributes,
+			ProvisionWatchTimeout:      *provisionWatchTimeout,
+			ProvisionTimeout:           *provisionTimeout,
+			ExtraPVLabels:              pvLabels,
+			ExtraPVCLabels:             pvcLabels,
+			WatchClient:                watchClient,
+		},
 	)
 	if err != nil {
 		logger.Fatal("creating-k8s-broker-error", err)
 	}
 
+	if *recoverPartialProvisionOnStart {
+		err = serviceBroker.RecoverPartialProvision(logger, *recoverPartialProvisionMinAge)
+		if err != nil {
+			logger.Error("recovering-partial-provision-error", err)
+		}
+	}
+
+	if *gcOrphanedPVsOnStart {
+		err = serviceBroker.DeleteOrphanedVolumes(logger)
+		if err != nil {
+			logger.Error("deleting-orphaned-volumes-error", err)
+		}
+	}
+
+	if *purgeStaleOnStart {
+		purged, err := serviceBroker.PurgeStalePVCs(context.Background())
+		if err != nil {
+			logger.Error("purging-stale-pvcs-error", err)
+		}
+		logger.Info("purged-stale-pvcs", lager.Data{"count": purged})
+	}
+
+	if gracePeriod > 0 {
+		interval := time.Minute
+		if gracePeriod < interval {
+			interval = gracePeriod
+		}
+		serviceBroker.StartDeferredDeletes(interval)
+	}
+
+	if *volumeHealthCheckInterval > 0 {
+		serviceBroker.StartHealthChecks(*volumeHealthCheckInterval)
+	}
+
+	if *watchNamespace {
+		serviceBroker.StartNamespaceWatch()
+	}
+
+	newBrokerHandler := func(credentials brokerapi.BrokerCredentials) http.Handler {
+		return brokerapi.New(serviceBroker, logger.Session("broker-api"), credentials)
+	}
+
 	credentials := brokerapi.BrokerCredentials{Username: username, Password: password}
-	handler := brokerapi.New(serviceBroker, logger.Session("broker-api"), credentials)
+	var vaultClient *vault.Client
+	if *vaultAddress != "" {
+		vaultClient = vault.NewClient(*vaultAddress, *vaultToken)
+		vaultCredentials, err := vaultClient.ReadCredentials(*vaultPath)
+		if err != nil {
+			logger.Fatal("reading-vault-credentials-error", err)
+		}
+		credentials = brokerapi.BrokerCredentials{Username: vaultCredentials.Username, Password: vaultCredentials.Password}
+	}
+
+	handler := &brokerHandler{}
+	handler.current.Store(newBrokerHandler(credentials))
+	if vaultClient != nil && *vaultCredentialTTL > 0 {
+		go refreshVaultCredentials(logger, vaultClient, *vaultCredentialTTL, func(credentials vault.Credentials) {
+			handler.current.Store(newBrokerHandler(brokerapi.BrokerCredentials{Username: credentials.Username, Password: credentials.Password}))
+		})
+	}
+
+	// the admin endpoints share the broker API's request IDs, audit log and
+	// rate limits; only the health check is served outside them
+	api := http.NewServeMux()
+	if adminUsername != "" && adminPassword != "" {
+		api.Handle("/admin/reload", admin.NewReloadHandler(logger, services, *servicesConfig, adminUsername, adminPassword))
+		api.Handle("/v2/service_instances", admin.NewInstancesHandler(logger, serviceBroker, adminUsername, adminPassword))
+		api.Handle("/admin/service_instances/", admin.NewBindingsHandler(logger, serviceBroker, adminUsername, adminPassword))
+	}
+	api.Handle("/", pagination.CatalogMiddleware(handler))
 
-	return http_server.New(*atAddress, handler)
+	limits := ratelimit.Limits{
+		ProvisionPerSecond:   *maxProvisionPerSecond,
+		BindPerSecond:        *maxBindPerSecond,
+		DeprovisionPerSecond: *maxDeprovisionPerSecond,
+	}
+	auditLogger := logger
+	if *auditLogFile != "" {
+		file, err := os.OpenFile(*auditLogFile, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+		if err != nil {
+			logger.Fatal("cannot-open-audit-log-file", err, lager.Data{"path": *auditLogFile})
+		}
+		auditLogger = lager.NewLogger("k8sbroker")
+		auditLogger.RegisterSink(lager.NewWriterSink(file, lager.INFO))
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/healthz", health.NewHealthHandler(logger, store, services))
+	mux.Handle("/", requestid.Middleware(audit.New(auditLogger, clock.NewClock(), ratelimit.New(limits, api))))
+
+	if *tlsCert != "" {
+		tlsConfig, err := createTLSConfig()
+		if err != nil {
+			logger.Fatal("failed-to-create-tls-config", err)
+		}
+		return utils.NewGracefulServer(*atAddress, mux, tlsConfig, *shutdownGracePeriod)
+	}
+
+	return utils.NewGracefulServer(*atAddress, mux, nil, *shutdownGracePeriod)
 }
 
-func ConvertPostgresError(err *pq.Error) string {
-	return ""
+// waitForKubeAPI asks the API server for its version, retrying so that the
+// broker can start while the cluster is still coming up.
+func waitForKubeAPI(logger lager.Logger, client discovery.ServerVersionInterface, retries int, interval time.Duration) error {
+	for attempt := 1; ; attempt++ {
+		_, err := client.ServerVersion()
+		if err == nil || attempt > retries {
+			return err
+		}
+
+		logger.Info("kube-api-not-ready", lager.Data{"attempt": attempt, "error": err.Error()})
+		time.Sleep(interval)
+	}
 }
 
-func ConvertMySqlError(err mysql.MySQLError) string {
-	return ""
+// splitList splits a comma separated flag value, dropping empty entries.
+func splitList(value string) []string {
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// brokerHandler serves the broker API with the current credentials. brokerapi
+// only takes credentials when the handler is built, so a refresh from Vault
+// swaps in a new handler.
+type brokerHandler struct {
+	current atomic.Value
+}
+
+func (h *brokerHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.current.Load().(http.Handler).ServeHTTP(w, r)
+}
+
+// refreshVaultCredentials reads the credentials again every ttl. The previous
+// credentials stay in use when Vault cannot be read.
+func refreshVaultCredentials(logger lager.Logger, client *vault.Client, ttl time.Duration, update func(vault.Credentials)) {
+	logger = logger.Session("refresh-vault-credentials")
+	ticker := time.NewTicker(ttl)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		credentials, err := client.ReadCredentials(*vaultPath)
+		if err != nil {
+			logger.Error("reading-vault-credentials-error", err)
+			continue
+		}
+		update(credentials)
+		logger.Info("refreshed")
+	}
+}
+
+func createTLSConfig() (*tls.Config, error) {
+	certificate, err := tls.LoadX509KeyPair(*tlsCert, *tlsKey)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if *tlsClientCA != "" {
+		caCert, err := ioutil.ReadFile(*tlsClientCA)
+		if err != nil {
+			return nil, err
+		}
+
+		clientCAs := x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("no certificates found in %s", *tlsClientCA)
+		}
+		tlsConfig.ClientCAs = clientCAs
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return tlsConfig, nil
+}
+
+// buildKubeConfig loads the kube config file, selecting the named context when
+// one is given. clientcmd.BuildConfigFromFlags always uses the current context.
+func buildKubeConfig(logger lager.Logger, path string, contextName string) (*rest.Config, error) {
+	if contextName == "" {
+		return clientcmd.BuildConfigFromFlags("", path)
+	}
+
+	rawConfig, err := clientcmd.LoadFromFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := rawConfig.Contexts[contextName]; !ok {
+		var available []string
+		for name := range rawConfig.Contexts {
+			available = append(available, name)
+		}
+		sort.Strings(available)
+		logger.Fatal("kube-context-not-found", fmt.Errorf("context %q not found in %s", contextName, path), lager.Data{"available-contexts": available})
+	}
+
+	logger.Info(fmt.Sprintf("Using kube context %s", contextName))
+	return clientcmd.NewNonInteractiveClientConfig(*rawConfig, contextName, &clientcmd.ConfigOverrides{}, nil).ClientConfig()
 }
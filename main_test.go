@@ -1,11 +1,22 @@
 package main
 
 import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
 	"io"
+	"math/big"
+	"net"
 	"net/http"
+	"net/http/httptest"
 	"os/exec"
 	"path/filepath"
 	"strconv"
+	"strings"
+	"syscall"
 
 	"encoding/json"
 	"io/ioutil"
@@ -114,6 +125,47 @@ func (r failRunner) Run(sigChan <-chan os.Signal, ready chan<- struct{}) error {
 	}
 }
 
+func writeSelfSignedCert(dir string) (string, string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	Expect(err).NotTo(HaveOccurred())
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	Expect(err).NotTo(HaveOccurred())
+
+	certPath := filepath.Join(dir, "broker.crt")
+	keyPath := filepath.Join(dir, "broker.key")
+	Expect(ioutil.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644)).To(Succeed())
+	Expect(ioutil.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}), 0600)).To(Succeed())
+	return certPath, keyPath
+}
+
+// unreachableServicesConfig writes a services config whose service names a
+// CSI endpoint that nothing listens on.
+func unreachableServicesConfig() string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	Expect(err).NotTo(HaveOccurred())
+	addr := listener.Addr().String()
+	Expect(listener.Close()).To(Succeed())
+
+	servicesConfig := filepath.Join(os.TempDir(), "unreachable-csi-services.json")
+	Expect(ioutil.WriteFile(servicesConfig, []byte(fmt.Sprintf(`[{
+		"id": "csi-service-id",
+		"name": "csi",
+		"connection_address": %q,
+		"plans": [{"id": "csi-plan-id", "name": "CSI"}]
+	}]`, addr)), 0644)).To(Succeed())
+	return servicesConfig
+}
+
 var _ = Describe("k8sbroker Main", func() {
 	Context("Missing required args", func() {
 		var process ifrit.Process
@@ -138,6 +190,194 @@ var _ = Describe("k8sbroker Main", func() {
 			process = ifrit.Invoke(volmanRunner)
 		})
 
+		It("shows usage when tlsCert is provided without tlsKey", func() {
+			args := []string{"-dataDir", os.TempDir(), "-servicesConfig", "./default_services.json", "-kubeConfig", "some-path", "-tlsCert", "some-cert"}
+			volmanRunner := failRunner{
+				Name:       "k8sbroker",
+				Command:    exec.Command(binaryPath, args...),
+				StartCheck: "Both tlsCert and tlsKey parameters must be provided to serve over TLS.",
+			}
+			process = ifrit.Invoke(volmanRunner)
+		})
+
+		It("shows usage when tlsKey is provided without tlsCert", func() {
+			args := []string{"-dataDir", os.TempDir(), "-servicesConfig", "./default_services.json", "-kubeConfig", "some-path", "-tlsKey", "some-key"}
+			volmanRunner := failRunner{
+				Name:       "k8sbroker",
+				Command:    exec.Command(binaryPath, args...),
+				StartCheck: "Both tlsCert and tlsKey parameters must be provided to serve over TLS.",
+			}
+			process = ifrit.Invoke(volmanRunner)
+		})
+
+		It("shows usage when neither kubeConfig nor kubeInCluster is provided", func() {
+			args := []string{"-dataDir", os.TempDir(), "-servicesConfig", "./default_services.json"}
+			volmanRunner := failRunner{
+				Name:       "k8sbroker",
+				Command:    exec.Command(binaryPath, args...),
+				StartCheck: "Exactly one of kubeConfig, kubeInCluster or cfKubeServiceName parameters must be provided.",
+			}
+			process = ifrit.Invoke(volmanRunner)
+		})
+
+		It("shows usage when both kubeConfig and kubeInCluster are provided", func() {
+			args := []string{"-dataDir", os.TempDir(), "-servicesConfig", "./default_services.json", "-kubeConfig", "some-path", "-kubeInCluster"}
+			volmanRunner := failRunner{
+				Name:       "k8sbroker",
+				Command:    exec.Command(binaryPath, args...),
+				StartCheck: "Exactly one of kubeConfig, kubeInCluster or cfKubeServiceName parameters must be provided.",
+			}
+			process = ifrit.Invoke(volmanRunner)
+		})
+
+		It("shows usage when both kubeConfig and cfKubeServiceName are provided", func() {
+			args := []string{"-dataDir", os.TempDir(), "-servicesConfig", "./default_services.json", "-kubeConfig", "some-path", "-cfKubeServiceName", "k8s"}
+			volmanRunner := failRunner{
+				Name:       "k8sbroker",
+				Command:    exec.Command(binaryPath, args...),
+				StartCheck: "Exactly one of kubeConfig, kubeInCluster or cfKubeServiceName parameters must be provided.",
+			}
+			process = ifrit.Invoke(volmanRunner)
+		})
+
+		It("shows usage when the PVC naming strategy is unknown", func() {
+			args := []string{"-dataDir", os.TempDir(), "-servicesConfig", "./default_services.json", "-kubeConfig", "some-path", "-pvcNamingStrategy", "random"}
+			volmanRunner := failRunner{
+				Name:       "k8sbroker",
+				Command:    exec.Command(binaryPath, args...),
+				StartCheck: "pvcNamingStrategy parameter must be volume-name, binding-id or volume-binding-id.",
+			}
+			process = ifrit.Invoke(volmanRunner)
+		})
+
+		It("shows usage when the resource name prefix is not a valid name", func() {
+			args := []string{"-dataDir", os.TempDir(), "-servicesConfig", "./default_services.json", "-kubeConfig", "some-path", "-resourceNamePrefix", "Team_A-"}
+			volmanRunner := failRunner{
+				Name:       "k8sbroker",
+				Command:    exec.Command(binaryPath, args...),
+				StartCheck: "resourceNamePrefix parameter must start with a lowercase letter or digit",
+			}
+			process = ifrit.Invoke(volmanRunner)
+		})
+
+		It("shows usage when the database sslmode is unknown", func() {
+			args := []string{"-dbDriver", "postgres", "-servicesConfig", "./default_services.json", "-kubeConfig", "some-path", "-dbSSLMode", "prefer"}
+			volmanRunner := failRunner{
+				Name:       "k8sbroker",
+				Command:    exec.Command(binaryPath, args...),
+				StartCheck: "dbSSLMode parameter must be disable, verify-ca or verify-full.",
+			}
+			process = ifrit.Invoke(volmanRunner)
+		})
+
+		It("shows usage when a verifying database sslmode has no CA cert", func() {
+			args := []string{"-dbDriver", "postgres", "-servicesConfig", "./default_services.json", "-kubeConfig", "some-path", "-dbSSLMode", "verify-full"}
+			volmanRunner := failRunner{
+				Name:       "k8sbroker",
+				Command:    exec.Command(binaryPath, args...),
+				StartCheck: "dbSSLMode verify-full requires dbCACertPath.",
+			}
+			process = ifrit.Invoke(volmanRunner)
+		})
+
+		It("lists the available contexts when the kube context does not exist", func() {
+			kubeConfig := filepath.Join(os.TempDir(), "kube-config-contexts.yml")
+			err := ioutil.WriteFile(kubeConfig, []byte(`apiVersion: v1
+kind: Config
+clusters:
+- cluster:
+    server: https://horse.org:4443
+  name: horse-cluster
+contexts:
+- context:
+    cluster: horse-cluster
+  name: staging
+- context:
+    cluster: horse-cluster
+  name: production
+current-context: staging`), 0644)
+			Expect(err).NotTo(HaveOccurred())
+
+			args := []string{
+				"-dataDir", os.TempDir(),
+				"-servicesConfig", "./default_services.json",
+				"-kubeConfig", kubeConfig,
+				"-kubeContextName", "missing",
+			}
+			volmanRunner := failRunner{
+				Name:       "k8sbroker",
+				Command:    exec.Command(binaryPath, args...),
+				StartCheck: `"available-contexts":["production","staging"]`,
+			}
+			process = ifrit.Invoke(volmanRunner)
+		})
+
+		It("exits once the connection retries to the Kubernetes API are used up", func() {
+			kubeAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}))
+			defer kubeAPI.Close()
+
+			kubeConfig := filepath.Join(os.TempDir(), "kube-config-unavailable.yml")
+			err := ioutil.WriteFile(kubeConfig, []byte(fmt.Sprintf(`apiVersion: v1
+kind: Config
+clusters:
+- cluster:
+    server: %s
+  name: unavailable-cluster
+contexts:
+- context:
+    cluster: unavailable-cluster
+  name: unavailable
+current-context: unavailable`, kubeAPI.URL)), 0644)
+			Expect(err).NotTo(HaveOccurred())
+
+			args := []string{
+				"-dataDir", os.TempDir(),
+				"-servicesConfig", "./default_services.json",
+				"-kubeConfig", kubeConfig,
+				"-maxConnectionRetries", "2",
+				"-connectionRetryInterval", "10ms",
+			}
+			volmanRunner := failRunner{
+				Name:       "k8sbroker",
+				Command:    exec.Command(binaryPath, args...),
+				StartCheck: "failed-to-reach-kube-api",
+			}
+			process = ifrit.Invoke(volmanRunner)
+		})
+
+		It("exits when a CSI endpoint cannot be reached and failOnCSIConnectionError is set", func() {
+			args := []string{
+				"-dataDir", os.TempDir(),
+				"-servicesConfig", unreachableServicesConfig(),
+				"-kubeConfig", filepath.Join(os.TempDir(), "kube-config.json"),
+				"-csiConnectionTimeout", "100ms",
+				"-failOnCSIConnectionError",
+			}
+			volmanRunner := failRunner{
+				Name:       "k8sbroker",
+				Command:    exec.Command(binaryPath, args...),
+				StartCheck: "failed-to-reach-csi-endpoints",
+			}
+			process = ifrit.Invoke(volmanRunner)
+		})
+
+		It("fails fast when the dashboard URL template is invalid", func() {
+			args := []string{
+				"-dataDir", os.TempDir(),
+				"-servicesConfig", "./default_services.json",
+				"-kubeConfig", filepath.Join(os.TempDir(), "kube-config.json"),
+				"-dashboardURLTemplate", "https://dashboard.example.com/{{.Name",
+			}
+			volmanRunner := failRunner{
+				Name:       "k8sbroker",
+				Command:    exec.Command(binaryPath, args...),
+				StartCheck: "parsing-dashboard-url-template-error",
+			}
+			process = ifrit.Invoke(volmanRunner)
+		})
+
 		AfterEach(func() {
 			ginkgomon.Kill(process) // this is only if incorrect implementation leaves process running
 		})
@@ -147,14 +387,17 @@ var _ = Describe("k8sbroker Main", func() {
 		var (
 			args               []string
 			listenAddr         string
+			metricsAddr        string
 			tempDir            string
 			username, password string
+			kubeAPIServer      *httptest.Server
 
 			process ifrit.Process
 		)
 
 		BeforeEach(func() {
 			listenAddr = "0.0.0.0:" + strconv.Itoa(8999+GinkgoParallelNode())
+			metricsAddr = "0.0.0.0:" + strconv.Itoa(9102+GinkgoParallelNode())
 			username = "admin"
 			password = "password"
 			tempDir = os.TempDir()
@@ -162,11 +405,13 @@ var _ = Describe("k8sbroker Main", func() {
 			os.Setenv("USERNAME", username)
 			os.Setenv("PASSWORD", password)
 
+			kubeAPIServer = httptest.NewServer(http.HandlerFunc(serveKubeVersion))
+
 			d1 := []byte(`current-context: federal-context
 apiVersion: v1
 clusters:
 - cluster:
-    server: https://horse.org:4443
+    server: ` + kubeAPIServer.URL + `
   name: horse-cluster
 contexts:
 - context:
@@ -186,7 +431,9 @@ users:
 			err := ioutil.WriteFile(kubeConfig, d1, 0644)
 			Expect(err).NotTo(HaveOccurred())
 
+			args = []string{}
 			args = append(args, "-listenAddr", listenAddr)
+			args = append(args, "-metricsAddr", metricsAddr)
 			args = append(args, "-dataDir", tempDir)
 			args = append(args, "-servicesConfig", "./default_services.json")
 			args = append(args, "-kubeConfig", kubeConfig)
@@ -203,6 +450,7 @@ users:
 
 		AfterEach(func() {
 			ginkgomon.Kill(process)
+			kubeAPIServer.Close()
 		})
 
 		httpDoWithAuth := func(method, endpoint string, body io.ReadCloser) (*http.Response, error) {
@@ -221,6 +469,272 @@ users:
 			Expect(resp.StatusCode).To(Equal(200))
 		})
 
+		It("should echo the request ID", func() {
+			req, err := http.NewRequest("GET", "http://"+listenAddr+"/v2/catalog", nil)
+			Expect(err).NotTo(HaveOccurred())
+			req.Header.Add("X-Broker-Api-Version", "2.14")
+			req.Header.Add("X-Request-ID", "some-request-id")
+			req.SetBasicAuth(username, password)
+
+			resp, err := http.DefaultClient.Do(req)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.Header.Get("X-Request-ID")).To(Equal("some-request-id"))
+		})
+
+		It("should serve a health check without credentials", func() {
+			resp, err := http.Get("http://" + listenAddr + "/healthz")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(200))
+
+			bytes, err := ioutil.ReadAll(resp.Body)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(bytes).To(MatchJSON(`{"status":"ok","store":"ok"}`))
+		})
+
+		Context("when a kube context name is given", func() {
+			BeforeEach(func() {
+				args = append(args, "-kubeContextName", "federal-context")
+			})
+
+			It("should listen on the given address", func() {
+				resp, err := httpDoWithAuth("GET", "/v2/catalog", nil)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(resp.StatusCode).To(Equal(200))
+			})
+		})
+
+		Context("when the kube client rate limits are given", func() {
+			BeforeEach(func() {
+				args = append(args, "-kubeQPS", "50.5", "-kubeMaxBurst", "100")
+			})
+
+			It("should listen on the given address", func() {
+				resp, err := httpDoWithAuth("GET", "/v2/catalog", nil)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(resp.StatusCode).To(Equal(200))
+			})
+		})
+
+		Context("when the kube credentials come from VCAP_SERVICES", func() {
+			BeforeEach(func() {
+				for i, arg := range args {
+					if arg == "-kubeConfig" {
+						args = append(args[:i], args[i+2:]...)
+						break
+					}
+				}
+				args = append(args, "-cfKubeServiceName", "k8s")
+				os.Setenv("VCAP_SERVICES", fmt.Sprintf(`{"user-provided": [{"name": "k8s", "credentials": {"host": %q, "token": "some-token"}}]}`, kubeAPIServer.URL))
+			})
+
+			AfterEach(func() {
+				os.Unsetenv("VCAP_SERVICES")
+			})
+
+			It("should listen on the given address", func() {
+				resp, err := httpDoWithAuth("GET", "/v2/catalog", nil)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(resp.StatusCode).To(Equal(200))
+			})
+		})
+
+		Context("when a services config dir is given", func() {
+			BeforeEach(func() {
+				servicesDir := filepath.Join(tempDir, "services")
+				Expect(os.MkdirAll(servicesDir, 0755)).To(Succeed())
+				Expect(ioutil.WriteFile(filepath.Join(servicesDir, "extra.json"), []byte(`[{"id": "extra-service-id", "name": "extra-nfs", "plans": [{"id": "extra-plan-id", "name": "Extra"}]}]`), 0644)).To(Succeed())
+				args = append(args, "-servicesConfigDir", servicesDir)
+			})
+
+			It("serves the services of both configs", func() {
+				resp, err := httpDoWithAuth("GET", "/v2/catalog", nil)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(resp.StatusCode).To(Equal(200))
+
+				var catalog brokerapi.CatalogResponse
+				Expect(json.NewDecoder(resp.Body).Decode(&catalog)).To(Succeed())
+				Expect(catalog.Services).To(HaveLen(2))
+				Expect(catalog.Services[1].ID).To(Equal("extra-service-id"))
+			})
+		})
+
+		Context("when TLS is configured", func() {
+			BeforeEach(func() {
+				certPath, keyPath := writeSelfSignedCert(tempDir)
+				args = append(args, "-tlsCert", certPath, "-tlsKey", keyPath)
+			})
+
+			It("serves the broker API over HTTPS", func() {
+				client := &http.Client{Transport: &http.Transport{
+					TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+				}}
+				resp, err := client.Get("https://" + listenAddr + "/healthz")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(resp.StatusCode).To(Equal(200))
+				Expect(resp.TLS).NotTo(BeNil())
+			})
+		})
+
+		Context("when admin credentials are set", func() {
+			BeforeEach(func() {
+				os.Setenv("ADMIN_USERNAME", "operator")
+				os.Setenv("ADMIN_PASSWORD", "operator-password")
+			})
+
+			AfterEach(func() {
+				os.Unsetenv("ADMIN_USERNAME")
+				os.Unsetenv("ADMIN_PASSWORD")
+			})
+
+			It("reloads the services config", func() {
+				req, err := http.NewRequest("POST", "http://"+listenAddr+"/admin/reload", nil)
+				Expect(err).NotTo(HaveOccurred())
+				req.SetBasicAuth("operator", "operator-password")
+
+				resp, err := http.DefaultClient.Do(req)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(resp.StatusCode).To(Equal(http.StatusNoContent))
+			})
+
+			It("rejects the broker credentials", func() {
+				resp, err := httpDoWithAuth("POST", "/admin/reload", nil)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+			})
+
+			It("echoes the request ID like the broker API", func() {
+				req, err := http.NewRequest("POST", "http://"+listenAddr+"/admin/reload", nil)
+				Expect(err).NotTo(HaveOccurred())
+				req.Header.Add("X-Request-ID", "some-admin-request-id")
+				req.SetBasicAuth("operator", "operator-password")
+
+				resp, err := http.DefaultClient.Do(req)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(resp.Header.Get("X-Request-ID")).To(Equal("some-admin-request-id"))
+			})
+		})
+
+		Context("when the Kubernetes API is not ready at first", func() {
+			var versionRequests chan struct{}
+
+			BeforeEach(func() {
+				versionRequests = make(chan struct{}, 10)
+				kubeAPIServer.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					if r.URL.Path != "/version" {
+						serveKubeVersion(w, r)
+						return
+					}
+					versionRequests <- struct{}{}
+					if len(versionRequests) < 3 {
+						w.WriteHeader(http.StatusServiceUnavailable)
+						return
+					}
+					serveKubeVersion(w, r)
+				})
+				args = append(args, "-connectionRetryInterval", "10ms")
+			})
+
+			It("retries until the API answers", func() {
+				Expect(versionRequests).To(HaveLen(3))
+
+				resp, err := httpDoWithAuth("GET", "/v2/catalog", nil)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(resp.StatusCode).To(Equal(200))
+			})
+		})
+
+		Context("when a CSI endpoint cannot be reached", func() {
+			BeforeEach(func() {
+				args = append(args, "-servicesConfig", unreachableServicesConfig())
+				args = append(args, "-csiConnectionTimeout", "100ms")
+			})
+
+			It("starts degraded", func() {
+				resp, err := httpDoWithAuth("GET", "/v2/catalog", nil)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(resp.StatusCode).To(Equal(200))
+			})
+		})
+
+		Context("when stopped during a request", func() {
+			var (
+				kubeAPI     *httptest.Server
+				kubeRequest chan struct{}
+			)
+
+			BeforeEach(func() {
+				kubeRequest = make(chan struct{}, 10)
+				kubeAPI = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					if r.URL.Path == "/version" {
+						serveKubeVersion(w, r)
+						return
+					}
+					if r.Method == "GET" && r.URL.Path == "/api/v1/persistentvolumes" {
+						w.Header().Set("Content-Type", "application/json")
+						w.Write([]byte(`{"kind": "PersistentVolumeList", "apiVersion": "v1", "items": []}`))
+						return
+					}
+					select {
+					case kubeRequest <- struct{}{}:
+					default:
+					}
+					time.Sleep(2 * time.Second)
+					w.WriteHeader(http.StatusInternalServerError)
+				}))
+
+				kubeConfig := filepath.Join(tempDir, "kube-config-slow.yml")
+				err := ioutil.WriteFile(kubeConfig, []byte(fmt.Sprintf(`apiVersion: v1
+kind: Config
+clusters:
+- cluster:
+    server: %s
+  name: slow-cluster
+contexts:
+- context:
+    cluster: slow-cluster
+  name: slow
+current-context: slow`, kubeAPI.URL)), 0644)
+				Expect(err).NotTo(HaveOccurred())
+
+				args = append(args, "-kubeConfig", kubeConfig, "-shutdownGracePeriod", "10s")
+			})
+
+			AfterEach(func() {
+				kubeAPI.Close()
+			})
+
+			It("finishes the request before exiting", func() {
+				responses := make(chan *http.Response, 1)
+				go func() {
+					defer GinkgoRecover()
+					body := `{
+						"service_id": "db404fc5-97fb-4806-9827-07e0e8d3bd51",
+						"plan_id": "190de554-4fc1-4008-ace9-5d3796140b48",
+						"organization_guid": "some-org",
+						"space_guid": "some-space",
+						"parameters": {"server": "10.0.0.5", "share": "/export"}
+					}`
+					resp, err := httpDoWithAuth("PUT", "/v2/service_instances/some-instance-id", ioutil.NopCloser(strings.NewReader(body)))
+					Expect(err).NotTo(HaveOccurred())
+					responses <- resp
+				}()
+
+				Eventually(kubeRequest, 5*time.Second).Should(Receive())
+				process.Signal(syscall.SIGTERM)
+
+				var resp *http.Response
+				Eventually(responses, 10*time.Second).Should(Receive(&resp))
+				Expect(resp.StatusCode).To(Equal(http.StatusInternalServerError))
+				Eventually(process.Wait(), 10*time.Second).Should(Receive())
+			})
+		})
+
+		It("should serve prometheus metrics without credentials", func() {
+			resp, err := http.Get("http://" + metricsAddr + "/metrics")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(200))
+		})
+
 		It("should pass services config through to catalog", func() {
 			resp, err := httpDoWithAuth("GET", "/v2/catalog", nil)
 			Expect(err).NotTo(HaveOccurred())
@@ -241,3 +755,8 @@ users:
 		})
 	})
 })
+
+func serveKubeVersion(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"major": "1", "minor": "13", "gitVersion": "v1.13.0"}`))
+}
@@ -0,0 +1,85 @@
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	Provision   = "provision"
+	Deprovision = "deprovision"
+	Bind        = "bind"
+	Unbind      = "unbind"
+)
+
+const (
+	StatusSuccess = "success"
+	StatusError   = "error"
+)
+
+var operations = []string{Provision, Deprovision, Bind, Unbind}
+
+type Metrics struct {
+	totals              map[string]*prometheus.CounterVec
+	durations           map[string]*prometheus.HistogramVec
+	volumeHealthFailure prometheus.Counter
+}
+
+// New creates a counter (e.g. provision_total) and a duration histogram
+// (e.g. provision_duration_seconds) for each broker operation, labelled by
+// status, and registers them with the given registerer.
+func New(registerer prometheus.Registerer) *Metrics {
+	m := &Metrics{
+		totals:    map[string]*prometheus.CounterVec{},
+		durations: map[string]*prometheus.HistogramVec{},
+	}
+
+	for _, operation := range operations {
+		m.totals[operation] = prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: operation + "_total",
+			Help: "Number of " + operation + " requests handled by the broker.",
+		}, []string{"status"})
+
+		m.durations[operation] = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    operation + "_duration_seconds",
+			Help:    "Time taken to handle " + operation + " requests.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"status"})
+
+		registerer.MustRegister(m.totals[operation], m.durations[operation])
+	}
+
+	m.volumeHealthFailure = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "volume_health_check_failed_total",
+		Help: "Number of persistent volumes found in the Failed phase by volume health checks.",
+	})
+	registerer.MustRegister(m.volumeHealthFailure)
+
+	return m
+}
+
+func (m *Metrics) Observe(operation string, duration time.Duration, err error) {
+	total, ok := m.totals[operation]
+	if !ok {
+		return
+	}
+
+	status := StatusSuccess
+	if err != nil {
+		status = StatusError
+	}
+
+	total.WithLabelValues(status).Inc()
+	m.durations[operation].WithLabelValues(status).Observe(duration.Seconds())
+}
+
+func (m *Metrics) VolumeHealthCheckFailed() {
+	m.volumeHealthFailure.Inc()
+}
+
+func Handler(gatherer prometheus.Gatherer) http.Handler {
+	return promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,70 @@
+package metrics_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http/httptest"
+	"time"
+
+	"code.cloudfoundry.org/k8sbroker/metrics"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var _ = Describe("Metrics", func() {
+	var (
+		registry *prometheus.Registry
+		m        *metrics.Metrics
+	)
+
+	scrape := func() string {
+		server := httptest.NewServer(metrics.Handler(registry))
+		defer server.Close()
+
+		resp, err := server.Client().Get(server.URL)
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+
+		body, err := ioutil.ReadAll(resp.Body)
+		Expect(err).NotTo(HaveOccurred())
+		return string(body)
+	}
+
+	BeforeEach(func() {
+		registry = prometheus.NewRegistry()
+		m = metrics.New(registry)
+	})
+
+	It("counts successful operations", func() {
+		m.Observe(metrics.Provision, time.Second, nil)
+		m.Observe(metrics.Provision, time.Second, nil)
+
+		Expect(scrape()).To(ContainSubstring(`provision_total{status="success"} 2`))
+	})
+
+	It("counts failed operations", func() {
+		m.Observe(metrics.Bind, time.Second, errors.New("badness"))
+
+		Expect(scrape()).To(ContainSubstring(`bind_total{status="error"} 1`))
+	})
+
+	It("records durations", func() {
+		m.Observe(metrics.Unbind, 2*time.Second, nil)
+
+		output := scrape()
+		Expect(output).To(ContainSubstring(`unbind_duration_seconds_sum{status="success"} 2`))
+		Expect(output).To(ContainSubstring(`unbind_duration_seconds_count{status="success"} 1`))
+	})
+
+	It("counts failed volume health checks", func() {
+		m.VolumeHealthCheckFailed()
+		Expect(scrape()).To(ContainSubstring("volume_health_check_failed_total 1"))
+	})
+
+	It("ignores unknown operations", func() {
+		m.Observe("update", time.Second, nil)
+
+		Expect(scrape()).NotTo(ContainSubstring("update"))
+	})
+})
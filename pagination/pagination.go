@@ -0,0 +1,111 @@
+package pagination
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/pivotal-cf/brokerapi"
+)
+
+const (
+	CatalogPath     = "/v2/catalog"
+	DefaultPageSize = 50
+)
+
+// Paginate returns the services on the given page, counting from 1, and the
+// number of the next page, or 0 when there is none.
+func Paginate(services []brokerapi.Service, page, pageSize int) ([]brokerapi.Service, int) {
+	start := (page - 1) * pageSize
+	if page < 1 || pageSize < 1 || start >= len(services) {
+		return []brokerapi.Service{}, 0
+	}
+
+	end := start + pageSize
+	if end >= len(services) {
+		return services[start:], 0
+	}
+	return services[start:end], page + 1
+}
+
+// CatalogMiddleware pages the catalog served by next when it is requested
+// with a page query parameter, and optionally a page_size. The next page is
+// linked in a Link header with rel="next". Other requests pass through.
+func CatalogMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if r.Method != http.MethodGet || r.URL.Path != CatalogPath || query.Get("page") == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		page, err := strconv.Atoi(query.Get("page"))
+		if err != nil || page < 1 {
+			writeError(w, "page must be a positive integer")
+			return
+		}
+		pageSize := DefaultPageSize
+		if query.Get("page_size") != "" {
+			pageSize, err = strconv.Atoi(query.Get("page_size"))
+			if err != nil || pageSize < 1 {
+				writeError(w, "page_size must be a positive integer")
+				return
+			}
+		}
+
+		buffered := &bufferedResponse{header: http.Header{}, status: http.StatusOK}
+		next.ServeHTTP(buffered, r)
+
+		var catalog brokerapi.CatalogResponse
+		if buffered.status != http.StatusOK || json.Unmarshal(buffered.body.Bytes(), &catalog) != nil {
+			buffered.copyTo(w)
+			return
+		}
+
+		var nextPage int
+		catalog.Services, nextPage = Paginate(catalog.Services, page, pageSize)
+		if nextPage > 0 {
+			w.Header().Set("Link", fmt.Sprintf(`<%s?page=%d&page_size=%d>; rel="next"`, r.URL.Path, nextPage, pageSize))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(catalog)
+	})
+}
+
+func writeError(w http.ResponseWriter, description string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(brokerapi.ErrorResponse{Description: description})
+}
+
+// bufferedResponse holds the catalog response so that it can be paged before
+// it is written.
+type bufferedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (b *bufferedResponse) Header() http.Header {
+	return b.header
+}
+
+func (b *bufferedResponse) WriteHeader(status int) {
+	b.status = status
+}
+
+func (b *bufferedResponse) Write(data []byte) (int, error) {
+	return b.body.Write(data)
+}
+
+func (b *bufferedResponse) copyTo(w http.ResponseWriter) {
+	for key, values := range b.header {
+		w.Header()[key] = values
+	}
+	w.WriteHeader(b.status)
+	w.Write(b.body.Bytes())
+}
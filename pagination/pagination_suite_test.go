@@ -0,0 +1,13 @@
+package pagination_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestPagination(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Pagination Suite")
+}
@@ -0,0 +1,128 @@
+package pagination_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+
+	"code.cloudfoundry.org/k8sbroker/pagination"
+	"github.com/pivotal-cf/brokerapi"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func servicesWithIDs(from, to int) []brokerapi.Service {
+	var services []brokerapi.Service
+	for i := from; i < to; i++ {
+		services = append(services, brokerapi.Service{ID: "service-" + strconv.Itoa(i)})
+	}
+	return services
+}
+
+var _ = Describe("Pagination", func() {
+	var services []brokerapi.Service
+
+	BeforeEach(func() {
+		services = servicesWithIDs(0, 100)
+	})
+
+	Describe("Paginate", func() {
+		It("returns the first page and points at the second", func() {
+			page, next := pagination.Paginate(services, 1, 30)
+			Expect(page).To(Equal(servicesWithIDs(0, 30)))
+			Expect(next).To(Equal(2))
+		})
+
+		It("returns a short last page", func() {
+			page, next := pagination.Paginate(services, 4, 30)
+			Expect(page).To(Equal(servicesWithIDs(90, 100)))
+			Expect(next).To(Equal(0))
+		})
+
+		It("has no next page when the last page is full", func() {
+			page, next := pagination.Paginate(services, 2, 50)
+			Expect(page).To(Equal(servicesWithIDs(50, 100)))
+			Expect(next).To(Equal(0))
+		})
+
+		It("returns nothing past the last page", func() {
+			page, next := pagination.Paginate(services, 5, 30)
+			Expect(page).To(BeEmpty())
+			Expect(next).To(Equal(0))
+		})
+	})
+
+	Describe("CatalogMiddleware", func() {
+		var (
+			status   int
+			handler  http.Handler
+			recorder *httptest.ResponseRecorder
+		)
+
+		BeforeEach(func() {
+			status = http.StatusOK
+			handler = pagination.CatalogMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(status)
+				json.NewEncoder(w).Encode(brokerapi.CatalogResponse{Services: services})
+			}))
+			recorder = httptest.NewRecorder()
+		})
+
+		catalog := func() []brokerapi.Service {
+			var response brokerapi.CatalogResponse
+			Expect(json.Unmarshal(recorder.Body.Bytes(), &response)).To(Succeed())
+			return response.Services
+		}
+
+		It("returns the whole catalog without a page", func() {
+			handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/v2/catalog", nil))
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(catalog()).To(HaveLen(100))
+			Expect(recorder.Header().Get("Link")).To(BeEmpty())
+		})
+
+		It("returns the requested page and links the next one", func() {
+			handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/v2/catalog?page=2&page_size=25", nil))
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			Expect(catalog()).To(Equal(servicesWithIDs(25, 50)))
+			Expect(recorder.Header().Get("Link")).To(Equal(`</v2/catalog?page=3&page_size=25>; rel="next"`))
+		})
+
+		It("uses the default page size", func() {
+			handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/v2/catalog?page=2", nil))
+			Expect(catalog()).To(Equal(servicesWithIDs(50, 100)))
+			Expect(recorder.Header().Get("Link")).To(BeEmpty())
+		})
+
+		It("rejects an invalid page", func() {
+			handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/v2/catalog?page=0", nil))
+			Expect(recorder.Code).To(Equal(http.StatusBadRequest))
+			Expect(recorder.Body.String()).To(MatchJSON(`{"description": "page must be a positive integer"}`))
+		})
+
+		It("rejects an invalid page size", func() {
+			handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/v2/catalog?page=1&page_size=all", nil))
+			Expect(recorder.Code).To(Equal(http.StatusBadRequest))
+		})
+
+		Context("when the catalog request fails", func() {
+			BeforeEach(func() {
+				status = http.StatusUnauthorized
+			})
+
+			It("passes the response through", func() {
+				handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/v2/catalog?page=1", nil))
+				Expect(recorder.Code).To(Equal(http.StatusUnauthorized))
+				Expect(recorder.Header().Get("Link")).To(BeEmpty())
+			})
+		})
+
+		It("ignores the page of other requests", func() {
+			handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/v2/service_instances/some-id?page=2", nil))
+			Expect(catalog()).To(HaveLen(100))
+		})
+	})
+})
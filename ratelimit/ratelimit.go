@@ -0,0 +1,78 @@
+package ratelimit
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	Provision   = "provision"
+	Deprovision = "deprovision"
+	Bind        = "bind"
+)
+
+// Limits are the number of requests per second allowed for each operation.
+// Zero means the operation is not limited.
+type Limits struct {
+	ProvisionPerSecond   int
+	DeprovisionPerSecond int
+	BindPerSecond        int
+}
+
+type RateLimiter struct {
+	limiters map[string]*rate.Limiter
+	next     http.Handler
+}
+
+func New(limits Limits, next http.Handler) *RateLimiter {
+	limiters := map[string]*rate.Limiter{}
+	for operation, perSecond := range map[string]int{
+		Provision:   limits.ProvisionPerSecond,
+		Deprovision: limits.DeprovisionPerSecond,
+		Bind:        limits.BindPerSecond,
+	} {
+		if perSecond > 0 {
+			limiters[operation] = rate.NewLimiter(rate.Limit(perSecond), perSecond)
+		}
+	}
+
+	return &RateLimiter{limiters: limiters, next: next}
+}
+
+func (l *RateLimiter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	limiter, ok := l.limiters[operation(r)]
+	if ok {
+		reservation := limiter.Reserve()
+		if delay := reservation.Delay(); delay > 0 {
+			reservation.Cancel()
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+	}
+
+	l.next.ServeHTTP(w, r)
+}
+
+// operation maps an Open Service Broker API request to the operation it
+// performs, or "" for requests that are not limited.
+func operation(r *http.Request) string {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) < 3 || parts[0] != "v2" || parts[1] != "service_instances" {
+		return ""
+	}
+
+	switch {
+	case len(parts) == 3 && r.Method == http.MethodPut:
+		return Provision
+	case len(parts) == 3 && r.Method == http.MethodDelete:
+		return Deprovision
+	case len(parts) == 5 && parts[3] == "service_bindings" && r.Method == http.MethodPut:
+		return Bind
+	}
+	return ""
+}
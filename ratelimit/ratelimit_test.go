@@ -0,0 +1,87 @@
+package ratelimit_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	"code.cloudfoundry.org/k8sbroker/ratelimit"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RateLimiter", func() {
+	var handler http.Handler
+
+	BeforeEach(func() {
+		handler = ratelimit.New(ratelimit.Limits{
+			ProvisionPerSecond:   2,
+			DeprovisionPerSecond: 1,
+			BindPerSecond:        3,
+		}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+	})
+
+	fire := func(method, path string, count int) []*httptest.ResponseRecorder {
+		var recorders []*httptest.ResponseRecorder
+		for i := 0; i < count; i++ {
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest(method, path, nil))
+			recorders = append(recorders, recorder)
+		}
+		return recorders
+	}
+
+	statuses := func(recorders []*httptest.ResponseRecorder) []int {
+		var codes []int
+		for _, recorder := range recorders {
+			codes = append(codes, recorder.Code)
+		}
+		return codes
+	}
+
+	table := []struct {
+		description string
+		method      string
+		path        string
+		count       int
+		expected    []int
+	}{
+		{"provision below the limit", "PUT", "/v2/service_instances/some-id", 2, []int{200, 200}},
+		{"provision above the limit", "PUT", "/v2/service_instances/some-id", 3, []int{200, 200, 429}},
+		{"deprovision below the limit", "DELETE", "/v2/service_instances/some-id", 1, []int{200}},
+		{"deprovision above the limit", "DELETE", "/v2/service_instances/some-id", 2, []int{200, 429}},
+		{"bind below the limit", "PUT", "/v2/service_instances/some-id/service_bindings/binding-id", 3, []int{200, 200, 200}},
+		{"bind above the limit", "PUT", "/v2/service_instances/some-id/service_bindings/binding-id", 4, []int{200, 200, 200, 429}},
+		{"catalog is not limited", "GET", "/v2/catalog", 5, []int{200, 200, 200, 200, 200}},
+		{"unbind is not limited", "DELETE", "/v2/service_instances/some-id/service_bindings/binding-id", 3, []int{200, 200, 200}},
+		{"last operation is not limited", "GET", "/v2/service_instances/some-id/last_operation", 3, []int{200, 200, 200}},
+	}
+
+	for _, entry := range table {
+		entry := entry
+		It(entry.description, func() {
+			Expect(statuses(fire(entry.method, entry.path, entry.count))).To(Equal(entry.expected))
+		})
+	}
+
+	It("sets Retry-After when the limit is exceeded", func() {
+		recorders := fire("DELETE", "/v2/service_instances/some-id", 2)
+		Expect(recorders[1].Header().Get("Retry-After")).To(Equal("1"))
+	})
+
+	It("limits each operation separately", func() {
+		fire("PUT", "/v2/service_instances/some-id", 2)
+		Expect(statuses(fire("DELETE", "/v2/service_instances/some-id", 1))).To(Equal([]int{200}))
+	})
+
+	Context("when an operation has no limit", func() {
+		BeforeEach(func() {
+			handler = ratelimit.New(ratelimit.Limits{}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		})
+
+		It("does not limit it", func() {
+			Expect(statuses(fire("PUT", "/v2/service_instances/some-id", 20))).NotTo(ContainElement(429))
+		})
+	})
+})
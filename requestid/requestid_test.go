@@ -0,0 +1,59 @@
+package requestid_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+
+	"code.cloudfoundry.org/k8sbroker/requestid"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Middleware", func() {
+	var (
+		request   *http.Request
+		recorder  *httptest.ResponseRecorder
+		contextID string
+	)
+
+	BeforeEach(func() {
+		request = httptest.NewRequest("GET", "/v2/catalog", nil)
+		contextID = ""
+	})
+
+	JustBeforeEach(func() {
+		recorder = httptest.NewRecorder()
+		handler := requestid.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			contextID = requestid.FromContext(r.Context())
+		}))
+		handler.ServeHTTP(recorder, request)
+	})
+
+	Context("when the request has an X-Request-ID", func() {
+		BeforeEach(func() {
+			request.Header.Set("X-Request-ID", "some-request-id")
+		})
+
+		It("stores it in the context", func() {
+			Expect(contextID).To(Equal("some-request-id"))
+		})
+
+		It("echoes it in the response", func() {
+			Expect(recorder.Header().Get("X-Request-ID")).To(Equal("some-request-id"))
+		})
+	})
+
+	Context("when the request has no X-Request-ID", func() {
+		It("generates one", func() {
+			Expect(contextID).To(MatchRegexp(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`))
+			Expect(recorder.Header().Get("X-Request-ID")).To(Equal(contextID))
+		})
+	})
+})
+
+var _ = Describe("FromContext", func() {
+	It("is empty when no request ID was stored", func() {
+		Expect(requestid.FromContext(context.Background())).To(BeEmpty())
+	})
+})
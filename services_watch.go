@@ -0,0 +1,67 @@
+package main
+
+import (
+	"path/filepath"
+
+	"code.cloudfoundry.org/k8sbroker/k8sbroker"
+	"code.cloudfoundry.org/lager"
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchServicesConfig reloads the services config whenever a file is created
+// in the directory of pathToServicesConfig or in servicesConfigDir, either of
+// which may be empty. A ConfigMap mounted as a volume is updated by swapping
+// in a new file or symlink, which shows up as a CREATE rather than a WRITE of
+// the config itself. Closing the returned watcher stops the reloads.
+func watchServicesConfig(logger lager.Logger, services k8sbroker.Services, pathToServicesConfig, servicesConfigDir string) (*fsnotify.Watcher, error) {
+	logger = logger.Session("watch-services-config", lager.Data{"path": pathToServicesConfig, "dir": servicesConfigDir})
+
+	var dirs []string
+	if pathToServicesConfig != "" {
+		dirs = append(dirs, filepath.Dir(pathToServicesConfig))
+	}
+	if servicesConfigDir != "" && (len(dirs) == 0 || filepath.Clean(servicesConfigDir) != dirs[0]) {
+		dirs = append(dirs, servicesConfigDir)
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, dir := range dirs {
+		err = watcher.Add(dir)
+		if err != nil {
+			watcher.Close()
+			return nil, err
+		}
+	}
+
+	go func() {
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if event.Op&fsnotify.Create == 0 {
+					continue
+				}
+
+				err := services.Reload(pathToServicesConfig)
+				if err != nil {
+					logger.Error("reloading-services-config-error", err, lager.Data{"file": event.Name})
+					continue
+				}
+				logger.Info("reloaded-services-config", lager.Data{"file": event.Name})
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				logger.Error("watching-services-config-error", err)
+			}
+		}
+	}()
+
+	return watcher, nil
+}
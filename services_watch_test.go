@@ -0,0 +1,113 @@
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"code.cloudfoundry.org/k8sbroker/k8sbroker/k8sbroker_fake"
+	"code.cloudfoundry.org/lager/lagertest"
+	"github.com/fsnotify/fsnotify"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("watchServicesConfig", func() {
+	var (
+		dir          string
+		configPath   string
+		fakeServices *k8sbroker_fake.FakeServices
+		logger       *lagertest.TestLogger
+		watcher      *fsnotify.Watcher
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "services-config")
+		Expect(err).NotTo(HaveOccurred())
+		configPath = filepath.Join(dir, "services.json")
+		Expect(ioutil.WriteFile(configPath, []byte(`[]`), 0644)).To(Succeed())
+
+		fakeServices = &k8sbroker_fake.FakeServices{}
+		logger = lagertest.NewTestLogger("test-broker")
+	})
+
+	JustBeforeEach(func() {
+		var err error
+		watcher, err = watchServicesConfig(logger, fakeServices, configPath, "")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		watcher.Close()
+		os.RemoveAll(dir)
+	})
+
+	// replaceConfig swaps in a new config the way a ConfigMap update does,
+	// replacing the file rather than writing to it.
+	replaceConfig := func() {
+		updated := filepath.Join(dir, "services.json.tmp")
+		Expect(ioutil.WriteFile(updated, []byte(`[{"id": "new-service-id"}]`), 0644)).To(Succeed())
+		Expect(os.Rename(updated, configPath)).To(Succeed())
+	}
+
+	It("reloads the services when the config is replaced", func() {
+		replaceConfig()
+
+		Eventually(fakeServices.ReloadCallCount).Should(BeNumerically(">=", 1))
+		Expect(fakeServices.ReloadArgsForCall(0)).To(Equal(configPath))
+		Eventually(logger.LogMessages).Should(ContainElement("test-broker.watch-services-config.reloaded-services-config"))
+	})
+
+	It("does not reload when the config is written in place", func() {
+		Expect(ioutil.WriteFile(configPath, []byte(`[]`), 0644)).To(Succeed())
+
+		Consistently(fakeServices.ReloadCallCount, "200ms").Should(Equal(0))
+	})
+
+	Context("when the new config cannot be loaded", func() {
+		BeforeEach(func() {
+			fakeServices.ReloadReturns(errors.New("bad-config"))
+		})
+
+		It("logs the error", func() {
+			replaceConfig()
+
+			Eventually(logger.LogMessages).Should(ContainElement("test-broker.watch-services-config.reloading-services-config-error"))
+		})
+	})
+
+	Context("when the config's directory does not exist", func() {
+		It("errors", func() {
+			_, err := watchServicesConfig(logger, fakeServices, filepath.Join(dir, "missing", "services.json"), "")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("when only a config directory is given", func() {
+		var configDir string
+
+		BeforeEach(func() {
+			var err error
+			configDir, err = ioutil.TempDir("", "services-config-dir")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(configDir)
+		})
+
+		It("reloads the services when a config is added to the directory", func() {
+			dirWatcher, err := watchServicesConfig(logger, fakeServices, "", configDir)
+			Expect(err).NotTo(HaveOccurred())
+			defer dirWatcher.Close()
+
+			Expect(ioutil.WriteFile(filepath.Join(configDir, "nfs.json"), []byte(`[]`), 0644)).To(Succeed())
+
+			Eventually(fakeServices.ReloadCallCount).Should(BeNumerically(">=", 1))
+			Expect(fakeServices.ReloadArgsForCall(0)).To(Equal(""))
+		})
+	})
+})
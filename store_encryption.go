@@ -0,0 +1,184 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"code.cloudfoundry.org/service-broker-store/brokerstore"
+	"github.com/pivotal-cf/brokerapi"
+)
+
+// encryptedPrefix marks a value written by EncryptingStore. Values without it
+// were stored before encryption was turned on and are returned as they are.
+const encryptedPrefix = "aes256gcm:"
+
+// EncryptingStore encrypts the fingerprint of each instance and the
+// parameters and context of each binding with AES-256-GCM before the wrapped
+// store sees them, so that no store backend keeps them in plaintext.
+type EncryptingStore struct {
+	brokerstore.Store
+	aead cipher.AEAD
+}
+
+// NewEncryptingStore wraps store with the given hex encoded 32 byte key. An
+// empty key returns store unchanged.
+func NewEncryptingStore(store brokerstore.Store, hexKey string) (brokerstore.Store, error) {
+	if hexKey == "" {
+		return store, nil
+	}
+
+	key, err := hex.DecodeString(hexKey)
+	if err != nil || len(key) != 32 {
+		return nil, errors.New("store encryption key must be 64 hex digits")
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &EncryptingStore{Store: store, aead: aead}, nil
+}
+
+func (s *EncryptingStore) RetrieveInstanceDetails(id string) (brokerstore.ServiceInstance, error) {
+	details, err := s.Store.RetrieveInstanceDetails(id)
+	if err != nil {
+		return details, err
+	}
+
+	sealed, ok := details.ServiceFingerPrint.(string)
+	if !ok || !strings.HasPrefix(sealed, encryptedPrefix) {
+		return details, nil
+	}
+
+	plaintext, err := s.open(sealed)
+	if err != nil {
+		return brokerstore.ServiceInstance{}, fmt.Errorf("cannot decrypt instance %s: %s", id, err.Error())
+	}
+
+	var fingerprint interface{}
+	err = json.Unmarshal(plaintext, &fingerprint)
+	if err != nil {
+		return brokerstore.ServiceInstance{}, err
+	}
+	details.ServiceFingerPrint = fingerprint
+	return details, nil
+}
+
+func (s *EncryptingStore) CreateInstanceDetails(id string, details brokerstore.ServiceInstance) error {
+	if details.ServiceFingerPrint != nil {
+		plaintext, err := json.Marshal(details.ServiceFingerPrint)
+		if err != nil {
+			return err
+		}
+		details.ServiceFingerPrint = s.seal(plaintext)
+	}
+	return s.Store.CreateInstanceDetails(id, details)
+}
+
+func (s *EncryptingStore) RetrieveBindingDetails(id string) (brokerapi.BindDetails, error) {
+	details, err := s.Store.RetrieveBindingDetails(id)
+	if err != nil {
+		return details, err
+	}
+
+	details.RawParameters, err = s.openRaw(details.RawParameters)
+	if err != nil {
+		return brokerapi.BindDetails{}, fmt.Errorf("cannot decrypt binding %s: %s", id, err.Error())
+	}
+	details.RawContext, err = s.openRaw(details.RawContext)
+	if err != nil {
+		return brokerapi.BindDetails{}, fmt.Errorf("cannot decrypt binding %s: %s", id, err.Error())
+	}
+	return details, nil
+}
+
+func (s *EncryptingStore) CreateBindingDetails(id string, details brokerapi.BindDetails) error {
+	details.RawParameters = s.sealRaw(details.RawParameters)
+	details.RawContext = s.sealRaw(details.RawContext)
+	return s.Store.CreateBindingDetails(id, details)
+}
+
+// IsInstanceConflict compares the decrypted instance, as the wrapped store
+// only sees ciphertext that differs on every write.
+func (s *EncryptingStore) IsInstanceConflict(id string, details brokerstore.ServiceInstance) bool {
+	existing, err := s.RetrieveInstanceDetails(id)
+	if err != nil {
+		return false
+	}
+	return !sameJSON(existing, details)
+}
+
+func (s *EncryptingStore) IsBindingConflict(id string, details brokerapi.BindDetails) bool {
+	existing, err := s.RetrieveBindingDetails(id)
+	if err != nil {
+		return false
+	}
+	return !sameJSON(existing, details)
+}
+
+func (s *EncryptingStore) seal(plaintext []byte) string {
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		panic(err)
+	}
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(s.aead.Seal(nonce, nonce, plaintext, nil))
+}
+
+func (s *EncryptingStore) open(sealed string) ([]byte, error) {
+	ciphertext, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(sealed, encryptedPrefix))
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < s.aead.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	nonce, ciphertext := ciphertext[:s.aead.NonceSize()], ciphertext[s.aead.NonceSize():]
+	return s.aead.Open(nil, nonce, ciphertext, nil)
+}
+
+// sealRaw keeps a raw JSON field valid JSON by storing its ciphertext as a
+// JSON string.
+func (s *EncryptingStore) sealRaw(raw json.RawMessage) json.RawMessage {
+	if len(raw) == 0 {
+		return raw
+	}
+	sealed, _ := json.Marshal(s.seal(raw))
+	return sealed
+}
+
+func (s *EncryptingStore) openRaw(raw json.RawMessage) (json.RawMessage, error) {
+	var sealed string
+	if err := json.Unmarshal(raw, &sealed); err != nil || !strings.HasPrefix(sealed, encryptedPrefix) {
+		return raw, nil
+	}
+	return s.open(sealed)
+}
+
+// sameJSON reports whether two values have the same JSON form, so that a
+// typed fingerprint matches the untyped one decrypted from the store.
+func sameJSON(a, b interface{}) bool {
+	var decodedA, decodedB interface{}
+	rawA, errA := json.Marshal(a)
+	rawB, errB := json.Marshal(b)
+	if errA != nil || errB != nil {
+		return false
+	}
+	if json.Unmarshal(rawA, &decodedA) != nil || json.Unmarshal(rawB, &decodedB) != nil {
+		return false
+	}
+	return reflect.DeepEqual(decodedA, decodedB)
+}
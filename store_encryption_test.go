@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"code.cloudfoundry.org/service-broker-store/brokerstore"
+	"code.cloudfoundry.org/service-broker-store/brokerstore/brokerstorefakes"
+	"github.com/pivotal-cf/brokerapi"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("EncryptingStore", func() {
+	const (
+		key      = "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"
+		otherKey = "1f1e1d1c1b1a191817161514131211100f0e0d0c0b0a09080706050403020100"
+	)
+
+	var (
+		fakeStore *brokerstorefakes.FakeStore
+		store     brokerstore.Store
+		instance  brokerstore.ServiceInstance
+		err       error
+	)
+
+	BeforeEach(func() {
+		fakeStore = &brokerstorefakes.FakeStore{}
+		store, err = NewEncryptingStore(fakeStore, key)
+		Expect(err).NotTo(HaveOccurred())
+
+		instance = brokerstore.ServiceInstance{
+			ServiceID: "some-service-id",
+			PlanID:    "some-plan-id",
+			ServiceFingerPrint: map[string]interface{}{
+				"Name":   "some-instance-id",
+				"Volume": map[string]interface{}{"spec": map[string]interface{}{"nfs": map[string]interface{}{"server": "10.0.0.5"}}},
+			},
+		}
+	})
+
+	// stored returns what the wrapped store was given for the instance, as a
+	// file backed store would write it.
+	stored := func() []byte {
+		Expect(fakeStore.CreateInstanceDetailsCallCount()).To(Equal(1))
+		_, details := fakeStore.CreateInstanceDetailsArgsForCall(0)
+		raw, err := json.Marshal(details)
+		Expect(err).NotTo(HaveOccurred())
+		return raw
+	}
+
+	restore := func(raw []byte) {
+		var details brokerstore.ServiceInstance
+		Expect(json.Unmarshal(raw, &details)).To(Succeed())
+		fakeStore.RetrieveInstanceDetailsReturns(details, nil)
+	}
+
+	It("does not hand the fingerprint to the wrapped store in plaintext", func() {
+		Expect(store.CreateInstanceDetails("some-instance-id", instance)).To(Succeed())
+
+		raw := stored()
+		Expect(string(raw)).NotTo(ContainSubstring("10.0.0.5"))
+		Expect(string(raw)).To(ContainSubstring("some-service-id"))
+	})
+
+	It("decrypts the fingerprint it stored", func() {
+		Expect(store.CreateInstanceDetails("some-instance-id", instance)).To(Succeed())
+		restore(stored())
+
+		details, err := store.RetrieveInstanceDetails("some-instance-id")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(details).To(Equal(instance))
+	})
+
+	It("cannot read the stored fingerprint without the key", func() {
+		Expect(store.CreateInstanceDetails("some-instance-id", instance)).To(Succeed())
+		restore(stored())
+
+		otherStore, err := NewEncryptingStore(fakeStore, otherKey)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = otherStore.RetrieveInstanceDetails("some-instance-id")
+		Expect(err).To(MatchError(ContainSubstring("cannot decrypt instance some-instance-id")))
+
+		details, err := fakeStore.RetrieveInstanceDetails("some-instance-id")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(details.ServiceFingerPrint).To(BeAssignableToTypeOf(""))
+		Expect(strings.HasPrefix(details.ServiceFingerPrint.(string), "aes256gcm:")).To(BeTrue())
+	})
+
+	It("returns fingerprints stored before encryption was turned on", func() {
+		fakeStore.RetrieveInstanceDetailsReturns(instance, nil)
+
+		details, err := store.RetrieveInstanceDetails("some-instance-id")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(details).To(Equal(instance))
+	})
+
+	It("passes retrieve errors through", func() {
+		fakeStore.RetrieveInstanceDetailsReturns(brokerstore.ServiceInstance{}, errors.New("not found"))
+
+		_, err := store.RetrieveInstanceDetails("some-instance-id")
+		Expect(err).To(MatchError("not found"))
+	})
+
+	It("compares instances by their decrypted fingerprint", func() {
+		Expect(store.CreateInstanceDetails("some-instance-id", instance)).To(Succeed())
+		restore(stored())
+
+		Expect(store.IsInstanceConflict("some-instance-id", instance)).To(BeFalse())
+
+		instance.PlanID = "other-plan-id"
+		Expect(store.IsInstanceConflict("some-instance-id", instance)).To(BeTrue())
+	})
+
+	Describe("bindings", func() {
+		var binding brokerapi.BindDetails
+
+		BeforeEach(func() {
+			binding = brokerapi.BindDetails{
+				AppGUID:       "some-app-guid",
+				RawParameters: json.RawMessage(`{"uid":"1000","secret":"hunter2"}`),
+			}
+		})
+
+		It("encrypts the parameters and decrypts them again", func() {
+			Expect(store.CreateBindingDetails("some-binding-id", binding)).To(Succeed())
+
+			_, details := fakeStore.CreateBindingDetailsArgsForCall(0)
+			raw, err := json.Marshal(details)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(raw)).NotTo(ContainSubstring("hunter2"))
+			Expect(details.RawContext).To(BeEmpty())
+
+			var restored brokerapi.BindDetails
+			Expect(json.Unmarshal(raw, &restored)).To(Succeed())
+			fakeStore.RetrieveBindingDetailsReturns(restored, nil)
+
+			retrieved, err := store.RetrieveBindingDetails("some-binding-id")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(retrieved.AppGUID).To(Equal("some-app-guid"))
+			Expect(retrieved.RawParameters).To(MatchJSON(`{"uid":"1000","secret":"hunter2"}`))
+			Expect(store.IsBindingConflict("some-binding-id", binding)).To(BeFalse())
+		})
+	})
+
+	Context("when no key is given", func() {
+		It("returns the wrapped store", func() {
+			store, err := NewEncryptingStore(fakeStore, "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(store).To(BeIdenticalTo(fakeStore))
+		})
+	})
+
+	Context("when the key is not 32 bytes of hex", func() {
+		It("errors", func() {
+			_, err := NewEncryptingStore(fakeStore, "abcd")
+			Expect(err).To(MatchError("store encryption key must be 64 hex digits"))
+		})
+	})
+})
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/service-broker-store/brokerstore"
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"github.com/pivotal-cf/brokerapi"
+)
+
+// BrokerStoreError gives a database error from the broker store a message an
+// operator can act on. The original error is kept for logging.
+type BrokerStoreError struct {
+	Message string
+	Err     error
+}
+
+func (e BrokerStoreError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Message, e.Err.Error())
+}
+
+func (e BrokerStoreError) Unwrap() error {
+	return e.Err
+}
+
+func ConvertPostgresError(err *pq.Error) BrokerStoreError {
+	switch err.Code {
+	case "23505":
+		return BrokerStoreError{Message: "instance or binding already exists", Err: err}
+	case "42P01":
+		return BrokerStoreError{Message: "database schema is not migrated", Err: err}
+	default:
+		return BrokerStoreError{Message: fmt.Sprintf("database error %s", err.Code), Err: err}
+	}
+}
+
+func ConvertMySqlError(err mysql.MySQLError) BrokerStoreError {
+	switch err.Number {
+	case 1062:
+		return BrokerStoreError{Message: "instance or binding already exists", Err: &err}
+	default:
+		return BrokerStoreError{Message: fmt.Sprintf("database error %d", err.Number), Err: &err}
+	}
+}
+
+func convertStoreError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return ConvertPostgresError(pqErr)
+	}
+
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return ConvertMySqlError(*mysqlErr)
+	}
+
+	return err
+}
+
+// errorConvertingStore passes every error returned by the wrapped store
+// through convertStoreError.
+type errorConvertingStore struct {
+	brokerstore.Store
+}
+
+func (s errorConvertingStore) RetrieveInstanceDetails(id string) (brokerstore.ServiceInstance, error) {
+	details, err := s.Store.RetrieveInstanceDetails(id)
+	return details, convertStoreError(err)
+}
+
+func (s errorConvertingStore) RetrieveBindingDetails(id string) (brokerapi.BindDetails, error) {
+	details, err := s.Store.RetrieveBindingDetails(id)
+	return details, convertStoreError(err)
+}
+
+func (s errorConvertingStore) CreateInstanceDetails(id string, details brokerstore.ServiceInstance) error {
+	return convertStoreError(s.Store.CreateInstanceDetails(id, details))
+}
+
+func (s errorConvertingStore) CreateBindingDetails(id string, details brokerapi.BindDetails) error {
+	return convertStoreError(s.Store.CreateBindingDetails(id, details))
+}
+
+func (s errorConvertingStore) DeleteInstanceDetails(id string) error {
+	return convertStoreError(s.Store.DeleteInstanceDetails(id))
+}
+
+func (s errorConvertingStore) DeleteBindingDetails(id string) error {
+	return convertStoreError(s.Store.DeleteBindingDetails(id))
+}
+
+func (s errorConvertingStore) Restore(logger lager.Logger) error {
+	return convertStoreError(s.Store.Restore(logger))
+}
+
+func (s errorConvertingStore) Save(logger lager.Logger) error {
+	return convertStoreError(s.Store.Save(logger))
+}
+
+func (s errorConvertingStore) Cleanup() error {
+	return convertStoreError(s.Store.Cleanup())
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/service-broker-store/brokerstore"
+	"code.cloudfoundry.org/service-broker-store/brokerstore/brokerstorefakes"
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Store errors", func() {
+	Describe("ConvertPostgresError", func() {
+		It("reports unique violations as an existing instance or binding", func() {
+			pqErr := &pq.Error{Code: "23505", Message: "duplicate key value"}
+			err := ConvertPostgresError(pqErr)
+			Expect(err.Message).To(Equal("instance or binding already exists"))
+			Expect(errors.Is(err, pqErr)).To(BeTrue())
+		})
+
+		It("reports a missing relation as an unmigrated schema", func() {
+			err := ConvertPostgresError(&pq.Error{Code: "42P01", Message: "relation does not exist"})
+			Expect(err.Message).To(Equal("database schema is not migrated"))
+			Expect(err.Error()).To(Equal("database schema is not migrated: pq: relation does not exist"))
+		})
+
+		It("keeps the code of other errors", func() {
+			err := ConvertPostgresError(&pq.Error{Code: "08006"})
+			Expect(err.Message).To(Equal("database error 08006"))
+		})
+	})
+
+	Describe("ConvertMySqlError", func() {
+		It("reports duplicate entries as an existing instance or binding", func() {
+			err := ConvertMySqlError(mysql.MySQLError{Number: 1062, Message: "Duplicate entry"})
+			Expect(err.Message).To(Equal("instance or binding already exists"))
+			Expect(err.Err).To(Equal(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry"}))
+		})
+
+		It("keeps the number of other errors", func() {
+			err := ConvertMySqlError(mysql.MySQLError{Number: 1045})
+			Expect(err.Message).To(Equal("database error 1045"))
+		})
+	})
+
+	Describe("errorConvertingStore", func() {
+		var (
+			fakeStore *brokerstorefakes.FakeStore
+			store     errorConvertingStore
+		)
+
+		BeforeEach(func() {
+			fakeStore = &brokerstorefakes.FakeStore{}
+			store = errorConvertingStore{fakeStore}
+		})
+
+		It("converts wrapped database errors", func() {
+			fakeStore.SaveReturns(fmt.Errorf("save failed: %w", &pq.Error{Code: "42P01"}))
+			err := store.Save(lagertest.NewTestLogger("test"))
+			Expect(err).To(BeAssignableToTypeOf(BrokerStoreError{}))
+			Expect(err.(BrokerStoreError).Message).To(Equal("database schema is not migrated"))
+		})
+
+		It("passes other errors through unchanged", func() {
+			storeErr := errors.New("not found")
+			fakeStore.RetrieveInstanceDetailsReturns(brokerstore.ServiceInstance{}, storeErr)
+			_, err := store.RetrieveInstanceDetails("some-instance-id")
+			Expect(err).To(Equal(storeErr))
+		})
+
+		It("returns nil when the store succeeds", func() {
+			Expect(store.CreateInstanceDetails("some-instance-id", brokerstore.ServiceInstance{})).To(Succeed())
+		})
+	})
+})
@@ -0,0 +1,48 @@
+package utils
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/tedsuo/ifrit"
+)
+
+// NewGracefulServer serves handler on address, over TLS when tlsConfig is not
+// nil. When signalled it stops accepting connections and waits up to
+// gracePeriod for in-flight requests to finish before closing the rest.
+func NewGracefulServer(address string, handler http.Handler, tlsConfig *tls.Config, gracePeriod time.Duration) ifrit.Runner {
+	return ifrit.RunFunc(func(signals <-chan os.Signal, ready chan<- struct{}) error {
+		listener, err := net.Listen("tcp", address)
+		if err != nil {
+			return err
+		}
+		if tlsConfig != nil {
+			listener = tls.NewListener(listener, tlsConfig)
+		}
+
+		server := &http.Server{Handler: handler}
+		serveErr := make(chan error, 1)
+		go func() {
+			serveErr <- server.Serve(listener)
+		}()
+		close(ready)
+
+		select {
+		case err := <-serveErr:
+			return err
+		case <-signals:
+			ctx, cancel := context.WithTimeout(context.Background(), gracePeriod)
+			defer cancel()
+
+			err := server.Shutdown(ctx)
+			if err != nil {
+				server.Close()
+			}
+			return err
+		}
+	})
+}
@@ -0,0 +1,73 @@
+package vault
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type Credentials struct {
+	Username string
+	Password string
+}
+
+// Client reads secrets through the Vault HTTP API.
+type Client struct {
+	address    string
+	token      string
+	httpClient *http.Client
+}
+
+func NewClient(address, token string) *Client {
+	return &Client{
+		address:    strings.TrimSuffix(address, "/"),
+		token:      token,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+type secretResponse struct {
+	Data map[string]interface{} `json:"data"`
+}
+
+// ReadCredentials reads the username and password keys of the secret at path,
+// e.g. "secret/k8sbroker". A version 2 key/value engine nests the keys in a
+// second "data" object, e.g. for the path "secret/data/k8sbroker".
+func (c *Client) ReadCredentials(path string) (Credentials, error) {
+	req, err := http.NewRequest(http.MethodGet, c.address+"/v1/"+strings.TrimPrefix(path, "/"), nil)
+	if err != nil {
+		return Credentials{}, err
+	}
+	req.Header.Set("X-Vault-Token", c.token)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return Credentials{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Credentials{}, fmt.Errorf("reading vault secret %s: unexpected status %d", path, resp.StatusCode)
+	}
+
+	var secret secretResponse
+	err = json.NewDecoder(resp.Body).Decode(&secret)
+	if err != nil {
+		return Credentials{}, err
+	}
+
+	data := secret.Data
+	if nested, ok := data["data"].(map[string]interface{}); ok {
+		data = nested
+	}
+
+	username, _ := data["username"].(string)
+	password, _ := data["password"].(string)
+	if username == "" || password == "" {
+		return Credentials{}, fmt.Errorf("vault secret %s must have username and password keys", path)
+	}
+
+	return Credentials{Username: username, Password: password}, nil
+}
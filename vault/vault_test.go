@@ -0,0 +1,90 @@
+package vault_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	"code.cloudfoundry.org/k8sbroker/vault"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Client", func() {
+	var (
+		server      *httptest.Server
+		status      int
+		body        string
+		request     *http.Request
+		credentials vault.Credentials
+		err         error
+	)
+
+	BeforeEach(func() {
+		status = http.StatusOK
+		body = `{"data": {"username": "some-user", "password": "some-password"}}`
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			request = r
+			w.WriteHeader(status)
+			w.Write([]byte(body))
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	JustBeforeEach(func() {
+		credentials, err = vault.NewClient(server.URL+"/", "some-token").ReadCredentials("secret/k8sbroker")
+	})
+
+	It("reads the secret with the token", func() {
+		Expect(err).NotTo(HaveOccurred())
+		Expect(request.Method).To(Equal(http.MethodGet))
+		Expect(request.URL.Path).To(Equal("/v1/secret/k8sbroker"))
+		Expect(request.Header.Get("X-Vault-Token")).To(Equal("some-token"))
+		Expect(credentials).To(Equal(vault.Credentials{Username: "some-user", Password: "some-password"}))
+	})
+
+	Context("when the secret is in a version 2 key/value engine", func() {
+		BeforeEach(func() {
+			body = `{"data": {"data": {"username": "some-user", "password": "some-password"}, "metadata": {"version": 3}}}`
+		})
+
+		It("reads the nested keys", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(credentials).To(Equal(vault.Credentials{Username: "some-user", Password: "some-password"}))
+		})
+	})
+
+	Context("when the secret has no password", func() {
+		BeforeEach(func() {
+			body = `{"data": {"username": "some-user"}}`
+		})
+
+		It("errors", func() {
+			Expect(err).To(MatchError("vault secret secret/k8sbroker must have username and password keys"))
+		})
+	})
+
+	Context("when vault refuses the request", func() {
+		BeforeEach(func() {
+			status = http.StatusForbidden
+			body = `{"errors": ["permission denied"]}`
+		})
+
+		It("errors with the status", func() {
+			Expect(err).To(MatchError("reading vault secret secret/k8sbroker: unexpected status 403"))
+		})
+	})
+
+	Context("when the response is not JSON", func() {
+		BeforeEach(func() {
+			body = "not json"
+		})
+
+		It("errors", func() {
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,145 @@
+package vcap
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+var defaultPorts = map[string]string{
+	"mysql":    "3306",
+	"postgres": "5432",
+}
+
+var uriSchemes = map[string][]string{
+	"mysql":    {"mysql"},
+	"postgres": {"postgres", "postgresql"},
+}
+
+type service struct {
+	Name        string                 `json:"name"`
+	Credentials map[string]interface{} `json:"credentials"`
+}
+
+// ParseVCAPServices returns the database credentials of the named service in
+// vcap, the content of VCAP_SERVICES. MySQL and Postgres services name their
+// credentials differently, so each field is read from the first of its known
+// keys, and from the service's uri when none is given. The port defaults to
+// the driver's usual port. The CA cert is empty when the service has none.
+func ParseVCAPServices(vcap, driver, serviceName string) (username, password, host, port, dbName, caCert string, err error) {
+	schemes, ok := uriSchemes[driver]
+	if !ok {
+		return "", "", "", "", "", "", fmt.Errorf("dbDriver %q cannot be read from VCAP_SERVICES, it must be mysql or postgres", driver)
+	}
+	if vcap == "" {
+		return "", "", "", "", "", "", errors.New("VCAP_SERVICES is not set")
+	}
+
+	var servicesByLabel map[string][]service
+	err = json.Unmarshal([]byte(vcap), &servicesByLabel)
+	if err != nil {
+		return "", "", "", "", "", "", fmt.Errorf("cannot parse VCAP_SERVICES: %s", err.Error())
+	}
+
+	credentials, found := findCredentials(servicesByLabel, serviceName)
+	if !found {
+		return "", "", "", "", "", "", fmt.Errorf("service %s not found in VCAP_SERVICES", serviceName)
+	}
+
+	uri := &url.URL{}
+	if rawURI := getString(credentials, "uri", "url"); rawURI != "" {
+		uri, err = url.Parse(rawURI)
+		if err != nil {
+			return "", "", "", "", "", "", fmt.Errorf("service %s has an invalid uri", serviceName)
+		}
+		if !contains(schemes, uri.Scheme) {
+			return "", "", "", "", "", "", fmt.Errorf("service %s has a %s uri, not a %s one", serviceName, uri.Scheme, driver)
+		}
+	}
+
+	username = getString(credentials, "username", "user")
+	if username == "" {
+		username = uri.User.Username()
+	}
+	password = getString(credentials, "password")
+	if password == "" {
+		password, _ = uri.User.Password()
+	}
+	host = getString(credentials, "hostname", "host")
+	if host == "" {
+		host = uri.Hostname()
+	}
+	port = getString(credentials, "port")
+	if port == "" {
+		port = uri.Port()
+	}
+	if port == "" {
+		port = defaultPorts[driver]
+	}
+	dbName = getString(credentials, "name", "db_name", "database")
+	if dbName == "" {
+		dbName = strings.TrimPrefix(uri.Path, "/")
+	}
+
+	caCert = getString(credentials, "ca_cert", "sslrootcert")
+	if tls, ok := credentials["tls"].(map[string]interface{}); ok && caCert == "" {
+		if cert, ok := tls["cert"].(map[string]interface{}); ok {
+			caCert = getString(cert, "ca")
+		}
+	}
+
+	if username == "" || password == "" || host == "" || dbName == "" {
+		return "", "", "", "", "", "", fmt.Errorf("service %s in VCAP_SERVICES needs username, password, host and database name credentials", serviceName)
+	}
+	if number, err := strconv.Atoi(port); err != nil || number <= 0 || number > 65535 {
+		return "", "", "", "", "", "", fmt.Errorf("service %s has an invalid port %q", serviceName, port)
+	}
+
+	return username, password, host, port, dbName, caCert, nil
+}
+
+func findCredentials(servicesByLabel map[string][]service, serviceName string) (map[string]interface{}, bool) {
+	for _, services := range servicesByLabel {
+		for _, service := range services {
+			if service.Name == serviceName {
+				return service.Credentials, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// getString returns the value of the first of keys in data. Numbers, such as
+// ports, are returned as integers.
+func getString(data map[string]interface{}, keys ...string) string {
+	value := getByAlias(data, keys...)
+	switch value := value.(type) {
+	case string:
+		return value
+	case float64:
+		return fmt.Sprintf("%.0f", value)
+	}
+	return ""
+}
+
+func getByAlias(data map[string]interface{}, keys ...string) interface{} {
+	for _, key := range keys {
+		value, ok := data[key]
+		if ok {
+			return value
+		}
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}